@@ -19,7 +19,9 @@ Examples:
   gridhouse benchmark --requests 10000 --concurrency 10
   gridhouse benchmark --commands SET,GET,INCR --requests 5000
   gridhouse benchmark --pipeline 10 --requests 10000
-  gridhouse benchmark --latency-hist --requests 1000`,
+  gridhouse benchmark -s /run/gridhouse.sock
+  gridhouse benchmark --latency-hist --requests 1000
+  gridhouse benchmark --tls --cacert ca.crt --cert client.crt --key client.key`,
 	Run: runBenchmark,
 }
 
@@ -29,9 +31,14 @@ func init() {
 	// Connection flags
 	benchmarkCmd.Flags().String("host", "127.0.0.1", "Redis server host")
 	benchmarkCmd.Flags().IntP("port", "p", 6380, "Redis server port")
+	benchmarkCmd.Flags().StringP("socket", "s", "", "Redis server Unix socket (overrides host and port)")
 	benchmarkCmd.Flags().String("password", "", "Redis server password")
 	benchmarkCmd.Flags().Int("db", 0, "Redis database number")
 	benchmarkCmd.Flags().Bool("tls", false, "Use TLS connection")
+	benchmarkCmd.Flags().String("cacert", "", "CA certificate file to verify the server with (default: system CAs)")
+	benchmarkCmd.Flags().String("cert", "", "Client certificate file to authenticate with")
+	benchmarkCmd.Flags().String("key", "", "Private key file of the client certificate")
+	benchmarkCmd.Flags().Bool("insecure", false, "Skip verifying the server certificate")
 
 	// Benchmark configuration
 	benchmarkCmd.Flags().Int("requests", 10000, "Total number of requests")
@@ -57,6 +64,7 @@ func runBenchmark(cmd *cobra.Command, _ []string) {
 	config := &benchmark.BenchmarkConfig{
 		Host:        getStringFlag(cmd, "host", "127.0.0.1"),
 		Port:        getIntFlag(cmd, "port", 6380),
+		Socket:      getStringFlag(cmd, "socket", ""),
 		Password:    getStringFlag(cmd, "password", ""),
 		Database:    getIntFlag(cmd, "db", 0),
 		Requests:    getIntFlag(cmd, "requests", 10000),
@@ -65,6 +73,10 @@ func runBenchmark(cmd *cobra.Command, _ []string) {
 		Timeout:     getDurationFlag(cmd, "timeout", 5*time.Second),
 		KeepAlive:   getBoolFlag(cmd, "keep-alive"),
 		TLS:         getBoolFlag(cmd, "tls"),
+		CACert:      getStringFlag(cmd, "cacert", ""),
+		Cert:        getStringFlag(cmd, "cert", ""),
+		Key:         getStringFlag(cmd, "key", ""),
+		Insecure:    getBoolFlag(cmd, "insecure"),
 		Commands:    strings.Split(getStringFlag(cmd, "commands", "PING,SET,GET,INCR,LPUSH,RPUSH,LPOP,RPOP,SADD,HSET,SPOP,ZADD,ZPOPMIN,LRANGE,MSET"), ","),
 		DataSize:    getIntFlag(cmd, "data-size", 2),
 		KeyPattern:  getStringFlag(cmd, "key-pattern", "key:__rand_int__"),
@@ -83,7 +95,11 @@ func runBenchmark(cmd *cobra.Command, _ []string) {
 	if !config.Quiet {
 		fmt.Printf("Redis Benchmark Tool\n")
 		fmt.Printf("===================\n")
-		fmt.Printf("Host: %s:%d\n", config.Host, config.Port)
+		if config.Socket != "" {
+			fmt.Printf("Socket: %s\n", config.Socket)
+		} else {
+			fmt.Printf("Host: %s:%d\n", config.Host, config.Port)
+		}
 		fmt.Printf("Requests: %d\n", config.Requests)
 		fmt.Printf("Concurrency: %d\n", config.Concurrency)
 		fmt.Printf("Pipeline: %d\n", config.Pipeline)
@@ -18,16 +18,23 @@ Connect to a GridHouse server and execute commands interactively or in batch mod
 Examples:
   gridhouse cli
   gridhouse cli --host 127.0.0.1 --port 6380
+  gridhouse cli -s /run/gridhouse.sock
   gridhouse cli --eval "SET key value"
-  gridhouse cli --file commands.txt`,
+  gridhouse cli --file commands.txt
+  gridhouse cli --tls --cacert ca.crt --cert client.crt --key client.key`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cli.RunCLI(&cli.CLIConfig{
 			Host:     getStringFlag(cmd, "host", "127.0.0.1"),
 			Port:     getIntFlag(cmd, "port", 6380),
+			Socket:   getStringFlag(cmd, "socket", ""),
 			Password: getStringFlag(cmd, "password", ""),
 			Database: getIntFlag(cmd, "db", 0),
 			Timeout:  getDurationFlag(cmd, "timeout", 5*time.Second),
 			TLS:      getBoolFlag(cmd, "tls"),
+			CACert:   getStringFlag(cmd, "cacert", ""),
+			Cert:     getStringFlag(cmd, "cert", ""),
+			Key:      getStringFlag(cmd, "key", ""),
+			Insecure: getBoolFlag(cmd, "insecure"),
 			Raw:      getBoolFlag(cmd, "raw"),
 			Eval:     getStringFlag(cmd, "eval", ""),
 			File:     getStringFlag(cmd, "file", ""),
@@ -42,10 +49,15 @@ func init() {
 	// Connection flags
 	cliCmd.Flags().String("host", "127.0.0.1", "GridHouse server host")
 	cliCmd.Flags().IntP("port", "p", 6380, "GridHouse server port")
+	cliCmd.Flags().StringP("socket", "s", "", "GridHouse server Unix socket (overrides host and port)")
 	cliCmd.Flags().StringP("password", "a", "", "GridHouse server password")
 	cliCmd.Flags().IntP("db", "d", 0, "Database number")
 	cliCmd.Flags().Duration("timeout", 5*time.Second, "Connection timeout")
 	cliCmd.Flags().Bool("tls", false, "Use TLS connection")
+	cliCmd.Flags().String("cacert", "", "CA certificate file to verify the server with (default: system CAs)")
+	cliCmd.Flags().String("cert", "", "Client certificate file to authenticate with")
+	cliCmd.Flags().String("key", "", "Private key file of the client certificate")
+	cliCmd.Flags().Bool("insecure", false, "Skip verifying the server certificate")
 
 	// Input/output flags
 	cliCmd.Flags().Bool("raw", false, "Use raw formatting for replies")
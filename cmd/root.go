@@ -19,48 +19,43 @@ import (
 const defaultReadBuffer = 256 * 1024
 const defaultWriteBuffer = 0
 const maxConnections = 1000
+const defaultDatabases = 16
 
 // rootCmd represents base command when called without subcommands
 var rootCmd = &cobra.Command{
-	Use:   "gridhouse",
+	Use:   "gridhouse [config-file]",
 	Short: "A Redis-compatible in-memory database server",
 	Long: `A Redis-compatible in-memory database server built in Go.
-Supports basic Redis commands like SET, GET, PING, ECHO with TTL support.`,
+Supports basic Redis commands like SET, GET, PING, ECHO with TTL support.
+
+Settings are read from the optional redis.conf style config file; flags
+given on the command line override it.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Initialize logger
 		logLevel := logger.LogLevel(getStringFlag(cmd, "log-level", "info"))
 		logger.Init(logLevel)
 
-		// Get persistence configuration from flags
-		var persistConfig = &persistence.Config{
-			Dir: getStringFlag(cmd, "dir", "./data"),
-		}
-
-		if getBoolFlag(cmd, "aof") {
-			persistConfig.AOFEnabled = true
-			persistConfig.AOFSyncMode = getAOFSyncMode(cmd)
-			persistConfig.AOFRewriteConfig = getAOFRewriteConfig(cmd)
-			persistConfig.RDBEnabled = getBoolFlag(cmd, "rdb")
-		}
-
-		if getBoolFlag(cmd, "rdb") {
-			persistConfig.RDBEnabled = true
-			persistConfig.RDBSaveConfig = &persistence.RDBSaveConfig{
-				SaveInterval: time.Duration(getIntFlag(cmd, "save-interval", 300)) * time.Second,
-				MinChanges:   getIntFlag(cmd, "min-changes", 1),
+		cfg := server.Config{Persistence: &persistence.Config{}, TLS: &server.TLSConfig{}}
+		applyFlags(cmd, &cfg, false)
+		if len(args) == 1 {
+			if err := server.LoadConfigFile(args[0], &cfg); err != nil {
+				logger.Errorf("Failed to load config file: %v", err)
+				os.Exit(1)
 			}
+			applyFlags(cmd, &cfg, true)
+		}
+		persistConfig := cfg.Persistence
+		if persistConfig.RDBEnabled && (len(persistConfig.SaveRules) == 0 ||
+			cmd.Flags().Changed("save-interval") || cmd.Flags().Changed("min-changes")) {
+			persistConfig.SaveRules = []persistence.SaveRule{{
+				Interval: time.Duration(getIntFlag(cmd, "save-interval", 300)) * time.Second,
+				Changes:  getIntFlag(cmd, "min-changes", 1),
+			}}
 		}
 
 		// Create server with ultra-fast execution
-		srv := server.New(server.Config{
-			Addr:           getStringFlag(cmd, "port", ":6380"),
-			Persistence:    persistConfig,
-			Password:       getStringFlag(cmd, "requirepass", ""),
-			SlaveOf:        getStringFlag(cmd, "slaveof", ""),
-			ReadBuffer:     getIntFlag(cmd, "read-buffer", defaultReadBuffer),
-			WriteBuffer:    getIntFlag(cmd, "write-buffer", defaultWriteBuffer),
-			MaxConnections: getInt64Flag(cmd, "max-connections", maxConnections),
-		})
+		srv := server.New(cfg)
 
 		// Start server
 		if err := srv.Start(); err != nil {
@@ -68,7 +63,11 @@ Supports basic Redis commands like SET, GET, PING, ECHO with TTL support.`,
 			os.Exit(1)
 		}
 
-		logger.Infof("Server started on %s", srv.Addr())
+		addr := srv.Addr()
+		if addr == "" {
+			addr = srv.TLSAddr()
+		}
+		logger.Infof("Server started on %s", addr)
 		if persistConfig.RDBEnabled || persistConfig.AOFEnabled {
 			logger.Infof("Persistence enabled - AOF: %v, RDB: %v", persistConfig.AOFEnabled, persistConfig.RDBEnabled)
 		}
@@ -85,6 +84,102 @@ Supports basic Redis commands like SET, GET, PING, ECHO with TTL support.`,
 	},
 }
 
+// applyFlags copies the flags into cfg; with changedOnly it only copies the
+// flags given on the command line, which override the config file
+func applyFlags(cmd *cobra.Command, cfg *server.Config, changedOnly bool) {
+	set := func(name string) bool {
+		return !changedOnly || cmd.Flags().Changed(name)
+	}
+	persistConfig := cfg.Persistence
+	tlsConfig := cfg.TLS
+
+	if set("log-level") {
+		cfg.LogLevel = logger.LogLevel(getStringFlag(cmd, "log-level", "info"))
+	}
+	if set("dir") {
+		persistConfig.Dir = getStringFlag(cmd, "dir", "./data")
+	}
+	if set("aof") {
+		persistConfig.AOFEnabled = getBoolFlag(cmd, "aof")
+	}
+	if set("aof-sync") {
+		persistConfig.AOFSyncMode = getAOFSyncMode(cmd)
+	}
+	if set("aof-rewrite") || set("aof-rewrite-growth-threshold") || set("aof-rewrite-min-size") || set("aof-rewrite-percentage") {
+		persistConfig.AOFRewriteConfig = getAOFRewriteConfig(cmd)
+	}
+	if set("rdb") {
+		persistConfig.RDBEnabled = getBoolFlag(cmd, "rdb")
+	}
+	if set("port") {
+		cfg.Addr = getStringFlag(cmd, "port", ":6380")
+	}
+	if set("requirepass") {
+		cfg.Password = getStringFlag(cmd, "requirepass", "")
+	}
+	if set("aclfile") {
+		cfg.ACLFile = getStringFlag(cmd, "aclfile", "")
+	}
+	if set("slaveof") {
+		cfg.SlaveOf = getStringFlag(cmd, "slaveof", "")
+	}
+	if set("read-buffer") {
+		cfg.ReadBuffer = getIntFlag(cmd, "read-buffer", defaultReadBuffer)
+	}
+	if set("write-buffer") {
+		cfg.WriteBuffer = getIntFlag(cmd, "write-buffer", defaultWriteBuffer)
+	}
+	if set("max-connections") {
+		cfg.MaxConnections = getInt64Flag(cmd, "max-connections", maxConnections)
+	}
+	if set("databases") {
+		cfg.Databases = getIntFlag(cmd, "databases", defaultDatabases)
+	}
+	if set("maxmemory") {
+		cfg.MaxMemory = getInt64Flag(cmd, "maxmemory", 0)
+	}
+	if set("maxmemory-policy") {
+		cfg.MaxMemoryPolicy = getStringFlag(cmd, "maxmemory-policy", "noeviction")
+	}
+	if set("maxmemory-samples") {
+		cfg.MaxMemorySamples = getIntFlag(cmd, "maxmemory-samples", 5)
+	}
+	if set("notify-keyspace-events") {
+		cfg.NotifyKeyspaceEvents = getStringFlag(cmd, "notify-keyspace-events", "")
+	}
+	if set("metrics-addr") {
+		cfg.MetricsAddr = getStringFlag(cmd, "metrics-addr", "")
+	}
+	if set("unixsocket") {
+		cfg.UnixSocket = getStringFlag(cmd, "unixsocket", "")
+	}
+	if set("unixsocketperm") {
+		perm, _ := cmd.Flags().GetUint32("unixsocketperm")
+		cfg.UnixSocketPerm = os.FileMode(perm)
+	}
+	if set("tls-port") {
+		tlsConfig.Addr = getStringFlag(cmd, "tls-port", "")
+	}
+	if set("tls-cert-file") {
+		tlsConfig.CertFile = getStringFlag(cmd, "tls-cert-file", "")
+	}
+	if set("tls-key-file") {
+		tlsConfig.KeyFile = getStringFlag(cmd, "tls-key-file", "")
+	}
+	if set("tls-ca-cert-file") {
+		tlsConfig.CAFile = getStringFlag(cmd, "tls-ca-cert-file", "")
+	}
+	if set("tls-auth-clients") {
+		tlsConfig.AuthClients = getStringFlag(cmd, "tls-auth-clients", "yes")
+	}
+	if set("tls-auth-clients-user") {
+		tlsConfig.AuthClientsUser = getStringFlag(cmd, "tls-auth-clients-user", "off")
+	}
+	if set("tls-replication") {
+		tlsConfig.Replication = getBoolFlag(cmd, "tls-replication")
+	}
+}
+
 // Execute adds child commands to root and sets flags appropriately.
 // Called by main.main(). Only needs to happen once to rootCmd.
 func Execute() {
@@ -120,9 +215,27 @@ func init() {
 	rootCmd.Flags().Int("write-buffer", defaultWriteBuffer, "Writer buffer size")
 	rootCmd.Flags().Int("read-buffer", defaultReadBuffer, "Writer buffer size")
 	rootCmd.Flags().Int64("max-connections", maxConnections, "Maximum allowed connections from clients")
+	rootCmd.Flags().Int("databases", defaultDatabases, "Number of logical databases")
+	rootCmd.Flags().Int64("maxmemory", 0, "Memory limit of the keys in bytes (0: no limit)")
+	rootCmd.Flags().String("maxmemory-policy", "noeviction", "Keys evicted past maxmemory (noeviction, allkeys-lru, volatile-lru, allkeys-lfu, volatile-lfu, allkeys-random, volatile-random, volatile-ttl)")
+	rootCmd.Flags().Int("maxmemory-samples", 5, "Keys sampled to pick each evicted key")
+	rootCmd.Flags().String("notify-keyspace-events", "", "Keyspace notification classes, e.g. KEA (empty disables them)")
+	rootCmd.Flags().String("unixsocket", "", "Unix socket path to listen on as well (with it set, --port :0 disables TCP)")
+	rootCmd.Flags().Uint32("unixsocketperm", 0, "Unix socket permissions, e.g. 0770 (default: from the umask)")
+	rootCmd.Flags().String("metrics-addr", "", "Address serving Prometheus /metrics, /healthz and /readyz, e.g. :9121 (empty disables it)")
 
 	// auth
 	rootCmd.Flags().String("requirepass", "", "Password for AUTH command")
+	rootCmd.Flags().String("aclfile", "", "File storing ACL users, loaded at startup and by ACL LOAD")
+
+	// TLS
+	rootCmd.Flags().String("tls-port", "", "TLS listen address, e.g. :6390 (empty disables TLS; with it set, --port :0 disables plaintext)")
+	rootCmd.Flags().String("tls-cert-file", "", "Server certificate file (PEM)")
+	rootCmd.Flags().String("tls-key-file", "", "Server private key file (PEM)")
+	rootCmd.Flags().String("tls-ca-cert-file", "", "CA certificates client and master certificates are verified against (PEM)")
+	rootCmd.Flags().String("tls-auth-clients", "yes", "Require client certificates (no, optional, yes)")
+	rootCmd.Flags().String("tls-auth-clients-user", "off", "Authenticate clients as the ACL user named by their certificate (off, cn)")
+	rootCmd.Flags().Bool("tls-replication", false, "Connect to the master over TLS")
 
 	// cluster/replica
 	rootCmd.Flags().String("slaveof", "", "Replicate from master (format: host:port)")
@@ -0,0 +1,143 @@
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCatalog knows a few commands and categories
+type testCatalog struct{}
+
+func (testCatalog) HasCommand(name string) bool {
+	switch name {
+	case "get", "set", "config", "flushall", "publish":
+		return true
+	}
+	return false
+}
+
+func (testCatalog) HasCategory(name string) bool {
+	return name == "read" || name == "write" || name == "dangerous"
+}
+
+func TestUserRules(t *testing.T) {
+	u := NewUser("alice")
+	require.NoError(t, u.SetRules([]string{"on", ">secret", "~cache:*", "%R~ro:*", "&news.*",
+		"+@read", "+set", "-@dangerous", "+config|get"}, testCatalog{}))
+
+	assert.True(t, u.CheckPassword("secret"))
+	assert.False(t, u.CheckPassword("wrong"))
+	assert.Equal(t, "on #2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b ~cache:* %R~ro:* &news.* "+
+		"-@all +@read +set -@dangerous +config|get", u.Describe())
+
+	assert.True(t, u.CanRun("get", "", []string{"read"}))
+	assert.True(t, u.CanRun("set", "", []string{"write"}))
+	assert.False(t, u.CanRun("flushall", "", []string{"write", "dangerous"}))
+	assert.True(t, u.CanRun("config", "get", []string{"dangerous"}))
+	assert.False(t, u.CanRun("config", "set", []string{"dangerous"}))
+
+	assert.True(t, u.CanAccessKey("cache:1", true))
+	assert.True(t, u.CanAccessKey("ro:1", false))
+	assert.False(t, u.CanAccessKey("ro:1", true))
+	assert.False(t, u.CanAccessKey("other", false))
+
+	assert.True(t, u.CanAccessChannel("news.tech", false))
+	assert.False(t, u.CanAccessChannel("news.*.x", true))
+	assert.True(t, u.CanAccessChannel("news.*", true))
+	assert.False(t, u.Unrestricted())
+
+	// Rules on all commands drop the earlier ones; reset starts over
+	require.NoError(t, u.SetRules([]string{"+@all", "-flushall", "<secret", "nopass"}, testCatalog{}))
+	assert.Equal(t, "+@all -flushall", u.CommandRules())
+	assert.True(t, u.CheckPassword("anything"))
+	require.NoError(t, u.SetRules([]string{"reset"}, testCatalog{}))
+	assert.Equal(t, "off resetchannels -@all", u.Describe())
+	assert.False(t, u.CheckPassword("anything"))
+
+	assert.True(t, DefaultUser().Unrestricted())
+}
+
+func TestUserRuleErrors(t *testing.T) {
+	for rule, msg := range map[string]string{
+		"+bogus":   "Unknown command or category name in ACL",
+		"-@bogus":  "Unknown command or category name in ACL",
+		"#abc":     "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters",
+		"%X~k":     "Syntax error",
+		"whatever": "Syntax error",
+		"":         "Syntax error",
+	} {
+		err := NewUser("u").SetRules([]string{rule}, testCatalog{})
+		assert.EqualError(t, err, "Error in ACL SETUSER modifier '"+rule+"': "+msg, rule)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	users := NewUsers(testCatalog{})
+	require.NoError(t, users.SetUser("bob", "on", ">pw", "+get", "~*"))
+	assert.NotNil(t, users.Authenticate("bob", "pw"))
+	assert.Nil(t, users.Authenticate("bob", "nope"))
+	assert.NotNil(t, users.Authenticate("default", "anything"))
+
+	// A failed SETUSER leaves the user unchanged
+	require.Error(t, users.SetUser("bob", "off", "+bogus"))
+	assert.True(t, users.Get("bob").Enabled())
+
+	_, err := users.Delete("bob", "default")
+	assert.Equal(t, ErrDefaultUser, err)
+	n, err := users.Delete("bob", "carol")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Nil(t, users.Get("bob"))
+}
+
+func TestUsersSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	users := NewUsers(testCatalog{})
+	require.NoError(t, users.SetUser("bob", "on", ">pw", "+get", "%W~out:*"))
+	require.NoError(t, users.Save(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "user bob on #"+HashPassword("pw")+" %W~out:* resetchannels -@all +get\n"+
+		"user default on nopass ~* &* +@all\n", string(data))
+
+	loaded := NewUsers(testCatalog{})
+	require.NoError(t, loaded.Load(path))
+	assert.Equal(t, users.Get("bob").Describe(), loaded.Get("bob").Describe())
+	assert.NotNil(t, loaded.Authenticate("bob", "pw"))
+
+	// An invalid file changes nothing
+	require.NoError(t, os.WriteFile(path, []byte("user carol on\nuser dave +bogus\n"), 0600))
+	assert.EqualError(t, loaded.Load(path), path+":2: Error in ACL SETUSER modifier '+bogus': Unknown command or category name in ACL")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\nbob on\n"), 0600))
+	assert.EqualError(t, loaded.Load(path), path+":2: should start with user keyword followed by the username")
+	assert.Nil(t, loaded.Get("carol"))
+	assert.NotNil(t, loaded.Get("bob"))
+}
+
+func TestLog(t *testing.T) {
+	var l Log
+	l.Add("command", "toplevel", "get", "bob", "id=1")
+	l.Add("key", "multi", "k", "bob", "id=1")
+	l.Add("command", "toplevel", "get", "bob", "id=2")
+
+	entries := l.Entries(-1)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "get", entries[0].Object)
+	assert.Equal(t, 2, entries[0].Count)
+	assert.Equal(t, "id=2", entries[0].ClientInfo)
+	assert.Equal(t, int64(0), entries[0].ID)
+	assert.Equal(t, "k", entries[1].Object)
+	assert.Len(t, l.Entries(1), 1)
+
+	for i := 0; i < logMaxLen+10; i++ {
+		l.Add("key", "toplevel", string(rune('a'+i)), "bob", "")
+	}
+	assert.Len(t, l.Entries(-1), logMaxLen)
+	l.Reset()
+	assert.Empty(t, l.Entries(-1))
+}
@@ -0,0 +1,82 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+// logMaxLen is the number of entries ACL LOG keeps
+const logMaxLen = 128
+
+// logMergeWindow is how recent an entry must be for a repeated denial to
+// be counted in it instead of getting an entry of its own
+const logMergeWindow = 60 * time.Second
+
+// LogEntry is a denied command or failed authentication
+type LogEntry struct {
+	Count      int
+	Reason     string // auth, command, key or channel
+	Context    string // toplevel or multi
+	Object     string // the command, key or channel denied
+	Username   string
+	ClientInfo string
+	ID         int64
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Log is the ACL LOG. The zero value is ready to use.
+type Log struct {
+	mu      sync.Mutex
+	entries []*LogEntry // newest first
+	nextID  int64
+}
+
+// Add records a denial; a recent entry for the same denial has its count
+// bumped instead
+func (l *Log) Add(reason, context, object, username, clientInfo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for i, e := range l.entries {
+		if e.Reason == reason && e.Context == context && e.Object == object && e.Username == username &&
+			now.Sub(e.Updated) < logMergeWindow {
+			e.Count++
+			e.Updated, e.ClientInfo = now, clientInfo
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+	e := &LogEntry{
+		Count: 1, Reason: reason, Context: context, Object: object, Username: username,
+		ClientInfo: clientInfo, ID: l.nextID, Created: now, Updated: now,
+	}
+	l.nextID++
+	l.entries = append([]*LogEntry{e}, l.entries...)
+	if len(l.entries) > logMaxLen {
+		l.entries = l.entries[:logMaxLen]
+	}
+}
+
+// Entries returns up to count entries, newest first; a negative count
+// returns them all
+func (l *Log) Entries(count int) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+	out := make([]LogEntry, count)
+	for i := range out {
+		out[i] = *l.entries[i]
+	}
+	return out
+}
+
+// Reset drops all entries
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
@@ -0,0 +1,451 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gridhouse/internal/glob"
+	"strings"
+)
+
+// Catalog tells SETUSER rules which commands and categories exist
+type Catalog interface {
+	HasCommand(name string) bool
+	HasCategory(name string) bool
+}
+
+// User is an ACL user: its passwords and what it may run and access. A
+// published user is never modified; changes are applied to a copy.
+type User struct {
+	Name string
+
+	enabled   bool
+	nopass    bool
+	passwords []string // SHA-256 hex digests, in the order they were added
+	commands  []commandRule
+	keys      []keyPattern
+	channels  []string
+
+	// unrestricted is set when the rules allow every command on every key
+	// and channel, so the checks can be skipped
+	unrestricted bool
+}
+
+// commandRule allows or denies a category, a command or one subcommand of
+// a command. The last rule matching a command decides.
+type commandRule struct {
+	allow    bool
+	category string // "all" for every command
+	command  string
+	sub      string
+}
+
+// keyPattern is a ~, %R~, %W~ or %RW~ key pattern
+type keyPattern struct {
+	pattern     string
+	read, write bool
+}
+
+var errSyntax = errors.New("Syntax error")
+
+// NewUser returns a user that is disabled and may run nothing, like the
+// users ACL SETUSER creates
+func NewUser(name string) *User {
+	return &User{Name: name}
+}
+
+// DefaultUser returns the default user: enabled, without password and
+// allowed everything
+func DefaultUser() *User {
+	u := NewUser("default")
+	u.enabled, u.nopass = true, true
+	u.commands = []commandRule{{allow: true, category: "all"}}
+	u.keys = []keyPattern{{pattern: "*", read: true, write: true}}
+	u.channels = []string{"*"}
+	u.update()
+	return u
+}
+
+// HashPassword returns the digest passwords are stored and listed as
+func HashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// clone returns a copy that can be modified without affecting u
+func (u *User) clone() *User {
+	c := *u
+	c.passwords = append([]string(nil), u.passwords...)
+	c.commands = append([]commandRule(nil), u.commands...)
+	c.keys = append([]keyPattern(nil), u.keys...)
+	c.channels = append([]string(nil), u.channels...)
+	return &c
+}
+
+// SetRules applies ACL SETUSER rules in order. u is left partially
+// modified on error, so callers work on a copy.
+func (u *User) SetRules(rules []string, catalog Catalog) error {
+	for _, rule := range rules {
+		if err := u.setRule(rule, catalog); err != nil {
+			return fmt.Errorf("Error in ACL SETUSER modifier '%s': %v", rule, err)
+		}
+	}
+	u.update()
+	return nil
+}
+
+func (u *User) setRule(rule string, catalog Catalog) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		u.enabled = true
+		return nil
+	case "off":
+		u.enabled = false
+		return nil
+	case "nopass":
+		u.nopass, u.passwords = true, nil
+		return nil
+	case "resetpass":
+		u.nopass, u.passwords = false, nil
+		return nil
+	case "allkeys":
+		u.keys = []keyPattern{{pattern: "*", read: true, write: true}}
+		return nil
+	case "resetkeys":
+		u.keys = nil
+		return nil
+	case "allchannels":
+		u.channels = []string{"*"}
+		return nil
+	case "resetchannels":
+		u.channels = nil
+		return nil
+	case "allcommands":
+		u.commands = []commandRule{{allow: true, category: "all"}}
+		return nil
+	case "nocommands":
+		u.commands = nil
+		return nil
+	case "reset":
+		for _, r := range []string{"resetpass", "resetkeys", "resetchannels", "nocommands", "off"} {
+			u.setRule(r, catalog)
+		}
+		return nil
+	}
+	if rule == "" {
+		return errSyntax
+	}
+
+	switch rule[0] {
+	case '>':
+		u.addPassword(HashPassword(rule[1:]))
+	case '<':
+		u.removePassword(HashPassword(rule[1:]))
+	case '#':
+		if !validHash(rule[1:]) {
+			return errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.addPassword(rule[1:])
+	case '!':
+		if !validHash(rule[1:]) {
+			return errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.removePassword(rule[1:])
+	case '~':
+		u.addKeyPattern(keyPattern{pattern: rule[1:], read: true, write: true})
+	case '%':
+		perms, pattern, ok := strings.Cut(rule[1:], "~")
+		if !ok || perms == "" {
+			return errSyntax
+		}
+		p := keyPattern{pattern: pattern}
+		for _, c := range strings.ToUpper(perms) {
+			switch c {
+			case 'R':
+				p.read = true
+			case 'W':
+				p.write = true
+			default:
+				return errSyntax
+			}
+		}
+		u.addKeyPattern(p)
+	case '&':
+		u.addChannel(rule[1:])
+	case '+', '-':
+		return u.addCommandRule(rule, catalog)
+	default:
+		return errSyntax
+	}
+	return nil
+}
+
+func validHash(hash string) bool {
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	for i := 0; i < len(hash); i++ {
+		if c := hash[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (u *User) addPassword(hash string) {
+	u.nopass = false
+	for _, p := range u.passwords {
+		if p == hash {
+			return
+		}
+	}
+	u.passwords = append(u.passwords, hash)
+}
+
+func (u *User) removePassword(hash string) {
+	for i, p := range u.passwords {
+		if p == hash {
+			u.passwords = append(u.passwords[:i:i], u.passwords[i+1:]...)
+			return
+		}
+	}
+}
+
+func (u *User) addKeyPattern(p keyPattern) {
+	for i, k := range u.keys {
+		if k.pattern == p.pattern {
+			u.keys[i].read = k.read || p.read
+			u.keys[i].write = k.write || p.write
+			return
+		}
+	}
+	u.keys = append(u.keys, p)
+}
+
+func (u *User) addChannel(pattern string) {
+	for _, c := range u.channels {
+		if c == pattern {
+			return
+		}
+	}
+	u.channels = append(u.channels, pattern)
+}
+
+// addCommandRule applies +<command>, -<command>, +<command>|<sub>,
+// -<command>|<sub>, +@<category> or -@<category>. A rule on all commands
+// makes the earlier rules moot, so they are dropped, and a repeated rule
+// replaces its earlier occurrence.
+func (u *User) addCommandRule(rule string, catalog Catalog) error {
+	r := commandRule{allow: rule[0] == '+'}
+	name := strings.ToLower(rule[1:])
+	if category, ok := strings.CutPrefix(name, "@"); ok {
+		if category != "all" && !catalog.HasCategory(category) {
+			return errors.New("Unknown command or category name in ACL")
+		}
+		r.category = category
+	} else {
+		r.command, r.sub, _ = strings.Cut(name, "|")
+		if !catalog.HasCommand(r.command) || strings.Contains(r.sub, "|") {
+			return errors.New("Unknown command or category name in ACL")
+		}
+	}
+
+	if r.category == "all" {
+		u.commands = nil
+	}
+	for i, c := range u.commands {
+		if c.category == r.category && c.command == r.command && c.sub == r.sub {
+			u.commands = append(u.commands[:i:i], u.commands[i+1:]...)
+			break
+		}
+	}
+	u.commands = append(u.commands, r)
+	return nil
+}
+
+// update recomputes what derives from the rules
+func (u *User) update() {
+	u.unrestricted = len(u.commands) == 1 && u.commands[0] == commandRule{allow: true, category: "all"} &&
+		contains(u.channels, "*")
+	if u.unrestricted {
+		u.unrestricted = false
+		for _, k := range u.keys {
+			if k == (keyPattern{pattern: "*", read: true, write: true}) {
+				u.unrestricted = true
+			}
+		}
+	}
+}
+
+// Enabled reports whether the user can authenticate
+func (u *User) Enabled() bool {
+	return u.enabled
+}
+
+// NoPass reports whether any password authenticates the user
+func (u *User) NoPass() bool {
+	return u.nopass
+}
+
+// CheckPassword reports whether password authenticates the user
+func (u *User) CheckPassword(password string) bool {
+	if !u.enabled {
+		return false
+	}
+	if u.nopass {
+		return true
+	}
+	hash := HashPassword(password)
+	for _, p := range u.passwords {
+		if p == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Unrestricted reports whether the user may run any command on any key and
+// channel
+func (u *User) Unrestricted() bool {
+	return u.unrestricted
+}
+
+// CanRun reports whether the user may run command, whose first argument is
+// sub and whose categories are given. Names are lower case.
+func (u *User) CanRun(command, sub string, categories []string) bool {
+	for i := len(u.commands) - 1; i >= 0; i-- {
+		r := u.commands[i]
+		switch {
+		case r.category == "all":
+		case r.category != "":
+			if !contains(categories, r.category) {
+				continue
+			}
+		case r.command != command || (r.sub != "" && r.sub != sub):
+			continue
+		}
+		return r.allow
+	}
+	return false
+}
+
+// CanAccessKey reports whether the user may read, or write, key
+func (u *User) CanAccessKey(key string, write bool) bool {
+	for _, k := range u.keys {
+		if (write && !k.write) || (!write && !k.read) {
+			continue
+		}
+		if k.pattern == "*" || glob.Match(k.pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessChannel reports whether the user may publish or subscribe to
+// channel. A pattern subscription must use one of the user's patterns
+// verbatim.
+func (u *User) CanAccessChannel(channel string, pattern bool) bool {
+	for _, c := range u.channels {
+		if c == "*" || (pattern && c == channel) || (!pattern && glob.Match(c, channel)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Flags returns the on/off and nopass flags ACL GETUSER reports
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// Passwords returns the password digests
+func (u *User) Passwords() []string {
+	return append([]string(nil), u.passwords...)
+}
+
+// CommandRules returns the command rules, as SETUSER takes them
+func (u *User) CommandRules() string {
+	parts := make([]string, 0, len(u.commands)+1)
+	if len(u.commands) == 0 || u.commands[0].category != "all" {
+		parts = append(parts, "-@all")
+	}
+	for _, r := range u.commands {
+		var b strings.Builder
+		if r.allow {
+			b.WriteByte('+')
+		} else {
+			b.WriteByte('-')
+		}
+		if r.category != "" {
+			b.WriteString("@" + r.category)
+		} else {
+			b.WriteString(r.command)
+			if r.sub != "" {
+				b.WriteString("|" + r.sub)
+			}
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// KeyPatterns returns the key patterns, as SETUSER takes them
+func (u *User) KeyPatterns() string {
+	parts := make([]string, 0, len(u.keys))
+	for _, k := range u.keys {
+		switch {
+		case k.read && k.write:
+			parts = append(parts, "~"+k.pattern)
+		case k.read:
+			parts = append(parts, "%R~"+k.pattern)
+		default:
+			parts = append(parts, "%W~"+k.pattern)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ChannelPatterns returns the channel patterns, as SETUSER takes them
+func (u *User) ChannelPatterns() string {
+	parts := make([]string, 0, len(u.channels))
+	for _, c := range u.channels {
+		parts = append(parts, "&"+c)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Describe returns the rules that recreate the user, as ACL LIST and the
+// ACL file show them
+func (u *User) Describe() string {
+	parts := u.Flags()
+	for _, p := range u.passwords {
+		parts = append(parts, "#"+p)
+	}
+	if keys := u.KeyPatterns(); keys != "" {
+		parts = append(parts, keys)
+	}
+	if channels := u.ChannelPatterns(); channels != "" {
+		parts = append(parts, channels)
+	} else {
+		parts = append(parts, "resetchannels")
+	}
+	return strings.Join(append(parts, u.CommandRules()), " ")
+}
@@ -0,0 +1,167 @@
+package acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrDefaultUser is returned when deleting the default user
+var ErrDefaultUser = errors.New("The 'default' user cannot be removed")
+
+// Users is the set of ACL users. It always has the default user.
+type Users struct {
+	mu      sync.RWMutex
+	users   map[string]*User
+	catalog Catalog
+}
+
+// NewUsers returns a set holding only the default user
+func NewUsers(catalog Catalog) *Users {
+	return &Users{
+		users:   map[string]*User{"default": DefaultUser()},
+		catalog: catalog,
+	}
+}
+
+// Get returns the user called name, nil if there is none
+func (us *Users) Get(name string) *User {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+	return us.users[name]
+}
+
+// SetUser applies rules to the user called name, creating it if needed.
+// Nothing changes unless all the rules are valid.
+func (us *Users) SetUser(name string, rules ...string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	u, ok := us.users[name]
+	if ok {
+		u = u.clone()
+	} else {
+		u = NewUser(name)
+	}
+	if err := u.SetRules(rules, us.catalog); err != nil {
+		return err
+	}
+	us.users[name] = u
+	return nil
+}
+
+// Delete removes the named users and returns how many existed
+func (us *Users) Delete(names ...string) (int, error) {
+	for _, name := range names {
+		if name == "default" {
+			return 0, ErrDefaultUser
+		}
+	}
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	deleted := 0
+	for _, name := range names {
+		if _, ok := us.users[name]; ok {
+			delete(us.users, name)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// List returns the users sorted by name
+func (us *Users) List() []*User {
+	us.mu.RLock()
+	list := make([]*User, 0, len(us.users))
+	for _, u := range us.users {
+		list = append(list, u)
+	}
+	us.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Authenticate returns the user that name and password authenticate, nil
+// when the user does not exist, is disabled or the password is wrong
+func (us *Users) Authenticate(name, password string) *User {
+	if u := us.Get(name); u != nil && u.CheckPassword(password) {
+		return u
+	}
+	return nil
+}
+
+// Load replaces the users with those of an ACL file, one "user <name>
+// <rules>" line per user. The default user is kept unless the file defines
+// it. Nothing changes if any line is invalid.
+func (us *Users) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := make(map[string]*User)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return fmt.Errorf("%s:%d: should start with user keyword followed by the username", path, n)
+		}
+		name := fields[1]
+		if _, ok := users[name]; ok {
+			return fmt.Errorf("%s:%d: duplicate user '%s' found", path, n, name)
+		}
+		u := NewUser(name)
+		if err := u.SetRules(fields[2:], us.catalog); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		users[name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if _, ok := users["default"]; !ok {
+		users["default"] = us.users["default"]
+	}
+	us.users = users
+	return nil
+}
+
+// Save writes the users to an ACL file, replacing it atomically
+func (us *Users) Save(path string) error {
+	var b strings.Builder
+	for _, u := range us.List() {
+		fmt.Fprintf(&b, "user %s %s\n", u.Name, u.Describe())
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if info, err := os.Stat(path); err == nil {
+		tmp.Chmod(info.Mode().Perm())
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -3,6 +3,7 @@ package aof
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -98,6 +99,12 @@ func (rm *RewriteManager) ShouldRewrite(currentSize int64) bool {
 
 // PerformRewrite performs the AOF rewrite operation
 func (rm *RewriteManager) PerformRewrite(store Store) error {
+	return rm.PerformRewriteDatabases([]Store{store})
+}
+
+// PerformRewriteDatabases rewrites the AOF from several numbered databases,
+// selecting each non-empty database before its keys
+func (rm *RewriteManager) PerformRewriteDatabases(stores []Store) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
@@ -118,7 +125,7 @@ func (rm *RewriteManager) PerformRewrite(store Store) error {
 
 	// Write all current data to the rewrite file
 	logger.Debug("Writing current data to rewrite file")
-	if err := rm.writeCurrentData(tempFile, store); err != nil {
+	if err := rm.writeCurrentData(tempFile, stores); err != nil {
 		logger.Errorf("Failed to write data during rewrite: %v", err)
 		_ = os.Remove(tempPath) // Clean up temp file, ignore error
 		return fmt.Errorf("failed to write data during rewrite: %w", err)
@@ -163,11 +170,30 @@ func (rm *RewriteManager) PerformRewrite(store Store) error {
 	return nil
 }
 
-// writeCurrentData writes all current data to the rewrite file
-func (rm *RewriteManager) writeCurrentData(file *os.File, store Store) error {
-	// Get all keys from the store
-	keys := store.Keys()
+// writeCurrentData writes all current data to the rewrite file. Replay
+// starts in database 0, so only the other databases need a SELECT.
+func (rm *RewriteManager) writeCurrentData(file *os.File, stores []Store) error {
+	for i, store := range stores {
+		keys := store.Keys()
+		if len(keys) == 0 {
+			continue
+		}
+		if i > 0 {
+			index := strconv.Itoa(i)
+			selectCmd := fmt.Sprintf("*2\r\n$6\r\nSELECT\r\n$%d\r\n%s\r\n", len(index), index)
+			if _, err := file.WriteString(selectCmd); err != nil {
+				return err
+			}
+		}
+		if err := writeKeys(file, store, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// writeKeys writes the commands recreating keys of store
+func writeKeys(file *os.File, store Store, keys []string) error {
 	// Write each key-value pair
 	for _, key := range keys {
 		value, exists := store.Get(key)
@@ -2,8 +2,11 @@ package aof
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gridhouse/internal/logger"
@@ -20,11 +23,37 @@ const (
 	No
 )
 
+// String returns the appendfsync name of the mode
+func (m SyncMode) String() string {
+	switch m {
+	case Always:
+		return "always"
+	case EverySec:
+		return "everysec"
+	case No:
+		return "no"
+	}
+	return fmt.Sprintf("SyncMode(%d)", int(m))
+}
+
+// ParseSyncMode parses an appendfsync value: always, everysec or no
+func ParseSyncMode(s string) (SyncMode, error) {
+	switch strings.ToLower(s) {
+	case "always":
+		return Always, nil
+	case "everysec":
+		return EverySec, nil
+	case "no":
+		return No, nil
+	}
+	return 0, fmt.Errorf("invalid AOF sync mode %q", s)
+}
+
 type Writer struct {
 	f              *os.File
 	buf            *bufio.Writer
 	mu             sync.Mutex
-	mode           SyncMode
+	mode           atomic.Int32 // SyncMode, changed live by SetSyncMode
 	lastSync       time.Time
 	stopChan       chan struct{}
 	rewriteManager *RewriteManager
@@ -60,7 +89,6 @@ func NewWriterWithRewrite(path string, mode SyncMode, rewriteConfig *RewriteConf
 	w := &Writer{
 		f:              f,
 		buf:            bufio.NewWriterSize(f, bufferSize),
-		mode:           mode,
 		stopChan:       make(chan struct{}),
 		rewriteManager: rewriteManager,
 		batch:          [][]byte{}, // Initialize batch buffer
@@ -73,11 +101,11 @@ func NewWriterWithRewrite(path string, mode SyncMode, rewriteConfig *RewriteConf
 		bgSyncResponse: make(chan error, 1),
 	}
 
-	// Start background fsync for EverySec mode
-	if mode == EverySec {
-		logger.Debug("Starting background fsync for EverySec mode")
-		go w.backgroundFsync()
-	}
+	w.mode.Store(int32(mode))
+
+	// The background fsync runs in every mode so that switching to
+	// EverySec later takes effect; it only syncs while in EverySec
+	go w.backgroundFsync()
 
 	// Start async AOF processor to eliminate blocking
 	w.asyncWG.Add(1)
@@ -148,8 +176,8 @@ func (w *Writer) backgroundFsync() {
 	for {
 		select {
 		case <-ticker.C:
-			if w.mode != EverySec {
-				return
+			if w.SyncMode() != EverySec {
+				continue
 			}
 			// Use dedicated write mutex for thread-safe access
 			w.writeMu.Lock()
@@ -193,6 +221,19 @@ func (w *Writer) backgroundFsync() {
 	}
 }
 
+// SyncMode returns when the writer fsyncs
+func (w *Writer) SyncMode() SyncMode {
+	return SyncMode(w.mode.Load())
+}
+
+// SetSyncMode changes when the writer fsyncs, starting with the next write
+func (w *Writer) SetSyncMode(mode SyncMode) {
+	if old := w.SyncMode(); old != mode {
+		logger.Infof("AOF sync mode changed from %s to %s", old, mode)
+	}
+	w.mode.Store(int32(mode))
+}
+
 // Thread-safe sync operation (needs writeMu held)
 func (w *Writer) performSyncUnsafe() error {
 	if err := w.buf.Flush(); err != nil {
@@ -316,6 +357,12 @@ func (w *Writer) Size() (int64, error) {
 
 // CheckAndRewrite checks if rewrite is needed and performs it
 func (w *Writer) CheckAndRewrite(store Store) error {
+	return w.CheckAndRewriteDatabases([]Store{store})
+}
+
+// CheckAndRewriteDatabases checks if rewrite is needed and performs it for
+// several numbered databases
+func (w *Writer) CheckAndRewriteDatabases(stores []Store) error {
 	if w.rewriteManager == nil {
 		return nil
 	}
@@ -340,7 +387,7 @@ func (w *Writer) CheckAndRewrite(store Store) error {
 	}
 
 	logger.Infof("Rewriting AOF file to %d bytes", size)
-	if err := w.rewriteManager.PerformRewrite(store); err != nil {
+	if err := w.rewriteManager.PerformRewriteDatabases(stores); err != nil {
 		return err
 	}
 
@@ -446,7 +493,7 @@ func (w *Writer) writeBatch(batch [][]byte) {
 	}
 
 	// Handle sync based on mode
-	if w.mode == Always {
+	if w.SyncMode() == Always {
 		// Immediate sync for Always mode
 		if err := w.performSyncUnsafe(); err != nil {
 			logger.Errorf("Failed to sync AOF in Always mode: %v", err)
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -75,6 +76,29 @@ func TestAOFSyncModes(t *testing.T) {
 	})
 }
 
+func TestAOFSetSyncMode(t *testing.T) {
+	for _, name := range []string{"always", "everysec", "no"} {
+		mode, err := ParseSyncMode(name)
+		require.NoError(t, err)
+		require.Equal(t, name, mode.String())
+	}
+	_, err := ParseSyncMode("sometimes")
+	require.Error(t, err)
+
+	file := filepath.Join(t.TempDir(), "mode.aof")
+	w, err := NewWriter(file, No)
+	require.NoError(t, err)
+	defer w.Close()
+
+	w.SetSyncMode(Always)
+	require.Equal(t, Always, w.SyncMode())
+	require.NoError(t, w.Append([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")))
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(file)
+		return err == nil && strings.Contains(string(b), "SET")
+	}, time.Second, 10*time.Millisecond)
+}
+
 func TestAOFSize(t *testing.T) {
 	dir := t.TempDir()
 	file := filepath.Join(dir, "size.aof")
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"crypto/tls"
 	"fmt"
+	"gridhouse/internal/tlsconfig"
 	"io"
 	"net"
 	"sort"
@@ -31,6 +32,7 @@ type BenchmarkResult struct {
 type BenchmarkConfig struct {
 	Host        string
 	Port        int
+	Socket      string // Unix socket path, used instead of Host and Port when set
 	Password    string
 	Database    int
 	Requests    int
@@ -39,6 +41,10 @@ type BenchmarkConfig struct {
 	Timeout     time.Duration
 	KeepAlive   bool
 	TLS         bool
+	CACert      string // CA certificates the server certificate is verified against, the system ones if empty
+	Cert        string // Client certificate presented to the server
+	Key         string
+	Insecure    bool // Skip verifying the server certificate
 	Commands    []string
 	DataSize    int
 	KeyPattern  string
@@ -155,13 +161,20 @@ func runWorker(config *BenchmarkConfig, command string, requests, workerID int)
 }
 
 func createConnection(config *BenchmarkConfig) (net.Conn, error) {
+	if config.Socket != "" {
+		return net.DialTimeout("unix", config.Socket, config.Timeout)
+	}
 	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
 
 	if config.TLS {
+		tlsConfig, err := tlsconfig.Client(config.CACert, config.Cert, config.Key, config.Insecure)
+		if err != nil {
+			return nil, err
+		}
 		return tls.DialWithDialer(&net.Dialer{
 			Timeout:   config.Timeout,
 			KeepAlive: config.Timeout,
-		}, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		}, "tcp", address, tlsConfig)
 	}
 
 	return net.DialTimeout("tcp", address, config.Timeout)
@@ -0,0 +1,240 @@
+package blocking
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gridhouse/internal/resp"
+)
+
+// Retry re-runs a blocked command. It reports blocked=true when the command
+// still has nothing to serve and the client must keep waiting.
+type Retry func() (value resp.Value, blocked bool, err error)
+
+// waiter is a client parked on one or more keys
+type waiter struct {
+	keys   []string
+	retry  Retry
+	result chan result
+
+	// Guarded by Manager.mu
+	claimed bool // a ServeReady call is currently retrying this waiter
+	expired bool // the waiter gave up while claimed
+	removed bool
+}
+
+type result struct {
+	value resp.Value
+	err   error
+	ok    bool
+}
+
+// Manager parks clients on keys and serves them in FIFO order when the keys
+// are signalled as ready. Serving happens after the signalling command has
+// completed, so a push inside MULTI/EXEC only wakes clients after EXEC.
+type Manager struct {
+	mu      sync.Mutex
+	waiters map[string][]*waiter
+	ready   []string
+	isReady map[string]bool
+	pending atomic.Bool
+	blocked atomic.Int64
+
+	// serveMu serialises serving and lets transactions hold serving off
+	serveMu sync.Mutex
+}
+
+// NewManager creates an empty blocking manager
+func NewManager() *Manager {
+	return &Manager{
+		waiters: make(map[string][]*waiter),
+		isReady: make(map[string]bool),
+	}
+}
+
+// Signal marks key as ready if any client is blocked on it. It is cheap
+// enough to be called for every key modification.
+func (m *Manager) Signal(key string) {
+	// Waiters count themselves before registering and mark their own keys
+	// ready, so a signal skipped here cannot be lost
+	if m.blocked.Load() == 0 {
+		return
+	}
+	m.mu.Lock()
+	if len(m.waiters[key]) > 0 && !m.isReady[key] {
+		m.isReady[key] = true
+		m.ready = append(m.ready, key)
+		m.pending.Store(true)
+	}
+	m.mu.Unlock()
+}
+
+// SignalAll marks every key clients are blocked on as ready, for changes
+// that do not go through single keys such as SWAPDB
+func (m *Manager) SignalAll() {
+	if m.blocked.Load() == 0 {
+		return
+	}
+	m.mu.Lock()
+	for key := range m.waiters {
+		if !m.isReady[key] {
+			m.isReady[key] = true
+			m.ready = append(m.ready, key)
+			m.pending.Store(true)
+		}
+	}
+	m.mu.Unlock()
+}
+
+// Blocked returns the number of parked clients
+func (m *Manager) Blocked() int64 {
+	return m.blocked.Load()
+}
+
+// Hold prevents blocked clients from being served until Release is called.
+// It is used to run a transaction without interleaved wake-ups.
+func (m *Manager) Hold() { m.serveMu.Lock() }
+
+// Release resumes serving after Hold
+func (m *Manager) Release() { m.serveMu.Unlock() }
+
+// Wait parks the caller on keys until retry succeeds, the timeout expires
+// (zero means wait forever) or cancel is closed. ok is false when the client
+// stopped waiting without being served.
+func (m *Manager) Wait(keys []string, timeout time.Duration, retry Retry, cancel <-chan struct{}) (value resp.Value, ok bool, err error) {
+	w := &waiter{keys: keys, retry: retry, result: make(chan result, 1)}
+	m.blocked.Add(1)
+	defer m.blocked.Add(-1)
+
+	m.mu.Lock()
+	for _, key := range keys {
+		m.waiters[key] = append(m.waiters[key], w)
+		// A push may have landed between the failed attempt and registration
+		if !m.isReady[key] {
+			m.isReady[key] = true
+			m.ready = append(m.ready, key)
+		}
+	}
+	m.pending.Store(true)
+	m.mu.Unlock()
+
+	m.ServeReady()
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case r := <-w.result:
+		return r.value, r.ok, r.err
+	case <-timer:
+	case <-cancel:
+	}
+
+	m.mu.Lock()
+	if w.claimed {
+		// A retry is in flight: its outcome decides
+		w.expired = true
+		m.mu.Unlock()
+		r := <-w.result
+		return r.value, r.ok, r.err
+	}
+	if !w.removed {
+		m.removeLocked(w)
+	}
+	m.mu.Unlock()
+
+	// Served right before giving up
+	select {
+	case r := <-w.result:
+		return r.value, r.ok, r.err
+	default:
+	}
+	return resp.Value{}, false, nil
+}
+
+// ServeReady serves clients blocked on keys signalled since the last call.
+// Waiters on each key are retried in arrival order.
+func (m *Manager) ServeReady() {
+	if !m.pending.Load() {
+		return
+	}
+	m.serveMu.Lock()
+	defer m.serveMu.Unlock()
+
+	for {
+		m.mu.Lock()
+		if len(m.ready) == 0 {
+			m.pending.Store(false)
+			m.mu.Unlock()
+			return
+		}
+		key := m.ready[0]
+		m.ready = m.ready[1:]
+		delete(m.isReady, key)
+		m.mu.Unlock()
+
+		m.serveKey(key)
+	}
+}
+
+func (m *Manager) serveKey(key string) {
+	tried := make(map[*waiter]bool)
+	for {
+		m.mu.Lock()
+		var w *waiter
+		for _, candidate := range m.waiters[key] {
+			if !candidate.claimed && !candidate.expired && !tried[candidate] {
+				w = candidate
+				break
+			}
+		}
+		if w == nil {
+			m.mu.Unlock()
+			return
+		}
+		w.claimed = true
+		m.mu.Unlock()
+
+		// Run without m.mu: the retry may modify keys and call Signal
+		value, blocked, err := w.retry()
+
+		m.mu.Lock()
+		w.claimed = false
+		if blocked {
+			tried[w] = true
+			if w.expired {
+				m.removeLocked(w)
+				w.result <- result{}
+			}
+			m.mu.Unlock()
+			continue
+		}
+		m.removeLocked(w)
+		w.result <- result{value: value, err: err, ok: true}
+		m.mu.Unlock()
+	}
+}
+
+// removeLocked unregisters w from all its keys. Caller holds m.mu.
+func (m *Manager) removeLocked(w *waiter) {
+	w.removed = true
+	for _, key := range w.keys {
+		queue := m.waiters[key]
+		for i, candidate := range queue {
+			if candidate == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(m.waiters, key)
+		} else {
+			m.waiters[key] = queue
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package blocking
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queue is a tiny list used to drive the manager in tests
+type queue struct {
+	mu    sync.Mutex
+	items []string
+}
+
+func (q *queue) push(m *Manager, key, item string) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+	m.Signal(key)
+	m.ServeReady()
+}
+
+func (q *queue) retry() (resp.Value, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return resp.Value{}, true, nil
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return resp.Value{Type: resp.BulkString, Str: item}, false, nil
+}
+
+func waitForBlocked(t *testing.T, m *Manager, n int64) {
+	t.Helper()
+	require.Eventually(t, func() bool { return m.Blocked() == n }, time.Second, time.Millisecond)
+}
+
+func TestWaitServedOnSignal(t *testing.T) {
+	m := NewManager()
+	q := &queue{}
+
+	done := make(chan resp.Value, 1)
+	go func() {
+		v, ok, err := m.Wait([]string{"k"}, 0, q.retry, nil)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+		done <- v
+	}()
+	waitForBlocked(t, m, 1)
+
+	q.push(m, "k", "a")
+	select {
+	case v := <-done:
+		assert.Equal(t, "a", v.Str)
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not served")
+	}
+	assert.Equal(t, int64(0), m.Blocked())
+}
+
+func TestWaitFIFO(t *testing.T) {
+	m := NewManager()
+	q := &queue{}
+
+	results := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		go func(id string) {
+			v, ok, _ := m.Wait([]string{"k"}, 0, func() (resp.Value, bool, error) {
+				v, blocked, err := q.retry()
+				if !blocked {
+					v.Str = id + ":" + v.Str
+				}
+				return v, blocked, err
+			}, nil)
+			if ok {
+				results <- v.Str
+			}
+		}(string(rune('A' + i)))
+		waitForBlocked(t, m, int64(i+1))
+	}
+
+	q.push(m, "k", "1")
+	q.push(m, "k", "2")
+	q.push(m, "k", "3")
+	// Each waiter got the element pushed in its arrival position
+	got := []string{<-results, <-results, <-results}
+	assert.ElementsMatch(t, []string{"A:1", "B:2", "C:3"}, got)
+}
+
+func TestWaitTimeoutAndCancel(t *testing.T) {
+	m := NewManager()
+	q := &queue{}
+
+	start := time.Now()
+	_, ok, err := m.Wait([]string{"k"}, 20*time.Millisecond, q.retry, nil)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	_, ok, _ = m.Wait([]string{"k"}, 0, q.retry, cancel)
+	assert.False(t, ok)
+
+	// Nobody is left waiting, so a push stays in the queue
+	q.push(m, "k", "a")
+	assert.Equal(t, []string{"a"}, q.items)
+	assert.Equal(t, int64(0), m.Blocked())
+}
+
+func TestWaitServedImmediately(t *testing.T) {
+	m := NewManager()
+	q := &queue{items: []string{"ready"}}
+
+	// Data that arrived before registration is picked up right away
+	v, ok, err := m.Wait([]string{"k"}, time.Second, q.retry, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "ready", v.Str)
+}
+
+func TestHoldDefersServing(t *testing.T) {
+	m := NewManager()
+	q := &queue{}
+
+	done := make(chan struct{})
+	go func() {
+		m.Wait([]string{"k"}, 0, q.retry, nil)
+		close(done)
+	}()
+	waitForBlocked(t, m, 1)
+
+	m.Hold()
+	q.mu.Lock()
+	q.items = append(q.items, "x")
+	q.mu.Unlock()
+	m.Signal("k")
+	go m.ServeReady()
+
+	select {
+	case <-done:
+		t.Fatal("served while held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.Release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("not served after release")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"crypto/tls"
 	"fmt"
+	"gridhouse/internal/tlsconfig"
 	"io"
 	"net"
 	"os"
@@ -18,10 +19,15 @@ import (
 type CLIConfig struct {
 	Host     string
 	Port     int
+	Socket   string // Unix socket path, used instead of Host and Port when set
 	Password string
 	Database int
 	Timeout  time.Duration
 	TLS      bool
+	CACert   string // CA certificates the server certificate is verified against, the system ones if empty
+	Cert     string // Client certificate presented to the server
+	Key      string
+	Insecure bool // Skip verifying the server certificate
 	Raw      bool
 	Eval     string
 	File     string
@@ -111,14 +117,29 @@ func (h *CommandHistory) ResetPosition() {
 	h.position = len(h.commands)
 }
 
+// serverName describes the server the CLI connects to in messages
+func serverName(config *CLIConfig) string {
+	if config.Socket != "" {
+		return config.Socket
+	}
+	return net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+}
+
 func createCLIConnection(config *CLIConfig) (net.Conn, error) {
+	if config.Socket != "" {
+		return net.DialTimeout("unix", config.Socket, config.Timeout)
+	}
 	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
 
 	if config.TLS {
+		tlsConfig, err := tlsconfig.Client(config.CACert, config.Cert, config.Key, config.Insecure)
+		if err != nil {
+			return nil, err
+		}
 		return tls.DialWithDialer(&net.Dialer{
 			Timeout:   config.Timeout,
 			KeepAlive: config.Timeout,
-		}, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		}, "tcp", address, tlsConfig)
 	}
 
 	return net.DialTimeout("tcp", address, config.Timeout)
@@ -295,7 +316,7 @@ func executePipe(conn net.Conn, raw bool) {
 
 func executeInteractive(conn net.Conn, config *CLIConfig) {
 	fmt.Printf("GridHouse CLI v1.0.0\n")
-	fmt.Printf("Connected to %s:%d\n", config.Host, config.Port)
+	fmt.Printf("Connected to %s\n", serverName(config))
 	if config.Database != 0 {
 		fmt.Printf("Using database %d\n", config.Database)
 	}
@@ -827,7 +848,7 @@ func RunCLI(config *CLIConfig, args []string) {
 	// Connect to server
 	conn, err := createCLIConnection(config)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error connecting to %s:%d: %v\n", config.Host, config.Port, err)
+		fmt.Fprintf(os.Stderr, "Error connecting to %s: %v\n", serverName(config), err)
 		os.Exit(1)
 	}
 	defer conn.Close()
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -251,6 +252,26 @@ func TestCLIConnectionCreation(t *testing.T) {
 	conn, err := createCLIConnection(config)
 	assert.Error(t, err)
 	assert.Nil(t, conn)
+
+	// A Unix socket replaces the host and port
+	socket := filepath.Join(t.TempDir(), "cli.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	config.Socket = socket
+	conn, err = createCLIConnection(config)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", conn.RemoteAddr().Network())
+	conn.Close()
+	assert.Equal(t, socket, serverName(config))
+	config.Socket = ""
+
+	// TLS certificates are loaded before dialing
+	config.TLS = true
+	config.CACert = "/nonexistent/ca.crt"
+	conn, err = createCLIConnection(config)
+	assert.ErrorContains(t, err, "failed to load CA certificates")
+	assert.Nil(t, conn)
 }
 
 func TestCLIAuthentication(t *testing.T) {
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"gridhouse/internal/resp"
+	"strconv"
+	"strings"
+)
+
+// AclGroup is an ACL command category; users are granted the commands of a
+// category with +@<category>
+type AclGroup string
+
+const (
+	AclKeyspace    AclGroup = "keyspace"
+	AclRead        AclGroup = "read"
+	AclWrite       AclGroup = "write"
+	AclString      AclGroup = "string"
+	AclList        AclGroup = "list"
+	AclSet         AclGroup = "set"
+	AclSortedSet   AclGroup = "sortedset"
+	AclHash        AclGroup = "hash"
+	AclStream      AclGroup = "stream"
+	AclPubSub      AclGroup = "pubsub"
+	AclAdmin       AclGroup = "admin"
+	AclFast        AclGroup = "fast"
+	AclSlow        AclGroup = "slow"
+	AclBlocking    AclGroup = "blocking"
+	AclDangerous   AclGroup = "dangerous"
+	AclConnection  AclGroup = "connection"
+	AclTransaction AclGroup = "transaction"
+)
+
+// AclGroups lists the categories in the order ACL CAT reports them
+var AclGroups = []AclGroup{
+	AclKeyspace, AclRead, AclWrite, AclSet, AclSortedSet, AclList, AclHash, AclString, AclPubSub,
+	AclAdmin, AclFast, AclSlow, AclBlocking, AclDangerous, AclConnection, AclTransaction, AclStream,
+}
+
+// InGroup reports whether the command belongs to group
+func (c *Command) InGroup(group AclGroup) bool {
+	for _, g := range c.ACLGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// KeysFunc returns the keys among the arguments of a command, for ACL key
+// checks
+type KeysFunc func(args []resp.Value) []string
+
+// KeyRange returns the arguments from first to last, every step arguments.
+// A negative last counts from the end, -1 being the last argument.
+func KeyRange(first, last, step int) KeysFunc {
+	return func(args []resp.Value) []string {
+		end := last
+		if end < 0 {
+			end += len(args)
+		}
+		if end >= len(args) {
+			end = len(args) - 1
+		}
+		var keys []string
+		for i := first; i <= end; i += step {
+			keys = append(keys, args[i].Str)
+		}
+		return keys
+	}
+}
+
+var (
+	// FirstKey is the key of commands taking a single key first
+	FirstKey = KeyRange(0, 0, 1)
+	// AllKeys is the keys of commands that only take keys
+	AllKeys = KeyRange(0, -1, 1)
+)
+
+// CountedKeys returns the keys following a key count at args[at], as BLMPOP
+// takes them
+func CountedKeys(at int) KeysFunc {
+	return func(args []resp.Value) []string {
+		if at >= len(args) {
+			return nil
+		}
+		n, err := strconv.Atoi(args[at].Str)
+		if err != nil || n <= 0 || at+1+n > len(args) {
+			return nil
+		}
+		return KeyRange(at+1, at+n, 1)(args)
+	}
+}
+
+// streamKeys returns the keys of XREAD and XREADGROUP: the first half of
+// the arguments after STREAMS
+func streamKeys(args []resp.Value) []string {
+	for i, arg := range args {
+		if strings.EqualFold(arg.Str, "STREAMS") {
+			rest := len(args) - i - 1
+			return KeyRange(i+1, i+rest/2, 1)(args)
+		}
+	}
+	return nil
+}
+
+// memoryKeys returns the key of MEMORY USAGE; the other subcommands take none
+func memoryKeys(args []resp.Value) []string {
+	if len(args) > 1 && strings.EqualFold(args[0].Str, "USAGE") {
+		return []string{args[1].Str}
+	}
+	return nil
+}
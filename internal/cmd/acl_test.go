@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"gridhouse/internal/resp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysFuncs(t *testing.T) {
+	args := func(list ...string) []resp.Value {
+		out := make([]resp.Value, len(list))
+		for i, s := range list {
+			out[i] = resp.Value{Type: resp.BulkString, Str: s}
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"k"}, FirstKey(args("k", "v")))
+	assert.Nil(t, FirstKey(args()))
+	assert.Equal(t, []string{"a", "b"}, KeyRange(0, -1, 2)(args("a", "1", "b", "2")))
+	assert.Equal(t, []string{"a", "b"}, KeyRange(0, -2, 1)(args("a", "b", "0")))
+	assert.Equal(t, []string{"a", "b"}, CountedKeys(1)(args("0", "2", "a", "b", "LEFT")))
+	assert.Nil(t, CountedKeys(1)(args("0", "5", "a")))
+	assert.Equal(t, []string{"s1", "s2"}, streamKeys(args("COUNT", "1", "STREAMS", "s1", "s2", "0", "$")))
+	assert.Nil(t, streamKeys(args("COUNT", "1")))
+	assert.Equal(t, []string{"k"}, memoryKeys(args("usage", "k", "SAMPLES", "5")))
+	assert.Nil(t, memoryKeys(args("STATS")))
+}
@@ -21,12 +21,14 @@ func AppendHandler(store Store) Handler {
 		if !exists {
 			// Key doesn't exist, create new string
 			store.Set(key, valueToAppend, time.Time{})
+			notifyKeyEvent(store, "append", key)
 			return resp.Value{Type: resp.Integer, Int: int64(len(valueToAppend))}, nil
 		}
 
 		// Key exists, append to existing value
 		newValue := currentValue + valueToAppend
 		store.Set(key, newValue, time.Time{})
+		notifyKeyEvent(store, "append", key)
 		return resp.Value{Type: resp.Integer, Int: int64(len(newValue))}, nil
 	}
 }
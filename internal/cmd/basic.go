@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"gridhouse/internal/resp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -30,8 +29,9 @@ func PingHandler() Handler {
 	}
 }
 
-// OptimizedSetHandler  not used besides tests, because of performance reasons
-func OptimizedSetHandler(store Store) Handler {
+// OptimizedSetHandler handles SET key value [NX|XX] [GET]
+// [EX seconds|PX ms|EXAT ts|PXAT ts-ms|KEEPTTL]
+func OptimizedSetHandler(s Store) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
 		if len(args) < 2 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SET' command")
@@ -39,36 +39,31 @@ func OptimizedSetHandler(store Store) Handler {
 
 		key := args[0].Str
 		value := args[1].Str
-		expiration := time.Time{}
 
-		// Parse optional expiration (optimized)
-		for i := 2; i < len(args); i++ {
-			if i+1 >= len(args) {
-				return resp.Value{}, fmt.Errorf("ERR syntax error")
-			}
+		// Fast path for the plain SET key value
+		if len(args) == 2 {
+			s.Set(key, value, time.Time{})
+			notifyKeyEvent(s, "set", key)
+			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		}
 
-			option := args[i].Str
-			// Fast string comparison instead of ToUpper
-			if len(option) == 2 && option[0] == 'E' && option[1] == 'X' {
-				sec, err := strconv.Atoi(args[i+1].Str)
-				if err != nil {
-					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
-				}
-				expiration = time.Now().Add(time.Duration(sec) * time.Second)
-				i++ // Skip the next argument since we consumed it
-			} else if len(option) == 2 && option[0] == 'P' && option[1] == 'X' {
-				msec, err := strconv.Atoi(args[i+1].Str)
-				if err != nil {
-					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
-				}
-				expiration = time.Now().Add(time.Duration(msec) * time.Millisecond)
-				i++ // Skip the next argument since we consumed it
-			} else {
-				return resp.Value{}, fmt.Errorf("ERR syntax error")
+		opts, err := parseSetOptions(args[2:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+		res, err := setString(s, key, value, opts)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if opts.Get {
+			if !res.Existed {
+				return resp.Value{Type: resp.BulkString, IsNull: true}, nil
 			}
+			return resp.Value{Type: resp.BulkString, Str: res.Old}, nil
+		}
+		if !res.Written {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
 		}
-
-		store.Set(key, value, expiration)
 		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
 	}
 }
@@ -99,6 +94,7 @@ func OptimizedDelHandler(store Store) Handler {
 		deleted := 0
 		for _, arg := range args {
 			if store.Del(arg.Str) {
+				notifyKeyEvent(store, "del", arg.Str)
 				deleted++
 			}
 		}
@@ -180,25 +176,9 @@ func OptimizedPTTLHandler(store Store) Handler {
 	}
 }
 
-// OptimizedExpireHandler is a faster version of ExpireHandler
+// OptimizedExpireHandler handles EXPIRE key seconds [NX|XX|GT|LT]
 func OptimizedExpireHandler(store Store) Handler {
-	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) != 2 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'EXPIRE' command")
-		}
-
-		key := args[0].Str
-		seconds, err := strconv.Atoi(args[1].Str)
-		if err != nil {
-			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
-		}
-
-		success := store.Expire(key, time.Duration(seconds)*time.Second)
-		if success {
-			return resp.Value{Type: resp.Integer, Int: 1}, nil
-		}
-		return resp.Value{Type: resp.Integer, Int: 0}, nil
-	}
+	return expireHandler(store, "expire", time.Second, false)
 }
 
 // keysToRespArrayOptimized converts keys to RESP array with pre-allocation
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlockedError is returned by blocking commands that have nothing to serve
+// yet. The server parks the client on Keys and re-runs the command whenever
+// one of them changes; once Timeout expires (zero waits forever) the client
+// receives TimeoutReply. Outside of a connection, e.g. inside MULTI/EXEC,
+// the command answers TimeoutReply right away. RetryArgs, when set, replace
+// the original arguments on retries, e.g. once XREAD has resolved "$".
+type BlockedError struct {
+	Keys         []string
+	Timeout      time.Duration
+	TimeoutReply resp.Value
+	RetryArgs    []resp.Value
+}
+
+func (e *BlockedError) Error() string {
+	return "ERR command would block"
+}
+
+var (
+	nullArrayReply = resp.Value{Type: resp.Array, IsNull: true}
+	nullBulkReply  = resp.Value{Type: resp.BulkString, IsNull: true}
+)
+
+// parseBlockTimeout parses a timeout given in (fractional) seconds
+func parseBlockTimeout(arg string) (time.Duration, error) {
+	secs, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return 0, fmt.Errorf("ERR timeout is not a float or out of range")
+	}
+	if secs < 0 {
+		return 0, fmt.Errorf("ERR timeout is negative")
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// parseListSide parses a LEFT|RIGHT argument
+func parseListSide(arg string) (left bool, err error) {
+	switch strings.ToUpper(arg) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, fmt.Errorf("ERR syntax error")
+}
+
+// popFirst pops up to count elements from the first non-empty list in keys
+func popFirst(store DataStore, keys []string, left bool, count int) (string, []string, error) {
+	for _, key := range keys {
+		list, err := store.GetList(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if list == nil || list.LLen() == 0 {
+			continue
+		}
+		var popped []string
+		for len(popped) < count {
+			var element string
+			var ok bool
+			if left {
+				element, ok = list.LPop()
+			} else {
+				element, ok = list.RPop()
+			}
+			if !ok {
+				break
+			}
+			popped = append(popped, element)
+		}
+		if len(popped) > 0 {
+			if left {
+				store.NotifyKeyEvent("lpop", key)
+			} else {
+				store.NotifyKeyEvent("rpop", key)
+			}
+			deleteIfEmpty(store, key)
+			return key, popped, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// listMove atomically pops from source and pushes to destination, even
+// when the two keys sit in different shards
+func listMove(ds DataStore, source, destination string, fromLeft, toLeft bool) (string, bool, error) {
+	// Check the destination type before touching the source
+	if _, err := ds.GetList(destination); err != nil {
+		return "", false, err
+	}
+	src, err := ds.GetList(source)
+	if err != nil || src == nil || src.LLen() == 0 {
+		return "", false, err
+	}
+	dst, err := ds.EnsureList(destination)
+	if err != nil {
+		return "", false, err
+	}
+	element, ok := store.ListMove(src, dst, fromLeft, toLeft)
+	if !ok {
+		return "", false, nil
+	}
+	if fromLeft {
+		ds.NotifyKeyEvent("lpop", source)
+	} else {
+		ds.NotifyKeyEvent("rpop", source)
+	}
+	deleteIfEmpty(ds, source)
+	if toLeft {
+		ds.NotifyKeyEvent("lpush", destination)
+	} else {
+		ds.NotifyKeyEvent("rpush", destination)
+	}
+	return element, true, nil
+}
+
+// BLPopHandler handles BLPOP key [key ...] timeout
+func BLPopHandler(store DataStore) Handler {
+	return blockingPopHandler(store, "BLPOP", true)
+}
+
+// BRPopHandler handles BRPOP key [key ...] timeout
+func BRPopHandler(store DataStore) Handler {
+	return blockingPopHandler(store, "BRPOP", false)
+}
+
+func blockingPopHandler(store DataStore, name string, left bool) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+		}
+		timeout, err := parseBlockTimeout(args[len(args)-1].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		keys := make([]string, len(args)-1)
+		for i, arg := range args[:len(args)-1] {
+			keys[i] = arg.Str
+		}
+
+		key, popped, err := popFirst(store, keys, left, 1)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(popped) == 0 {
+			return resp.Value{}, &BlockedError{Keys: keys, Timeout: timeout, TimeoutReply: nullArrayReply}
+		}
+		return resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.BulkString, Str: key},
+			{Type: resp.BulkString, Str: popped[0]},
+		}}, nil
+	}
+}
+
+// BLMoveHandler handles BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+func BLMoveHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 5 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'BLMOVE' command")
+		}
+		fromLeft, err := parseListSide(args[2].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		toLeft, err := parseListSide(args[3].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		timeout, err := parseBlockTimeout(args[4].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+
+		element, ok, err := listMove(store, args[0].Str, args[1].Str, fromLeft, toLeft)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !ok {
+			return resp.Value{}, &BlockedError{Keys: []string{args[0].Str}, Timeout: timeout, TimeoutReply: nullBulkReply}
+		}
+		return resp.Value{Type: resp.BulkString, Str: element}, nil
+	}
+}
+
+// BLMPopHandler handles BLMPOP timeout numkeys key [key ...] LEFT|RIGHT [COUNT count]
+func BLMPopHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 4 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'BLMPOP' command")
+		}
+		timeout, err := parseBlockTimeout(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		keys, left, count, err := parseMPop(args[1:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+
+		key, popped, err := popFirst(store, keys, left, count)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(popped) == 0 {
+			return resp.Value{}, &BlockedError{Keys: keys, Timeout: timeout, TimeoutReply: nullArrayReply}
+		}
+		return mpopReply(key, popped), nil
+	}
+}
+
+// parseMPop parses the numkeys key [key ...] LEFT|RIGHT [COUNT count]
+// arguments shared by LMPOP and BLMPOP
+func parseMPop(args []resp.Value) (keys []string, left bool, count int, err error) {
+	numKeys, err := strconv.Atoi(args[0].Str)
+	if err != nil || numKeys <= 0 {
+		return nil, false, 0, fmt.Errorf("ERR numkeys should be greater than 0")
+	}
+	if len(args) < 1+numKeys+1 {
+		return nil, false, 0, fmt.Errorf("ERR syntax error")
+	}
+	keys = make([]string, numKeys)
+	for i := range keys {
+		keys[i] = args[1+i].Str
+	}
+	rest := args[1+numKeys:]
+	left, err = parseListSide(rest[0].Str)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	count = 1
+	switch {
+	case len(rest) == 1:
+	case len(rest) == 3 && strings.EqualFold(rest[1].Str, "COUNT"):
+		count, err = strconv.Atoi(rest[2].Str)
+		if err != nil || count <= 0 {
+			return nil, false, 0, fmt.Errorf("ERR count should be greater than 0")
+		}
+	default:
+		return nil, false, 0, fmt.Errorf("ERR syntax error")
+	}
+	return keys, left, count, nil
+}
+
+// mpopReply builds the [key, [element ...]] reply of LMPOP and BLMPOP
+func mpopReply(key string, popped []string) resp.Value {
+	elements := make([]resp.Value, len(popped))
+	for i, e := range popped {
+		elements[i] = resp.Value{Type: resp.BulkString, Str: e}
+	}
+	return resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.BulkString, Str: key},
+		{Type: resp.Array, Array: elements},
+	}}
+}
+
+// rewriteBlockingPop propagates a served BLPOP/BRPOP/BLMPOP as the LPOP or
+// RPOP it performed on the key that was served
+func rewriteBlockingPop(left bool) func(args []string, result resp.Value) (string, []string, bool) {
+	name := "RPOP"
+	if left {
+		name = "LPOP"
+	}
+	return func(args []string, result resp.Value) (string, []string, bool) {
+		if result.IsNull || len(result.Array) != 2 {
+			return "", nil, false
+		}
+		key := result.Array[0].Str
+		if popped := result.Array[1]; popped.Type == resp.Array {
+			return name, []string{key, strconv.Itoa(len(popped.Array))}, true
+		}
+		return name, []string{key}, true
+	}
+}
+
+// rewriteBlockingMove propagates a served BLMOVE as LMOVE
+func rewriteBlockingMove(args []string, result resp.Value) (string, []string, bool) {
+	if result.IsNull || len(args) != 5 {
+		return "", nil, false
+	}
+	return "LMOVE", args[:4], true
+}
+
+// rewriteBlockingMPop propagates a served BLMPOP as LPOP or RPOP with a count
+func rewriteBlockingMPop(args []string, result resp.Value) (string, []string, bool) {
+	return rewriteCountedPop(args, 1, result)
+}
+
+// rewriteMPop propagates LMPOP as LPOP or RPOP with a count
+func rewriteMPop(args []string, result resp.Value) (string, []string, bool) {
+	return rewriteCountedPop(args, 0, result)
+}
+
+// rewriteCountedPop rewrites a pop taking numkeys at args[at] followed by
+// the keys and LEFT|RIGHT
+func rewriteCountedPop(args []string, at int, result resp.Value) (string, []string, bool) {
+	numKeys, err := strconv.Atoi(args[at])
+	if err != nil || len(args) < at+2+numKeys {
+		return "", nil, false
+	}
+	return rewriteBlockingPop(strings.EqualFold(args[at+1+numKeys], "LEFT"))(args, result)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBLPopHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := BLPopHandler(ds)
+
+	// Empty lists block with the given timeout
+	_, err := handler(bulkArgs("a", "b", "1.5"))
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, []string{"a", "b"}, blocked.Keys)
+	assert.Equal(t, 1500*time.Millisecond, blocked.Timeout)
+	assert.True(t, blocked.TimeoutReply.IsNull)
+
+	l, _ := ds.EnsureList("b")
+	l.RPush("x", "y")
+	result, err := handler(bulkArgs("a", "b", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "b"},
+		{Type: resp.BulkString, Str: "x"},
+	}, result.Array)
+
+	result, err = BRPopHandler(ds)(bulkArgs("b", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, "y", result.Array[1].Str)
+}
+
+func TestBlockingTimeoutValidation(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	_, err := BLPopHandler(ds)(bulkArgs("k", "-1"))
+	assert.EqualError(t, err, "ERR timeout is negative")
+	_, err = BLPopHandler(ds)(bulkArgs("k", "abc"))
+	assert.EqualError(t, err, "ERR timeout is not a float or out of range")
+
+	ds.Set("str", "v", time.Time{})
+	_, err = BLPopHandler(ds)(bulkArgs("str", "0"))
+	assert.True(t, errors.Is(err, store.ErrWrongType))
+}
+
+func TestBLMoveHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := BLMoveHandler(ds)
+
+	_, err := handler(bulkArgs("src", "dst", "LEFT", "RIGHT", "0"))
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, []string{"src"}, blocked.Keys)
+
+	src, _ := ds.EnsureList("src")
+	src.RPush("a", "b")
+	result, err := handler(bulkArgs("src", "dst", "RIGHT", "LEFT", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Str)
+	dst, _ := ds.GetList("dst")
+	require.NotNil(t, dst)
+	assert.Equal(t, []string{"b"}, dst.LRange(0, -1))
+
+	_, err = handler(bulkArgs("src", "dst", "UP", "LEFT", "0"))
+	assert.EqualError(t, err, "ERR syntax error")
+
+	// Wrong destination type leaves the source untouched
+	ds.Set("str", "v", time.Time{})
+	_, err = handler(bulkArgs("src", "str", "LEFT", "LEFT", "0"))
+	assert.True(t, errors.Is(err, store.ErrWrongType))
+	assert.Equal(t, 1, src.LLen())
+}
+
+func TestBLMPopHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := BLMPopHandler(ds)
+
+	_, err := handler(bulkArgs("0", "2", "a", "b", "LEFT"))
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, []string{"a", "b"}, blocked.Keys)
+
+	l, _ := ds.EnsureList("b")
+	l.RPush("1", "2", "3")
+	result, err := handler(bulkArgs("0", "2", "a", "b", "RIGHT", "COUNT", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Array[0].Str)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "3"},
+		{Type: resp.BulkString, Str: "2"},
+	}, result.Array[1].Array)
+
+	name, args, ok := rewriteBlockingMPop([]string{"0", "2", "a", "b", "RIGHT", "COUNT", "2"}, result)
+	require.True(t, ok)
+	assert.Equal(t, "RPOP", name)
+	assert.Equal(t, []string{"b", "2"}, args)
+
+	_, err = handler(bulkArgs("0", "0", "a", "LEFT"))
+	assert.EqualError(t, err, "ERR numkeys should be greater than 0")
+	_, err = handler(bulkArgs("0", "1", "a", "LEFT", "COUNT", "0"))
+	assert.EqualError(t, err, "ERR count should be greater than 0")
+}
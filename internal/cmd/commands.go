@@ -8,8 +8,6 @@ import (
 	"time"
 )
 
-type AclGroup string // TODO
-
 // Store interface for commands that need to access data
 type Store interface {
 	Set(key, value string, expiration time.Time)
@@ -36,6 +34,15 @@ type Command struct {
 	Handler   Handler
 	ReadOnly  bool
 	ACLGroups []AclGroup
+	// Keys and Channels locate the keys and pub/sub channels among the
+	// arguments for ACL checks; nil when the command takes none. Keys of a
+	// ReadOnly command need read access, those of others write access.
+	Keys     KeysFunc
+	Channels KeysFunc
+	// Rewrite optionally replaces the command sent to the AOF and replicas,
+	// e.g. a served BLPOP is propagated as the LPOP it performed. ok=false
+	// means nothing needs to be propagated.
+	Rewrite func(args []string, result resp.Value) (name string, newArgs []string, ok bool)
 }
 
 // Registry holds all registered commands
@@ -104,512 +111,1150 @@ type PersistenceManager interface {
 // RegisterPersistenceCommands registers persistence-related commands
 func RegisterPersistenceCommands(registry *Registry, persist PersistenceManager, db *store.UltraOptimizedDB) {
 	registry.Register(&Command{
-		Name:     "SAVE",
-		Arity:    0,
-		Handler:  SaveHandler(persist),
-		ReadOnly: false,
+		Name:      "SAVE",
+		Arity:     0,
+		Handler:   SaveHandler(persist),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 	registry.Register(&Command{
-		Name:     "BGSAVE",
-		Arity:    0,
-		Handler:  BgsaveHandler(persist),
-		ReadOnly: false,
+		Name:      "BGSAVE",
+		Arity:     0,
+		Handler:   BgsaveHandler(persist),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 	registry.Register(&Command{
-		Name:     "FLUSHDB",
-		Arity:    -1,
-		Handler:  FlushDBWithPersistenceHandler(db, persist),
-		ReadOnly: false,
+		Name:      "FLUSHDB",
+		Arity:     -1,
+		Handler:   FlushDBWithPersistenceHandler(db, persist),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow, AclDangerous},
 	})
 }
 
-// RegisterServerCommands registers server-related commands
-func RegisterServerCommands(registry *Registry, stats interface{}) {
+// RegisterServerCommands registers server-related commands. params are the
+// settings CONFIG serves; rewrite backs CONFIG REWRITE and is nil without a
+// config file.
+func RegisterServerCommands(registry *Registry, stats interface{}, params ConfigParams, rewrite func() error) {
 	// INFO command
 	registry.Register(&Command{
-		Name:     "INFO",
-		Arity:    -1,
-		Handler:  InfoHandler(stats),
-		ReadOnly: true,
+		Name:      "INFO",
+		Arity:     -1,
+		Handler:   InfoHandler(stats),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclSlow, AclDangerous},
 	})
 
 	// CONFIG command
 	registry.Register(&Command{
-		Name:     "CONFIG",
-		Arity:    -1,
-		Handler:  ConfigHandler(),
-		ReadOnly: true,
+		Name:      "CONFIG",
+		Arity:     -1,
+		Handler:   ConfigHandlerWithRewrite(params, stats, rewrite),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 
 	// PING command
 	registry.Register(&Command{
-		Name:     "PING",
-		Arity:    -1,
-		Handler:  PingHandler(),
-		ReadOnly: true,
+		Name:      "PING",
+		Arity:     -1,
+		Handler:   PingHandler(),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclFast, AclConnection},
 	})
 }
 
 // RegisterOptimizedCommands registers optimized command handlers
 func RegisterOptimizedCommands(registry *Registry, store DataStore) {
 	registry.Register(&Command{
-		Name:     "SET",
-		Arity:    -1,
-		Handler:  OptimizedSetHandler(store),
-		ReadOnly: false,
+		Name:      "SET",
+		Arity:     -1,
+		Handler:   OptimizedSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSet,
+	})
+
+	registry.Register(&Command{
+		Name:      "SETNX",
+		Arity:     2, // SETNX key value
+		Handler:   SetNXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSetNX,
+	})
+
+	registry.Register(&Command{
+		Name:      "SETEX",
+		Arity:     3, // SETEX key seconds value
+		Handler:   SetEXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSetExpiring("EX"),
+	})
+
+	registry.Register(&Command{
+		Name:      "PSETEX",
+		Arity:     3, // PSETEX key milliseconds value
+		Handler:   PSetEXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSetExpiring("PX"),
+	})
+
+	registry.Register(&Command{
+		Name:      "GETSET",
+		Arity:     2, // GETSET key value
+		Handler:   GetSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteGetSet,
+	})
+
+	registry.Register(&Command{
+		Name:      "GETDEL",
+		Arity:     1, // GETDEL key
+		Handler:   GetDelHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteGetDel,
+	})
+
+	registry.Register(&Command{
+		Name:      "GETEX",
+		Arity:     -1, // Variable arity: GETEX key [EX|PX|EXAT|PXAT time|PERSIST]
+		Handler:   GetExHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteGetEx,
+	})
+
+	registry.Register(&Command{
+		Name:      "GET",
+		Arity:     1,
+		Handler:   OptimizedGetHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "DEL",
+		Arity:     -1, // Variable arity: DEL key [key ...]
+		Handler:   OptimizedDelHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow},
+		Keys:      AllKeys,
+	})
+
+	registry.Register(&Command{
+		Name:      "EXISTS",
+		Arity:     -1, // Variable arity: EXISTS key [key ...]
+		Handler:   OptimizedExistsHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      AllKeys,
+	})
+
+	registry.Register(&Command{
+		Name:      "TTL",
+		Arity:     1,
+		Handler:   OptimizedTTLHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "PTTL",
+		Arity:     1,
+		Handler:   OptimizedPTTLHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "EXPIRE",
+		Arity:     -1, // Variable arity: EXPIRE key seconds [NX|XX|GT|LT]
+		Handler:   OptimizedExpireHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteExpire(time.Second, false),
+	})
+
+	registry.Register(&Command{
+		Name:      "PEXPIRE",
+		Arity:     -1, // Variable arity: PEXPIRE key milliseconds [NX|XX|GT|LT]
+		Handler:   PExpireHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteExpire(time.Millisecond, false),
+	})
+
+	registry.Register(&Command{
+		Name:      "EXPIREAT",
+		Arity:     -1, // Variable arity: EXPIREAT key unix-time-seconds [NX|XX|GT|LT]
+		Handler:   ExpireAtHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteExpire(time.Second, true),
+	})
+
+	registry.Register(&Command{
+		Name:      "PEXPIREAT",
+		Arity:     -1, // Variable arity: PEXPIREAT key unix-time-milliseconds [NX|XX|GT|LT]
+		Handler:   PExpireAtHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteExpire(time.Millisecond, true),
+	})
+
+	registry.Register(&Command{
+		Name:      "PERSIST",
+		Arity:     1, // PERSIST key
+		Handler:   PersistHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewritePersist,
 	})
 
 	registry.Register(&Command{
-		Name:     "GET",
-		Arity:    1,
-		Handler:  OptimizedGetHandler(store),
-		ReadOnly: true,
+		Name:      "EXPIRETIME",
+		Arity:     1, // EXPIRETIME key
+		Handler:   ExpireTimeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "DEL",
-		Arity:    -1, // Variable arity: DEL key [key ...]
-		Handler:  OptimizedDelHandler(store),
-		ReadOnly: false,
+		Name:      "PEXPIRETIME",
+		Arity:     1, // PEXPIRETIME key
+		Handler:   PExpireTimeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "EXISTS",
-		Arity:    -1, // Variable arity: EXISTS key [key ...]
-		Handler:  OptimizedExistsHandler(store),
-		ReadOnly: true,
+		Name:      "INCR",
+		Arity:     1,
+		Handler:   OptimizedIncrHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "TTL",
-		Arity:    1,
-		Handler:  OptimizedTTLHandler(store),
-		ReadOnly: true,
+		Name:      "DECR",
+		Arity:     1,
+		Handler:   OptimizedDecrHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "PTTL",
-		Arity:    1,
-		Handler:  OptimizedPTTLHandler(store),
-		ReadOnly: true,
+		Name:      "INCRBY",
+		Arity:     2,
+		Handler:   IncrByHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "EXPIRE",
-		Arity:    2,
-		Handler:  OptimizedExpireHandler(store),
-		ReadOnly: false,
+		Name:      "DECRBY",
+		Arity:     2,
+		Handler:   DecrByHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "INCR",
-		Arity:    1,
-		Handler:  OptimizedIncrHandler(store),
-		ReadOnly: false,
+		Name:      "INCRBYFLOAT",
+		Arity:     2,
+		Handler:   IncrByFloatHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "DECR",
-		Arity:    1,
-		Handler:  OptimizedDecrHandler(store),
-		ReadOnly: false,
+		Name:      "DEL",
+		Arity:     -1,
+		Handler:   OptimizedDelHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow},
+		Keys:      AllKeys,
 	})
 
 	registry.Register(&Command{
-		Name:     "INCRBY",
-		Arity:    2,
-		Handler:  IncrByHandler(store),
-		ReadOnly: false,
+		Name:      "EXISTS",
+		Arity:     -1,
+		Handler:   OptimizedExistsHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      AllKeys,
 	})
 
 	registry.Register(&Command{
-		Name:     "DECRBY",
-		Arity:    2,
-		Handler:  DecrByHandler(store),
-		ReadOnly: false,
+		Name:      "TTL",
+		Arity:     1,
+		Handler:   OptimizedTTLHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "INCRBYFLOAT",
-		Arity:    2,
-		Handler:  IncrByFloatHandler(store),
-		ReadOnly: false,
+		Name:      "PTTL",
+		Arity:     1,
+		Handler:   OptimizedPTTLHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "DEL",
-		Arity:    -1,
-		Handler:  OptimizedDelHandler(store),
-		ReadOnly: false,
+		Name:      "MSET",
+		Arity:     -1,
+		Handler:   MSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      KeyRange(0, -1, 2),
 	})
 
 	registry.Register(&Command{
-		Name:     "EXISTS",
-		Arity:    -1,
-		Handler:  OptimizedExistsHandler(store),
-		ReadOnly: true,
+		Name:      "MSETNX",
+		Arity:     -1, // Variable arity: MSETNX key value [key value ...]
+		Handler:   MSetNXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      KeyRange(0, -1, 2),
+		Rewrite:   rewriteMSetNX,
 	})
 
 	registry.Register(&Command{
-		Name:     "TTL",
-		Arity:    1,
-		Handler:  OptimizedTTLHandler(store),
-		ReadOnly: true,
+		Name:      "MGET",
+		Arity:     -1,
+		Handler:   MGetHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclFast},
+		Keys:      AllKeys,
 	})
 
 	registry.Register(&Command{
-		Name:     "PTTL",
-		Arity:    1,
-		Handler:  OptimizedPTTLHandler(store),
-		ReadOnly: true,
+		Name:      "STRLEN",
+		Arity:     1,
+		Handler:   StrlenHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "MSET",
-		Arity:    -1,
-		Handler:  MSetHandler(store),
-		ReadOnly: false,
+		Name:      "APPEND",
+		Arity:     2,
+		Handler:   AppendHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "MGET",
-		Arity:    -1,
-		Handler:  MGetHandler(store),
-		ReadOnly: true,
+		Name:      "SETRANGE",
+		Arity:     3, // SETRANGE key offset value
+		Handler:   SetRangeHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "STRLEN",
-		Arity:    1,
-		Handler:  StrlenHandler(store),
-		ReadOnly: true,
+		Name:      "LCS",
+		Arity:     -1, // Variable arity: LCS key1 key2 [LEN] [IDX] [MINMATCHLEN len] [WITHMATCHLEN]
+		Handler:   LCSHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclSlow},
+		Keys:      KeyRange(0, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "APPEND",
-		Arity:    2,
-		Handler:  AppendHandler(store),
-		ReadOnly: false,
+		Name:      "KEYS",
+		Arity:     -1,
+		Handler:   OptimizedKeysHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclSlow, AclDangerous},
 	})
 
 	registry.Register(&Command{
-		Name:     "KEYS",
-		Arity:    -1,
-		Handler:  OptimizedKeysHandler(store),
-		ReadOnly: true,
+		Name:      "RENAME",
+		Arity:     2,
+		Handler:   RenameHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow},
+		Keys:      KeyRange(0, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "RENAME",
-		Arity:    2,
-		Handler:  RenameHandler(store),
-		ReadOnly: false,
+		Name:      "RENAMENX",
+		Arity:     2,
+		Handler:   RenameNxHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      KeyRange(0, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "RENAMENX",
-		Arity:    2,
-		Handler:  RenameNxHandler(store),
-		ReadOnly: false,
+		Name:      "COPY",
+		Arity:     -1, // Variable arity: 2 or 3 arguments
+		Handler:   CopyHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow},
+		Keys:      KeyRange(0, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "COPY",
-		Arity:    -1, // Variable arity: 2 or 3 arguments
-		Handler:  CopyHandler(store),
-		ReadOnly: false,
+		Name:      "OBJECT",
+		Arity:     -1, // OBJECT <subcommand> [key]
+		Handler:   ObjectHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclSlow},
+		Keys:      KeyRange(1, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "MEMORY",
-		Arity:    -1, // Variable arity: MEMORY USAGE key OR MEMORY STATS
-		Handler:  MemoryHandler(store),
-		ReadOnly: true,
+		Name:      "MEMORY",
+		Arity:     -1, // Variable arity: MEMORY USAGE key OR MEMORY STATS
+		Handler:   MemoryHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSlow},
+		Keys:      memoryKeys,
 	})
 
 	registry.Register(&Command{
-		Name:     "FLUSHDB",
-		Arity:    -1,
-		Handler:  FlushDBHandler(store),
-		ReadOnly: false,
+		Name:      "FLUSHDB",
+		Arity:     -1,
+		Handler:   FlushDBHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow, AclDangerous},
 	})
 
 	// Register list commands
 	registry.Register(&Command{
-		Name:     "LPUSH",
-		Arity:    -1, // Variable arity: LPUSH key element [element ...]
-		Handler:  LPushHandler(store),
-		ReadOnly: false,
+		Name:      "LPUSH",
+		Arity:     -1, // Variable arity: LPUSH key element [element ...]
+		Handler:   LPushHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "RPUSH",
+		Arity:     -1, // Variable arity: RPUSH key element [element ...]
+		Handler:   RPushHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LPOP",
+		Arity:     -1, // Variable arity: LPOP key [count]
+		Handler:   LPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "RPOP",
+		Arity:     -1, // Variable arity: RPOP key [count]
+		Handler:   RPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LPUSHX",
+		Arity:     -1, // Variable arity: LPUSHX key element [element ...]
+		Handler:   LPushXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewritePushX("LPUSH"),
+	})
+
+	registry.Register(&Command{
+		Name:      "RPUSHX",
+		Arity:     -1, // Variable arity: RPUSHX key element [element ...]
+		Handler:   RPushXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewritePushX("RPUSH"),
 	})
 
 	registry.Register(&Command{
-		Name:     "RPUSH",
-		Arity:    -1, // Variable arity: RPUSH key element [element ...]
-		Handler:  RPushHandler(store),
-		ReadOnly: false,
+		Name:      "LMPOP",
+		Arity:     -1, // Variable arity: LMPOP numkeys key [key ...] LEFT|RIGHT [COUNT count]
+		Handler:   LMPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      CountedKeys(0),
+		Rewrite:   rewriteMPop,
 	})
 
 	registry.Register(&Command{
-		Name:     "LPOP",
-		Arity:    1,
-		Handler:  LPopHandler(store),
-		ReadOnly: false,
+		Name:      "LMOVE",
+		Arity:     4, // LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+		Handler:   LMoveHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      KeyRange(0, 1, 1),
 	})
 
 	registry.Register(&Command{
-		Name:     "RPOP",
-		Arity:    1,
-		Handler:  RPopHandler(store),
-		ReadOnly: false,
+		Name:      "RPOPLPUSH",
+		Arity:     2, // RPOPLPUSH source destination
+		Handler:   RPopLPushHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      KeyRange(0, 1, 1),
+		Rewrite:   rewriteRPopLPush,
 	})
 
 	registry.Register(&Command{
-		Name:     "LLEN",
-		Arity:    1,
-		Handler:  LLenHandler(store),
-		ReadOnly: true,
+		Name:      "BLPOP",
+		Arity:     -1,
+		Handler:   BLPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow, AclBlocking},
+		Keys:      KeyRange(0, -2, 1),
+		Rewrite:   rewriteBlockingPop(true),
 	})
 
 	registry.Register(&Command{
-		Name:     "LRANGE",
-		Arity:    3, // LRANGE key start stop
-		Handler:  LRangeHandler(store),
-		ReadOnly: true,
+		Name:      "BRPOP",
+		Arity:     -1,
+		Handler:   BRPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow, AclBlocking},
+		Keys:      KeyRange(0, -2, 1),
+		Rewrite:   rewriteBlockingPop(false),
 	})
 
 	registry.Register(&Command{
-		Name:     "HSET",
-		Arity:    -1,
-		Handler:  HSetHandler(store),
-		ReadOnly: false,
+		Name:      "BLMOVE",
+		Arity:     5,
+		Handler:   BLMoveHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow, AclBlocking},
+		Keys:      KeyRange(0, 1, 1),
+		Rewrite:   rewriteBlockingMove,
+	})
+
+	registry.Register(&Command{
+		Name:      "BLMPOP",
+		Arity:     -1,
+		Handler:   BLMPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow, AclBlocking},
+		Keys:      CountedKeys(1),
+		Rewrite:   rewriteBlockingMPop,
+	})
+
+	registry.Register(&Command{
+		Name:      "LLEN",
+		Arity:     1,
+		Handler:   LLenHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclList, AclFast},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LRANGE",
+		Arity:     3, // LRANGE key start stop
+		Handler:   LRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LINDEX",
+		Arity:     2, // LINDEX key index
+		Handler:   LIndexHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LPOS",
+		Arity:     -1, // Variable arity: LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+		Handler:   LPosHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LSET",
+		Arity:     3, // LSET key index element
+		Handler:   LSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LINSERT",
+		Arity:     4, // LINSERT key BEFORE|AFTER pivot element
+		Handler:   LInsertHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LREM",
+		Arity:     3, // LREM key count element
+		Handler:   LRemHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "LTRIM",
+		Arity:     3, // LTRIM key start stop
+		Handler:   LTrimHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclList, AclSlow},
+		Keys:      FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "HSET",
+		Arity:     -1,
+		Handler:   HSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "HGET",
+		Arity:     2,
+		Handler:   HGetHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HGET",
-		Arity:    2,
-		Handler:  HGetHandler(store),
-		ReadOnly: true,
+		Name:      "HDEL",
+		Arity:     -1,
+		Handler:   HDelHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HDEL",
-		Arity:    -1,
-		Handler:  HDelHandler(store),
-		ReadOnly: false,
+		Name:      "HEXISTS",
+		Arity:     2,
+		Handler:   HExistsHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HEXISTS",
-		Arity:    2,
-		Handler:  HExistsHandler(store),
-		ReadOnly: true,
+		Name:      "HGETALL",
+		Arity:     1,
+		Handler:   HGetAllHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HGETALL",
-		Arity:    1,
-		Handler:  HGetAllHandler(store),
-		ReadOnly: true,
+		Name:      "HKEYS",
+		Arity:     1,
+		Handler:   HKeysHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HKEYS",
-		Arity:    1,
-		Handler:  HKeysHandler(store),
-		ReadOnly: true,
+		Name:      "HVALS",
+		Arity:     1,
+		Handler:   HValsHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HVALS",
-		Arity:    1,
-		Handler:  HValsHandler(store),
-		ReadOnly: true,
+		Name:      "HLEN",
+		Arity:     1,
+		Handler:   HLenHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HLEN",
-		Arity:    1,
-		Handler:  HLenHandler(store),
-		ReadOnly: true,
+		Name:      "HINCRBY",
+		Arity:     3,
+		Handler:   HIncrByHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HINCRBY",
-		Arity:    3,
-		Handler:  HIncrByHandler(store),
-		ReadOnly: false,
+		Name:      "HINCRBYFLOAT",
+		Arity:     3,
+		Handler:   HIncrByFloatHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "HINCRBYFLOAT",
-		Arity:    3,
-		Handler:  HIncrByFloatHandler(store),
-		ReadOnly: false,
+		Name:      "HMSET",
+		Arity:     -1, // HMSET key field value [field value ...]
+		Handler:   HMSetHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteHMSet,
+	})
+	registry.Register(&Command{
+		Name:      "HSETNX",
+		Arity:     3,
+		Handler:   HSetNXHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclHash, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteHSetNX,
+	})
+	registry.Register(&Command{
+		Name:      "HMGET",
+		Arity:     -1, // HMGET key field [field ...]
+		Handler:   HMGetHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "HSTRLEN",
+		Arity:     2,
+		Handler:   HStrLenHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "HRANDFIELD",
+		Arity:     -1, // HRANDFIELD key [count [WITHVALUES]]
+		Handler:   HRandFieldHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclSlow},
+		Keys:      FirstKey,
 	})
 
 	registry.Register(&Command{
-		Name:     "SAdd",
-		Arity:    -1,
-		Handler:  SAddHandler(store),
-		ReadOnly: false,
+		Name:      "SAdd",
+		Arity:     -1,
+		Handler:   SAddHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "SRem",
+		Arity:     -1, // SREM key member [member ...]
+		Handler:   SRemHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "SIsMember",
+		Arity:     2,
+		Handler:   SIsMemberHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "SMembers",
+		Arity:     1,
+		Handler:   SMembersHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "SCard",
+		Arity:     1,
+		Handler:   SCardHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "SRem",
-		Arity:    2,
-		Handler:  SRemHandler(store),
-		ReadOnly: false,
+		Name:      "SPop",
+		Arity:     -1, // SPOP key [count]
+		Handler:   SPopHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSPop,
 	})
 	registry.Register(&Command{
-		Name:     "SIsMember",
-		Arity:    2,
-		Handler:  SIsMemberHandler(store),
-		ReadOnly: true,
+		Name:      "SRANDMEMBER",
+		Arity:     -1, // SRANDMEMBER key [count]
+		Handler:   SRandMemberHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "SMembers",
-		Arity:    1,
-		Handler:  SMembersHandler(store),
-		ReadOnly: true,
+		Name:      "SMISMEMBER",
+		Arity:     -1, // SMISMEMBER key member [member ...]
+		Handler:   SMIsMemberHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "SCard",
-		Arity:    1,
-		Handler:  SCardHandler(store),
-		ReadOnly: true,
+		Name:      "SMOVE",
+		Arity:     3,
+		Handler:   SMoveHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclFast},
+		Keys:      KeyRange(0, 1, 1),
 	})
 	registry.Register(&Command{
-		Name:     "SPop",
-		Arity:    1,
-		Handler:  SPopHandler(store),
-		ReadOnly: true,
+		Name:      "SUNION",
+		Arity:     -1, // SUNION key [key ...]
+		Handler:   SUnionHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SINTER",
+		Arity:     -1, // SINTER key [key ...]
+		Handler:   SInterHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SDIFF",
+		Arity:     -1, // SDIFF key [key ...]
+		Handler:   SDiffHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SUNIONSTORE",
+		Arity:     -1, // SUNIONSTORE destination key [key ...]
+		Handler:   SUnionStoreHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SINTERSTORE",
+		Arity:     -1, // SINTERSTORE destination key [key ...]
+		Handler:   SInterStoreHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SDIFFSTORE",
+		Arity:     -1, // SDIFFSTORE destination key [key ...]
+		Handler:   SDiffStoreHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSet, AclSlow},
+		Keys:      AllKeys,
+	})
+	registry.Register(&Command{
+		Name:      "SINTERCARD",
+		Arity:     -1, // SINTERCARD numkeys key [key ...] [LIMIT limit]
+		Handler:   SInterCardHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      CountedKeys(0),
 	})
 
 	// Sorted Set commands
 	registry.Register(&Command{
-		Name:     "ZADD",
-		Arity:    -1,
-		Handler:  ZAddHandler(store),
-		ReadOnly: false,
+		Name:      "ZADD",
+		Arity:     -1,
+		Handler:   ZAddHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSortedSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "ZREM",
-		Arity:    -1,
-		Handler:  ZRemHandler(store),
-		ReadOnly: false,
+		Name:      "ZREM",
+		Arity:     -1,
+		Handler:   ZRemHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSortedSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "ZCARD",
-		Arity:    1,
-		Handler:  ZCardHandler(store),
-		ReadOnly: true,
+		Name:      "ZCARD",
+		Arity:     1,
+		Handler:   ZCardHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSortedSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "ZSCORE",
-		Arity:    2,
-		Handler:  ZScoreHandler(store),
-		ReadOnly: true,
+		Name:      "ZSCORE",
+		Arity:     2,
+		Handler:   ZScoreHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSortedSet, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "ZRANGE",
-		Arity:    -1,
-		Handler:  ZRangeHandler(store),
-		ReadOnly: true,
+		Name:      "ZRANGE",
+		Arity:     -1,
+		Handler:   ZRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSortedSet, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "ZPOPMIN",
-		Arity:    -1,
-		Handler:  ZPopMinHandler(store),
-		ReadOnly: false,
+		Name:      "ZPOPMIN",
+		Arity:     -1,
+		Handler:   ZPopMinHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclSortedSet, AclFast},
+		Keys:      FirstKey,
 	})
 
 	// Misc simple commands
 	registry.Register(&Command{
-		Name:     "ECHO",
-		Arity:    1,
-		Handler:  EchoHandler(),
-		ReadOnly: true,
+		Name:      "ECHO",
+		Arity:     1,
+		Handler:   EchoHandler(),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclFast, AclConnection},
 	})
 
 	// DBSIZE
 	registry.Register(&Command{
-		Name:     "DBSIZE",
-		Arity:    0,
-		Handler:  DBSizeHandler(store),
-		ReadOnly: true,
+		Name:      "DBSIZE",
+		Arity:     0,
+		Handler:   DBSizeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
 	})
 
 	// GETRANGE (alias SUBSTR)
 	registry.Register(&Command{
-		Name:     "GETRANGE",
-		Arity:    3,
-		Handler:  GetRangeHandler(store),
-		ReadOnly: true,
+		Name:      "GETRANGE",
+		Arity:     3,
+		Handler:   GetRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclSlow},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "SUBSTR",
-		Arity:    3,
-		Handler:  GetRangeHandler(store),
-		ReadOnly: true,
+		Name:      "SUBSTR",
+		Arity:     3,
+		Handler:   GetRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclSlow},
+		Keys:      FirstKey,
 	})
 
 	// Cursor-based key scan
 	registry.Register(&Command{
-		Name:     "SCAN",
-		Arity:    -1,
-		Handler:  ScanHandler(store),
-		ReadOnly: true,
+		Name:      "SCAN",
+		Arity:     -1,
+		Handler:   ScanHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclSlow},
 	})
 
 	// Set scan
 	registry.Register(&Command{
-		Name:     "SSCAN",
-		Arity:    -1,
-		Handler:  SScanHandler(store),
-		ReadOnly: true,
+		Name:      "SSCAN",
+		Arity:     -1,
+		Handler:   SScanHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclSet, AclSlow},
+		Keys:      FirstKey,
 	})
 
 	// Hash scan
 	registry.Register(&Command{
-		Name:     "HSCAN",
-		Arity:    -1,
-		Handler:  HScanHandler(store),
-		ReadOnly: true,
+		Name:      "HSCAN",
+		Arity:     -1,
+		Handler:   HScanHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclHash, AclSlow},
+		Keys:      FirstKey,
 	})
 
 	// TYPE
 	registry.Register(&Command{
-		Name:     "TYPE",
-		Arity:    1,
-		Handler:  TypeHandler(store),
-		ReadOnly: true,
+		Name:      "TYPE",
+		Arity:     1,
+		Handler:   TypeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclKeyspace, AclRead, AclFast},
+		Keys:      FirstKey,
 	})
 
 	// Streams
 	registry.Register(&Command{
-		Name:     "XADD",
-		Arity:    -1,
-		Handler:  XAddHandler(store),
-		ReadOnly: false,
+		Name:      "XADD",
+		Arity:     -1,
+		Handler:   XAddHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteXAdd,
 	})
 	registry.Register(&Command{
-		Name:     "XLEN",
-		Arity:    1,
-		Handler:  XLenHandler(store),
-		ReadOnly: true,
+		Name:      "XLEN",
+		Arity:     1,
+		Handler:   XLenHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclFast},
+		Keys:      FirstKey,
 	})
 	registry.Register(&Command{
-		Name:     "XRANGE",
-		Arity:    -1,
-		Handler:  XRangeHandler(store),
-		ReadOnly: true,
-	})
-	registry.Register(&Command{
-		Name:     "XDEL",
-		Arity:    -1,
-		Handler:  XDelHandler(store),
-		ReadOnly: false,
-	})
+		Name:      "XRANGE",
+		Arity:     -1,
+		Handler:   XRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclSlow},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "XDEL",
+		Arity:     -1,
+		Handler:   XDelHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "XTRIM",
+		Arity:     -1,
+		Handler:   XTrimHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclSlow},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "XREAD",
+		Arity:     -1,
+		Handler:   XReadHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclSlow, AclBlocking},
+		Keys:      streamKeys,
+	})
+	registry.Register(&Command{
+		Name:      "XGROUP",
+		Arity:     -1,
+		Handler:   XGroupHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclSlow},
+		Keys:      KeyRange(1, 1, 1),
+	})
+	registry.Register(&Command{
+		Name:      "XREADGROUP",
+		Arity:     -1,
+		Handler:   XReadGroupHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclSlow, AclBlocking},
+		Keys:      streamKeys,
+		Rewrite:   rewriteXReadGroup,
+	})
+	registry.Register(&Command{
+		Name:      "XACK",
+		Arity:     -1,
+		Handler:   XAckHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "XPENDING",
+		Arity:     -1,
+		Handler:   XPendingHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclSlow},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "XCLAIM",
+		Arity:     -1,
+		Handler:   XClaimHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteXClaim(store),
+	})
+	registry.Register(&Command{
+		Name:      "XAUTOCLAIM",
+		Arity:     -1,
+		Handler:   XAutoClaimHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+		Rewrite:   rewriteXAutoClaim,
+	})
+	registry.Register(&Command{
+		Name:      "XREVRANGE",
+		Arity:     -1,
+		Handler:   XRevRangeHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclSlow},
+		Keys:      FirstKey,
+	})
 	registry.Register(&Command{
-		Name:     "XTRIM",
-		Arity:    -1,
-		Handler:  XTrimHandler(store),
-		ReadOnly: false,
-	})
+		Name:      "XSETID",
+		Arity:     -1,
+		Handler:   XSetIDHandler(store),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclStream, AclFast},
+		Keys:      FirstKey,
+	})
 	registry.Register(&Command{
-		Name:     "XREAD",
-		Arity:    -1,
-		Handler:  XReadHandler(store),
-		ReadOnly: true,
+		Name:      "XINFO",
+		Arity:     -1,
+		Handler:   XInfoHandler(store),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclStream, AclSlow},
+		Keys:      KeyRange(1, 1, 1),
 	})
 }
@@ -1,61 +1,297 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"gridhouse/internal/glob"
 	"gridhouse/internal/resp"
+	"gridhouse/internal/stats"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// ConfigParam is a server setting exposed through CONFIG GET and CONFIG SET.
+// A nil Set makes the parameter immutable.
+type ConfigParam struct {
+	Get func() string
+	Set func(value string) error
+
+	// Apply, when set, is called once CONFIG SET has set all the
+	// parameters it was given, so that settings depending on each other,
+	// like a certificate and its key, are checked together. An error
+	// restores the values they had.
+	Apply func() error
+}
+
+// ConfigParams maps lower-case parameter names to their accessors
+type ConfigParams map[string]ConfigParam
+
+// ImmutableConfigParam returns a parameter that always reports value
+func ImmutableConfigParam(value string) ConfigParam {
+	return ConfigParam{Get: func() string { return value }}
+}
+
+// BoolConfigParam returns a yes/no parameter; a nil set makes it immutable
+func BoolConfigParam(get func() bool, set func(bool) error) ConfigParam {
+	param := ConfigParam{Get: func() string { return FormatConfigBool(get()) }}
+	if set != nil {
+		param.Set = func(value string) error {
+			b, err := ParseConfigBool(value)
+			if err != nil {
+				return err
+			}
+			return set(b)
+		}
+	}
+	return param
+}
+
+// IntConfigParam returns an integer parameter accepting values between min
+// and max inclusive; a nil set makes it immutable
+func IntConfigParam(min, max int64, get func() int64, set func(int64) error) ConfigParam {
+	param := ConfigParam{Get: func() string { return strconv.FormatInt(get(), 10) }}
+	if set != nil {
+		param.Set = func(value string) error {
+			n, err := ParseConfigInt(value, min, max)
+			if err != nil {
+				return err
+			}
+			return set(n)
+		}
+	}
+	return param
+}
+
+// EnumConfigParam returns a parameter accepting one of values, in any case;
+// set receives the value in lower case. A nil set makes it immutable.
+func EnumConfigParam(values []string, get func() string, set func(string) error) ConfigParam {
+	param := ConfigParam{Get: get}
+	if set != nil {
+		param.Set = func(value string) error {
+			value = strings.ToLower(value)
+			for _, v := range values {
+				if v == value {
+					return set(value)
+				}
+			}
+			return fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(values, ", "))
+		}
+	}
+	return param
+}
+
+// ParseConfigBool parses a yes/no config value
+func ParseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, errors.New("argument must be 'yes' or 'no'")
+}
+
+// FormatConfigBool formats b as a yes/no config value
+func FormatConfigBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// ParseConfigInt parses an integer config value between min and max
+// inclusive
+func ParseConfigInt(value string, min, max int64) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("argument couldn't be parsed into an integer")
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+	}
+	return n, nil
+}
+
+// ParseConfigMemory parses a byte count with an optional redis.conf unit:
+// k, m and g are powers of 1000, kb, mb and gb powers of 1024
+func ParseConfigMemory(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"b", 1}}
+	lower, scale := strings.ToLower(value), int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(lower, unit.suffix) {
+			lower, scale = strings.TrimSuffix(lower, unit.suffix), unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/scale {
+		return 0, errors.New("argument must be a memory value")
+	}
+	return n * scale, nil
+}
+
 // ConfigHandler handles the CONFIG command
 func ConfigHandler() Handler {
+	return ConfigHandlerWithParams(ConfigParams{
+		"databases": ImmutableConfigParam(strconv.Itoa(DefaultDatabases)),
+	})
+}
+
+// ConfigHandlerWithParams handles the CONFIG command over params
+func ConfigHandlerWithParams(params ConfigParams) Handler {
+	return ConfigHandlerWithStats(params, nil)
+}
+
+// ConfigHandlerWithStats handles the CONFIG command like
+// ConfigHandlerWithParams; CONFIG RESETSTAT resets the stats of statsProvider
+// when it exposes GetStats() *stats.OptimizedStatsManager
+func ConfigHandlerWithStats(params ConfigParams, statsProvider interface{}) Handler {
+	return ConfigHandlerWithRewrite(params, statsProvider, nil)
+}
+
+// ConfigHandlerWithRewrite handles the CONFIG command like
+// ConfigHandlerWithStats; CONFIG REWRITE calls rewrite, which is nil when
+// the server runs without a config file
+func ConfigHandlerWithRewrite(params ConfigParams, statsProvider interface{}, rewrite func() error) Handler {
+	type provider interface {
+		GetStats() *stats.OptimizedStatsManager
+	}
 	return func(args []resp.Value) (resp.Value, error) {
 		if len(args) == 0 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CONFIG' command")
 		}
 
-		subcommand := args[0].Str
-		switch subcommand {
-		case "GET", "get":
+		switch strings.ToUpper(args[0].Str) {
+		case "GET":
 			if len(args) < 2 {
 				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CONFIG GET' command")
 			}
-
-			// Return proper format for CONFIG GET (key-value pairs)
-			configKey := args[1].Str
-			var configValue string
-			switch configKey {
-			case "port":
-				configValue = "6380"
-			case "bind":
-				configValue = "0.0.0.0"
-			case "timeout":
-				configValue = "0"
-			case "tcp-keepalive":
-				configValue = "300"
-			case "databases":
-				configValue = "16"
-			case "appendonly":
-				configValue = "true"
-			case "save":
-				configValue = "3600 1 300 100 60 10000"
-			default:
-				configValue = ""
-			}
-
-			if configValue == "" {
-				return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
-			}
-
-			return resp.Value{Type: resp.Array, Array: []resp.Value{
-				{Type: resp.BulkString, Str: configKey},
-				{Type: resp.BulkString, Str: configValue},
-			}}, nil
-		case "SET", "set":
-			if len(args) < 3 {
+			return configGet(params, args[1:]), nil
+		case "SET":
+			if len(args) < 3 || len(args)%2 == 0 {
 				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CONFIG SET' command")
 			}
+			if err := configSet(params, args[1:]); err != nil {
+				return resp.Value{}, err
+			}
+			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		case "RESETSTAT":
+			if len(args) != 1 {
+				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CONFIG RESETSTAT' command")
+			}
+			if p, ok := statsProvider.(provider); ok && p != nil {
+				p.GetStats().ResetStats()
+			}
 			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		case "REWRITE":
+			if len(args) != 1 {
+				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CONFIG REWRITE' command")
+			}
+			if rewrite == nil {
+				return resp.Value{}, fmt.Errorf("ERR The server is running without a config file")
+			}
+			if err := rewrite(); err != nil {
+				return resp.Value{}, fmt.Errorf("ERR Rewriting config file: %v", err)
+			}
+			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		case "HELP":
+			lines := []string{
+				"CONFIG <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+				"GET <pattern>",
+				"    Return parameters matching the glob-like <pattern> and their values.",
+				"SET <directive> <value>",
+				"    Set the configuration <directive> to <value>.",
+				"RESETSTAT",
+				"    Reset statistics reported by the INFO command.",
+				"REWRITE",
+				"    Rewrite the configuration file.",
+				"HELP",
+				"    Print this help.",
+			}
+			out := make([]resp.Value, len(lines))
+			for i, line := range lines {
+				out[i] = resp.Value{Type: resp.SimpleString, Str: line}
+			}
+			return resp.Value{Type: resp.Array, Array: out}, nil
 		default:
 			return resp.Value{}, fmt.Errorf("ERR unknown subcommand or wrong number of arguments for 'CONFIG' command")
 		}
 	}
 }
+
+// configGet returns the parameters matching any of the glob patterns, sorted
+// by name
+func configGet(params ConfigParams, patterns []resp.Value) resp.Value {
+	var names []string
+	for name := range params {
+		for _, pattern := range patterns {
+			if glob.MatchNoCase(pattern.Str, name) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	out := make([]resp.Value, 0, 2*len(names))
+	for _, name := range names {
+		out = append(out,
+			resp.Value{Type: resp.BulkString, Str: name},
+			resp.Value{Type: resp.BulkString, Str: params[name].Get()})
+	}
+	return resp.Value{Type: resp.Map, Array: out}
+}
+
+// configSet applies name, value pairs. Either all of them are applied or,
+// when one fails, the ones already applied are restored.
+func configSet(params ConfigParams, pairs []resp.Value) error {
+	seen := make(map[string]bool, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		name := pairs[i].Str
+		key := strings.ToLower(name)
+		param, ok := params[key]
+		if !ok {
+			return fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", name)
+		}
+		if param.Set == nil {
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - can't set immutable config", name)
+		}
+		if seen[key] {
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - duplicate parameter", name)
+		}
+		seen[key] = true
+	}
+
+	old := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		name := pairs[i].Str
+		param := params[strings.ToLower(name)]
+		previous := param.Get()
+		if err := param.Set(pairs[i+1].Str); err != nil {
+			for j := len(old) - 1; j >= 0; j-- {
+				params[strings.ToLower(pairs[2*j].Str)].Set(old[j])
+			}
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %v", name, err)
+		}
+		old = append(old, previous)
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		name := pairs[i].Str
+		param := params[strings.ToLower(name)]
+		if param.Apply == nil {
+			continue
+		}
+		if err := param.Apply(); err != nil {
+			for j := len(old) - 1; j >= 0; j-- {
+				params[strings.ToLower(pairs[2*j].Str)].Set(old[j])
+			}
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %v", name, err)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"gridhouse/internal/resp"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfigHandler(t *testing.T) {
@@ -29,58 +32,15 @@ func TestConfigHandler(t *testing.T) {
 		assert.Equal(t, resp.Value{}, result)
 	})
 
-	t.Run("CONFIG GET port", func(t *testing.T) {
+	t.Run("CONFIG GET parameter the handler doesn't serve", func(t *testing.T) {
 		args := []resp.Value{
 			{Type: resp.BulkString, Str: "GET"},
 			{Type: resp.BulkString, Str: "port"},
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, resp.BulkString, result.Array[0].Type)
-		assert.Equal(t, "port", result.Array[0].Str)
-		assert.Equal(t, resp.BulkString, result.Array[1].Type)
-		assert.Equal(t, "6380", result.Array[1].Str)
-	})
-
-	t.Run("CONFIG GET bind", func(t *testing.T) {
-		args := []resp.Value{
-			{Type: resp.BulkString, Str: "GET"},
-			{Type: resp.BulkString, Str: "bind"},
-		}
-		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "bind", result.Array[0].Str)
-		assert.Equal(t, "0.0.0.0", result.Array[1].Str)
-	})
-
-	t.Run("CONFIG GET timeout", func(t *testing.T) {
-		args := []resp.Value{
-			{Type: resp.BulkString, Str: "GET"},
-			{Type: resp.BulkString, Str: "timeout"},
-		}
-		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "timeout", result.Array[0].Str)
-		assert.Equal(t, "0", result.Array[1].Str)
-	})
-
-	t.Run("CONFIG GET tcp-keepalive", func(t *testing.T) {
-		args := []resp.Value{
-			{Type: resp.BulkString, Str: "GET"},
-			{Type: resp.BulkString, Str: "tcp-keepalive"},
-		}
-		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "tcp-keepalive", result.Array[0].Str)
-		assert.Equal(t, "300", result.Array[1].Str)
+		assert.Equal(t, resp.Map, result.Type)
+		assert.Len(t, result.Array, 0)
 	})
 
 	t.Run("CONFIG GET databases", func(t *testing.T) {
@@ -90,38 +50,12 @@ func TestConfigHandler(t *testing.T) {
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
+		assert.Equal(t, resp.Map, result.Type)
 		assert.Len(t, result.Array, 2)
 		assert.Equal(t, "databases", result.Array[0].Str)
 		assert.Equal(t, "16", result.Array[1].Str)
 	})
 
-	t.Run("CONFIG GET appendonly", func(t *testing.T) {
-		args := []resp.Value{
-			{Type: resp.BulkString, Str: "GET"},
-			{Type: resp.BulkString, Str: "appendonly"},
-		}
-		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "appendonly", result.Array[0].Str)
-		assert.Equal(t, "true", result.Array[1].Str)
-	})
-
-	t.Run("CONFIG GET save", func(t *testing.T) {
-		args := []resp.Value{
-			{Type: resp.BulkString, Str: "GET"},
-			{Type: resp.BulkString, Str: "save"},
-		}
-		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
-		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "save", result.Array[0].Str)
-		assert.Equal(t, "3600 1 300 100 60 10000", result.Array[1].Str)
-	})
-
 	t.Run("CONFIG GET unknown key", func(t *testing.T) {
 		args := []resp.Value{
 			{Type: resp.BulkString, Str: "GET"},
@@ -129,21 +63,21 @@ func TestConfigHandler(t *testing.T) {
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
+		assert.Equal(t, resp.Map, result.Type)
 		assert.Len(t, result.Array, 0) // Empty array for unknown keys
 	})
 
 	t.Run("CONFIG GET case insensitive", func(t *testing.T) {
 		args := []resp.Value{
 			{Type: resp.BulkString, Str: "get"},
-			{Type: resp.BulkString, Str: "port"},
+			{Type: resp.BulkString, Str: "DATABASES"},
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
+		assert.Equal(t, resp.Map, result.Type)
 		assert.Len(t, result.Array, 2)
-		assert.Equal(t, "port", result.Array[0].Str)
-		assert.Equal(t, "6380", result.Array[1].Str)
+		assert.Equal(t, "databases", result.Array[0].Str)
+		assert.Equal(t, "16", result.Array[1].Str)
 	})
 
 	t.Run("CONFIG SET with insufficient arguments", func(t *testing.T) {
@@ -157,28 +91,28 @@ func TestConfigHandler(t *testing.T) {
 		assert.Equal(t, resp.Value{}, result)
 	})
 
-	t.Run("CONFIG SET with correct arguments", func(t *testing.T) {
+	t.Run("CONFIG SET with an odd number of arguments", func(t *testing.T) {
 		args := []resp.Value{
 			{Type: resp.BulkString, Str: "SET"},
 			{Type: resp.BulkString, Str: "port"},
 			{Type: resp.BulkString, Str: "6381"},
+			{Type: resp.BulkString, Str: "bind"},
 		}
 		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.SimpleString, result.Type)
-		assert.Equal(t, "OK", result.Str)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "wrong number of arguments for 'CONFIG SET' command")
+		assert.Equal(t, resp.Value{}, result)
 	})
 
-	t.Run("CONFIG SET case insensitive", func(t *testing.T) {
+	t.Run("CONFIG SET unknown parameter", func(t *testing.T) {
 		args := []resp.Value{
 			{Type: resp.BulkString, Str: "set"},
 			{Type: resp.BulkString, Str: "port"},
 			{Type: resp.BulkString, Str: "6381"},
 		}
 		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.SimpleString, result.Type)
-		assert.Equal(t, "OK", result.Str)
+		assert.EqualError(t, err, "ERR Unknown option or number of arguments for CONFIG SET - 'port'")
+		assert.Equal(t, resp.Value{}, result)
 	})
 
 	t.Run("CONFIG with unknown subcommand", func(t *testing.T) {
@@ -208,7 +142,7 @@ func TestConfigHandler(t *testing.T) {
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
+		assert.Equal(t, resp.Map, result.Type)
 		assert.Len(t, result.Array, 0) // Empty array for empty key
 	})
 
@@ -219,9 +153,8 @@ func TestConfigHandler(t *testing.T) {
 			{Type: resp.BulkString, Str: ""},
 		}
 		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.SimpleString, result.Type)
-		assert.Equal(t, "OK", result.Str)
+		assert.EqualError(t, err, "ERR Unknown option or number of arguments for CONFIG SET - ''")
+		assert.Equal(t, resp.Value{}, result)
 	})
 
 	t.Run("CONFIG GET with special characters in key", func(t *testing.T) {
@@ -231,7 +164,7 @@ func TestConfigHandler(t *testing.T) {
 		}
 		result, err := handler(args)
 		assert.NoError(t, err)
-		assert.Equal(t, resp.Array, result.Type)
+		assert.Equal(t, resp.Map, result.Type)
 		assert.Len(t, result.Array, 0) // Empty array for unknown key
 	})
 
@@ -242,8 +175,130 @@ func TestConfigHandler(t *testing.T) {
 			{Type: resp.BulkString, Str: "special-value-123"},
 		}
 		result, err := handler(args)
-		assert.NoError(t, err)
-		assert.Equal(t, resp.SimpleString, result.Type)
-		assert.Equal(t, "OK", result.Str)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Unknown option")
+		assert.Equal(t, resp.Value{}, result)
+	})
+}
+
+func TestConfigHandlerWithParams(t *testing.T) {
+	value := ""
+	handler := ConfigHandlerWithParams(ConfigParams{
+		"databases": ImmutableConfigParam("4"),
+		"notify-keyspace-events": {
+			Get: func() string { return value },
+			Set: func(v string) error {
+				if v == "bad" {
+					return errors.New("Invalid event class character")
+				}
+				value = v
+				return nil
+			},
+		},
 	})
+
+	// Parameters are reported even when empty
+	result, err := handler(bulkArgs("GET", "notify-keyspace-events"))
+	require.NoError(t, err)
+	assert.Equal(t, bulkArgs("notify-keyspace-events", ""), result.Array)
+
+	result, err = handler(bulkArgs("SET", "NOTIFY-KEYSPACE-EVENTS", "KEA"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", result.Str)
+	assert.Equal(t, "KEA", value)
+
+	_, err = handler(bulkArgs("SET", "notify-keyspace-events", "bad"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'notify-keyspace-events') - Invalid event class character")
+	assert.Equal(t, "KEA", value)
+
+	_, err = handler(bulkArgs("SET", "databases", "8"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'databases') - can't set immutable config")
+	result, err = handler(bulkArgs("GET", "databases"))
+	require.NoError(t, err)
+	assert.Equal(t, "4", result.Array[1].Str)
+
+	// Patterns are globs, several can be given and results are sorted
+	result, err = handler(bulkArgs("GET", "notify-*", "data*", "*-events"))
+	require.NoError(t, err)
+	assert.Equal(t, bulkArgs("databases", "4", "notify-keyspace-events", "KEA"), result.Array)
+
+	_, err = handler(bulkArgs("REWRITE"))
+	require.EqualError(t, err, "ERR The server is running without a config file")
+}
+
+func TestConfigHandlerSetIsAtomic(t *testing.T) {
+	enabled, level, limit := false, "info", int64(10)
+	handler := ConfigHandlerWithRewrite(ConfigParams{
+		"enabled": BoolConfigParam(func() bool { return enabled }, func(b bool) error { enabled = b; return nil }),
+		"level": EnumConfigParam([]string{"debug", "info"}, func() string { return level },
+			func(v string) error { level = v; return nil }),
+		"limit": IntConfigParam(0, 100, func() int64 { return limit }, func(n int64) error { limit = n; return nil }),
+	}, nil, func() error { return errors.New("disk full") })
+
+	result, err := handler(bulkArgs("SET", "enabled", "YES", "level", "DEBUG", "limit", "20"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", result.Str)
+	assert.True(t, enabled)
+	assert.Equal(t, "debug", level)
+	assert.Equal(t, int64(20), limit)
+
+	result, err = handler(bulkArgs("GET", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, bulkArgs("enabled", "yes", "level", "debug", "limit", "20"), result.Array)
+
+	// A failing value leaves every parameter as it was
+	_, err = handler(bulkArgs("SET", "enabled", "no", "level", "info", "limit", "200"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'limit') - argument must be between 0 and 100 inclusive")
+	assert.True(t, enabled)
+	assert.Equal(t, "debug", level)
+	assert.Equal(t, int64(20), limit)
+
+	_, err = handler(bulkArgs("SET", "limit", "x"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'limit') - argument couldn't be parsed into an integer")
+	_, err = handler(bulkArgs("SET", "enabled", "maybe"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'enabled') - argument must be 'yes' or 'no'")
+	_, err = handler(bulkArgs("SET", "level", "trace"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'level') - argument(s) must be one of the following: debug, info")
+	_, err = handler(bulkArgs("SET", "limit", "1", "LIMIT", "2"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'LIMIT') - duplicate parameter")
+
+	_, err = handler(bulkArgs("REWRITE"))
+	require.EqualError(t, err, "ERR Rewriting config file: disk full")
+}
+
+func TestConfigHandlerSetApply(t *testing.T) {
+	cert, key := "a.crt", "a.key"
+	applied := 0
+	apply := func() error {
+		applied++
+		if strings.TrimSuffix(cert, ".crt") != strings.TrimSuffix(key, ".key") {
+			return errors.New("certificate and key don't match")
+		}
+		return nil
+	}
+	str := func(v *string) ConfigParam {
+		return ConfigParam{Get: func() string { return *v }, Set: func(s string) error { *v = s; return nil }, Apply: apply}
+	}
+	handler := ConfigHandlerWithParams(ConfigParams{"cert": str(&cert), "key": str(&key)})
+
+	// Settings are checked together once all of them are set
+	_, err := handler(bulkArgs("SET", "cert", "b.crt", "key", "b.key"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	_, err = handler(bulkArgs("SET", "cert", "c.crt"))
+	require.EqualError(t, err, "ERR CONFIG SET failed (possibly related to argument 'cert') - certificate and key don't match")
+	assert.Equal(t, "b.crt", cert)
+}
+
+func TestParseConfigMemory(t *testing.T) {
+	for value, want := range map[string]int64{"100": 100, "1k": 1000, "1KB": 1024, "2mb": 2 << 20, "1g": 1e9, "1gb": 1 << 30, "5b": 5} {
+		n, err := ParseConfigMemory(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, n, value)
+	}
+	for _, value := range []string{"", "mb", "-1", "1tb", "99999999999gb"} {
+		_, err := ParseConfigMemory(value)
+		assert.EqualError(t, err, "argument must be a memory value", value)
+	}
 }
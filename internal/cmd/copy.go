@@ -124,6 +124,7 @@ func CopyHandler(dataStore DataStore) Handler {
 			}
 		}
 
+		dataStore.NotifyKeyEvent("copy_to", destKey)
 		return resp.Value{Type: resp.Integer, Int: 1}, nil
 	}
 }
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"strconv"
+	"strings"
+)
+
+// DefaultDatabases is the number of databases a server has unless configured
+const DefaultDatabases = 16
+
+// Databases is the set of numbered databases of a server
+type Databases interface {
+	Count() int
+	Flush(index int)
+	FlushAll()
+	Swap(a, b int)
+	Move(key string, from, to int) bool
+	Empty() bool
+}
+
+// RegisterDatabaseCommands registers the commands that work across
+// databases for the registry serving database index. persist may be nil.
+func RegisterDatabaseCommands(registry *Registry, dbs Databases, index int, persist PersistenceManager) {
+	registry.Register(&Command{
+		Name:      "SELECT",
+		Arity:     -1,
+		Handler:   SelectHandler(dbs),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclFast, AclConnection},
+	})
+	registry.Register(&Command{
+		Name:      "SWAPDB",
+		Arity:     -1,
+		Handler:   SwapDBHandler(dbs),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast, AclDangerous},
+	})
+	registry.Register(&Command{
+		Name:      "MOVE",
+		Arity:     -1,
+		Handler:   MoveHandler(dbs, index),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclFast},
+		Keys:      FirstKey,
+	})
+	registry.Register(&Command{
+		Name:      "FLUSHDB",
+		Arity:     -1,
+		Handler:   FlushDBInDatabasesHandler(dbs, index, persist),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow, AclDangerous},
+	})
+	registry.Register(&Command{
+		Name:      "FLUSHALL",
+		Arity:     -1,
+		Handler:   FlushAllHandler(dbs, persist),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclKeyspace, AclWrite, AclSlow, AclDangerous},
+	})
+}
+
+// SelectHandler validates SELECT index. Switching the database is
+// connection state, so the server applies it once the command succeeds.
+func SelectHandler(dbs Databases) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SELECT' command")
+		}
+		if _, err := parseDBIndex(dbs, args[0].Str, "ERR value is not an integer or out of range"); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// SwapDBHandler handles SWAPDB index1 index2
+func SwapDBHandler(dbs Databases) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SWAPDB' command")
+		}
+		a, err := parseDBIndex(dbs, args[0].Str, "ERR invalid first DB index")
+		if err != nil {
+			return resp.Value{}, err
+		}
+		b, err := parseDBIndex(dbs, args[1].Str, "ERR invalid second DB index")
+		if err != nil {
+			return resp.Value{}, err
+		}
+		dbs.Swap(a, b)
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// MoveHandler handles MOVE key db for keys of database index
+func MoveHandler(dbs Databases, index int) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'MOVE' command")
+		}
+		to, err := parseDBIndex(dbs, args[1].Str, "ERR value is not an integer or out of range")
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if to == index {
+			return resp.Value{}, fmt.Errorf("ERR source and destination objects are the same")
+		}
+		if dbs.Move(args[0].Str, index, to) {
+			return resp.Value{Type: resp.Integer, Int: 1}, nil
+		}
+		return resp.Value{Type: resp.Integer, Int: 0}, nil
+	}
+}
+
+// FlushDBInDatabasesHandler handles FLUSHDB [ASYNC|SYNC] for database index.
+// The persistence files are only cleared once no database holds keys, as
+// they also carry the other databases.
+func FlushDBInDatabasesHandler(dbs Databases, index int, persist PersistenceManager) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if err := parseFlushMode(args); err != nil {
+			return resp.Value{}, err
+		}
+		dbs.Flush(index)
+		if persist != nil && dbs.Empty() {
+			// Memory is already cleared; FLUSHDB succeeds regardless
+			_ = persist.ClearData()
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// FlushAllHandler handles FLUSHALL [ASYNC|SYNC]
+func FlushAllHandler(dbs Databases, persist PersistenceManager) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if err := parseFlushMode(args); err != nil {
+			return resp.Value{}, err
+		}
+		dbs.FlushAll()
+		if persist != nil {
+			// Memory is already cleared; FLUSHALL succeeds regardless
+			_ = persist.ClearData()
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// parseDBIndex parses a database index, returning notInteger when s is not
+// a number
+func parseDBIndex(dbs Databases, s, notInteger string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s", notInteger)
+	}
+	if n < 0 || n >= dbs.Count() {
+		return 0, fmt.Errorf("ERR DB index is out of range")
+	}
+	return n, nil
+}
+
+// parseFlushMode accepts the optional ASYNC or SYNC of FLUSHDB and FLUSHALL;
+// both flush synchronously
+func parseFlushMode(args []resp.Value) error {
+	switch {
+	case len(args) == 0:
+		return nil
+	case len(args) == 1 && (strings.EqualFold(args[0].Str, "ASYNC") || strings.EqualFold(args[0].Str, "SYNC")):
+		return nil
+	default:
+		return fmt.Errorf("ERR syntax error")
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clearCounter struct {
+	PersistenceManager
+	clears int
+}
+
+func (c *clearCounter) ClearData() error {
+	c.clears++
+	return nil
+}
+
+func TestDatabaseCommands(t *testing.T) {
+	dbs := store.NewDatabases(3)
+	defer dbs.Close()
+	persist := &clearCounter{}
+	registry := NewRegistry()
+	RegisterDatabaseCommands(registry, dbs, 0, persist)
+
+	res, err := registry.Execute("SELECT", bulkArgs("2"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", res.Str)
+	_, err = registry.Execute("SELECT", bulkArgs("3"))
+	require.EqualError(t, err, "ERR DB index is out of range")
+	_, err = registry.Execute("SELECT", bulkArgs("x"))
+	require.EqualError(t, err, "ERR value is not an integer or out of range")
+
+	dbs.DB(0).Set("k", "v", time.Time{})
+	res, err = registry.Execute("MOVE", bulkArgs("k", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Int)
+	res, err = registry.Execute("MOVE", bulkArgs("k", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), res.Int)
+	_, err = registry.Execute("MOVE", bulkArgs("k", "0"))
+	require.EqualError(t, err, "ERR source and destination objects are the same")
+
+	_, err = registry.Execute("SWAPDB", bulkArgs("a", "1"))
+	require.EqualError(t, err, "ERR invalid first DB index")
+	_, err = registry.Execute("SWAPDB", bulkArgs("0", "9"))
+	require.EqualError(t, err, "ERR DB index is out of range")
+	_, err = registry.Execute("SWAPDB", bulkArgs("0", "1"))
+	require.NoError(t, err)
+	assert.True(t, dbs.DB(0).Exists("k"))
+
+	// FLUSHDB keeps the persistence files while other databases hold keys
+	dbs.DB(2).Set("other", "v", time.Time{})
+	_, err = registry.Execute("FLUSHDB", bulkArgs("ASYNC"))
+	require.NoError(t, err)
+	assert.False(t, dbs.DB(0).Exists("k"))
+	assert.Equal(t, 0, persist.clears)
+	_, err = registry.Execute("FLUSHDB", bulkArgs("LATER"))
+	require.EqualError(t, err, "ERR syntax error")
+
+	_, err = registry.Execute("FLUSHALL", nil)
+	require.NoError(t, err)
+	assert.True(t, dbs.Empty())
+	assert.Equal(t, 1, persist.clears)
+
+	res, err = ConfigHandlerWithParams(ConfigParams{
+		"databases": ImmutableConfigParam(strconv.Itoa(dbs.Count())),
+	})(bulkArgs("GET", "databases"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", res.Array[1].Str)
+}
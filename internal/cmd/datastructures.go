@@ -18,4 +18,46 @@ type DataStore interface {
 	GetOrCreateSortedSet(key string) *store.SortedSet
 	// Stream operations
 	GetOrCreateStream(key string) *store.Stream
+
+	// Type-checked access used by command handlers. Get* never creates the
+	// key; Ensure* creates it when missing. Both return store.ErrWrongType
+	// when the key holds another kind of value.
+	GetList(key string) (*store.List, error)
+	EnsureList(key string) (*store.List, error)
+	GetSet(key string) (*store.Set, error)
+	EnsureSet(key string) (*store.Set, error)
+	StoreSet(key string, members []string) bool
+	GetHash(key string) (*store.Hash, error)
+	EnsureHash(key string) (*store.Hash, error)
+	GetSortedSet(key string) (*store.SortedSet, error)
+	EnsureSortedSet(key string) (*store.SortedSet, error)
+	GetStream(key string) (*store.Stream, error)
+	EnsureStream(key string) (*store.Stream, error)
+	// DeleteIfEmpty deletes key once a command removed its last element
+	DeleteIfEmpty(key string) bool
+
+	// NotifyKeyEvent reports a modification so that blocked clients and
+	// other key watchers can react to it
+	NotifyKeyEvent(event, key string)
+}
+
+// keyNotifier is implemented by stores that report key events
+type keyNotifier interface {
+	NotifyKeyEvent(event, key string)
+}
+
+// notifyKeyEvent reports event on key when store supports key events.
+// Handlers that only need a Store use it instead of DataStore.NotifyKeyEvent.
+func notifyKeyEvent(store Store, event, key string) {
+	if n, ok := store.(keyNotifier); ok {
+		n.NotifyKeyEvent(event, key)
+	}
+}
+
+// deleteIfEmpty deletes key when the command left its collection empty and
+// reports the "del" event, as Redis does after removing the last element
+func deleteIfEmpty(ds DataStore, key string) {
+	if ds.DeleteIfEmpty(key) {
+		ds.NotifyKeyEvent("del", key)
+	}
 }
@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
 	"gridhouse/internal/store"
 )
 
@@ -14,3 +20,134 @@ func newMockDataStore() *mockDataStore {
 		UltraOptimizedDB: store.NewUltraOptimizedDB(),
 	}
 }
+
+// newScopedMockDataStore returns a mock store that is closed when tb ends,
+// so that its background cleanup stops and its shard maps can be collected
+func newScopedMockDataStore(tb testing.TB) *mockDataStore {
+	ds := newMockDataStore()
+	tb.Cleanup(ds.Close)
+	return ds
+}
+
+func bulkArgs(args ...string) []resp.Value {
+	vals := make([]resp.Value, len(args))
+	for i, a := range args {
+		vals[i] = resp.Value{Type: resp.BulkString, Str: a}
+	}
+	return vals
+}
+
+func TestDataStructureHandlersWrongType(t *testing.T) {
+	ds := newMockDataStore()
+	ds.Set("str", "value", time.Time{})
+	l, _ := ds.EnsureList("list")
+	l.RPush("a")
+
+	cases := []struct {
+		name    string
+		handler Handler
+		args    []resp.Value
+	}{
+		{"LPUSH", LPushHandler(ds), bulkArgs("str", "x")},
+		{"RPUSH", RPushHandler(ds), bulkArgs("str", "x")},
+		{"LPOP", LPopHandler(ds), bulkArgs("str")},
+		{"RPOP", RPopHandler(ds), bulkArgs("str")},
+		{"LLEN", LLenHandler(ds), bulkArgs("str")},
+		{"LRANGE", LRangeHandler(ds), bulkArgs("str", "0", "-1")},
+		{"LINDEX", LIndexHandler(ds), bulkArgs("str", "0")},
+		{"SADD", SAddHandler(ds), bulkArgs("list", "x")},
+		{"SREM", SRemHandler(ds), bulkArgs("list", "x")},
+		{"SISMEMBER", SIsMemberHandler(ds), bulkArgs("list", "x")},
+		{"SMEMBERS", SMembersHandler(ds), bulkArgs("list")},
+		{"SCARD", SCardHandler(ds), bulkArgs("list")},
+		{"SPOP", SPopHandler(ds), bulkArgs("list")},
+		{"HSET", HSetHandler(ds), bulkArgs("list", "f", "v")},
+		{"HGET", HGetHandler(ds), bulkArgs("list", "f")},
+		{"HGETALL", HGetAllHandler(ds), bulkArgs("list")},
+		{"HINCRBY", HIncrByHandler(ds), bulkArgs("list", "f", "1")},
+		{"ZADD", ZAddHandler(ds), bulkArgs("list", "1", "m")},
+		{"ZSCORE", ZScoreHandler(ds), bulkArgs("list", "m")},
+		{"ZRANGE", ZRangeHandler(ds), bulkArgs("list", "0", "-1")},
+		{"XADD", XAddHandler(ds), bulkArgs("list", "*", "f", "v")},
+		{"XLEN", XLenHandler(ds), bulkArgs("list")},
+		{"XRANGE", XRangeHandler(ds), bulkArgs("list", "-", "+")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.handler(tc.args)
+			if !errors.Is(err, store.ErrWrongType) {
+				t.Fatalf("expected WRONGTYPE, got %v", err)
+			}
+		})
+	}
+
+	// The original values must survive the failed commands
+	if v, ok := ds.Get("str"); !ok || v != "value" {
+		t.Fatalf("string value was modified: %q %v", v, ok)
+	}
+	if l.LLen() != 1 {
+		t.Fatalf("list was modified, len=%d", l.LLen())
+	}
+}
+
+func TestDataStructureReadsDoNotCreateKeys(t *testing.T) {
+	ds := newMockDataStore()
+	reads := []struct {
+		handler Handler
+		args    []resp.Value
+	}{
+		{LLenHandler(ds), bulkArgs("k")},
+		{LPopHandler(ds), bulkArgs("k")},
+		{LRangeHandler(ds), bulkArgs("k", "0", "-1")},
+		{SCardHandler(ds), bulkArgs("k")},
+		{SIsMemberHandler(ds), bulkArgs("k", "m")},
+		{HGetHandler(ds), bulkArgs("k", "f")},
+		{HLenHandler(ds), bulkArgs("k")},
+		{ZCardHandler(ds), bulkArgs("k")},
+		{XLenHandler(ds), bulkArgs("k")},
+		{XReadHandler(ds), bulkArgs("STREAMS", "k", "0-0")},
+	}
+	for _, r := range reads {
+		if _, err := r.handler(r.args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if ds.Exists("k") {
+		t.Fatalf("read-only commands created the key")
+	}
+}
+
+func TestEmptiedCollectionsAreDeleted(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	var events []string
+	ds.OnKeyEvent(func(event, key string) { events = append(events, event+":"+key) })
+
+	if _, err := RPushHandler(ds)(bulkArgs("k", "a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := LPopHandler(ds)(bulkArgs("k")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.Exists("k") {
+		t.Fatalf("popping the last element kept the list")
+	}
+	if want := []string{"rpush:k", "lpop:k", "del:k"}; !slices.Equal(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	// The key is free for another type
+	if v, err := SAddHandler(ds)(bulkArgs("k", "x")); err != nil || v.Int != 1 {
+		t.Fatalf("SADD after the pop = %v, %v", v, err)
+	}
+
+	if _, err := ZAddHandler(ds)(bulkArgs("z", "1", "a", "2", "b")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ZRemHandler(ds)(bulkArgs("z", "a"))
+	if !ds.Exists("z") {
+		t.Fatalf("ZREM deleted a non-empty sorted set")
+	}
+	ZPopMinHandler(ds)(bulkArgs("z"))
+	if ds.Exists("z") {
+		t.Fatalf("popping the last member kept the sorted set")
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expiryStore is implemented by stores that take absolute and conditional
+// expirations
+type expiryStore interface {
+	ExpireAt(key string, at time.Time, flags store.ExpireFlags) bool
+	Persist(key string) bool
+	PExpireTime(key string) int64
+}
+
+// expiryOf returns the expiration operations of s. Stores without them get
+// them emulated on top of PTTL and Expire.
+func expiryOf(s Store) expiryStore {
+	if es, ok := s.(expiryStore); ok {
+		return es
+	}
+	return plainExpiry{s}
+}
+
+// plainExpiry emulates expiryStore for stores that only offer Store
+type plainExpiry struct{ Store }
+
+func (p plainExpiry) ExpireAt(key string, at time.Time, flags store.ExpireFlags) bool {
+	current := p.PExpireTime(key)
+	if current == -2 {
+		return false
+	}
+	if current == -1 {
+		current = 0
+	} else {
+		current *= int64(time.Millisecond)
+	}
+	if !flags.Allows(current, at.UnixNano()) {
+		return false
+	}
+	if d := time.Until(at); d > 0 {
+		return p.Expire(key, d)
+	}
+	return p.Del(key)
+}
+
+func (p plainExpiry) Persist(key string) bool {
+	value, ok := p.Get(key)
+	if !ok || p.PTTL(key) < 0 {
+		return false
+	}
+	p.Set(key, value, time.Time{})
+	return true
+}
+
+func (p plainExpiry) PExpireTime(key string) int64 {
+	ms := p.PTTL(key)
+	if ms < 0 {
+		return ms
+	}
+	return time.Now().Add(time.Duration(ms) * time.Millisecond).UnixMilli()
+}
+
+// expireDeadline converts the time argument of EXPIRE, PEXPIRE, EXPIREAT
+// or PEXPIREAT, counted in unit from now or from the Unix epoch, into an
+// absolute time. Times in the past are allowed and delete the key.
+func expireDeadline(arg string, unit time.Duration, absolute bool, command string) (time.Time, error) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	base := int64(0)
+	if !absolute {
+		base = time.Now().UnixNano()
+	}
+	if n > (math.MaxInt64-base)/int64(unit) || n < -math.MaxInt64/int64(unit) {
+		return time.Time{}, fmt.Errorf("ERR invalid expire time in '%s' command", command)
+	}
+	return time.Unix(0, base+n*int64(unit)), nil
+}
+
+// parseExpireFlags parses the NX, XX, GT and LT options of the EXPIRE family
+func parseExpireFlags(args []resp.Value) (store.ExpireFlags, error) {
+	var flags store.ExpireFlags
+	for _, arg := range args {
+		switch strings.ToUpper(arg.Str) {
+		case "NX":
+			flags |= store.ExpireNX
+		case "XX":
+			flags |= store.ExpireXX
+		case "GT":
+			flags |= store.ExpireGT
+		case "LT":
+			flags |= store.ExpireLT
+		default:
+			return 0, fmt.Errorf("ERR Unsupported option %s", arg.Str)
+		}
+	}
+	if flags&store.ExpireNX != 0 && flags != store.ExpireNX {
+		return 0, fmt.Errorf("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if flags&store.ExpireGT != 0 && flags&store.ExpireLT != 0 {
+		return 0, fmt.Errorf("ERR GT and LT options at the same time are not compatible")
+	}
+	return flags, nil
+}
+
+// PExpireHandler handles PEXPIRE key milliseconds [NX|XX|GT|LT]
+func PExpireHandler(s Store) Handler {
+	return expireHandler(s, "pexpire", time.Millisecond, false)
+}
+
+// ExpireAtHandler handles EXPIREAT key unix-time-seconds [NX|XX|GT|LT]
+func ExpireAtHandler(s Store) Handler {
+	return expireHandler(s, "expireat", time.Second, true)
+}
+
+// PExpireAtHandler handles PEXPIREAT key unix-time-milliseconds [NX|XX|GT|LT]
+func PExpireAtHandler(s Store) Handler {
+	return expireHandler(s, "pexpireat", time.Millisecond, true)
+}
+
+// expireHandler serves the EXPIRE family. A deadline that has already
+// passed deletes the key.
+func expireHandler(s Store, name string, unit time.Duration, absolute bool) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToUpper(name))
+		}
+		at, err := expireDeadline(args[1].Str, unit, absolute, name)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		flags, err := parseExpireFlags(args[2:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+
+		key := args[0].Str
+		if !expiryOf(s).ExpireAt(key, at, flags) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		if at.After(time.Now()) {
+			notifyKeyEvent(s, "expire", key)
+		} else {
+			notifyKeyEvent(s, "del", key)
+		}
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+// rewriteExpire propagates a command of the EXPIRE family that took effect
+// as PEXPIREAT, so that replaying the AOF later keeps the deadline
+func rewriteExpire(unit time.Duration, absolute bool) func(args []string, result resp.Value) (string, []string, bool) {
+	return func(args []string, result resp.Value) (string, []string, bool) {
+		if result.Int != 1 || len(args) < 2 {
+			return "", nil, false
+		}
+		at, err := expireDeadline(args[1], unit, absolute, "pexpireat")
+		if err != nil {
+			return "", nil, false
+		}
+		return "PEXPIREAT", []string{args[0], strconv.FormatInt(at.UnixMilli(), 10)}, true
+	}
+}
+
+// PersistHandler handles PERSIST key
+func PersistHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'PERSIST' command")
+		}
+		if !expiryOf(s).Persist(args[0].Str) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		notifyKeyEvent(s, "persist", args[0].Str)
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+// rewritePersist propagates a PERSIST only when it removed an expiration
+func rewritePersist(args []string, result resp.Value) (string, []string, bool) {
+	return "PERSIST", args, result.Int == 1
+}
+
+// ExpireTimeHandler handles EXPIRETIME key
+func ExpireTimeHandler(s Store) Handler {
+	return expireTimeHandler(s, "EXPIRETIME", time.Second)
+}
+
+// PExpireTimeHandler handles PEXPIRETIME key
+func PExpireTimeHandler(s Store) Handler {
+	return expireTimeHandler(s, "PEXPIRETIME", time.Millisecond)
+}
+
+func expireTimeHandler(s Store, name string, unit time.Duration) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+		}
+		ms := expiryOf(s).PExpireTime(args[0].Str)
+		if ms >= 0 {
+			ms /= int64(unit / time.Millisecond)
+		}
+		return resp.Value{Type: resp.Integer, Int: ms}, nil
+	}
+}
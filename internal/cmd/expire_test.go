@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireFamily(t *testing.T) {
+	db := store.NewUltraOptimizedDB()
+	defer db.Close()
+	list, err := db.EnsureList("list")
+	require.NoError(t, err)
+	list.RPush("a")
+
+	result, err := PExpireHandler(db)(bulkArgs("list", "100000", "XX"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	result, err = PExpireHandler(db)(bulkArgs("list", "100000", "nx"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	assert.InDelta(t, 100, db.TTL("list"), 1)
+
+	at := time.Now().Add(time.Hour).Unix()
+	result, err = ExpireAtHandler(db)(bulkArgs("list", strconv.FormatInt(at, 10), "GT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	result, err = ExpireTimeHandler(db)(bulkArgs("list"))
+	require.NoError(t, err)
+	assert.Equal(t, at, result.Int)
+	result, err = PExpireTimeHandler(db)(bulkArgs("list"))
+	require.NoError(t, err)
+	assert.Equal(t, at*1000, result.Int)
+
+	result, err = PExpireAtHandler(db)(bulkArgs("list", strconv.FormatInt(at*1000+1, 10), "LT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+
+	result, err = PersistHandler(db)(bulkArgs("list"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	result, err = ExpireTimeHandler(db)(bulkArgs("list"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), result.Int)
+	result, err = ExpireTimeHandler(db)(bulkArgs("missing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-2), result.Int)
+
+	result, err = OptimizedExpireHandler(db)(bulkArgs("list", "-1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	assert.False(t, db.Exists("list"))
+
+	_, err = OptimizedExpireHandler(db)(bulkArgs("k", "10", "NX", "GT"))
+	assert.EqualError(t, err, "ERR NX and XX, GT or LT options at the same time are not compatible")
+	_, err = OptimizedExpireHandler(db)(bulkArgs("k", "10", "GT", "LT"))
+	assert.EqualError(t, err, "ERR GT and LT options at the same time are not compatible")
+	_, err = OptimizedExpireHandler(db)(bulkArgs("k", "10", "SOON"))
+	assert.EqualError(t, err, "ERR Unsupported option SOON")
+	_, err = OptimizedExpireHandler(db)(bulkArgs("k", "9223372036854775807"))
+	assert.EqualError(t, err, "ERR invalid expire time in 'expire' command")
+}
+
+func TestExpireOnPlainStore(t *testing.T) {
+	s := NewMockStore()
+	s.Set("k", "v", time.Time{})
+
+	result, err := OptimizedExpireHandler(s)(bulkArgs("k", "100", "GT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	result, err = OptimizedExpireHandler(s)(bulkArgs("k", "100", "LT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	assert.Greater(t, s.TTL("k"), int64(0))
+
+	result, err = PersistHandler(s)(bulkArgs("k"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	assert.Equal(t, int64(-1), s.TTL("k"))
+}
+
+func TestRewriteExpire(t *testing.T) {
+	one := resp.Value{Type: resp.Integer, Int: 1}
+
+	name, args, ok := rewriteExpire(time.Second, true)([]string{"k", "100", "NX"}, one)
+	require.True(t, ok)
+	assert.Equal(t, "PEXPIREAT", name)
+	assert.Equal(t, []string{"k", "100000"}, args)
+
+	_, args, ok = rewriteExpire(time.Millisecond, false)([]string{"k", "5000"}, one)
+	require.True(t, ok)
+	at, err := strconv.ParseInt(args[1], 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(5*time.Second).UnixMilli(), at, 1000)
+
+	_, _, ok = rewriteExpire(time.Second, false)([]string{"k", "10"}, resp.Value{Type: resp.Integer})
+	assert.False(t, ok)
+}
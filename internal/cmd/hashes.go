@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"gridhouse/internal/resp"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // Hash Commands
@@ -11,39 +13,78 @@ import (
 // HSetHandler handles the HSET command
 func HSetHandler(store DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) < 3 || len(args)%2 == 0 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'HSET' command")
+		added, err := setHashFields(store, "HSET", args)
+		if err != nil {
+			return resp.Value{}, err
 		}
+		return resp.Value{Type: resp.Integer, Int: int64(added)}, nil
+	}
+}
 
-		key := args[0].Str
-		hash := store.GetOrCreateHash(key)
+// HMSetHandler handles HMSET key field value [field value ...], the older
+// form of HSET that replies OK
+func HMSetHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if _, err := setHashFields(store, "HMSET", args); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
 
-		// Handle multiple field-value pairs
-		if len(args) == 3 {
-			// Single field-value pair
-			field := args[1].Str
-			value := args[2].Str
-			isNew := hash.HSet(field, value)
-			if isNew {
-				return resp.Value{Type: resp.Integer, Int: 1}, nil
-			}
-			return resp.Value{Type: resp.Integer, Int: 0}, nil
+// rewriteHMSet propagates HMSET as the equivalent HSET
+func rewriteHMSet(args []string, result resp.Value) (string, []string, bool) {
+	return "HSET", args, true
+}
+
+// setHashFields stores the field-value pairs of HSET or HMSET and returns
+// how many fields were new
+func setHashFields(store DataStore, name string, args []resp.Value) (int, error) {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return 0, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+	}
+
+	key := args[0].Str
+	hash, err := store.EnsureHash(key)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for i := 1; i < len(args); i += 2 {
+		if hash.HSet(args[i].Str, args[i+1].Str) {
+			added++
 		}
+	}
+	store.NotifyKeyEvent("hset", key)
+	return added, nil
+}
 
-		// Multiple field-value pairs
-		added := 0
-		for i := 1; i < len(args); i += 2 {
-			field := args[i].Str
-			value := args[i+1].Str
-			if hash.HSet(field, value) {
-				added++
-			}
+// HSetNXHandler handles HSETNX key field value
+func HSetNXHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'HSETNX' command")
 		}
 
-		return resp.Value{Type: resp.Integer, Int: int64(added)}, nil
+		key := args[0].Str
+		hash, err := store.EnsureHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !hash.HSetNX(args[1].Str, args[2].Str) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		store.NotifyKeyEvent("hset", key)
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
 	}
 }
 
+// rewriteHSetNX propagates an HSETNX that set the field as HSET
+func rewriteHSetNX(args []string, result resp.Value) (string, []string, bool) {
+	return "HSET", args, result.Int == 1
+}
+
 // HGetHandler handles the HGET command
 func HGetHandler(store DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
@@ -54,7 +95,13 @@ func HGetHandler(store DataStore) Handler {
 		key := args[0].Str
 		field := args[1].Str
 
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
 		value, exists := hash.HGet(field)
 		if !exists {
 			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
@@ -64,6 +111,58 @@ func HGetHandler(store DataStore) Handler {
 	}
 }
 
+// HMGetHandler handles HMGET key field [field ...]
+func HMGetHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'HMGET' command")
+		}
+
+		hash, err := store.GetHash(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		fields := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			fields[i] = arg.Str
+		}
+		values := make([]string, len(fields))
+		found := make([]bool, len(fields))
+		if hash != nil {
+			values, found = hash.HMGet(fields...)
+		}
+
+		array := make([]resp.Value, len(fields))
+		for i := range fields {
+			if found[i] {
+				array[i] = resp.Value{Type: resp.BulkString, Str: values[i]}
+			} else {
+				array[i] = resp.Value{Type: resp.BulkString, IsNull: true}
+			}
+		}
+		return resp.Value{Type: resp.Array, Array: array}, nil
+	}
+}
+
+// HStrLenHandler handles HSTRLEN key field
+func HStrLenHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'HSTRLEN' command")
+		}
+
+		hash, err := store.GetHash(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		var value string
+		if hash != nil {
+			value, _ = hash.HGet(args[1].Str)
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(len(value))}, nil
+	}
+}
+
 // HDelHandler handles the HDEL command
 func HDelHandler(store DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
@@ -77,8 +176,18 @@ func HDelHandler(store DataStore) Handler {
 			fields[i] = arg.Str
 		}
 
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		removed := hash.HDel(fields...)
+		if removed > 0 {
+			store.NotifyKeyEvent("hdel", key)
+			deleteIfEmpty(store, key)
+		}
 
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
@@ -94,10 +203,12 @@ func HExistsHandler(store DataStore) Handler {
 		key := args[0].Str
 		field := args[1].Str
 
-		hash := store.GetOrCreateHash(key)
-		exists := hash.HExists(field)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 
-		if exists {
+		if hash != nil && hash.HExists(field) {
 			return resp.Value{Type: resp.Integer, Int: 1}, nil
 		}
 		return resp.Value{Type: resp.Integer, Int: 0}, nil
@@ -112,10 +223,16 @@ func HGetAllHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Map, Array: []resp.Value{}}, nil
+		}
 		fields := hash.HGetAll()
 
-		// Convert to RESP array of field-value pairs
+		// Convert to a RESP map of field-value pairs
 		array := make([]resp.Value, len(fields)*2)
 		i := 0
 		for field, value := range fields {
@@ -124,7 +241,7 @@ func HGetAllHandler(store DataStore) Handler {
 			i += 2
 		}
 
-		return resp.Value{Type: resp.Array, Array: array}, nil
+		return resp.Value{Type: resp.Map, Array: array}, nil
 	}
 }
 
@@ -136,7 +253,13 @@ func HKeysHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		keys := hash.HKeys()
 
 		// Convert to RESP array
@@ -157,7 +280,13 @@ func HValsHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		values := hash.HVals()
 
 		// Convert to RESP array
@@ -178,7 +307,13 @@ func HLenHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		length := hash.HLen()
 
 		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
@@ -199,11 +334,15 @@ func HIncrByHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
 		}
 
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.EnsureHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		newValue, err := hash.HIncrBy(field, increment)
 		if err != nil {
 			return resp.Value{}, fmt.Errorf("ERR hash value is not an integer")
 		}
+		store.NotifyKeyEvent("hincrby", key)
 
 		return resp.Value{Type: resp.Integer, Int: newValue}, nil
 	}
@@ -223,12 +362,68 @@ func HIncrByFloatHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR value is not a valid float")
 		}
 
-		hash := store.GetOrCreateHash(key)
+		hash, err := store.EnsureHash(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		newValue, err := hash.HIncrByFloat(field, increment)
 		if err != nil {
 			return resp.Value{}, fmt.Errorf("ERR hash value is not a valid float")
 		}
+		store.NotifyKeyEvent("hincrbyfloat", key)
 
 		return resp.Value{Type: resp.BulkString, Str: fmt.Sprintf("%f", newValue)}, nil
 	}
 }
+
+// HRandFieldHandler handles HRANDFIELD key [count [WITHVALUES]]. A negative
+// count may return the same field several times.
+func HRandFieldHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 1 || len(args) > 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'HRANDFIELD' command")
+		}
+
+		hash, err := store.GetHash(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(args) == 1 {
+			if hash != nil {
+				if pairs := hash.HRandField(1); len(pairs) == 1 {
+					return resp.Value{Type: resp.BulkString, Str: pairs[0][0]}, nil
+				}
+			}
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+
+		count, err := strconv.ParseInt(args[1].Str, 10, 64)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		withValues := false
+		if len(args) == 3 {
+			if !strings.EqualFold(args[2].Str, "WITHVALUES") {
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+			withValues = true
+		}
+		if count < -math.MaxInt32 || count > math.MaxInt32 {
+			return resp.Value{}, fmt.Errorf("ERR value is out of range")
+		}
+		if hash == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
+
+		// Like ZRANGE WITHSCORES, values follow their fields in a flat array
+		pairs := hash.HRandField(int(count))
+		array := make([]resp.Value, 0, len(pairs)*2)
+		for _, pair := range pairs {
+			array = append(array, resp.Value{Type: resp.BulkString, Str: pair[0]})
+			if withValues {
+				array = append(array, resp.Value{Type: resp.BulkString, Str: pair[1]})
+			}
+		}
+		return resp.Value{Type: resp.Array, Array: array}, nil
+	}
+}
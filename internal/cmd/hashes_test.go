@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -190,7 +192,7 @@ func TestHGetAllHandler(t *testing.T) {
 
 	result, err := handler(args)
 	require.NoError(t, err)
-	assert.Equal(t, resp.Array, result.Type)
+	assert.Equal(t, resp.Map, result.Type)
 	assert.Len(t, result.Array, 4) // field1, value1, field2, value2
 
 	// Test wrong number of arguments
@@ -419,3 +421,138 @@ func TestHIncrByFloatHandler(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not a valid float")
 }
+
+func TestHMSetAndHMGet(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+
+	result, err := HMSetHandler(ds)(bulkArgs("myhash", "a", "1", "b", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.SimpleString, result.Type)
+	assert.Equal(t, "OK", result.Str)
+
+	_, err = HMSetHandler(ds)(bulkArgs("myhash", "a"))
+	assert.EqualError(t, err, "ERR wrong number of arguments for 'HMSET' command")
+
+	result, err = HMGetHandler(ds)(bulkArgs("myhash", "a", "missing", "b"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 3)
+	assert.Equal(t, "1", result.Array[0].Str)
+	assert.True(t, result.Array[1].IsNull)
+	assert.Equal(t, "2", result.Array[2].Str)
+
+	result, err = HMGetHandler(ds)(bulkArgs("nohash", "a"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 1)
+	assert.True(t, result.Array[0].IsNull)
+
+	ds.Set("str", "value", time.Time{})
+	_, err = HMGetHandler(ds)(bulkArgs("str", "a"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+
+	name, args, ok := rewriteHMSet([]string{"myhash", "a", "1"}, result)
+	require.True(t, ok)
+	assert.Equal(t, "HSET", name)
+	assert.Equal(t, []string{"myhash", "a", "1"}, args)
+}
+
+func TestHSetNXHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := HSetNXHandler(ds)
+
+	result, err := handler(bulkArgs("myhash", "field", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	_, _, ok := rewriteHSetNX([]string{"myhash", "field", "a"}, result)
+	assert.True(t, ok)
+
+	result, err = handler(bulkArgs("myhash", "field", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	_, _, ok = rewriteHSetNX([]string{"myhash", "field", "b"}, result)
+	assert.False(t, ok)
+
+	hash, _ := ds.GetHash("myhash")
+	value, _ := hash.HGet("field")
+	assert.Equal(t, "a", value)
+}
+
+func TestHStrLenHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := HStrLenHandler(ds)
+	hash, _ := ds.EnsureHash("myhash")
+	hash.HSet("field", "hello")
+
+	result, err := handler(bulkArgs("myhash", "field"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Int)
+
+	result, err = handler(bulkArgs("myhash", "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	result, err = handler(bulkArgs("nohash", "field"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+}
+
+func TestHRandFieldHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := HRandFieldHandler(ds)
+	hash, _ := ds.EnsureHash("myhash")
+	hash.HSet("a", "1")
+	hash.HSet("b", "2")
+	hash.HSet("c", "3")
+
+	result, err := handler(bulkArgs("myhash"))
+	require.NoError(t, err)
+	assert.Contains(t, []string{"a", "b", "c"}, result.Str)
+
+	result, err = handler(bulkArgs("myhash", "2"))
+	require.NoError(t, err)
+	assert.Len(t, result.Array, 2)
+
+	result, err = handler(bulkArgs("myhash", "-5", "WITHVALUES"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 10)
+	for i := 0; i < len(result.Array); i += 2 {
+		value, ok := hash.HGet(result.Array[i].Str)
+		require.True(t, ok)
+		assert.Equal(t, value, result.Array[i+1].Str)
+	}
+
+	result, err = handler(bulkArgs("nohash"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+	result, err = handler(bulkArgs("nohash", "3"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Array, result.Type)
+	assert.Empty(t, result.Array)
+
+	_, err = handler(bulkArgs("myhash", "x"))
+	assert.EqualError(t, err, "ERR value is not an integer or out of range")
+	_, err = handler(bulkArgs("myhash", "2", "WITHSCORES"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestHDelDeletesEmptiedHash(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	hash, err := ds.EnsureHash("h")
+	require.NoError(t, err)
+	hash.HSet("f1", "v1")
+	hash.HSet("f2", "v2")
+	var events []string
+	ds.OnKeyEvent(func(event, key string) { events = append(events, event+":"+key) })
+
+	_, err = HDelHandler(ds)(bulkArgs("h", "f1"))
+	require.NoError(t, err)
+	assert.True(t, ds.Exists("h"))
+
+	result, err := HDelHandler(ds)(bulkArgs("h", "f2", "f3"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	assert.False(t, ds.Exists("h"))
+	assert.Equal(t, []string{"hdel:h", "hdel:h", "del:h"}, events)
+
+	// The key no longer holds a stale hash
+	_, err = SAddHandler(ds)(bulkArgs("h", "m"))
+	require.NoError(t, err)
+}
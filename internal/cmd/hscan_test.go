@@ -86,3 +86,33 @@ func TestHScanWrongType(t *testing.T) {
 		t.Fatalf("expected WRONGTYPE error")
 	}
 }
+
+func TestHScanNoValues(t *testing.T) {
+	store := newScopedMockDataStore(t)
+	h := store.GetOrCreateHash("myhash")
+	h.HSet("a", "1")
+	h.HSet("b", "2")
+
+	hscan := HScanHandler(store)
+	res, err := hscan(bulkArgs("myhash", "0", "NOVALUES"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if res.Array[0].Str != "0" || len(res.Array[1].Array) != 2 {
+		t.Fatalf("expected the two fields alone, got %v", res.Array[1].Array)
+	}
+	for _, f := range res.Array[1].Array {
+		if f.Str != "a" && f.Str != "b" {
+			t.Fatalf("unexpected field %q", f.Str)
+		}
+	}
+
+	// NOVALUES combines with MATCH
+	res, err = hscan(bulkArgs("myhash", "0", "MATCH", "a", "novalues"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(res.Array[1].Array) != 1 || res.Array[1].Array[0].Str != "a" {
+		t.Fatalf("expected only field a, got %v", res.Array[1].Array)
+	}
+}
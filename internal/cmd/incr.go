@@ -21,6 +21,7 @@ func OptimizedIncrHandler(store Store) Handler {
 		if !exists {
 			// Key doesn't exist, start with 1
 			store.Set(key, "1", time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: 1}, nil
 		}
 
@@ -28,6 +29,7 @@ func OptimizedIncrHandler(store Store) Handler {
 		if currentInt, err := strconv.ParseInt(currentValue, 10, 64); err == nil {
 			newValue := currentInt + 1
 			store.Set(key, fmt.Sprintf("%d", newValue), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: newValue}, nil
 		}
 
@@ -42,6 +44,7 @@ func OptimizedIncrHandler(store Store) Handler {
 				resultStr = fmt.Sprintf("%.10g", newValue)
 			}
 			store.Set(key, resultStr, time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.BulkString, Str: resultStr}, nil
 		}
 
@@ -62,6 +65,7 @@ func OptimizedDecrHandler(store Store) Handler {
 		if !exists {
 			// Key doesn't exist, start with -1
 			store.Set(key, "-1", time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: -1}, nil
 		}
 
@@ -69,6 +73,7 @@ func OptimizedDecrHandler(store Store) Handler {
 		if currentInt, err := strconv.ParseInt(currentValue, 10, 64); err == nil {
 			newValue := currentInt - 1
 			store.Set(key, fmt.Sprintf("%d", newValue), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: newValue}, nil
 		}
 
@@ -83,6 +88,7 @@ func OptimizedDecrHandler(store Store) Handler {
 				resultStr = fmt.Sprintf("%.10g", newValue)
 			}
 			store.Set(key, resultStr, time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.BulkString, Str: resultStr}, nil
 		}
 
@@ -111,6 +117,7 @@ func IncrByHandler(store Store) Handler {
 		if !exists {
 			// Key doesn't exist, start with increment
 			store.Set(key, fmt.Sprintf("%d", increment), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: increment}, nil
 		}
 
@@ -121,6 +128,7 @@ func IncrByHandler(store Store) Handler {
 				return resp.Value{}, err
 			}
 			store.Set(key, fmt.Sprintf("%d", newValue), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: newValue}, nil
 		}
 
@@ -135,6 +143,7 @@ func IncrByHandler(store Store) Handler {
 				resultStr = fmt.Sprintf("%.10g", newValue)
 			}
 			store.Set(key, resultStr, time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.BulkString, Str: resultStr}, nil
 		}
 
@@ -163,6 +172,7 @@ func DecrByHandler(store Store) Handler {
 		if !exists {
 			// Key doesn't exist, start with -decrement
 			store.Set(key, fmt.Sprintf("%d", -decrement), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: -decrement}, nil
 		}
 
@@ -173,6 +183,7 @@ func DecrByHandler(store Store) Handler {
 				return resp.Value{}, err
 			}
 			store.Set(key, fmt.Sprintf("%d", newValue), time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.Integer, Int: newValue}, nil
 		}
 
@@ -187,6 +198,7 @@ func DecrByHandler(store Store) Handler {
 				resultStr = fmt.Sprintf("%.10g", newValue)
 			}
 			store.Set(key, resultStr, time.Time{})
+			notifyKeyEvent(store, "incrby", key)
 			return resp.Value{Type: resp.BulkString, Str: resultStr}, nil
 		}
 
@@ -269,6 +281,7 @@ func IncrByFloatHandler(store Store) Handler {
 
 		// Set the new value
 		store.Set(key, resultStr, time.Time{})
+		notifyKeyEvent(store, "incrbyfloat", key)
 		return resp.Value{Type: resp.BulkString, Str: resultStr}, nil
 	}
 }
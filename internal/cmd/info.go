@@ -5,7 +5,9 @@ import (
 	"gridhouse/internal/resp"
 	"gridhouse/internal/stats"
 	"os"
+	"sort"
 	"strings"
+	"time"
 )
 
 // InfoHandler handles the INFO command using live server stats
@@ -15,11 +17,18 @@ func InfoHandler(statsProvider interface{}) Handler {
 	type provider interface {
 		GetStats() *stats.OptimizedStatsManager
 	}
+	// Providers that count keys on demand refresh the keyspace stats first
+	type keyspaceProvider interface {
+		UpdateKeyspaceStats()
+	}
 	return func(args []resp.Value) (resp.Value, error) {
 		var mgr *stats.OptimizedStatsManager
 		if p, ok := statsProvider.(provider); ok && p != nil {
 			mgr = p.GetStats()
 		}
+		if p, ok := statsProvider.(keyspaceProvider); ok && p != nil {
+			p.UpdateKeyspaceStats()
+		}
 		// Build sections from snapshot (if mgr is nil, use zero-values)
 		var snap stats.StatsSnapshot
 		if mgr != nil {
@@ -48,9 +57,9 @@ func InfoHandler(statsProvider interface{}) Handler {
 			return "# Clients\r\n" +
 				fmt.Sprintf("maxclients:%d\r\n", snap.MaxConnections) +
 				fmt.Sprintf("connected_clients:%d\r\n", snap.ActiveConnections) +
-				fmt.Sprintf("blocked_clients:%d\r\n", 0) + // TODO: since blocking commands are not part of gridhouse yet, we print 0
+				fmt.Sprintf("blocked_clients:%d\r\n", snap.BlockedClients) +
 				fmt.Sprintf("tracking_clients:%d\r\n", 0) + // TODO: since tracing commands are not part of gridhouse yet, we print 0
-				fmt.Sprintf("pubsub_clients:%d\r\n", 0) + // TODO: since pubsub commands are not part of gridhouse yet, we print 0
+				fmt.Sprintf("pubsub_clients:%d\r\n", snap.PubSubClients) +
 				fmt.Sprintf("watching_clients:%d\r\n", 0) // TODO: since watching commands are not part of gridhouse yet, we print 0
 		}
 		buildCPU := func() string {
@@ -71,39 +80,64 @@ func InfoHandler(statsProvider interface{}) Handler {
 			return "# Stats\r\n" +
 				fmt.Sprintf("total_connections_received:%d\r\n", snap.TotalConnectionsReceived) +
 				fmt.Sprintf("total_commands_processed:%d\r\n", snap.TotalCommandsProcessed) +
+				fmt.Sprintf("evicted_keys:%d\r\n", snap.EvictedKeys) +
 				fmt.Sprintf("acl_access_denied_auth:%d\r\n", 0) + // TODO: we need to add counter for denied accesses
 				fmt.Sprintf("total_reads_processed:%d\r\n", 0) + // TODO: we need to add counter for read commands
 				fmt.Sprintf("total_writes_processed:%d\r\n", 0) // TODO: we need to add counter for write commands
 		}
+		// Per-command sections list the commands in name order
+		commandNames := func() []string {
+			names := make([]string, 0, len(snap.CommandStats))
+			for cmd := range snap.CommandStats {
+				names = append(names, cmd)
+			}
+			sort.Strings(names)
+			return names
+		}
 		buildCommands := func() string {
 			b := strings.Builder{}
-			b.WriteString("# Commands\r\n")
-			if snap.CommandsByType != nil {
-				for cmd, calls := range snap.CommandsByType {
-					// We don't track latency here; keep 0 like Redis if unavailable
-					b.WriteString(fmt.Sprintf("cmdstat_%s:calls=%d,usec=0,usec_per_call=0\r\n", strings.ToLower(cmd), calls))
+			b.WriteString("# Commandstats\r\n")
+			for _, cmd := range commandNames() {
+				st := snap.CommandStats[cmd]
+				perCall := 0.0
+				if st.Calls > 0 {
+					perCall = float64(st.Usec) / float64(st.Calls)
+				}
+				b.WriteString(fmt.Sprintf("cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d\r\n",
+					strings.ToLower(cmd), st.Calls, st.Usec, perCall, st.RejectedCalls, st.FailedCalls))
+			}
+			return b.String()
+		}
+		buildLatency := func() string {
+			usec := func(d time.Duration) float64 { return float64(d) / float64(time.Microsecond) }
+			b := strings.Builder{}
+			b.WriteString("# Latencystats\r\n")
+			for _, cmd := range commandNames() {
+				st := snap.CommandStats[cmd]
+				if st.Calls == 0 {
+					continue
 				}
+				b.WriteString(fmt.Sprintf("latency_percentiles_usec_%s:p50=%.3f,p99=%.3f,p99.9=%.3f\r\n",
+					strings.ToLower(cmd), usec(st.P50), usec(st.P99), usec(st.P999)))
 			}
 			return b.String()
 		}
 		buildKeyspace := func() string {
 			b := strings.Builder{}
 			b.WriteString("# Keyspace\r\n")
-			// If database stats available, print db0 aggregate only
+			// One line per non-empty database, or an empty db0 if there are none
 			if len(snap.DatabaseKeys) == 0 {
 				b.WriteString("db0:keys=0,expires=0,avg_ttl=0\r\n")
 				return b.String()
 			}
-			var totalKeys int64
-			var totalExpires int64
-			for db, k := range snap.DatabaseKeys {
-				_ = db
-				totalKeys += k
-				if snap.DatabaseExpires != nil {
-					totalExpires += snap.DatabaseExpires[db]
-				}
+			dbs := make([]int, 0, len(snap.DatabaseKeys))
+			for db := range snap.DatabaseKeys {
+				dbs = append(dbs, db)
+			}
+			sort.Ints(dbs)
+			for _, db := range dbs {
+				b.WriteString(fmt.Sprintf("db%d:keys=%d,expires=%d,avg_ttl=0\r\n", db, snap.DatabaseKeys[db], snap.DatabaseExpires[db]))
 			}
-			b.WriteString(fmt.Sprintf("db0:keys=%d,expires=%d,avg_ttl=0\r\n", totalKeys, totalExpires))
 			return b.String()
 		}
 
@@ -122,13 +156,15 @@ func InfoHandler(statsProvider interface{}) Handler {
 			info.WriteString(buildMemory())
 		case "STATS", "stats":
 			info.WriteString(buildStats())
-		case "COMMANDS", "commands":
+		case "COMMANDSTATS", "commandstats", "COMMANDS", "commands":
 			info.WriteString(buildCommands())
+		case "LATENCYSTATS", "latencystats":
+			info.WriteString(buildLatency())
 		case "KEYSPACE", "keyspace":
 			info.WriteString(buildKeyspace())
 		case "CPU", "cpu":
 			info.WriteString(buildCPU())
-		case "":
+		case "", "all", "everything":
 			info.WriteString(buildServer())
 			info.WriteString("\r\n")
 			info.WriteString(buildClients())
@@ -142,6 +178,10 @@ func InfoHandler(statsProvider interface{}) Handler {
 			info.WriteString(buildCommands())
 			info.WriteString("\r\n")
 			info.WriteString(buildKeyspace())
+			if section != "" {
+				info.WriteString("\r\n")
+				info.WriteString(buildLatency())
+			}
 		default:
 			// Unsupported section -> empty bulk string like Redis
 			return resp.Value{Type: resp.BulkString, Str: ""}, nil
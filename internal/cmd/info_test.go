@@ -4,6 +4,7 @@ import (
 	"gridhouse/internal/resp"
 	"gridhouse/internal/stats"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,3 +61,30 @@ func TestInfoHandlerCPU(t *testing.T) {
 	assert.Contains(t, result.Str, "used_cpu_sys_main_thread:")
 	assert.Contains(t, result.Str, "used_cpu_user_main_thread:")
 }
+
+func TestInfoHandlerCommandStats(t *testing.T) {
+	mockStats := &mockServerStatsProvider{
+		stats: stats.NewOptimizedStatsManager(),
+	}
+	mockStats.stats.RecordCommand("SET", 3*time.Microsecond, false)
+	mockStats.stats.RecordCommand("SET", 5*time.Microsecond, true)
+	mockStats.stats.RecordRejectedCommand("GET")
+
+	handler := InfoHandler(mockStats)
+	result, err := handler([]resp.Value{{Type: resp.BulkString, Str: "commandstats"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "# Commandstats\r\n"+
+		"cmdstat_get:calls=0,usec=0,usec_per_call=0.00,rejected_calls=1,failed_calls=0\r\n"+
+		"cmdstat_set:calls=2,usec=8,usec_per_call=4.00,rejected_calls=0,failed_calls=1\r\n\r\n", result.Str)
+
+	result, err = handler([]resp.Value{{Type: resp.BulkString, Str: "latencystats"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "# Latencystats\r\nlatency_percentiles_usec_set:p50=3.071,p99=5.119,p99.9=5.119\r\n\r\n", result.Str)
+
+	// CONFIG RESETSTAT clears them
+	_, err = ConfigHandlerWithStats(ConfigParams{}, mockStats)([]resp.Value{{Type: resp.BulkString, Str: "RESETSTAT"}})
+	assert.NoError(t, err)
+	result, err = handler([]resp.Value{{Type: resp.BulkString, Str: "commandstats"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "# Commandstats\r\n\r\n", result.Str)
+}
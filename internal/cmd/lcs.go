@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"strconv"
+	"strings"
+)
+
+// lcsMatch is a common run of bytes, as inclusive ranges of both strings
+type lcsMatch struct {
+	a, b [2]int
+}
+
+// LCSHandler handles LCS key1 key2 [LEN] [IDX] [MINMATCHLEN len] [WITHMATCHLEN].
+// Missing keys count as empty strings.
+func LCSHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LCS' command")
+		}
+		var wantLen, wantIdx, withMatchLen bool
+		minMatchLen := 0
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i].Str) {
+			case "LEN":
+				wantLen = true
+			case "IDX":
+				wantIdx = true
+			case "WITHMATCHLEN":
+				withMatchLen = true
+			case "MINMATCHLEN":
+				if i+1 >= len(args) {
+					return resp.Value{}, fmt.Errorf("ERR syntax error")
+				}
+				n, err := strconv.Atoi(args[i+1].Str)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+				}
+				if n > 0 {
+					minMatchLen = n
+				}
+				i++
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+		}
+		if wantLen && wantIdx {
+			return resp.Value{}, fmt.Errorf("ERR If you want both the length and indexes, please just use IDX.")
+		}
+
+		var values [2]string
+		for i := range values {
+			if ds.GetDataType(args[i].Str) != store.TypeString {
+				return resp.Value{}, store.ErrWrongType
+			}
+			values[i], _ = ds.Get(args[i].Str)
+		}
+		a, b := values[0], values[1]
+		if (len(a)+1)*(len(b)+1) > store.MaxStringLength/4 {
+			return resp.Value{}, fmt.Errorf("ERR Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len")
+		}
+
+		lcs, matches := longestCommonSubsequence(a, b, minMatchLen)
+		switch {
+		case wantLen:
+			return resp.Value{Type: resp.Integer, Int: int64(len(lcs))}, nil
+		case wantIdx:
+			return lcsIdxReply(matches, len(lcs), withMatchLen), nil
+		}
+		return resp.Value{Type: resp.BulkString, Str: lcs}, nil
+	}
+}
+
+// longestCommonSubsequence returns the LCS of a and b together with the runs
+// of it that are contiguous in both strings and at least minMatchLen long,
+// from the end of the strings to their start as Redis lists them
+func longestCommonSubsequence(a, b string, minMatchLen int) (string, []lcsMatch) {
+	// dp[i*cols+j] is the LCS length of a[:i] and b[:j]
+	cols := len(b) + 1
+	dp := make([]uint32, (len(a)+1)*cols)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				dp[i*cols+j] = dp[(i-1)*cols+j-1] + 1
+			case dp[(i-1)*cols+j] > dp[i*cols+j-1]:
+				dp[i*cols+j] = dp[(i-1)*cols+j]
+			default:
+				dp[i*cols+j] = dp[i*cols+j-1]
+			}
+		}
+	}
+
+	lcs := make([]byte, dp[len(a)*cols+len(b)])
+	idx := len(lcs)
+	var matches []lcsMatch
+	var cur lcsMatch
+	inMatch := false
+	flush := func() {
+		if inMatch && cur.a[1]-cur.a[0]+1 >= minMatchLen {
+			matches = append(matches, cur)
+		}
+		inMatch = false
+	}
+	for i, j := len(a), len(b); i > 0 && j > 0; {
+		if a[i-1] == b[j-1] {
+			idx--
+			lcs[idx] = a[i-1]
+			if inMatch && cur.a[0] == i && cur.b[0] == j {
+				// Extend the run backwards
+				cur.a[0]--
+				cur.b[0]--
+			} else {
+				flush()
+				cur = lcsMatch{a: [2]int{i - 1, i - 1}, b: [2]int{j - 1, j - 1}}
+				inMatch = true
+			}
+			i--
+			j--
+			continue
+		}
+		flush()
+		if dp[(i-1)*cols+j] > dp[i*cols+j-1] {
+			i--
+		} else {
+			j--
+		}
+	}
+	flush()
+	return string(lcs), matches
+}
+
+func lcsIdxReply(matches []lcsMatch, length int, withMatchLen bool) resp.Value {
+	rangeReply := func(r [2]int) resp.Value {
+		return resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.Integer, Int: int64(r[0])},
+			{Type: resp.Integer, Int: int64(r[1])},
+		}}
+	}
+	list := make([]resp.Value, len(matches))
+	for i, m := range matches {
+		entry := []resp.Value{rangeReply(m.a), rangeReply(m.b)}
+		if withMatchLen {
+			entry = append(entry, resp.Value{Type: resp.Integer, Int: int64(m.a[1] - m.a[0] + 1)})
+		}
+		list[i] = resp.Value{Type: resp.Array, Array: entry}
+	}
+	return resp.Value{Type: resp.Map, Array: []resp.Value{
+		{Type: resp.BulkString, Str: "matches"},
+		{Type: resp.Array, Array: list},
+		{Type: resp.BulkString, Str: "len"},
+		{Type: resp.Integer, Int: int64(length)},
+	}}
+}
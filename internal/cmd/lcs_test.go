@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLCSHandler(t *testing.T) {
+	db := store.NewUltraOptimizedDB()
+	defer db.Close()
+	lcs := LCSHandler(db)
+	db.Set("key1", "ohmytext", time.Time{})
+	db.Set("key2", "mynewtext", time.Time{})
+
+	result, err := lcs(bulkArgs("key1", "key2"))
+	require.NoError(t, err)
+	assert.Equal(t, "mytext", result.Str)
+
+	result, err = lcs(bulkArgs("key1", "key2", "LEN"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), result.Int)
+
+	span := func(from, to int64) resp.Value {
+		return resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.Integer, Int: from}, {Type: resp.Integer, Int: to},
+		}}
+	}
+	result, err = lcs(bulkArgs("key1", "key2", "IDX"))
+	require.NoError(t, err)
+	require.Equal(t, resp.Map, result.Type)
+	assert.Equal(t, "matches", result.Array[0].Str)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.Array, Array: []resp.Value{span(4, 7), span(5, 8)}},
+		{Type: resp.Array, Array: []resp.Value{span(2, 3), span(0, 1)}},
+	}, result.Array[1].Array)
+	assert.Equal(t, int64(6), result.Array[3].Int)
+
+	result, err = lcs(bulkArgs("key1", "key2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN"))
+	require.NoError(t, err)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.Array, Array: []resp.Value{span(4, 7), span(5, 8), {Type: resp.Integer, Int: 4}}},
+	}, result.Array[1].Array)
+
+	result, err = lcs(bulkArgs("key1", "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, "", result.Str)
+
+	_, err = lcs(bulkArgs("key1", "key2", "LEN", "IDX"))
+	assert.Error(t, err)
+	_, err = db.EnsureList("list")
+	require.NoError(t, err)
+	_, err = lcs(bulkArgs("key1", "list"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gridhouse/internal/resp"
 	"strconv"
+	"strings"
 )
 
 // LPushHandler handles the LPUSH command
@@ -19,8 +20,12 @@ func LPushHandler(store DataStore) Handler {
 			elements[i] = arg.Str
 		}
 
-		list := store.GetOrCreateList(key)
+		list, err := store.EnsureList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		length := list.LPush(elements...)
+		store.NotifyKeyEvent("lpush", key)
 
 		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
 	}
@@ -39,48 +44,118 @@ func RPushHandler(store DataStore) Handler {
 			elements[i] = arg.Str
 		}
 
-		list := store.GetOrCreateList(key)
+		list, err := store.EnsureList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		length := list.RPush(elements...)
+		store.NotifyKeyEvent("rpush", key)
 
 		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
 	}
 }
 
-// LPopHandler handles the LPOP command
+// LPopHandler handles LPOP key [count]
 func LPopHandler(store DataStore) Handler {
+	return popHandler(store, "LPOP", true)
+}
+
+// RPopHandler handles RPOP key [count]
+func RPopHandler(store DataStore) Handler {
+	return popHandler(store, "RPOP", false)
+}
+
+// popHandler pops a single element, or with a count up to count elements
+// as an array
+func popHandler(store DataStore, name string, left bool) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) != 1 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LPOP' command")
+		if len(args) != 1 && len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
 		}
 
 		key := args[0].Str
-		list := store.GetOrCreateList(key)
+		count := -1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1].Str)
+			if err != nil || n < 0 {
+				return resp.Value{}, fmt.Errorf("ERR value is out of range, must be positive")
+			}
+			count = n
+		}
+		missing := nullBulkReply
+		if count >= 0 {
+			missing = nullArrayReply
+		}
 
-		element, exists := list.LPop()
-		if !exists {
-			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil || list.LLen() == 0 {
+			return missing, nil
+		}
+		if count == 0 {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
 		}
 
-		return resp.Value{Type: resp.BulkString, Str: element}, nil
+		_, popped, err := popFirst(store, []string{key}, left, max(count, 1))
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(popped) == 0 {
+			return missing, nil
+		}
+		if count < 0 {
+			return resp.Value{Type: resp.BulkString, Str: popped[0]}, nil
+		}
+		elements := make([]resp.Value, len(popped))
+		for i, element := range popped {
+			elements[i] = resp.Value{Type: resp.BulkString, Str: element}
+		}
+		return resp.Value{Type: resp.Array, Array: elements}, nil
 	}
 }
 
-// RPopHandler handles the RPOP command
-func RPopHandler(store DataStore) Handler {
+// LPushXHandler handles LPUSHX key element [element ...]
+func LPushXHandler(store DataStore) Handler {
+	return pushXHandler(store, "LPUSHX", true)
+}
+
+// RPushXHandler handles RPUSHX key element [element ...]
+func RPushXHandler(store DataStore) Handler {
+	return pushXHandler(store, "RPUSHX", false)
+}
+
+// pushXHandler pushes onto a list only if it already exists
+func pushXHandler(store DataStore, name string, left bool) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) != 1 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'RPOP' command")
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
 		}
 
 		key := args[0].Str
-		list := store.GetOrCreateList(key)
+		elements := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			elements[i] = arg.Str
+		}
 
-		element, exists := list.RPop()
-		if !exists {
-			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil || list.LLen() == 0 {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		var length int
+		if left {
+			length = list.LPush(elements...)
+			store.NotifyKeyEvent("lpush", key)
+		} else {
+			length = list.RPush(elements...)
+			store.NotifyKeyEvent("rpush", key)
 		}
 
-		return resp.Value{Type: resp.BulkString, Str: element}, nil
+		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
 	}
 }
 
@@ -92,7 +167,13 @@ func LLenHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		list := store.GetOrCreateList(key)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		length := list.LLen()
 
 		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
@@ -117,7 +198,13 @@ func LRangeHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
 		}
 
-		list := store.GetOrCreateList(key)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		elements := list.LRange(start, stop)
 
 		// Convert to RESP array
@@ -143,7 +230,13 @@ func LIndexHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
 		}
 
-		list := store.GetOrCreateList(key)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
 		element, exists := list.LIndex(index)
 		if !exists {
 			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
@@ -167,12 +260,19 @@ func LSetHandler(store DataStore) Handler {
 		}
 
 		value := args[2].Str
-		list := store.GetOrCreateList(key)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil {
+			return resp.Value{}, fmt.Errorf("ERR no such key")
+		}
 
 		success := list.LSet(index, value)
 		if !success {
 			return resp.Value{}, fmt.Errorf("ERR index out of range")
 		}
+		store.NotifyKeyEvent("lset", key)
 
 		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
 	}
@@ -192,8 +292,18 @@ func LRemHandler(store DataStore) Handler {
 		}
 
 		value := args[2].Str
-		list := store.GetOrCreateList(key)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		removed := list.LRem(count, value)
+		if removed > 0 {
+			store.NotifyKeyEvent("lrem", key)
+			deleteIfEmpty(store, key)
+		}
 
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
@@ -217,9 +327,200 @@ func LTrimHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
 		}
 
-		list := store.GetOrCreateList(key)
-		list.LTrim(start, stop)
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list != nil {
+			list.LTrim(start, stop)
+			store.NotifyKeyEvent("ltrim", key)
+			deleteIfEmpty(store, key)
+		}
 
 		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
 	}
 }
+
+// rewritePushX propagates LPUSHX and RPUSHX as the LPUSH or RPUSH they
+// performed, and nothing when the list did not exist
+func rewritePushX(name string) func(args []string, result resp.Value) (string, []string, bool) {
+	return func(args []string, result resp.Value) (string, []string, bool) {
+		if result.Int == 0 {
+			return "", nil, false
+		}
+		return name, args, true
+	}
+}
+
+// rewriteRPopLPush propagates RPOPLPUSH as the LMOVE it is
+func rewriteRPopLPush(args []string, result resp.Value) (string, []string, bool) {
+	if result.IsNull || len(args) != 2 {
+		return "", nil, false
+	}
+	return "LMOVE", []string{args[0], args[1], "RIGHT", "LEFT"}, true
+}
+
+// LInsertHandler handles LINSERT key BEFORE|AFTER pivot element
+func LInsertHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 4 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LINSERT' command")
+		}
+
+		var before bool
+		switch strings.ToUpper(args[1].Str) {
+		case "BEFORE":
+			before = true
+		case "AFTER":
+		default:
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		}
+
+		key := args[0].Str
+		list, err := store.GetList(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if list == nil || list.LLen() == 0 {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		length := list.LInsert(before, args[2].Str, args[3].Str)
+		if length > 0 {
+			store.NotifyKeyEvent("linsert", key)
+		}
+
+		return resp.Value{Type: resp.Integer, Int: int64(length)}, nil
+	}
+}
+
+// LPosHandler handles LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func LPosHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LPOS' command")
+		}
+
+		rank, count, maxLen := 1, -1, 0
+		for i := 2; i < len(args); i += 2 {
+			if i+1 >= len(args) {
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1].Str)
+			if err != nil {
+				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			switch strings.ToUpper(args[i].Str) {
+			case "RANK":
+				if n == 0 {
+					return resp.Value{}, fmt.Errorf("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+				}
+				rank = n
+			case "COUNT":
+				if n < 0 {
+					return resp.Value{}, fmt.Errorf("ERR COUNT can't be negative")
+				}
+				count = n
+			case "MAXLEN":
+				if n < 0 {
+					return resp.Value{}, fmt.Errorf("ERR MAXLEN can't be negative")
+				}
+				maxLen = n
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+		}
+
+		list, err := store.GetList(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		var matches []int
+		if list != nil {
+			limit := count
+			if limit < 0 {
+				limit = 1
+			}
+			matches = list.LPos(args[1].Str, rank, limit, maxLen)
+		}
+
+		// Without COUNT the reply is the first match alone
+		if count < 0 {
+			if len(matches) == 0 {
+				return nullBulkReply, nil
+			}
+			return resp.Value{Type: resp.Integer, Int: int64(matches[0])}, nil
+		}
+		array := make([]resp.Value, len(matches))
+		for i, index := range matches {
+			array[i] = resp.Value{Type: resp.Integer, Int: int64(index)}
+		}
+		return resp.Value{Type: resp.Array, Array: array}, nil
+	}
+}
+
+// LMoveHandler handles LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+func LMoveHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 4 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LMOVE' command")
+		}
+		fromLeft, err := parseListSide(args[2].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		toLeft, err := parseListSide(args[3].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+
+		element, ok, err := listMove(store, args[0].Str, args[1].Str, fromLeft, toLeft)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !ok {
+			return nullBulkReply, nil
+		}
+		return resp.Value{Type: resp.BulkString, Str: element}, nil
+	}
+}
+
+// RPopLPushHandler handles RPOPLPUSH source destination, which is LMOVE
+// source destination RIGHT LEFT
+func RPopLPushHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'RPOPLPUSH' command")
+		}
+
+		element, ok, err := listMove(store, args[0].Str, args[1].Str, false, true)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !ok {
+			return nullBulkReply, nil
+		}
+		return resp.Value{Type: resp.BulkString, Str: element}, nil
+	}
+}
+
+// LMPopHandler handles LMPOP numkeys key [key ...] LEFT|RIGHT [COUNT count]
+func LMPopHandler(store DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'LMPOP' command")
+		}
+		keys, left, count, err := parseMPop(args)
+		if err != nil {
+			return resp.Value{}, err
+		}
+
+		key, popped, err := popFirst(store, keys, left, count)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(popped) == 0 {
+			return nullArrayReply, nil
+		}
+		return mpopReply(key, popped), nil
+	}
+}
@@ -95,6 +95,7 @@ func TestLPopHandler(t *testing.T) {
 	// Test wrong number of arguments
 	args = []resp.Value{
 		{Type: resp.BulkString, Str: "mylist"},
+		{Type: resp.BulkString, Str: "1"},
 		{Type: resp.BulkString, Str: "extra"},
 	}
 
@@ -124,6 +125,7 @@ func TestRPopHandler(t *testing.T) {
 	// Test wrong number of arguments
 	args = []resp.Value{
 		{Type: resp.BulkString, Str: "mylist"},
+		{Type: resp.BulkString, Str: "1"},
 		{Type: resp.BulkString, Str: "extra"},
 	}
 
@@ -392,3 +394,268 @@ func TestLTrimHandler(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "wrong number of arguments")
 }
+
+func TestPopCountHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	lpop := LPopHandler(store)
+	rpop := RPopHandler(store)
+
+	list := store.GetOrCreateList("mylist")
+	list.RPush("a", "b", "c", "d")
+
+	result, err := lpop(bulkArgs("mylist", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "a"},
+		{Type: resp.BulkString, Str: "b"},
+	}, result.Array)
+
+	// A count beyond the length pops what is there
+	result, err = rpop(bulkArgs("mylist", "5"))
+	require.NoError(t, err)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "d"},
+		{Type: resp.BulkString, Str: "c"},
+	}, result.Array)
+
+	assert.False(t, store.Exists("mylist"))
+
+	result, err = lpop(bulkArgs("missing", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, nullArrayReply, result)
+
+	list = store.GetOrCreateList("mylist")
+	list.RPush("x")
+	result, err = lpop(bulkArgs("mylist", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Value{Type: resp.Array, Array: []resp.Value{}}, result)
+	assert.Equal(t, 1, list.LLen())
+
+	_, err = lpop(bulkArgs("mylist", "-1"))
+	assert.EqualError(t, err, "ERR value is out of range, must be positive")
+	_, err = rpop(bulkArgs("mylist", "x"))
+	assert.EqualError(t, err, "ERR value is out of range, must be positive")
+}
+
+func TestPushXHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	lpushx := LPushXHandler(store)
+	rpushx := RPushXHandler(store)
+
+	result, err := lpushx(bulkArgs("mylist", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	list, err := store.GetList("mylist")
+	require.NoError(t, err)
+	assert.Nil(t, list)
+
+	store.GetOrCreateList("mylist").RPush("b")
+	result, err = lpushx(bulkArgs("mylist", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Int)
+	result, err = rpushx(bulkArgs("mylist", "c", "d"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Int)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, store.GetOrCreateList("mylist").LRange(0, -1))
+
+	name, args, ok := rewritePushX("RPUSH")([]string{"mylist", "c", "d"}, result)
+	require.True(t, ok)
+	assert.Equal(t, "RPUSH", name)
+	assert.Equal(t, []string{"mylist", "c", "d"}, args)
+	_, _, ok = rewritePushX("LPUSH")([]string{"missing", "a"}, resp.Value{Type: resp.Integer})
+	assert.False(t, ok)
+
+	_, err = rpushx(bulkArgs("mylist"))
+	assert.EqualError(t, err, "ERR wrong number of arguments for 'RPUSHX' command")
+}
+
+func TestLInsertHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	handler := LInsertHandler(store)
+
+	result, err := handler(bulkArgs("mylist", "BEFORE", "a", "x"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+
+	list := store.GetOrCreateList("mylist")
+	list.RPush("a", "b", "a")
+	result, err = handler(bulkArgs("mylist", "before", "a", "x"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Int)
+	result, err = handler(bulkArgs("mylist", "AFTER", "b", "y"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Int)
+	assert.Equal(t, []string{"x", "a", "b", "y", "a"}, list.LRange(0, -1))
+
+	result, err = handler(bulkArgs("mylist", "AFTER", "missing", "z"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), result.Int)
+
+	_, err = handler(bulkArgs("mylist", "AROUND", "a", "z"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestLPosHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	handler := LPosHandler(store)
+	ints := func(ns ...int64) []resp.Value {
+		out := make([]resp.Value, len(ns))
+		for i, n := range ns {
+			out[i] = resp.Value{Type: resp.Integer, Int: n}
+		}
+		return out
+	}
+
+	store.GetOrCreateList("mylist").RPush("a", "b", "c", "1", "2", "3", "c", "c")
+
+	result, err := handler(bulkArgs("mylist", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Value{Type: resp.Integer, Int: 2}, result)
+	result, err = handler(bulkArgs("mylist", "c", "RANK", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), result.Int)
+	result, err = handler(bulkArgs("mylist", "c", "RANK", "-1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Int)
+
+	result, err = handler(bulkArgs("mylist", "c", "COUNT", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, ints(2, 6), result.Array)
+	result, err = handler(bulkArgs("mylist", "c", "COUNT", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, ints(2, 6, 7), result.Array)
+	result, err = handler(bulkArgs("mylist", "c", "RANK", "-1", "COUNT", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, ints(7, 6), result.Array)
+	result, err = handler(bulkArgs("mylist", "c", "COUNT", "0", "MAXLEN", "7"))
+	require.NoError(t, err)
+	assert.Equal(t, ints(2, 6), result.Array)
+
+	result, err = handler(bulkArgs("mylist", "z"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+	result, err = handler(bulkArgs("missing", "z", "COUNT", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Value{Type: resp.Array, Array: []resp.Value{}}, result)
+
+	_, err = handler(bulkArgs("mylist", "c", "RANK", "0"))
+	assert.ErrorContains(t, err, "ERR RANK can't be zero")
+	_, err = handler(bulkArgs("mylist", "c", "COUNT", "-1"))
+	assert.EqualError(t, err, "ERR COUNT can't be negative")
+	_, err = handler(bulkArgs("mylist", "c", "MAXLEN", "-1"))
+	assert.EqualError(t, err, "ERR MAXLEN can't be negative")
+	_, err = handler(bulkArgs("mylist", "c", "COUNT"))
+	assert.EqualError(t, err, "ERR syntax error")
+	_, err = handler(bulkArgs("mylist", "c", "LIMIT", "1"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestLMoveHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	handler := LMoveHandler(store)
+
+	result, err := handler(bulkArgs("src", "dst", "LEFT", "RIGHT"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+	dst, err := store.GetList("dst")
+	require.NoError(t, err)
+	assert.Nil(t, dst)
+
+	src := store.GetOrCreateList("src")
+	src.RPush("a", "b", "c")
+	result, err = handler(bulkArgs("src", "dst", "LEFT", "RIGHT"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", result.Str)
+	assert.Equal(t, []string{"a"}, store.GetOrCreateList("dst").LRange(0, -1))
+
+	// Moving within one list rotates it
+	result, err = handler(bulkArgs("src", "src", "RIGHT", "LEFT"))
+	require.NoError(t, err)
+	assert.Equal(t, "c", result.Str)
+	assert.Equal(t, []string{"c", "b"}, src.LRange(0, -1))
+
+	result, err = RPopLPushHandler(store)(bulkArgs("src", "dst"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Str)
+	assert.Equal(t, []string{"b", "a"}, store.GetOrCreateList("dst").LRange(0, -1))
+	name, args, ok := rewriteRPopLPush([]string{"src", "dst"}, result)
+	require.True(t, ok)
+	assert.Equal(t, "LMOVE", name)
+	assert.Equal(t, []string{"src", "dst", "RIGHT", "LEFT"}, args)
+
+	_, err = handler(bulkArgs("src", "dst", "UP", "LEFT"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestLMPopHandler(t *testing.T) {
+	store := newMockDataStore()
+	defer store.Close()
+	handler := LMPopHandler(store)
+
+	result, err := handler(bulkArgs("2", "a", "b", "LEFT"))
+	require.NoError(t, err)
+	assert.Equal(t, nullArrayReply, result)
+
+	store.GetOrCreateList("b").RPush("1", "2", "3")
+	result, err = handler(bulkArgs("2", "a", "b", "LEFT", "COUNT", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Array[0].Str)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "1"},
+		{Type: resp.BulkString, Str: "2"},
+	}, result.Array[1].Array)
+
+	name, args, ok := rewriteMPop([]string{"2", "a", "b", "LEFT", "COUNT", "2"}, result)
+	require.True(t, ok)
+	assert.Equal(t, "LPOP", name)
+	assert.Equal(t, []string{"b", "2"}, args)
+
+	_, err = handler(bulkArgs("0", "a", "LEFT"))
+	assert.EqualError(t, err, "ERR numkeys should be greater than 0")
+	_, err = handler(bulkArgs("1", "a", "MIDDLE"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestListCommandsDeleteEmptiedList(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(DataStore) Handler
+		args    []string
+		event   string
+	}{
+		{"LPOP", LPopHandler, []string{"l"}, "lpop"},
+		{"RPOP count", RPopHandler, []string{"l", "10"}, "rpop"},
+		{"LREM", LRemHandler, []string{"l", "0", "a"}, "lrem"},
+		{"LTRIM", LTrimHandler, []string{"l", "5", "10"}, "ltrim"},
+		{"LMPOP", LMPopHandler, []string{"2", "nokey", "l", "RIGHT", "COUNT", "10"}, "rpop"},
+		{"LMOVE", LMoveHandler, []string{"l", "dst", "LEFT", "RIGHT"}, "lpop"},
+		{"RPOPLPUSH", RPopLPushHandler, []string{"l", "dst"}, "rpop"},
+		{"BLPOP", BLPopHandler, []string{"l", "0"}, "lpop"},
+		{"BLMOVE", BLMoveHandler, []string{"l", "dst", "RIGHT", "LEFT", "0"}, "rpop"},
+		{"BLMPOP", BLMPopHandler, []string{"0", "1", "l", "LEFT"}, "lpop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newScopedMockDataStore(t)
+			list, err := store.EnsureList("l")
+			require.NoError(t, err)
+			list.RPush("a")
+			var events []string
+			store.OnKeyEvent(func(event, key string) {
+				if key == "l" {
+					events = append(events, event)
+				}
+			})
+
+			_, err = tt.handler(store)(bulkArgs(tt.args...))
+			require.NoError(t, err)
+			assert.False(t, store.Exists("l"))
+			assert.Equal(t, []string{tt.event, "del"}, events)
+		})
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"gridhouse/internal/logger"
 	"gridhouse/internal/resp"
 	"gridhouse/internal/store"
 	"runtime"
@@ -60,55 +59,46 @@ func (m *memoryUsageCalculator) CalculateUsage(key string) int64 {
 	return keySize + 64
 }
 
-func (m *memoryUsageCalculator) tryGetList(key string) *store.List {
-	defer func() {
-		if err := recover(); err != nil {
-			logger.Error(err)
-		}
-	}()
+// typedStore returns the store as a DataStore when it exposes type information
+func (m *memoryUsageCalculator) typedStore() (DataStore, bool) {
+	ds, ok := m.store.(DataStore)
+	return ds, ok
+}
 
-	// For now, return nil - in a real implementation we'd need type information
-	// This is a simplified approach for the MEMORY command demo
-	return nil
+func (m *memoryUsageCalculator) tryGetList(key string) *store.List {
+	ds, ok := m.typedStore()
+	if !ok {
+		return nil
+	}
+	list, err := ds.GetList(key)
+	if err != nil {
+		return nil
+	}
+	return list
 }
 
 func (m *memoryUsageCalculator) tryGetHash(key string) *store.Hash {
-	defer func() {
-		if err := recover(); err != nil {
-			logger.Error(err)
-		}
-	}()
-
-	// For now, return nil - in a real implementation we'd need type information
-	// This is a simplified approach for the MEMORY command demo
-	return nil
+	ds, ok := m.typedStore()
+	if !ok {
+		return nil
+	}
+	hash, err := ds.GetHash(key)
+	if err != nil {
+		return nil
+	}
+	return hash
 }
 
 func (m *memoryUsageCalculator) tryGetSet(key string) *store.Set {
-	defer func() {
-		if err := recover(); err != nil {
-			logger.Error(err)
-		}
-	}()
-
-	// For now, return nil - in a real implementation we'd need type information
-	// This is a simplified approach for the MEMORY command demo
-	return nil
-}
-
-// Helper methods to check if data structures exist (simplified approach)
-func (m *memoryUsageCalculator) listExists(key string) bool { //nolint:unused
-	// This is a simplified check - in a real implementation,
-	// we'd need type information from the store
-	return false
-}
-
-func (m *memoryUsageCalculator) hashExists(key string) bool { //nolint:unused
-	return false
-}
-
-func (m *memoryUsageCalculator) setExists(key string) bool { //nolint:unused
-	return false
+	ds, ok := m.typedStore()
+	if !ok {
+		return nil
+	}
+	set, err := ds.GetSet(key)
+	if err != nil {
+		return nil
+	}
+	return set
 }
 
 func (m *memoryUsageCalculator) calculateListUsage(list *store.List) int64 {
@@ -28,6 +28,7 @@ func MSetHandler(store Store) Handler {
 
 			// Set the key-value pair (no expiration for MSET)
 			store.Set(key, value, time.Time{})
+			notifyKeyEvent(store, "set", key)
 		}
 
 		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
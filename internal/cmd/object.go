@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"strings"
+)
+
+// objectInspector is implemented by stores that track how keys are stored
+type objectInspector interface {
+	Object(key string) (store.ObjectInfo, bool)
+}
+
+// ObjectHandler handles OBJECT ENCODING, FREQ, IDLETIME, REFCOUNT and HELP.
+// Inspecting a key does not count as accessing it.
+func ObjectHandler(s Store) Handler {
+	inspector, _ := s.(objectInspector)
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) == 0 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'OBJECT' command")
+		}
+		sub := strings.ToUpper(args[0].Str)
+		if sub == "HELP" && len(args) == 1 {
+			return objectHelp(), nil
+		}
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR unknown subcommand or wrong number of arguments for '%s'. Try OBJECT HELP.", args[0].Str)
+		}
+
+		switch sub {
+		case "ENCODING", "FREQ", "IDLETIME", "REFCOUNT":
+		default:
+			return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s'. Try OBJECT HELP.", args[0].Str)
+		}
+		if inspector == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		info, ok := inspector.Object(args[1].Str)
+		if !ok {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		switch sub {
+		case "ENCODING":
+			return resp.Value{Type: resp.BulkString, Str: info.Encoding}, nil
+		case "FREQ":
+			return resp.Value{Type: resp.Integer, Int: int64(info.Freq)}, nil
+		case "IDLETIME":
+			return resp.Value{Type: resp.Integer, Int: info.IdleTime}, nil
+		}
+		// Values are never shared between keys
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+func objectHelp() resp.Value {
+	lines := []string{
+		"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+		"ENCODING <key>",
+		"    Return the kind of internal representation used in order to store the value",
+		"    associated with a <key>.",
+		"FREQ <key>",
+		"    Return the access frequency index of the <key>. The returned integer is",
+		"    proportional to the logarithm of the recent access frequency of the key.",
+		"IDLETIME <key>",
+		"    Return the idle time of the <key>, that is the approximated number of",
+		"    seconds elapsed since the last access to the key.",
+		"REFCOUNT <key>",
+		"    Return the number of references of the value associated with the specified",
+		"    <key>.",
+		"HELP",
+		"    Print this help.",
+	}
+	out := make([]resp.Value, len(lines))
+	for i, line := range lines {
+		out[i] = resp.Value{Type: resp.SimpleString, Str: line}
+	}
+	return resp.Value{Type: resp.Array, Array: out}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectHandler(t *testing.T) {
+	db := store.NewUltraOptimizedDB()
+	defer db.Close()
+	handler := ObjectHandler(db)
+	object := func(args ...string) (resp.Value, error) {
+		values := make([]resp.Value, len(args))
+		for i, arg := range args {
+			values[i] = resp.Value{Type: resp.BulkString, Str: arg}
+		}
+		return handler(values)
+	}
+
+	db.Set("int", "12345", time.Time{})
+	db.Set("padded", "012", time.Time{})
+	db.Set("short", "hello", time.Time{})
+	db.Set("long", strings.Repeat("x", 45), time.Time{})
+	_, err := db.EnsureList("list")
+	require.NoError(t, err)
+	_, err = db.EnsureSet("set")
+	require.NoError(t, err)
+	_, err = db.EnsureHash("hash")
+	require.NoError(t, err)
+	_, err = db.EnsureSortedSet("zset")
+	require.NoError(t, err)
+	_, err = db.EnsureStream("stream")
+	require.NoError(t, err)
+
+	for key, want := range map[string]string{
+		"int": "int", "padded": "embstr", "short": "embstr", "long": "raw",
+		"list": "deque", "set": "hashtable", "hash": "hashtable", "zset": "sortedslice", "stream": "stream",
+	} {
+		res, err := object("encoding", key)
+		require.NoError(t, err)
+		assert.Equal(t, resp.Value{Type: resp.BulkString, Str: want}, res, key)
+	}
+
+	res, err := object("IDLETIME", "int")
+	require.NoError(t, err)
+	assert.Equal(t, resp.Value{Type: resp.Integer, Int: 0}, res)
+	res, err = object("FREQ", "int")
+	require.NoError(t, err)
+	assert.Equal(t, resp.Integer, res.Type)
+	res, err = object("REFCOUNT", "int")
+	require.NoError(t, err)
+	assert.Equal(t, resp.Value{Type: resp.Integer, Int: 1}, res)
+
+	res, err = object("ENCODING", "missing")
+	require.NoError(t, err)
+	assert.True(t, res.IsNull)
+
+	res, err = object("HELP")
+	require.NoError(t, err)
+	assert.Equal(t, "OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:", res.Array[0].Str)
+
+	_, err = object("ENCODING")
+	assert.EqualError(t, err, "ERR unknown subcommand or wrong number of arguments for 'ENCODING'. Try OBJECT HELP.")
+	_, err = object("BOGUS", "int")
+	assert.EqualError(t, err, "ERR unknown subcommand 'BOGUS'. Try OBJECT HELP.")
+	_, err = object()
+	assert.EqualError(t, err, "ERR wrong number of arguments for 'OBJECT' command")
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"strings"
+)
+
+// PubSub is the registry used by PUBLISH, SPUBLISH and PUBSUB.
+// Subscriptions themselves are connection state and are handled by the server.
+type PubSub interface {
+	Publish(channel, message string) int
+	SPublish(channel, message string) int
+	Channels(pattern string) []string
+	ShardChannels(pattern string) []string
+	NumSub(channels ...string) []int
+	ShardNumSub(channels ...string) []int
+	NumPat() int
+}
+
+// PublishHandler handles PUBLISH channel message
+func PublishHandler(ps PubSub) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'publish' command")
+		}
+		n := ps.Publish(args[0].Str, args[1].Str)
+		return resp.Value{Type: resp.Integer, Int: int64(n)}, nil
+	}
+}
+
+// SPublishHandler handles SPUBLISH shardchannel message
+func SPublishHandler(ps PubSub) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'spublish' command")
+		}
+		n := ps.SPublish(args[0].Str, args[1].Str)
+		return resp.Value{Type: resp.Integer, Int: int64(n)}, nil
+	}
+}
+
+// PubSubHandler handles PUBSUB CHANNELS|NUMSUB|NUMPAT|SHARDCHANNELS|SHARDNUMSUB
+func PubSubHandler(ps PubSub) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'pubsub' command")
+		}
+		sub := strings.ToUpper(args[0].Str)
+		switch sub {
+		case "CHANNELS", "SHARDCHANNELS":
+			if len(args) > 2 {
+				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'pubsub|%s' command", strings.ToLower(sub))
+			}
+			pattern := ""
+			if len(args) == 2 {
+				pattern = args[1].Str
+			}
+			var names []string
+			if sub == "CHANNELS" {
+				names = ps.Channels(pattern)
+			} else {
+				names = ps.ShardChannels(pattern)
+			}
+			out := make([]resp.Value, len(names))
+			for i, name := range names {
+				out[i] = resp.Value{Type: resp.BulkString, Str: name}
+			}
+			return resp.Value{Type: resp.Array, Array: out}, nil
+		case "NUMSUB", "SHARDNUMSUB":
+			channels := make([]string, len(args)-1)
+			for i, a := range args[1:] {
+				channels[i] = a.Str
+			}
+			var counts []int
+			if sub == "NUMSUB" {
+				counts = ps.NumSub(channels...)
+			} else {
+				counts = ps.ShardNumSub(channels...)
+			}
+			out := make([]resp.Value, 0, len(channels)*2)
+			for i, ch := range channels {
+				out = append(out,
+					resp.Value{Type: resp.BulkString, Str: ch},
+					resp.Value{Type: resp.Integer, Int: int64(counts[i])})
+			}
+			return resp.Value{Type: resp.Array, Array: out}, nil
+		case "NUMPAT":
+			if len(args) != 1 {
+				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'pubsub|numpat' command")
+			}
+			return resp.Value{Type: resp.Integer, Int: int64(ps.NumPat())}, nil
+		default:
+			return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s' for 'PUBSUB' command", args[0].Str)
+		}
+	}
+}
+
+// RegisterPubSubCommands registers the stateless pub/sub commands.
+// SUBSCRIBE and friends change connection state and are handled by the server.
+func RegisterPubSubCommands(registry *Registry, ps PubSub) {
+	registry.Register(&Command{
+		Name:      "PUBLISH",
+		Arity:     2,
+		Handler:   PublishHandler(ps),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclPubSub, AclFast},
+		Channels:  FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "SPUBLISH",
+		Arity:     2,
+		Handler:   SPublishHandler(ps),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclPubSub, AclFast},
+		Channels:  FirstKey,
+	})
+
+	registry.Register(&Command{
+		Name:      "PUBSUB",
+		Arity:     -1,
+		Handler:   PubSubHandler(ps),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclPubSub, AclSlow},
+	})
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	"gridhouse/internal/pubsub"
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubCommands(t *testing.T) {
+	hub := pubsub.NewHub()
+	sub := pubsub.NewSubscriber("c1", 16)
+	hub.Subscribe(sub, "news.sport", "news.tech")
+	hub.PSubscribe(sub, "news.*")
+	hub.SSubscribe(sub, "orders")
+
+	registry := NewRegistry()
+	RegisterPubSubCommands(registry, hub)
+
+	result, err := registry.Execute("PUBLISH", bulkArgs("news.sport", "goal"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Int)
+
+	result, err = registry.Execute("SPUBLISH", bulkArgs("orders", "o1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+
+	result, err = registry.Execute("PUBSUB", bulkArgs("CHANNELS", "*tech"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 1)
+	assert.Equal(t, "news.tech", result.Array[0].Str)
+
+	result, err = registry.Execute("PUBSUB", bulkArgs("NUMSUB", "news.sport", "other"))
+	require.NoError(t, err)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.BulkString, Str: "news.sport"}, {Type: resp.Integer, Int: 1},
+		{Type: resp.BulkString, Str: "other"}, {Type: resp.Integer, Int: 0},
+	}, result.Array)
+
+	result, err = registry.Execute("PUBSUB", bulkArgs("NUMPAT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+
+	result, err = registry.Execute("PUBSUB", bulkArgs("SHARDNUMSUB", "orders"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Array[1].Int)
+
+	_, err = registry.Execute("PUBSUB", bulkArgs("BOGUS"))
+	assert.Error(t, err)
+	_, err = registry.Execute("PUBLISH", bulkArgs("only-channel"))
+	assert.Error(t, err)
+}
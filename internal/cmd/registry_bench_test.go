@@ -25,6 +25,23 @@ func (m *mockStore) GetOrCreateHash(key string) *store.Hash           { return s
 func (m *mockStore) GetOrCreateSortedSet(key string) *store.SortedSet { return store.NewSortedSet() }
 func (m *mockStore) GetOrCreateStream(key string) *store.Stream       { return store.NewStream() }
 func (m *mockStore) GetDataType(key string) store.DataType            { return store.TypeString }
+func (m *mockStore) GetList(key string) (*store.List, error)          { return store.NewList(), nil }
+func (m *mockStore) EnsureList(key string) (*store.List, error)       { return store.NewList(), nil }
+func (m *mockStore) GetSet(key string) (*store.Set, error)            { return store.NewSet(), nil }
+func (m *mockStore) EnsureSet(key string) (*store.Set, error)         { return store.NewSet(), nil }
+func (m *mockStore) StoreSet(key string, members []string) bool       { return false }
+func (m *mockStore) GetHash(key string) (*store.Hash, error)          { return store.NewHash(), nil }
+func (m *mockStore) EnsureHash(key string) (*store.Hash, error)       { return store.NewHash(), nil }
+func (m *mockStore) GetSortedSet(key string) (*store.SortedSet, error) {
+	return store.NewSortedSet(), nil
+}
+func (m *mockStore) EnsureSortedSet(key string) (*store.SortedSet, error) {
+	return store.NewSortedSet(), nil
+}
+func (m *mockStore) GetStream(key string) (*store.Stream, error)    { return store.NewStream(), nil }
+func (m *mockStore) EnsureStream(key string) (*store.Stream, error) { return store.NewStream(), nil }
+func (m *mockStore) DeleteIfEmpty(key string) bool                  { return false }
+func (m *mockStore) NotifyKeyEvent(event, key string)               {}
 
 // BenchmarkRegistryGet measures command lookup performance
 func BenchmarkRegistryGet(b *testing.B) {
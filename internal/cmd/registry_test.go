@@ -133,6 +133,22 @@ func (m *MockStore) GetDataType(key string) store.DataType {
 	return store.TypeString
 }
 
+func (m *MockStore) GetList(key string) (*store.List, error)           { return nil, nil }
+func (m *MockStore) EnsureList(key string) (*store.List, error)        { return store.NewList(), nil }
+func (m *MockStore) GetSet(key string) (*store.Set, error)             { return nil, nil }
+func (m *MockStore) EnsureSet(key string) (*store.Set, error)          { return store.NewSet(), nil }
+func (m *MockStore) StoreSet(key string, members []string) bool        { return false }
+func (m *MockStore) GetHash(key string) (*store.Hash, error)           { return nil, nil }
+func (m *MockStore) EnsureHash(key string) (*store.Hash, error)        { return store.NewHash(), nil }
+func (m *MockStore) GetSortedSet(key string) (*store.SortedSet, error) { return nil, nil }
+func (m *MockStore) EnsureSortedSet(key string) (*store.SortedSet, error) {
+	return store.NewSortedSet(), nil
+}
+func (m *MockStore) GetStream(key string) (*store.Stream, error)    { return nil, nil }
+func (m *MockStore) EnsureStream(key string) (*store.Stream, error) { return store.NewStream(), nil }
+func (m *MockStore) DeleteIfEmpty(key string) bool                  { return false }
+func (m *MockStore) NotifyKeyEvent(event, key string)               {}
+
 func TestRegistryRegistration(t *testing.T) {
 	registry := NewRegistry()
 
@@ -111,6 +111,8 @@ func RenameHandler(dataStore DataStore) Handler {
 		// Delete the old key only if it's different from the new key
 		if oldKey != newKey {
 			dataStore.Del(oldKey)
+			dataStore.NotifyKeyEvent("rename_from", oldKey)
+			dataStore.NotifyKeyEvent("rename_to", newKey)
 		}
 
 		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
@@ -189,6 +189,117 @@ func (m *RenameMockStore) GetDataType(key string) store.DataType {
 	return store.TypeString
 }
 
+func (m *RenameMockStore) GetList(key string) (*store.List, error) {
+	if val, ok := m.data[key]; ok {
+		if list, ok := val.(*store.List); ok {
+			return list, nil
+		}
+		return nil, store.ErrWrongType
+	}
+	return nil, nil
+}
+
+func (m *RenameMockStore) EnsureList(key string) (*store.List, error) {
+	if _, ok := m.data[key]; ok {
+		return m.GetList(key)
+	}
+	return m.GetOrCreateList(key), nil
+}
+
+func (m *RenameMockStore) GetSet(key string) (*store.Set, error) {
+	if val, ok := m.data[key]; ok {
+		if set, ok := val.(*store.Set); ok {
+			return set, nil
+		}
+		return nil, store.ErrWrongType
+	}
+	return nil, nil
+}
+
+func (m *RenameMockStore) EnsureSet(key string) (*store.Set, error) {
+	if _, ok := m.data[key]; ok {
+		return m.GetSet(key)
+	}
+	return m.GetOrCreateSet(key), nil
+}
+
+func (m *RenameMockStore) StoreSet(key string, members []string) bool {
+	_, existed := m.data[key]
+	delete(m.data, key)
+	if len(members) > 0 {
+		m.GetOrCreateSet(key).SAdd(members...)
+	}
+	return existed
+}
+
+func (m *RenameMockStore) GetHash(key string) (*store.Hash, error) {
+	if val, ok := m.data[key]; ok {
+		if hash, ok := val.(*store.Hash); ok {
+			return hash, nil
+		}
+		return nil, store.ErrWrongType
+	}
+	return nil, nil
+}
+
+func (m *RenameMockStore) EnsureHash(key string) (*store.Hash, error) {
+	if _, ok := m.data[key]; ok {
+		return m.GetHash(key)
+	}
+	return m.GetOrCreateHash(key), nil
+}
+
+func (m *RenameMockStore) GetSortedSet(key string) (*store.SortedSet, error) {
+	if val, ok := m.data[key]; ok {
+		if zset, ok := val.(*store.SortedSet); ok {
+			return zset, nil
+		}
+		return nil, store.ErrWrongType
+	}
+	return nil, nil
+}
+
+func (m *RenameMockStore) EnsureSortedSet(key string) (*store.SortedSet, error) {
+	if _, ok := m.data[key]; ok {
+		return m.GetSortedSet(key)
+	}
+	return m.GetOrCreateSortedSet(key), nil
+}
+
+func (m *RenameMockStore) GetStream(key string) (*store.Stream, error) {
+	if val, ok := m.data[key]; ok {
+		if stream, ok := val.(*store.Stream); ok {
+			return stream, nil
+		}
+		return nil, store.ErrWrongType
+	}
+	return nil, nil
+}
+
+func (m *RenameMockStore) EnsureStream(key string) (*store.Stream, error) {
+	if _, ok := m.data[key]; ok {
+		return m.GetStream(key)
+	}
+	return m.GetOrCreateStream(key), nil
+}
+
+func (m *RenameMockStore) DeleteIfEmpty(key string) bool {
+	var empty bool
+	switch v := m.data[key].(type) {
+	case *store.List:
+		empty = v.LLen() == 0
+	case *store.Set:
+		empty = v.SCard() == 0
+	case *store.Hash:
+		empty = v.HLen() == 0
+	case *store.SortedSet:
+		empty = v.ZCard() == 0
+	}
+	return empty && m.Del(key)
+}
+
+func (m *RenameMockStore) NotifyKeyEvent(event, key string) {}
+
 func TestRenameHandler(t *testing.T) {
 	store := NewRenameMockStore()
 	handler := RenameHandler(store)
@@ -111,6 +111,8 @@ func RenameNxHandler(dataStore DataStore) Handler {
 
 		// Delete the old key
 		dataStore.Del(oldKey)
+		dataStore.NotifyKeyEvent("rename_from", oldKey)
+		dataStore.NotifyKeyEvent("rename_to", newKey)
 
 		return resp.Value{Type: resp.Integer, Int: 1}, nil
 	}
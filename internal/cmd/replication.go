@@ -252,31 +252,35 @@ func SyncHandler(manager ReplicationManager) Handler {
 // RegisterReplicationCommands registers replication-related commands
 func RegisterReplicationCommands(registry *Registry, manager ReplicationManager) {
 	registry.Register(&Command{
-		Name:     "ROLE",
-		Arity:    0,
-		Handler:  RoleHandler(manager),
-		ReadOnly: true,
+		Name:      "ROLE",
+		Arity:     0,
+		Handler:   RoleHandler(manager),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclAdmin, AclFast, AclDangerous},
 	})
 
 	// Full replication protocol commands
 	registry.Register(&Command{
-		Name:     "PSYNC",
-		Arity:    -1,
-		Handler:  PSyncHandler(manager),
-		ReadOnly: true,
+		Name:      "PSYNC",
+		Arity:     -1,
+		Handler:   PSyncHandler(manager),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 
 	registry.Register(&Command{
-		Name:     "REPLCONF",
-		Arity:    -1,
-		Handler:  ReplConfHandler(manager),
-		ReadOnly: true,
+		Name:      "REPLCONF",
+		Arity:     -1,
+		Handler:   ReplConfHandler(manager),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 
 	registry.Register(&Command{
-		Name:     "SYNC",
-		Arity:    0,
-		Handler:  SyncHandler(manager),
-		ReadOnly: true,
+		Name:      "SYNC",
+		Arity:     0,
+		Handler:   SyncHandler(manager),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclAdmin, AclSlow, AclDangerous},
 	})
 }
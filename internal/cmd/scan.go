@@ -174,14 +174,15 @@ func SScanHandler(ds DataStore) Handler {
 		}
 
 		// Type check: if key exists and not a set, error
-		if ds.Exists(key) && ds.GetDataType(key) != st.TypeSet {
-			return resp.Value{}, fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+		set, err := ds.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
 		}
 
 		// Snapshot members
 		members := []string{}
-		if ds.Exists(key) {
-			members = ds.GetOrCreateSet(key).SMembers()
+		if set != nil {
+			members = set.SMembers()
 		}
 
 		// Filter by pattern
@@ -218,7 +219,7 @@ func SScanHandler(ds DataStore) Handler {
 	}
 }
 
-// HScanHandler implements: HSCAN key cursor [MATCH pattern] [COUNT count]
+// HScanHandler implements: HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
 // Cursor is an integer offset into the filtered list of fields. Returns [next-cursor, [field, value, ...]]
 func HScanHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
@@ -233,6 +234,7 @@ func HScanHandler(ds DataStore) Handler {
 		// Options
 		var pattern string
 		count := 10
+		noValues := false
 		i := 2
 		for i < len(args) {
 			opt := strings.ToUpper(args[i].Str)
@@ -256,20 +258,24 @@ func HScanHandler(ds DataStore) Handler {
 				}
 				count = c
 				i += 2
+			case "NOVALUES":
+				noValues = true
+				i++
 			default:
 				return resp.Value{}, fmt.Errorf("ERR syntax error")
 			}
 		}
 
 		// Type check: if the key exists but is not a hash, return WRONGTYPE
-		if ds.Exists(key) && ds.GetDataType(key) != st.TypeHash {
-			return resp.Value{}, fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+		hash, err := ds.GetHash(key)
+		if err != nil {
+			return resp.Value{}, err
 		}
 
 		// Snapshot fields and values
 		var fieldsMap map[string]string
-		if ds.Exists(key) {
-			fieldsMap = ds.GetOrCreateHash(key).HGetAll()
+		if hash != nil {
+			fieldsMap = hash.HGetAll()
 		} else {
 			fieldsMap = map[string]string{}
 		}
@@ -304,15 +310,15 @@ func HScanHandler(ds DataStore) Handler {
 			nextCursor = 0
 		}
 
-		// Build alternating field/value array for the selected page
-		pairsCount := end - cursor
-		arr := make([]resp.Value, pairsCount*2)
-		idx := 0
+		// Build alternating field/value array for the selected page, or
+		// only the fields with NOVALUES
+		arr := make([]resp.Value, 0, (end-cursor)*2)
 		for i := cursor; i < end; i++ {
 			f := fields[i]
-			arr[idx] = resp.Value{Type: resp.BulkString, Str: f}
-			arr[idx+1] = resp.Value{Type: resp.BulkString, Str: fieldsMap[f]}
-			idx += 2
+			arr = append(arr, resp.Value{Type: resp.BulkString, Str: f})
+			if !noValues {
+				arr = append(arr, resp.Value{Type: resp.BulkString, Str: fieldsMap[f]})
+			}
 		}
 
 		cursorVal := resp.Value{Type: resp.BulkString, Str: strconv.Itoa(nextCursor)}
@@ -3,6 +3,10 @@ package cmd
 import (
 	"fmt"
 	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"math"
+	"strconv"
+	"strings"
 )
 
 // SAddHandler handles the SADD command
@@ -18,8 +22,14 @@ func SAddHandler(store DataStore) Handler {
 			elements[i] = arg.Str
 		}
 
-		set := store.GetOrCreateSet(key)
+		set, err := store.EnsureSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		added := set.SAdd(elements...)
+		if added > 0 {
+			store.NotifyKeyEvent("sadd", key)
+		}
 
 		return resp.Value{Type: resp.Integer, Int: int64(added)}, nil
 	}
@@ -38,8 +48,18 @@ func SRemHandler(store DataStore) Handler {
 			elements[i] = arg.Str
 		}
 
-		set := store.GetOrCreateSet(key)
+		set, err := store.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if set == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		removed := set.SRem(elements...)
+		if removed > 0 {
+			store.NotifyKeyEvent("srem", key)
+			deleteIfEmpty(store, key)
+		}
 
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
@@ -55,10 +75,12 @@ func SIsMemberHandler(store DataStore) Handler {
 		key := args[0].Str
 		member := args[1].Str
 
-		set := store.GetOrCreateSet(key)
-		isMember := set.SIsMember(member)
+		set, err := store.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 
-		if isMember {
+		if set != nil && set.SIsMember(member) {
 			return resp.Value{Type: resp.Integer, Int: 1}, nil
 		}
 		return resp.Value{Type: resp.Integer, Int: 0}, nil
@@ -73,7 +95,13 @@ func SMembersHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		set := store.GetOrCreateSet(key)
+		set, err := store.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if set == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		members := set.SMembers()
 
 		// Convert to RESP array
@@ -94,28 +122,304 @@ func SCardHandler(store DataStore) Handler {
 		}
 
 		key := args[0].Str
-		set := store.GetOrCreateSet(key)
+		set, err := store.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if set == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		card := set.SCard()
 
 		return resp.Value{Type: resp.Integer, Int: int64(card)}, nil
 	}
 }
 
-// SPopHandler handles the SPOP command
+// SPopHandler handles SPOP key [count]. Without a count it pops a single
+// member; with one it pops up to count members as an array.
 func SPopHandler(store DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) != 1 {
+		if len(args) != 1 && len(args) != 2 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SPOP' command")
 		}
 
 		key := args[0].Str
-		set := store.GetOrCreateSet(key)
+		count := -1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1].Str)
+			if err != nil || n < 0 {
+				return resp.Value{}, fmt.Errorf("ERR value is out of range, must be positive")
+			}
+			count = n
+		}
+
+		set, err := store.GetSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if count >= 0 {
+			var popped []string
+			if set != nil && count > 0 {
+				popped = set.SPopN(count)
+			}
+			if len(popped) > 0 {
+				store.NotifyKeyEvent("spop", key)
+				deleteIfEmpty(store, key)
+			}
+			return keysToRespArray(popped), nil
+		}
+		if set == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
 
 		element, exists := set.SPop()
 		if !exists {
 			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
 		}
+		store.NotifyKeyEvent("spop", key)
+		deleteIfEmpty(store, key)
 
 		return resp.Value{Type: resp.BulkString, Str: element}, nil
 	}
 }
+
+// rewriteSPop propagates the members SPOP picked at random as SREM, so that
+// the AOF and replicas remove the same ones
+func rewriteSPop(args []string, result resp.Value) (string, []string, bool) {
+	members := []string{args[0]}
+	switch {
+	case result.Type == resp.BulkString && !result.IsNull:
+		members = append(members, result.Str)
+	case result.Type == resp.Array:
+		for _, member := range result.Array {
+			members = append(members, member.Str)
+		}
+	}
+	return "SREM", members, len(members) > 1
+}
+
+// SRandMemberHandler handles SRANDMEMBER key [count]. A negative count may
+// return the same member several times.
+func SRandMemberHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SRANDMEMBER' command")
+		}
+
+		set, err := ds.GetSet(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(args) == 1 {
+			if set != nil {
+				if members := set.SRandMember(1); len(members) == 1 {
+					return resp.Value{Type: resp.BulkString, Str: members[0]}, nil
+				}
+			}
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+
+		count, err := strconv.ParseInt(args[1].Str, 10, 64)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		if count < -math.MaxInt32 || count > math.MaxInt32 {
+			return resp.Value{}, fmt.Errorf("ERR value is out of range")
+		}
+		if set == nil {
+			return keysToRespArray(nil), nil
+		}
+		return keysToRespArray(set.SRandMember(int(count))), nil
+	}
+}
+
+// SMIsMemberHandler handles SMISMEMBER key member [member ...]
+func SMIsMemberHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SMISMEMBER' command")
+		}
+
+		set, err := ds.GetSet(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		members := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			members[i] = arg.Str
+		}
+		found := make([]bool, len(members))
+		if set != nil {
+			found = set.SMIsMember(members...)
+		}
+
+		reply := make([]resp.Value, len(found))
+		for i, ok := range found {
+			reply[i] = resp.Value{Type: resp.Integer}
+			if ok {
+				reply[i].Int = 1
+			}
+		}
+		return resp.Value{Type: resp.Array, Array: reply}, nil
+	}
+}
+
+// SMoveHandler handles SMOVE source destination member
+func SMoveHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SMOVE' command")
+		}
+		source, destination, member := args[0].Str, args[1].Str, args[2].Str
+
+		// Check the destination type before touching the source
+		if _, err := ds.GetSet(destination); err != nil {
+			return resp.Value{}, err
+		}
+		src, err := ds.GetSet(source)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if src == nil || !src.SIsMember(member) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		dst, err := ds.EnsureSet(destination)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !store.SetMove(src, dst, member) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		if source != destination {
+			ds.NotifyKeyEvent("srem", source)
+			deleteIfEmpty(ds, source)
+			ds.NotifyKeyEvent("sadd", destination)
+		}
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+// setsAt returns the sets stored at keys, nil for missing keys. Any key of
+// another type fails the whole command.
+func setsAt(ds DataStore, keys []resp.Value) ([]*store.Set, error) {
+	sets := make([]*store.Set, len(keys))
+	for i, key := range keys {
+		set, err := ds.GetSet(key.Str)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+// setAlgebra computes the members of a SUNION, SINTER or SDIFF over sets
+type setAlgebra func(sets []*store.Set) []string
+
+func unionOf(sets []*store.Set) []string { return store.SetUnion(sets...) }
+func interOf(sets []*store.Set) []string { return store.SetInter(0, sets...) }
+func diffOf(sets []*store.Set) []string  { return store.SetDiff(sets[0], sets[1:]...) }
+
+// SUnionHandler handles SUNION key [key ...]
+func SUnionHandler(ds DataStore) Handler {
+	return setAlgebraHandler(ds, "SUNION", unionOf)
+}
+
+// SInterHandler handles SINTER key [key ...]
+func SInterHandler(ds DataStore) Handler {
+	return setAlgebraHandler(ds, "SINTER", interOf)
+}
+
+// SDiffHandler handles SDIFF key [key ...]
+func SDiffHandler(ds DataStore) Handler {
+	return setAlgebraHandler(ds, "SDIFF", diffOf)
+}
+
+func setAlgebraHandler(ds DataStore, name string, op setAlgebra) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+		}
+		sets, err := setsAt(ds, args)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		return keysToRespArray(op(sets)), nil
+	}
+}
+
+// SUnionStoreHandler handles SUNIONSTORE destination key [key ...]
+func SUnionStoreHandler(ds DataStore) Handler {
+	return setAlgebraStoreHandler(ds, "SUNIONSTORE", unionOf)
+}
+
+// SInterStoreHandler handles SINTERSTORE destination key [key ...]
+func SInterStoreHandler(ds DataStore) Handler {
+	return setAlgebraStoreHandler(ds, "SINTERSTORE", interOf)
+}
+
+// SDiffStoreHandler handles SDIFFSTORE destination key [key ...]
+func SDiffStoreHandler(ds DataStore) Handler {
+	return setAlgebraStoreHandler(ds, "SDIFFSTORE", diffOf)
+}
+
+// setAlgebraStoreHandler replaces the destination with the result in a
+// single write, whatever it held before. An empty result deletes it.
+func setAlgebraStoreHandler(ds DataStore, name string, op setAlgebra) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+		}
+		sets, err := setsAt(ds, args[1:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+		members := op(sets)
+
+		destination := args[0].Str
+		existed := ds.StoreSet(destination, members)
+		if len(members) > 0 {
+			ds.NotifyKeyEvent(strings.ToLower(name), destination)
+		} else if existed {
+			ds.NotifyKeyEvent("del", destination)
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(len(members))}, nil
+	}
+}
+
+// SInterCardHandler handles SINTERCARD numkeys key [key ...] [LIMIT limit]
+func SInterCardHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SINTERCARD' command")
+		}
+		numKeys, err := strconv.Atoi(args[0].Str)
+		if err != nil || numKeys <= 0 {
+			return resp.Value{}, fmt.Errorf("ERR numkeys should be greater than 0")
+		}
+		if numKeys > len(args)-1 {
+			return resp.Value{}, fmt.Errorf("ERR Number of keys can't be greater than number of args")
+		}
+
+		limit := 0
+		for i := 1 + numKeys; i < len(args); i += 2 {
+			if !strings.EqualFold(args[i].Str, "LIMIT") || i+1 >= len(args) {
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1].Str)
+			if err != nil {
+				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			if n < 0 {
+				return resp.Value{}, fmt.Errorf("ERR LIMIT can't be negative")
+			}
+			limit = n
+		}
+
+		sets, err := setsAt(ds, args[1:1+numKeys])
+		if err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(len(store.SetInter(limit, sets...)))}, nil
+	}
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -227,6 +229,7 @@ func TestSPopHandler(t *testing.T) {
 	// Test wrong number of arguments
 	args = []resp.Value{
 		{Type: resp.BulkString, Str: "myset"},
+		{Type: resp.BulkString, Str: "1"},
 		{Type: resp.BulkString, Str: "extra"},
 	}
 
@@ -234,3 +237,241 @@ func TestSPopHandler(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "wrong number of arguments")
 }
+
+func TestSPopCount(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := SPopHandler(ds)
+	set, _ := ds.EnsureSet("myset")
+	set.SAdd("a", "b", "c")
+
+	result, err := handler(bulkArgs("myset", "2"))
+	require.NoError(t, err)
+	require.Equal(t, resp.Array, result.Type)
+	assert.Len(t, result.Array, 2)
+	assert.Equal(t, 1, set.SCard())
+
+	result, err = handler(bulkArgs("myset", "0"))
+	require.NoError(t, err)
+	assert.Empty(t, result.Array)
+
+	result, err = handler(bulkArgs("missing", "3"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Array, result.Type)
+	assert.Empty(t, result.Array)
+
+	_, err = handler(bulkArgs("myset", "-1"))
+	assert.EqualError(t, err, "ERR value is out of range, must be positive")
+
+	// The popped members are propagated as SREM
+	name, args, ok := rewriteSPop([]string{"myset", "2"}, resp.Value{Type: resp.Array, Array: bulkArgs("a", "b")})
+	require.True(t, ok)
+	assert.Equal(t, "SREM", name)
+	assert.Equal(t, []string{"myset", "a", "b"}, args)
+	name, args, ok = rewriteSPop([]string{"myset"}, resp.Value{Type: resp.BulkString, Str: "c"})
+	require.True(t, ok)
+	assert.Equal(t, []string{"myset", "c"}, args)
+	_, _, ok = rewriteSPop([]string{"myset"}, resp.Value{Type: resp.BulkString, IsNull: true})
+	assert.False(t, ok)
+	_, _, ok = rewriteSPop([]string{"myset", "2"}, resp.Value{Type: resp.Array})
+	assert.False(t, ok)
+}
+
+func TestSRandMemberHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := SRandMemberHandler(ds)
+	set, _ := ds.EnsureSet("myset")
+	set.SAdd("a", "b", "c")
+
+	result, err := handler(bulkArgs("myset"))
+	require.NoError(t, err)
+	assert.Contains(t, []string{"a", "b", "c"}, result.Str)
+
+	result, err = handler(bulkArgs("myset", "5"))
+	require.NoError(t, err)
+	assert.Len(t, result.Array, 3)
+
+	result, err = handler(bulkArgs("myset", "-5"))
+	require.NoError(t, err)
+	assert.Len(t, result.Array, 5)
+	assert.Equal(t, 3, set.SCard())
+
+	result, err = handler(bulkArgs("missing"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+	result, err = handler(bulkArgs("missing", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Array, result.Type)
+	assert.Empty(t, result.Array)
+
+	_, err = handler(bulkArgs("myset", "x"))
+	assert.EqualError(t, err, "ERR value is not an integer or out of range")
+}
+
+func TestSMIsMemberHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := SMIsMemberHandler(ds)
+	set, _ := ds.EnsureSet("myset")
+	set.SAdd("a", "c")
+
+	result, err := handler(bulkArgs("myset", "a", "b", "c"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 3)
+	assert.Equal(t, []int64{1, 0, 1}, []int64{result.Array[0].Int, result.Array[1].Int, result.Array[2].Int})
+
+	result, err = handler(bulkArgs("missing", "a"))
+	require.NoError(t, err)
+	require.Len(t, result.Array, 1)
+	assert.Equal(t, int64(0), result.Array[0].Int)
+}
+
+func TestSMoveHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := SMoveHandler(ds)
+	src, _ := ds.EnsureSet("src")
+	src.SAdd("a", "b")
+	ds.Set("str", "value", time.Time{})
+
+	result, err := handler(bulkArgs("src", "dst", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	dst, _ := ds.GetSet("dst")
+	require.NotNil(t, dst)
+	assert.True(t, dst.SIsMember("a"))
+	assert.False(t, src.SIsMember("a"))
+
+	result, err = handler(bulkArgs("src", "dst", "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	result, err = handler(bulkArgs("nosuchkey", "dst", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+
+	// A destination of another type fails without touching the source
+	_, err = handler(bulkArgs("src", "str", "b"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+	assert.True(t, src.SIsMember("b"))
+}
+
+func TestSetAlgebraHandlers(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	for key, members := range map[string][]string{
+		"key1": {"a", "b", "c", "d"},
+		"key2": {"c"},
+		"key3": {"a", "c", "e"},
+	} {
+		set, _ := ds.EnsureSet(key)
+		set.SAdd(members...)
+	}
+	ds.Set("str", "value", time.Time{})
+
+	members := func(v resp.Value) []string {
+		out := make([]string, len(v.Array))
+		for i, m := range v.Array {
+			out[i] = m.Str
+		}
+		return out
+	}
+
+	result, err := SUnionHandler(ds)(bulkArgs("key1", "key2", "key3", "missing"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, members(result))
+	result, err = SInterHandler(ds)(bulkArgs("key1", "key2", "key3"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c"}, members(result))
+	result, err = SInterHandler(ds)(bulkArgs("key1", "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, result.Array)
+	result, err = SDiffHandler(ds)(bulkArgs("key1", "key2", "key3"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b", "d"}, members(result))
+
+	_, err = SUnionHandler(ds)(bulkArgs("key1", "str"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+
+	// STORE variants replace the destination, whatever it held
+	result, err = SInterStoreHandler(ds)(bulkArgs("str", "key1", "key3"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Int)
+	dst, err := ds.GetSet("str")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c"}, dst.SMembers())
+
+	result, err = SUnionStoreHandler(ds)(bulkArgs("dst", "key2", "key3"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.Int)
+	result, err = SDiffStoreHandler(ds)(bulkArgs("dst", "key1", "key1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	assert.False(t, ds.Exists("dst"))
+
+	// The sources are checked before the destination is written
+	ds.Set("str", "value", time.Time{})
+	_, err = SUnionStoreHandler(ds)(bulkArgs("key2", "key1", "str"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+	set, _ := ds.GetSet("key2")
+	assert.Equal(t, 1, set.SCard())
+}
+
+func TestSInterCardHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	handler := SInterCardHandler(ds)
+	a, _ := ds.EnsureSet("a")
+	a.SAdd("1", "2", "3")
+	b, _ := ds.EnsureSet("b")
+	b.SAdd("2", "3", "4")
+
+	result, err := handler(bulkArgs("2", "a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Int)
+	result, err = handler(bulkArgs("2", "a", "b", "LIMIT", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	result, err = handler(bulkArgs("2", "a", "b", "limit", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Int)
+	result, err = handler(bulkArgs("2", "a", "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+
+	_, err = handler(bulkArgs("0", "a"))
+	assert.EqualError(t, err, "ERR numkeys should be greater than 0")
+	_, err = handler(bulkArgs("3", "a", "b"))
+	assert.EqualError(t, err, "ERR Number of keys can't be greater than number of args")
+	_, err = handler(bulkArgs("2", "a", "b", "LIMIT", "-1"))
+	assert.EqualError(t, err, "ERR LIMIT can't be negative")
+	_, err = handler(bulkArgs("1", "a", "b"))
+	assert.EqualError(t, err, "ERR syntax error")
+}
+
+func TestSetCommandsDeleteEmptiedSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(DataStore) Handler
+		args    []string
+		event   string
+	}{
+		{"SREM", SRemHandler, []string{"s", "a", "b"}, "srem"},
+		{"SPOP", SPopHandler, []string{"s"}, "spop"},
+		{"SPOP count", SPopHandler, []string{"s", "10"}, "spop"},
+		{"SMOVE", SMoveHandler, []string{"s", "dst", "a"}, "srem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := newScopedMockDataStore(t)
+			set, err := ds.EnsureSet("s")
+			require.NoError(t, err)
+			set.SAdd("a")
+			var events []string
+			ds.OnKeyEvent(func(event, key string) {
+				if key == "s" {
+					events = append(events, event)
+				}
+			})
+
+			_, err = tt.handler(ds)(bulkArgs(tt.args...))
+			require.NoError(t, err)
+			assert.False(t, ds.Exists("s"))
+			assert.Equal(t, []string{tt.event, "del"}, events)
+		})
+	}
+}
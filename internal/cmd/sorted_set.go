@@ -27,8 +27,18 @@ func ZPopMinHandler(store DataStore) Handler {
 			}
 			count = c
 		}
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.GetSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if z == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		popped := z.ZPopMin(count)
+		if len(popped) > 0 {
+			store.NotifyKeyEvent("zpopmin", key)
+			deleteIfEmpty(store, key)
+		}
 		// Build array of member, score pairs
 		arr := make([]resp.Value, 0, len(popped)*2)
 		for _, e := range popped {
@@ -55,8 +65,12 @@ func ZAddHandler(store DataStore) Handler {
 			member := args[i+1].Str
 			pairs[member] = score
 		}
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.EnsureSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
 		added := z.ZAdd(pairs)
+		store.NotifyKeyEvent("zadd", key)
 		return resp.Value{Type: resp.Integer, Int: int64(added)}, nil
 	}
 }
@@ -72,8 +86,18 @@ func ZRemHandler(store DataStore) Handler {
 		for i := 1; i < len(args); i++ {
 			members[i-1] = args[i].Str
 		}
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.GetSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if z == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		removed := z.ZRem(members...)
+		if removed > 0 {
+			store.NotifyKeyEvent("zrem", key)
+			deleteIfEmpty(store, key)
+		}
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
 }
@@ -85,7 +109,13 @@ func ZCardHandler(store DataStore) Handler {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'ZCARD' command")
 		}
 		key := args[0].Str
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.GetSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if z == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		return resp.Value{Type: resp.Integer, Int: int64(z.ZCard())}, nil
 	}
 }
@@ -98,9 +128,15 @@ func ZScoreHandler(store DataStore) Handler {
 		}
 		key := args[0].Str
 		member := args[1].Str
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.GetSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if z == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
 		if score, ok := z.ZScore(member); ok {
-			return resp.Value{Type: resp.BulkString, Str: strconv.FormatFloat(score, 'f', -1, 64)}, nil
+			return resp.Value{Type: resp.Double, Str: resp.FormatDouble(score)}, nil
 		}
 		return resp.Value{Type: resp.BulkString, IsNull: true}, nil
 	}
@@ -125,7 +161,13 @@ func ZRangeHandler(store DataStore) Handler {
 				withScores = true
 			}
 		}
-		z := store.GetOrCreateSortedSet(key)
+		z, err := store.GetSortedSet(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if z == nil {
+			return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+		}
 		res := z.ZRange(start, stop, withScores)
 		arr := make([]resp.Value, len(res))
 		for i, s := range res {
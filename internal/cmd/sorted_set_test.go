@@ -67,7 +67,7 @@ func TestZAddErrorsAndZScoreHandler(t *testing.T) {
 	require.NoError(t, err)
 	res, err := zscore([]resp.Value{{Type: resp.BulkString, Str: "myz"}, {Type: resp.BulkString, Str: "pi"}})
 	require.NoError(t, err)
-	assert.Equal(t, resp.BulkString, res.Type)
+	assert.Equal(t, resp.Double, res.Type)
 	assert.Equal(t, "3.14", res.Str)
 
 	// Missing member returns null bulk
@@ -0,0 +1,682 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+)
+
+// noGroupError reports a missing stream or consumer group
+func noGroupError(key, group string) error {
+	return fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+// groupStream returns the stream at key if it has the named consumer group
+func groupStream(ds DataStore, key, group string) (*store.Stream, error) {
+	stream, err := ds.GetStream(key)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil || !stream.HasGroup(group) {
+		return nil, noGroupError(key, group)
+	}
+	return stream, nil
+}
+
+// parseGroupID parses the ID of XGROUP CREATE and SETID; "$" is the last ID
+// of the stream. It also returns the default entries-read counter for it.
+func parseGroupID(stream *store.Stream, arg string) (store.StreamID, int64, error) {
+	if arg == "$" {
+		return stream.LastID(), int64(stream.Meta().EntriesAdded), nil
+	}
+	id, err := parseReadStreamID(arg)
+	if err != nil {
+		return store.StreamID{}, 0, err
+	}
+	if id == (store.StreamID{}) {
+		return id, 0, nil
+	}
+	return id, stream.EstimateEntriesRead(id), nil
+}
+
+// parseEntriesRead parses an optional trailing ENTRIESREAD n argument pair
+func parseEntriesRead(args []resp.Value) (int64, bool, error) {
+	if len(args) == 0 {
+		return 0, false, nil
+	}
+	if len(args) != 2 || !strings.EqualFold(args[0].Str, "ENTRIESREAD") {
+		return 0, false, fmt.Errorf("ERR syntax error")
+	}
+	n, err := strconv.ParseInt(args[1].Str, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	if n < -1 {
+		return 0, false, fmt.Errorf("ERR value for ENTRIESREAD must be positive or -1")
+	}
+	return n, true, nil
+}
+
+// XGROUP CREATE key group id|$ [MKSTREAM] [ENTRIESREAD n]
+// XGROUP SETID key group id|$ [ENTRIESREAD n]
+// XGROUP DESTROY key group
+// XGROUP CREATECONSUMER key group consumer
+// XGROUP DELCONSUMER key group consumer
+func XGroupHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) == 0 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XGROUP' command")
+		}
+		sub := strings.ToUpper(args[0].Str)
+		var arityOK bool
+		switch sub {
+		case "CREATE", "SETID":
+			arityOK = len(args) >= 4
+		case "DESTROY":
+			arityOK = len(args) == 3
+		case "CREATECONSUMER", "DELCONSUMER":
+			arityOK = len(args) == 4
+		default:
+			return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s' for 'XGROUP' command", args[0].Str)
+		}
+		if !arityOK {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XGROUP|%s' command", strings.ToLower(sub))
+		}
+		key, group := args[1].Str, args[2].Str
+
+		if sub == "CREATE" {
+			rest := args[4:]
+			mkStream := false
+			if len(rest) > 0 && strings.EqualFold(rest[0].Str, "MKSTREAM") {
+				mkStream = true
+				rest = rest[1:]
+			}
+			entriesRead, explicit, err := parseEntriesRead(rest)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			stream, err := ds.GetStream(key)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if stream == nil {
+				if !mkStream {
+					return resp.Value{}, fmt.Errorf("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+				}
+				if stream, err = ds.EnsureStream(key); err != nil {
+					return resp.Value{}, err
+				}
+			}
+			id, defaultRead, err := parseGroupID(stream, args[3].Str)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if !explicit {
+				entriesRead = defaultRead
+			}
+			if err := stream.CreateGroup(group, id, entriesRead); err != nil {
+				return resp.Value{}, err
+			}
+			ds.NotifyKeyEvent("xgroup-create", key)
+			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		}
+
+		stream, err := ds.GetStream(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{}, fmt.Errorf("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+		}
+
+		switch sub {
+		case "SETID":
+			entriesRead, explicit, err := parseEntriesRead(args[4:])
+			if err != nil {
+				return resp.Value{}, err
+			}
+			id, defaultRead, err := parseGroupID(stream, args[3].Str)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if !explicit {
+				entriesRead = defaultRead
+			}
+			if err := stream.SetGroupID(group, id, entriesRead); err != nil {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+			}
+			ds.NotifyKeyEvent("xgroup-setid", key)
+			return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+		case "DESTROY":
+			if !stream.DestroyGroup(group) {
+				return resp.Value{Type: resp.Integer, Int: 0}, nil
+			}
+			// Clients blocked in XREADGROUP on this group get a NOGROUP error
+			ds.NotifyKeyEvent("xgroup-destroy", key)
+			return resp.Value{Type: resp.Integer, Int: 1}, nil
+		case "CREATECONSUMER":
+			created, err := stream.CreateConsumer(group, args[3].Str)
+			if err != nil {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+			}
+			if !created {
+				return resp.Value{Type: resp.Integer, Int: 0}, nil
+			}
+			ds.NotifyKeyEvent("xgroup-createconsumer", key)
+			return resp.Value{Type: resp.Integer, Int: 1}, nil
+		default: // DELCONSUMER
+			pending, err := stream.DelConsumer(group, args[3].Str)
+			if err != nil {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+			}
+			ds.NotifyKeyEvent("xgroup-delconsumer", key)
+			return resp.Value{Type: resp.Integer, Int: int64(pending)}, nil
+		}
+	}
+}
+
+// XREADGROUP GROUP group consumer [COUNT n] [BLOCK ms] [NOACK] STREAMS key [key ...] id [id ...]
+// An id of ">" delivers entries never delivered to the group; any other id
+// returns the consumer's pending entries after it. The client only blocks
+// when every id is ">" and nothing new is available.
+func XReadGroupHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		opts, err := parseXReadOptions(args, true)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		n := len(opts.keys)
+		streams := make([]*store.Stream, n)
+		after := make([]store.StreamID, n)
+		newOnly := true
+		for i, key := range opts.keys {
+			stream, err := ds.GetStream(key)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if stream == nil || !stream.HasGroup(opts.group) {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", key, opts.group)
+			}
+			streams[i] = stream
+			if opts.ids[i] != ">" {
+				if after[i], err = parseReadStreamID(opts.ids[i]); err != nil {
+					return resp.Value{}, err
+				}
+				newOnly = false
+			}
+		}
+
+		var results []resp.Value
+		for i, stream := range streams {
+			isNew := opts.ids[i] == ">"
+			entries, err := stream.ReadGroup(opts.group, opts.consumer, isNew, after[i], opts.count, opts.noAck)
+			if errors.Is(err, store.ErrNoGroup) {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", opts.keys[i], opts.group)
+			}
+			if err != nil {
+				return resp.Value{}, err
+			}
+			// History reads always report the stream, even when empty
+			if len(entries) > 0 || !isNew {
+				results = append(results, streamReply(opts.keys[i], entries))
+			}
+		}
+
+		if len(results) > 0 {
+			return resp.Value{Type: resp.Array, Array: results}, nil
+		}
+		if !opts.block || !newOnly {
+			return nullArrayReply, nil
+		}
+		return resp.Value{}, &BlockedError{
+			Keys:         opts.keys,
+			Timeout:      opts.timeout,
+			TimeoutReply: nullArrayReply,
+		}
+	}
+}
+
+// rewriteXReadGroup drops BLOCK so that the AOF and replicas never wait, and
+// skips reads that delivered nothing
+func rewriteXReadGroup(args []string, result resp.Value) (string, []string, bool) {
+	if result.IsNull {
+		return "", nil, false
+	}
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if strings.EqualFold(args[i], "STREAMS") {
+			out = append(out, args[i:]...)
+			break
+		}
+		if strings.EqualFold(args[i], "BLOCK") && i+1 < len(args) {
+			i++
+			continue
+		}
+		out = append(out, args[i])
+	}
+	return "XREADGROUP", out, true
+}
+
+// XACK key group id [id ...]
+func XAckHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XACK' command")
+		}
+		ids, err := parseStreamIDs(args[2:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+		stream, err := ds.GetStream(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		acked, err := stream.Ack(args[1].Str, ids)
+		if err != nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(acked)}, nil
+	}
+}
+
+// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+func XPendingHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XPENDING' command")
+		}
+		key, group := args[0].Str, args[1].Str
+
+		extended := len(args) > 2
+		var minIdle time.Duration
+		var start, end store.StreamID
+		count := 0
+		consumer := ""
+		if extended {
+			rest := args[2:]
+			if strings.EqualFold(rest[0].Str, "IDLE") {
+				if len(rest) < 2 {
+					return resp.Value{}, fmt.Errorf("ERR syntax error")
+				}
+				ms, err := strconv.ParseInt(rest[1].Str, 10, 64)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+				}
+				minIdle = time.Duration(ms) * time.Millisecond
+				rest = rest[2:]
+			}
+			if len(rest) != 3 && len(rest) != 4 {
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+			var err error
+			if start, err = parseRangeID(rest[0].Str, true); err != nil {
+				return resp.Value{}, err
+			}
+			if end, err = parseRangeID(rest[1].Str, false); err != nil {
+				return resp.Value{}, err
+			}
+			if count, err = strconv.Atoi(rest[2].Str); err != nil {
+				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			if len(rest) == 4 {
+				consumer = rest[3].Str
+			}
+		}
+
+		stream, err := groupStream(ds, key, group)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		pending, err := stream.Pending(group)
+		if err != nil {
+			return resp.Value{}, noGroupError(key, group)
+		}
+
+		if !extended {
+			return pendingSummary(pending), nil
+		}
+
+		now := time.Now()
+		out := []resp.Value{}
+		for _, pe := range pending {
+			if count <= 0 || len(out) == count {
+				break
+			}
+			if pe.ID.Ms < start.Ms || (pe.ID.Ms == start.Ms && pe.ID.Seq < start.Seq) {
+				continue
+			}
+			if pe.ID.Ms > end.Ms || (pe.ID.Ms == end.Ms && pe.ID.Seq > end.Seq) {
+				break
+			}
+			idle := now.Sub(pe.DeliveryTime)
+			if (consumer != "" && pe.Consumer != consumer) || idle < minIdle {
+				continue
+			}
+			out = append(out, resp.Value{Type: resp.Array, Array: []resp.Value{
+				{Type: resp.BulkString, Str: pe.ID.String()},
+				{Type: resp.BulkString, Str: pe.Consumer},
+				{Type: resp.Integer, Int: idle.Milliseconds()},
+				{Type: resp.Integer, Int: int64(pe.DeliveryCount)},
+			}})
+		}
+		return resp.Value{Type: resp.Array, Array: out}, nil
+	}
+}
+
+// pendingSummary builds the XPENDING reply without a range: the number of
+// pending entries, the smallest and greatest IDs and a count per consumer
+func pendingSummary(pending []store.PendingEntry) resp.Value {
+	if len(pending) == 0 {
+		return resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.Integer, Int: 0},
+			nullBulkReply,
+			nullBulkReply,
+			nullArrayReply,
+		}}
+	}
+	perConsumer := make(map[string]int)
+	var names []string
+	for _, pe := range pending {
+		if perConsumer[pe.Consumer] == 0 {
+			names = append(names, pe.Consumer)
+		}
+		perConsumer[pe.Consumer]++
+	}
+	sort.Strings(names)
+	consumers := make([]resp.Value, len(names))
+	for i, name := range names {
+		consumers[i] = resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.BulkString, Str: name},
+			{Type: resp.BulkString, Str: strconv.Itoa(perConsumer[name])},
+		}}
+	}
+	return resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.Integer, Int: int64(len(pending))},
+		{Type: resp.BulkString, Str: pending[0].ID.String()},
+		{Type: resp.BulkString, Str: pending[len(pending)-1].ID.String()},
+		{Type: resp.Array, Array: consumers},
+	}}
+}
+
+// splitClaimArgs splits the arguments following XCLAIM's min-idle-time into
+// the IDs and the options that follow them
+func splitClaimArgs(args []string) (ids []string, options []string) {
+	for i, arg := range args {
+		if _, err := parseReadStreamID(arg); err != nil {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// parseMinIdle parses the min-idle-time argument of XCLAIM and XAUTOCLAIM
+func parseMinIdle(arg, command string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ERR Invalid min-idle-time argument for %s", command)
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME ms-unix-time]
+// [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID id]
+func XClaimHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 5 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XCLAIM' command")
+		}
+		key, group, consumer := args[0].Str, args[1].Str, args[2].Str
+		minIdle, err := parseMinIdle(args[3].Str, "XCLAIM")
+		if err != nil {
+			return resp.Value{}, err
+		}
+		rest := make([]string, len(args)-4)
+		for i, a := range args[4:] {
+			rest[i] = a.Str
+		}
+		idArgs, options := splitClaimArgs(rest)
+		if len(idArgs) == 0 {
+			return resp.Value{}, fmt.Errorf("ERR Invalid stream ID specified as stream command argument")
+		}
+		ids := make([]store.StreamID, len(idArgs))
+		for i, a := range idArgs {
+			ids[i], _ = parseReadStreamID(a)
+		}
+
+		opts := store.ClaimOptions{MinIdle: minIdle, RetryCount: -1}
+		for i := 0; i < len(options); i++ {
+			opt := strings.ToUpper(options[i])
+			switch opt {
+			case "FORCE":
+				opts.Force = true
+				continue
+			case "JUSTID":
+				opts.JustID = true
+				continue
+			}
+			if i+1 >= len(options) {
+				return resp.Value{}, fmt.Errorf("ERR Unrecognized XCLAIM option '%s'", options[i])
+			}
+			value := options[i+1]
+			i++
+			switch opt {
+			case "IDLE", "TIME":
+				ms, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR Invalid %s option argument for XCLAIM", opt)
+				}
+				if opt == "IDLE" {
+					opts.DeliveryTime = time.Now().Add(-time.Duration(ms) * time.Millisecond)
+				} else {
+					opts.DeliveryTime = time.UnixMilli(ms)
+				}
+			case "RETRYCOUNT":
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || n < 0 {
+					return resp.Value{}, fmt.Errorf("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+				}
+				opts.RetryCount = n
+			case "LASTID":
+				id, err := parseReadStreamID(value)
+				if err != nil {
+					return resp.Value{}, err
+				}
+				opts.LastID = &id
+			default:
+				return resp.Value{}, fmt.Errorf("ERR Unrecognized XCLAIM option '%s'", options[i-1])
+			}
+		}
+
+		stream, err := groupStream(ds, key, group)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		claimed, _, err := stream.Claim(group, consumer, ids, opts)
+		if err != nil {
+			return resp.Value{}, noGroupError(key, group)
+		}
+		if opts.JustID {
+			return idsToResp(entryIDs(claimed)), nil
+		}
+		return entriesToResp(claimed), nil
+	}
+}
+
+// rewriteXClaim propagates the requested IDs XCLAIM handled: the entries it
+// claimed and those it dropped from the PEL because they were deleted from
+// the stream. The min-idle-time is zero and IDLE is made an absolute TIME, so
+// that replaying it does not depend on idle times.
+func rewriteXClaim(ds DataStore) func(args []string, result resp.Value) (string, []string, bool) {
+	return func(args []string, result resp.Value) (string, []string, bool) {
+		claimed := make(map[string]bool, len(result.Array))
+		for _, v := range result.Array {
+			if v.Type == resp.Array {
+				claimed[v.Array[0].Str] = true
+			} else {
+				claimed[v.Str] = true
+			}
+		}
+		idArgs, options := splitClaimArgs(args[4:])
+		stream, _ := ds.GetStream(args[0])
+		out := []string{args[0], args[1], args[2], "0"}
+		for _, arg := range idArgs {
+			id, _ := parseReadStreamID(arg)
+			handled := claimed[id.String()]
+			if !handled && stream != nil {
+				handled = !stream.IsPending(args[1], id) && len(stream.XRange(id, id, 1)) == 0
+			}
+			if handled {
+				out = append(out, id.String())
+			}
+		}
+		if len(out) == 4 {
+			return "", nil, false
+		}
+		for i := 0; i < len(options); i++ {
+			if strings.EqualFold(options[i], "IDLE") && i+1 < len(options) {
+				idle, _ := strconv.ParseInt(options[i+1], 10, 64)
+				out = append(out, "TIME", strconv.FormatInt(time.Now().UnixMilli()-idle, 10))
+				i++
+				continue
+			}
+			out = append(out, options[i])
+		}
+		return "XCLAIM", out, true
+	}
+}
+
+// XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+func XAutoClaimHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) < 5 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XAUTOCLAIM' command")
+		}
+		key, group, consumer := args[0].Str, args[1].Str, args[2].Str
+		minIdle, err := parseMinIdle(args[3].Str, "XAUTOCLAIM")
+		if err != nil {
+			return resp.Value{}, err
+		}
+		start := store.StreamID{}
+		if args[4].Str != "-" {
+			if start, err = parseReadStreamID(args[4].Str); err != nil {
+				return resp.Value{}, err
+			}
+		}
+		count := 100
+		justID := false
+		for i := 5; i < len(args); i++ {
+			switch strings.ToUpper(args[i].Str) {
+			case "COUNT":
+				if i+1 >= len(args) {
+					return resp.Value{}, fmt.Errorf("ERR syntax error")
+				}
+				n, err := strconv.Atoi(args[i+1].Str)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+				}
+				if n < 1 {
+					return resp.Value{}, fmt.Errorf("ERR COUNT must be > 0")
+				}
+				count = n
+				i++
+			case "JUSTID":
+				justID = true
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+		}
+
+		stream, err := groupStream(ds, key, group)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		opts := store.ClaimOptions{MinIdle: minIdle, RetryCount: -1, JustID: justID}
+		next, claimed, deleted, err := stream.AutoClaim(group, consumer, start, count, opts)
+		if err != nil {
+			return resp.Value{}, noGroupError(key, group)
+		}
+		var entries resp.Value
+		if justID {
+			entries = idsToResp(entryIDs(claimed))
+		} else {
+			entries = entriesToResp(claimed)
+		}
+		return resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.BulkString, Str: next.String()},
+			entries,
+			idsToResp(deleted),
+		}}, nil
+	}
+}
+
+// rewriteXAutoClaim propagates XAUTOCLAIM as an XCLAIM of the entries it
+// claimed and of the deleted entries it dropped from the PEL
+func rewriteXAutoClaim(args []string, result resp.Value) (string, []string, bool) {
+	if len(result.Array) != 3 {
+		return "", nil, false
+	}
+	out := []string{args[0], args[1], args[2], "0"}
+	justID := false
+	for _, a := range args[5:] {
+		if strings.EqualFold(a, "JUSTID") {
+			justID = true
+		}
+	}
+	for _, v := range result.Array[1].Array {
+		if v.Type == resp.Array {
+			out = append(out, v.Array[0].Str)
+		} else {
+			out = append(out, v.Str)
+		}
+	}
+	for _, v := range result.Array[2].Array {
+		out = append(out, v.Str)
+	}
+	if len(out) == 4 {
+		return "", nil, false
+	}
+	if justID {
+		out = append(out, "JUSTID")
+	}
+	return "XCLAIM", out, true
+}
+
+func parseStreamIDs(args []resp.Value) ([]store.StreamID, error) {
+	ids := make([]store.StreamID, len(args))
+	for i, a := range args {
+		id, err := parseReadStreamID(a.Str)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func entryIDs(entries []store.StreamEntry) []store.StreamID {
+	ids := make([]store.StreamID, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func idsToResp(ids []store.StreamID) resp.Value {
+	arr := make([]resp.Value, len(ids))
+	for i, id := range ids {
+		arr[i] = resp.Value{Type: resp.BulkString, Str: id.String()}
+	}
+	return resp.Value{Type: resp.Array, Array: arr}
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedGroupStream(t *testing.T, ds DataStore, key string, ids ...string) {
+	t.Helper()
+	xadd := XAddHandler(ds)
+	for _, id := range ids {
+		_, err := xadd(bulkArgs(key, id, "f", id))
+		require.NoError(t, err)
+	}
+}
+
+func TestXGroupHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	xgroup := XGroupHandler(ds)
+
+	_, err := xgroup(bulkArgs("CREATE", "s", "g", "$"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MKSTREAM")
+
+	res, err := xgroup(bulkArgs("CREATE", "s", "g", "$", "MKSTREAM"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", res.Str)
+
+	_, err = xgroup(bulkArgs("CREATE", "s", "g", "0"))
+	require.EqualError(t, err, "BUSYGROUP Consumer Group name already exists")
+
+	res, err = xgroup(bulkArgs("CREATECONSUMER", "s", "g", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Int)
+
+	res, err = xgroup(bulkArgs("SETID", "s", "g", "0", "ENTRIESREAD", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", res.Str)
+
+	_, err = xgroup(bulkArgs("SETID", "s", "nope", "0"))
+	require.EqualError(t, err, "NOGROUP No such consumer group 'nope' for key name 's'")
+
+	res, err = xgroup(bulkArgs("DELCONSUMER", "s", "g", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), res.Int)
+
+	res, err = xgroup(bulkArgs("DESTROY", "s", "g"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Int)
+
+	_, err = xgroup(bulkArgs("FOO", "s", "g"))
+	require.EqualError(t, err, "ERR unknown subcommand 'FOO' for 'XGROUP' command")
+}
+
+func TestXReadGroupAckPending(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	seedGroupStream(t, ds, "s", "1-0", "2-0", "3-0")
+	_, err := XGroupHandler(ds)(bulkArgs("CREATE", "s", "g", "0"))
+	require.NoError(t, err)
+	xreadgroup := XReadGroupHandler(ds)
+
+	_, err = xreadgroup(bulkArgs("GROUP", "nope", "c", "STREAMS", "s", ">"))
+	require.EqualError(t, err, "NOGROUP No such key 's' or consumer group 'nope' in XREADGROUP with GROUP option")
+
+	res, err := xreadgroup(bulkArgs("GROUP", "g", "alice", "COUNT", "2", "STREAMS", "s", ">"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	entries := res.Array[0].Array[1].Array
+	require.Len(t, entries, 2)
+	assert.Equal(t, "1-0", entries[0].Array[0].Str)
+
+	res, err = xreadgroup(bulkArgs("GROUP", "g", "bob", "STREAMS", "s", ">"))
+	require.NoError(t, err)
+	assert.Equal(t, "3-0", res.Array[0].Array[1].Array[0].Array[0].Str)
+
+	// Nothing new: null reply, or a BlockedError with BLOCK
+	res, err = xreadgroup(bulkArgs("GROUP", "g", "bob", "STREAMS", "s", ">"))
+	require.NoError(t, err)
+	assert.True(t, res.IsNull)
+	_, err = xreadgroup(bulkArgs("GROUP", "g", "bob", "BLOCK", "100", "STREAMS", "s", ">"))
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, 100*time.Millisecond, blocked.Timeout)
+
+	// History reads report the stream even when there is nothing pending
+	res, err = xreadgroup(bulkArgs("GROUP", "g", "carol", "BLOCK", "100", "STREAMS", "s", "0"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Empty(t, res.Array[0].Array[1].Array)
+
+	res, err = XAckHandler(ds)(bulkArgs("s", "g", "1-0", "5-0"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res.Int)
+
+	xpending := XPendingHandler(ds)
+	res, err = xpending(bulkArgs("s", "g"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.Array[0].Int)
+	assert.Equal(t, "2-0", res.Array[1].Str)
+	assert.Equal(t, "3-0", res.Array[2].Str)
+	assert.Equal(t, []resp.Value{
+		{Type: resp.Array, Array: bulkArgs("alice", "1")},
+		{Type: resp.Array, Array: bulkArgs("bob", "1")},
+	}, res.Array[3].Array)
+
+	res, err = xpending(bulkArgs("s", "g", "-", "+", "10", "bob"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "3-0", res.Array[0].Array[0].Str)
+	assert.Equal(t, "bob", res.Array[0].Array[1].Str)
+	assert.Equal(t, int64(1), res.Array[0].Array[3].Int)
+
+	res, err = xpending(bulkArgs("s", "g", "IDLE", "60000", "-", "+", "10"))
+	require.NoError(t, err)
+	assert.Empty(t, res.Array)
+
+	_, err = xpending(bulkArgs("s", "nope"))
+	require.EqualError(t, err, "NOGROUP No such key 's' or consumer group 'nope'")
+
+	name, args, ok := rewriteXReadGroup([]string{"GROUP", "g", "c", "BLOCK", "0", "COUNT", "1", "STREAMS", "s", ">"}, res)
+	require.True(t, ok)
+	assert.Equal(t, "XREADGROUP", name)
+	assert.Equal(t, []string{"GROUP", "g", "c", "COUNT", "1", "STREAMS", "s", ">"}, args)
+	_, _, ok = rewriteXReadGroup(nil, nullArrayReply)
+	assert.False(t, ok)
+}
+
+func TestXClaimAndXAutoClaim(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	seedGroupStream(t, ds, "s", "1-0", "2-0", "3-0")
+	_, err := XGroupHandler(ds)(bulkArgs("CREATE", "s", "g", "0"))
+	require.NoError(t, err)
+	_, err = XReadGroupHandler(ds)(bulkArgs("GROUP", "g", "alice", "STREAMS", "s", ">"))
+	require.NoError(t, err)
+	xclaim := XClaimHandler(ds)
+	rewrite := rewriteXClaim(ds)
+
+	args := []string{"s", "g", "bob", "3600000", "1-0", "2-0", "JUSTID"}
+	res, err := xclaim(bulkArgs(args...))
+	require.NoError(t, err)
+	assert.Empty(t, res.Array)
+	_, _, ok := rewrite(args, res)
+	assert.False(t, ok)
+
+	args = []string{"s", "g", "bob", "0", "1-0", "9-0", "RETRYCOUNT", "5"}
+	res, err = xclaim(bulkArgs(args...))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "1-0", res.Array[0].Array[0].Str)
+	name, newArgs, ok := rewrite(args, res)
+	require.True(t, ok)
+	assert.Equal(t, "XCLAIM", name)
+	assert.Equal(t, []string{"s", "g", "bob", "0", "1-0", "9-0", "RETRYCOUNT", "5"}, newArgs)
+
+	_, err = xclaim(bulkArgs("s", "g", "bob", "0", "1-0", "BOGUS"))
+	require.EqualError(t, err, "ERR Unrecognized XCLAIM option 'BOGUS'")
+
+	_, err = XDelHandler(ds)(bulkArgs("s", "2-0"))
+	require.NoError(t, err)
+	args = []string{"s", "g", "carol", "0", "0", "COUNT", "10"}
+	res, err = XAutoClaimHandler(ds)(bulkArgs(args...))
+	require.NoError(t, err)
+	assert.Equal(t, "0-0", res.Array[0].Str)
+	require.Len(t, res.Array[1].Array, 2)
+	assert.Equal(t, bulkArgs("2-0"), res.Array[2].Array)
+	name, newArgs, ok = rewriteXAutoClaim(args, res)
+	require.True(t, ok)
+	assert.Equal(t, "XCLAIM", name)
+	assert.Equal(t, []string{"s", "g", "carol", "0", "1-0", "3-0", "2-0"}, newArgs)
+
+	_, err = XAutoClaimHandler(ds)(bulkArgs("s", "g", "c", "0", "0", "COUNT", "0"))
+	require.EqualError(t, err, "ERR COUNT must be > 0")
+
+	// An entry deleted from the stream is dropped from the PEL and propagated
+	// even though it is not in the reply, and IDLE becomes an absolute TIME
+	_, err = XDelHandler(ds)(bulkArgs("s", "3-0"))
+	require.NoError(t, err)
+	args = []string{"s", "g", "dave", "3600000", "1-0", "3-0", "IDLE", "1000", "JUSTID"}
+	res, err = xclaim(bulkArgs(args...))
+	require.NoError(t, err)
+	assert.Empty(t, res.Array)
+	name, newArgs, ok = rewrite(args, res)
+	require.True(t, ok)
+	assert.Equal(t, "XCLAIM", name)
+	require.Len(t, newArgs, 8)
+	assert.Equal(t, []string{"s", "g", "dave", "0", "3-0", "TIME"}, newArgs[:6])
+	assert.Equal(t, "JUSTID", newArgs[7])
+	at, err := strconv.ParseInt(newArgs[6], 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().UnixMilli()-1000, at, 1000)
+}
+
+func TestXAddRewriteUsesGeneratedID(t *testing.T) {
+	name, args, ok := rewriteXAdd([]string{"s", "*", "f", "v"}, resp.Value{Type: resp.BulkString, Str: "5-1"})
+	require.True(t, ok)
+	assert.Equal(t, "XADD", name)
+	assert.Equal(t, []string{"s", "5-1", "f", "v"}, args)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+)
+
+// streamNodeMaxEntries is the number of entries Redis packs into one radix
+// tree node; XINFO reports the equivalent node counts for compatibility
+const streamNodeMaxEntries = 100
+
+// XINFO STREAM key [FULL [COUNT n]]
+// XINFO GROUPS key
+// XINFO CONSUMERS key group
+func XInfoHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) == 0 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XINFO' command")
+		}
+		sub := strings.ToUpper(args[0].Str)
+		var arityOK bool
+		switch sub {
+		case "STREAM":
+			arityOK = len(args) >= 2
+		case "GROUPS":
+			arityOK = len(args) == 2
+		case "CONSUMERS":
+			arityOK = len(args) == 3
+		default:
+			return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s' for 'XINFO' command", args[0].Str)
+		}
+		if !arityOK {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XINFO|%s' command", strings.ToLower(sub))
+		}
+		key := args[1].Str
+		stream, err := ds.GetStream(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{}, fmt.Errorf("ERR no such key")
+		}
+
+		switch sub {
+		case "STREAM":
+			full, count, err := parseXInfoStreamOptions(args[2:])
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if full {
+				return xinfoStreamFull(stream, count), nil
+			}
+			return xinfoStream(stream), nil
+		case "GROUPS":
+			groups := stream.Groups()
+			out := make([]resp.Value, len(groups))
+			for i, g := range groups {
+				out[i] = infoMap(
+					"name", bulkReply(g.Name),
+					"consumers", intReply(int64(len(g.Consumers))),
+					"pending", intReply(int64(len(g.Pending))),
+					"last-delivered-id", bulkReply(g.LastID.String()),
+					"entries-read", optionalIntReply(g.EntriesRead),
+					"lag", optionalIntReply(g.Lag),
+				)
+			}
+			return resp.Value{Type: resp.Array, Array: out}, nil
+		default: // CONSUMERS
+			group := args[2].Str
+			g, ok := stream.Group(group)
+			if !ok {
+				return resp.Value{}, fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+			}
+			now := time.Now()
+			out := make([]resp.Value, len(g.Consumers))
+			for i, c := range g.Consumers {
+				out[i] = infoMap(
+					"name", bulkReply(c.Name),
+					"pending", intReply(int64(c.Pending)),
+					"idle", intReply(now.Sub(c.SeenTime).Milliseconds()),
+					"inactive", intReply(sinceMillis(now, c.ActiveTime)),
+				)
+			}
+			return resp.Value{Type: resp.Array, Array: out}, nil
+		}
+	}
+}
+
+// parseXInfoStreamOptions parses [FULL [COUNT n]]; COUNT defaults to 10 and
+// 0 means no limit
+func parseXInfoStreamOptions(args []resp.Value) (bool, int, error) {
+	if len(args) == 0 {
+		return false, 0, nil
+	}
+	if !strings.EqualFold(args[0].Str, "FULL") {
+		return false, 0, fmt.Errorf("ERR syntax error")
+	}
+	count := 10
+	switch len(args) {
+	case 1:
+	case 3:
+		if !strings.EqualFold(args[1].Str, "COUNT") {
+			return false, 0, fmt.Errorf("ERR syntax error")
+		}
+		n, err := strconv.Atoi(args[2].Str)
+		if err != nil {
+			return false, 0, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			n = 0
+		}
+		count = n
+	default:
+		return false, 0, fmt.Errorf("ERR syntax error")
+	}
+	return true, count, nil
+}
+
+// streamHeader returns the XINFO STREAM fields shared by the full and the
+// summary form
+func streamHeader(meta store.StreamMeta) []resp.Value {
+	keys := (meta.Length + streamNodeMaxEntries - 1) / streamNodeMaxEntries
+	return infoMap(
+		"length", intReply(int64(meta.Length)),
+		"radix-tree-keys", intReply(int64(keys)),
+		"radix-tree-nodes", intReply(int64(keys+1)),
+		"last-generated-id", bulkReply(meta.LastID.String()),
+		"max-deleted-entry-id", bulkReply(meta.MaxDeletedID.String()),
+		"entries-added", intReply(int64(meta.EntriesAdded)),
+		"recorded-first-entry-id", bulkReply(meta.FirstID.String()),
+	).Array
+}
+
+func xinfoStream(stream *store.Stream) resp.Value {
+	fields := streamHeader(stream.Meta())
+	first, last := nullBulkReply, nullBulkReply
+	if entries := stream.XRange(store.StreamID{}, store.StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}, 1); len(entries) > 0 {
+		first = entriesToResp(entries).Array[0]
+	}
+	if e, ok := stream.LastEntry(); ok {
+		last = entriesToResp([]store.StreamEntry{e}).Array[0]
+	}
+	fields = append(fields,
+		bulkReply("groups"), intReply(int64(len(stream.Groups()))),
+		bulkReply("first-entry"), first,
+		bulkReply("last-entry"), last,
+	)
+	return resp.Value{Type: resp.Map, Array: fields}
+}
+
+func xinfoStreamFull(stream *store.Stream, count int) resp.Value {
+	fields := streamHeader(stream.Meta())
+	entries := stream.XRange(store.StreamID{}, store.StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}, count)
+	groups := stream.Groups()
+	groupReplies := make([]resp.Value, len(groups))
+	for i, g := range groups {
+		pel := g.Pending
+		if count > 0 && len(pel) > count {
+			pel = pel[:count]
+		}
+		pending := make([]resp.Value, len(pel))
+		for j, pe := range pel {
+			pending[j] = resp.Value{Type: resp.Array, Array: []resp.Value{
+				bulkReply(pe.ID.String()),
+				bulkReply(pe.Consumer),
+				intReply(pe.DeliveryTime.UnixMilli()),
+				intReply(int64(pe.DeliveryCount)),
+			}}
+		}
+		consumers := make([]resp.Value, len(g.Consumers))
+		for j, c := range g.Consumers {
+			own := []resp.Value{}
+			for _, pe := range g.Pending {
+				if pe.Consumer != c.Name {
+					continue
+				}
+				if count > 0 && len(own) == count {
+					break
+				}
+				own = append(own, resp.Value{Type: resp.Array, Array: []resp.Value{
+					bulkReply(pe.ID.String()),
+					intReply(pe.DeliveryTime.UnixMilli()),
+					intReply(int64(pe.DeliveryCount)),
+				}})
+			}
+			active := int64(-1)
+			if !c.ActiveTime.IsZero() {
+				active = c.ActiveTime.UnixMilli()
+			}
+			consumers[j] = infoMap(
+				"name", bulkReply(c.Name),
+				"seen-time", intReply(c.SeenTime.UnixMilli()),
+				"active-time", intReply(active),
+				"pel-count", intReply(int64(c.Pending)),
+				"pending", resp.Value{Type: resp.Array, Array: own},
+			)
+		}
+		groupReplies[i] = infoMap(
+			"name", bulkReply(g.Name),
+			"last-delivered-id", bulkReply(g.LastID.String()),
+			"entries-read", optionalIntReply(g.EntriesRead),
+			"lag", optionalIntReply(g.Lag),
+			"pel-count", intReply(int64(len(g.Pending))),
+			"pending", resp.Value{Type: resp.Array, Array: pending},
+			"consumers", resp.Value{Type: resp.Array, Array: consumers},
+		)
+	}
+	fields = append(fields,
+		bulkReply("entries"), entriesToResp(entries),
+		bulkReply("groups"), resp.Value{Type: resp.Array, Array: groupReplies},
+	)
+	return resp.Value{Type: resp.Map, Array: fields}
+}
+
+// infoMap builds a map reply from alternating names and values; RESP2
+// clients get it as a flat [name, value, ...] array
+func infoMap(pairs ...any) resp.Value {
+	out := make([]resp.Value, 0, len(pairs))
+	for i := 0; i < len(pairs); i += 2 {
+		out = append(out, bulkReply(pairs[i].(string)), pairs[i+1].(resp.Value))
+	}
+	return resp.Value{Type: resp.Map, Array: out}
+}
+
+func bulkReply(s string) resp.Value {
+	return resp.Value{Type: resp.BulkString, Str: s}
+}
+
+func intReply(n int64) resp.Value {
+	return resp.Value{Type: resp.Integer, Int: n}
+}
+
+// optionalIntReply returns a null reply for the -1 "unknown" marker
+func optionalIntReply(n int64) resp.Value {
+	if n < 0 {
+		return nullBulkReply
+	}
+	return intReply(n)
+}
+
+// sinceMillis returns the milliseconds elapsed since t, or -1 if t is zero
+func sinceMillis(now, t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return now.Sub(t).Milliseconds()
+}
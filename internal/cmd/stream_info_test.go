@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"testing"
+
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// infoField returns the value following name in a flat XINFO reply
+func infoField(t *testing.T, v resp.Value, name string) resp.Value {
+	t.Helper()
+	for i := 0; i+1 < len(v.Array); i += 2 {
+		if v.Array[i].Str == name {
+			return v.Array[i+1]
+		}
+	}
+	t.Fatalf("field %q not found", name)
+	return resp.Value{}
+}
+
+func TestXInfoHandler(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	seedGroupStream(t, ds, "s", "1-0", "2-0", "3-0")
+	_, err := XGroupHandler(ds)(bulkArgs("CREATE", "s", "g", "0"))
+	require.NoError(t, err)
+	_, err = XReadGroupHandler(ds)(bulkArgs("GROUP", "g", "alice", "COUNT", "1", "STREAMS", "s", ">"))
+	require.NoError(t, err)
+	_, err = XDelHandler(ds)(bulkArgs("s", "3-0"))
+	require.NoError(t, err)
+	xinfo := XInfoHandler(ds)
+
+	res, err := xinfo(bulkArgs("STREAM", "s"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), infoField(t, res, "length").Int)
+	assert.Equal(t, int64(1), infoField(t, res, "radix-tree-keys").Int)
+	assert.Equal(t, int64(2), infoField(t, res, "radix-tree-nodes").Int)
+	assert.Equal(t, "3-0", infoField(t, res, "last-generated-id").Str)
+	assert.Equal(t, "3-0", infoField(t, res, "max-deleted-entry-id").Str)
+	assert.Equal(t, int64(3), infoField(t, res, "entries-added").Int)
+	assert.Equal(t, "1-0", infoField(t, res, "recorded-first-entry-id").Str)
+	assert.Equal(t, int64(1), infoField(t, res, "groups").Int)
+	assert.Equal(t, "1-0", infoField(t, res, "first-entry").Array[0].Str)
+	assert.Equal(t, "2-0", infoField(t, res, "last-entry").Array[0].Str)
+
+	res, err = xinfo(bulkArgs("STREAM", "s", "FULL", "COUNT", "1"))
+	require.NoError(t, err)
+	assert.Len(t, infoField(t, res, "entries").Array, 1)
+	groups := infoField(t, res, "groups").Array
+	require.Len(t, groups, 1)
+	assert.Equal(t, int64(1), infoField(t, groups[0], "pel-count").Int)
+	consumers := infoField(t, groups[0], "consumers").Array
+	require.Len(t, consumers, 1)
+	assert.Equal(t, "alice", infoField(t, consumers[0], "name").Str)
+	assert.Len(t, infoField(t, consumers[0], "pending").Array, 1)
+
+	res, err = xinfo(bulkArgs("GROUPS", "s"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "g", infoField(t, res.Array[0], "name").Str)
+	assert.Equal(t, int64(1), infoField(t, res.Array[0], "consumers").Int)
+	assert.Equal(t, "1-0", infoField(t, res.Array[0], "last-delivered-id").Str)
+	assert.Equal(t, int64(1), infoField(t, res.Array[0], "entries-read").Int)
+	// The deleted entry after the last delivered ID makes the lag unknown
+	assert.True(t, infoField(t, res.Array[0], "lag").IsNull)
+
+	_, err = XGroupHandler(ds)(bulkArgs("CREATECONSUMER", "s", "g", "bob"))
+	require.NoError(t, err)
+	res, err = xinfo(bulkArgs("CONSUMERS", "s", "g"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 2)
+	assert.Equal(t, int64(1), infoField(t, res.Array[0], "pending").Int)
+	assert.Equal(t, int64(-1), infoField(t, res.Array[1], "inactive").Int)
+
+	_, err = xinfo(bulkArgs("CONSUMERS", "s", "nope"))
+	require.EqualError(t, err, "NOGROUP No such consumer group 'nope' for key name 's'")
+	_, err = xinfo(bulkArgs("STREAM", "missing"))
+	require.EqualError(t, err, "ERR no such key")
+	_, err = xinfo(bulkArgs("FOO", "s"))
+	require.EqualError(t, err, "ERR unknown subcommand 'FOO' for 'XINFO' command")
+}
@@ -5,63 +5,229 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"gridhouse/internal/resp"
 	"gridhouse/internal/store"
 )
 
-// XAddHandler handles XADD key id field value [field value ...]
-// Minimal options: supports id of "*" or explicit "ms-seq".
+// XAddHandler handles XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold
+// [LIMIT count]] *|id field value [field value ...]
+// The id may be "*", an explicit "ms-seq" or "ms-*" to auto-generate only the
+// sequence number.
 func XAddHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		// need at least: key, id, field, value
 		if len(args) < 4 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XADD' command")
 		}
 		key := args[0].Str
-		idStr := strings.ToLower(args[1].Str)
+		opts, err := parseXAddOptions(args)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		idStr := strings.ToLower(args[opts.idAt].Str)
 
 		// parse field-value pairs
-		if (len(args)-2)%2 != 0 {
+		rest := args[opts.idAt+1:]
+		if len(rest) == 0 || len(rest)%2 != 0 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XADD' command")
 		}
-
-		fields := make(map[string]string, (len(args)-2)/2)
-		for i := 2; i < len(args); i += 2 {
-			field := args[i].Str
-			value := args[i+1].Str
-			fields[field] = value
+		fields := make(map[string]string, len(rest)/2)
+		for i := 0; i < len(rest); i += 2 {
+			fields[rest[i].Str] = rest[i+1].Str
 		}
 
-		stream := ds.GetOrCreateStream(key)
-
 		var provided *store.StreamID
-		if idStr == "*" {
-			provided = nil
+		autoSeq := false
+		if idStr != "*" {
+			if ms, ok := strings.CutSuffix(idStr, "-*"); ok {
+				n, err := strconv.ParseUint(ms, 10, 64)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR Invalid stream ID specified as stream command argument")
+				}
+				provided = &store.StreamID{Ms: n}
+				autoSeq = true
+			} else {
+				id, err := parseReadStreamID(idStr)
+				if err != nil {
+					return resp.Value{}, err
+				}
+				provided = &id
+			}
+			// 0-0 is the only ID an empty stream rejects, so refusing it here
+			// keeps a failed XADD from creating the key
+			if !autoSeq && *provided == (store.StreamID{}) {
+				return resp.Value{}, fmt.Errorf("ERR The ID specified in XADD must be greater than 0-0")
+			}
+		}
+
+		var stream *store.Stream
+		if opts.noMkStream {
+			stream, err = ds.GetStream(key)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			if stream == nil {
+				return nullBulkReply, nil
+			}
 		} else {
-			id, err := parseExactStreamID(idStr)
+			stream, err = ds.EnsureStream(key)
 			if err != nil {
 				return resp.Value{}, err
 			}
-			provided = &id
 		}
 
-		id, err := stream.XAdd(provided, fields)
+		var id store.StreamID
+		if autoSeq {
+			id, err = stream.XAddAutoSeq(provided.Ms, fields)
+		} else {
+			id, err = stream.XAdd(provided, fields)
+		}
 		if err != nil {
 			return resp.Value{}, err
 		}
+		ds.NotifyKeyEvent("xadd", key)
+		if opts.trim != nil && opts.trim.apply(stream) > 0 {
+			ds.NotifyKeyEvent("xtrim", key)
+		}
 
 		return resp.Value{Type: resp.BulkString, Str: id.String()}, nil
 	}
 }
 
+// xaddOptions are the parsed options of XADD
+type xaddOptions struct {
+	noMkStream bool
+	trim       *streamTrim
+	idAt       int // index of the entry ID in the arguments
+}
+
+// parseXAddOptions parses the options between the key and the entry ID
+func parseXAddOptions(args []resp.Value) (*xaddOptions, error) {
+	opts := &xaddOptions{}
+	i := 1
+	for i < len(args) {
+		switch strings.ToUpper(args[i].Str) {
+		case "NOMKSTREAM":
+			opts.noMkStream = true
+			i++
+		case "MAXLEN", "MINID":
+			trim, next, err := parseStreamTrim(args, i)
+			if err != nil {
+				return nil, err
+			}
+			opts.trim = trim
+			i = next
+		default:
+			opts.idAt = i
+			return opts, nil
+		}
+	}
+	return nil, fmt.Errorf("ERR wrong number of arguments for 'XADD' command")
+}
+
+// streamTrim is a MAXLEN or MINID trimming strategy of XADD and XTRIM
+type streamTrim struct {
+	minID  bool
+	maxLen int
+	min    store.StreamID
+	limit  int // 0 means unlimited
+}
+
+// defaultTrimLimit caps the entries removed by an approximate trim without
+// an explicit LIMIT
+const defaultTrimLimit = 10000
+
+// parseStreamTrim parses MAXLEN|MINID [=|~] threshold [LIMIT count] starting
+// at args[i] and returns the index following it
+func parseStreamTrim(args []resp.Value, i int) (*streamTrim, int, error) {
+	trim := &streamTrim{minID: strings.EqualFold(args[i].Str, "MINID")}
+	i++
+	approx := false
+	if i < len(args) && (args[i].Str == "~" || args[i].Str == "=") {
+		approx = args[i].Str == "~"
+		i++
+	}
+	if i >= len(args) {
+		return nil, 0, fmt.Errorf("ERR syntax error")
+	}
+	if trim.minID {
+		id, err := parseReadStreamID(args[i].Str)
+		if err != nil {
+			return nil, 0, err
+		}
+		trim.min = id
+	} else {
+		n, err := strconv.Atoi(args[i].Str)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			return nil, 0, fmt.Errorf("ERR The MAXLEN argument must be >= 0.")
+		}
+		trim.maxLen = n
+	}
+	i++
+	if approx {
+		trim.limit = defaultTrimLimit
+	}
+	if i+1 < len(args) && strings.EqualFold(args[i].Str, "LIMIT") {
+		n, err := strconv.Atoi(args[i+1].Str)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			return nil, 0, fmt.Errorf("ERR The LIMIT argument must be >= 0.")
+		}
+		if !approx {
+			return nil, 0, fmt.Errorf("ERR syntax error, LIMIT cannot be used without the special ~ option")
+		}
+		trim.limit = n
+		i += 2
+	}
+	return trim, i, nil
+}
+
+// apply trims the stream and returns the number of removed entries
+func (t *streamTrim) apply(stream *store.Stream) int {
+	if t.minID {
+		return stream.TrimMinID(t.min, t.limit)
+	}
+	return stream.TrimMaxLen(t.maxLen, t.limit)
+}
+
+// rewriteXAdd propagates the generated ID instead of "*" or "ms-*" so that
+// the AOF and replicas store the same entry
+func rewriteXAdd(args []string, result resp.Value) (string, []string, bool) {
+	if result.IsNull {
+		return "", nil, false
+	}
+	values := make([]resp.Value, len(args))
+	for i, a := range args {
+		values[i] = resp.Value{Type: resp.BulkString, Str: a}
+	}
+	opts, err := parseXAddOptions(values)
+	if err != nil {
+		return "", nil, false
+	}
+	out := append([]string{}, args...)
+	out[opts.idAt] = result.Str
+	return "XADD", out, true
+}
+
 // XLEN key
 func XLenHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
 		if len(args) != 1 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XLEN' command")
 		}
-		stream := ds.GetOrCreateStream(args[0].Str)
+		stream, err := ds.GetStream(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		return resp.Value{Type: resp.Integer, Int: int64(stream.XLen())}, nil
 	}
 }
@@ -69,38 +235,62 @@ func XLenHandler(ds DataStore) Handler {
 // XRANGE key start end [COUNT n]
 func XRangeHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) < 3 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XRANGE' command")
-		}
-		key := args[0].Str
-		startStr := strings.ToLower(args[1].Str)
-		endStr := strings.ToLower(args[2].Str)
-		count := 0
-		if len(args) == 5 {
-			if !strings.EqualFold(args[3].Str, "COUNT") {
-				return resp.Value{}, fmt.Errorf("ERR syntax error")
-			}
-			n, err := strconv.Atoi(args[4].Str)
-			if err != nil || n < 0 {
-				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
-			}
-			count = n
-		} else if len(args) != 3 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XRANGE' command")
-		}
+		return streamRange(ds, args, "XRANGE", false)
+	}
+}
 
-		stream := ds.GetOrCreateStream(key)
-		startID, err := parseRangeID(startStr, true)
-		if err != nil {
-			return resp.Value{}, err
+// XREVRANGE key end start [COUNT n]
+func XRevRangeHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		return streamRange(ds, args, "XREVRANGE", true)
+	}
+}
+
+// streamRange implements XRANGE and, with rev, XREVRANGE whose bounds are
+// given from the end to the start
+func streamRange(ds DataStore, args []resp.Value, name string, rev bool) (resp.Value, error) {
+	if len(args) < 3 {
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+	}
+	key := args[0].Str
+	startStr := strings.ToLower(args[1].Str)
+	endStr := strings.ToLower(args[2].Str)
+	if rev {
+		startStr, endStr = endStr, startStr
+	}
+	count := 0
+	if len(args) == 5 {
+		if !strings.EqualFold(args[3].Str, "COUNT") {
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
 		}
-		endID, err := parseRangeID(endStr, false)
-		if err != nil {
-			return resp.Value{}, err
+		n, err := strconv.Atoi(args[4].Str)
+		if err != nil || n < 0 {
+			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
 		}
-		entries := stream.XRange(startID, endID, count)
-		return entriesToResp(entries), nil
+		count = n
+	} else if len(args) != 3 {
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+	}
+
+	startID, err := parseRangeID(startStr, true)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	endID, err := parseRangeID(endStr, false)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	stream, err := ds.GetStream(key)
+	if err != nil {
+		return resp.Value{}, err
 	}
+	if stream == nil {
+		return resp.Value{Type: resp.Array, Array: []resp.Value{}}, nil
+	}
+	if rev {
+		return entriesToResp(stream.XRevRange(endID, startID, count)), nil
+	}
+	return entriesToResp(stream.XRange(startID, endID, count)), nil
 }
 
 // XDEL key id [id ...]
@@ -118,91 +308,269 @@ func XDelHandler(ds DataStore) Handler {
 			}
 			ids = append(ids, id)
 		}
-		stream := ds.GetOrCreateStream(key)
+		stream, err := ds.GetStream(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
 		removed := stream.XDel(ids)
+		if removed > 0 {
+			ds.NotifyKeyEvent("xdel", key)
+		}
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
 }
 
-// XTRIM key MAXLEN [~] count
+// XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
 func XTrimHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
-		if len(args) < 3 || len(args) > 4 {
+		if len(args) < 3 {
 			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XTRIM' command")
 		}
 		key := args[0].Str
-		if !strings.EqualFold(args[1].Str, "MAXLEN") {
+		if !strings.EqualFold(args[1].Str, "MAXLEN") && !strings.EqualFold(args[1].Str, "MINID") {
 			return resp.Value{}, fmt.Errorf("ERR syntax error")
 		}
-		approx := false
-		var nStr string
-		if len(args) == 4 {
-			if args[2].Str == "~" {
-				approx = true
-				nStr = args[3].Str
-			} else {
-				return resp.Value{}, fmt.Errorf("ERR syntax error")
-			}
-		} else {
-			nStr = args[2].Str
+		trim, next, err := parseStreamTrim(args, 1)
+		if err != nil {
+			return resp.Value{}, err
 		}
-		n, err := strconv.Atoi(nStr)
-		if err != nil || n < 0 {
-			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+		if next != len(args) {
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		}
+		stream, err := ds.GetStream(key)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if stream == nil {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		removed := trim.apply(stream)
+		if removed > 0 {
+			ds.NotifyKeyEvent("xtrim", key)
 		}
-		stream := ds.GetOrCreateStream(key)
-		// For now, ignore approx and always do exact trim
-		removed := stream.XTrimMaxLen(n)
-		_ = approx
 		return resp.Value{Type: resp.Integer, Int: int64(removed)}, nil
 	}
 }
 
-// XREAD [COUNT n] STREAMS key id  (minimal, single stream)
-func XReadHandler(ds DataStore) Handler {
+// XSETID key last-id [ENTRIESADDED entries-added] [MAXDELETEDID max-deleted-id]
+func XSetIDHandler(ds DataStore) Handler {
 	return func(args []resp.Value) (resp.Value, error) {
 		if len(args) < 2 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XREAD' command")
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XSETID' command")
+		}
+		key := args[0].Str
+		last, err := parseReadStreamID(args[1].Str)
+		if err != nil {
+			return resp.Value{}, err
 		}
-		count := 0
-		idx := 0
-		if strings.EqualFold(args[idx].Str, "COUNT") {
-			if len(args) < 4 {
-				return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XREAD' command")
+		var entriesAdded *uint64
+		var maxDeleted *store.StreamID
+		for i := 2; i < len(args); i += 2 {
+			if i+1 >= len(args) {
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
 			}
-			c, err := strconv.Atoi(args[idx+1].Str)
-			if err != nil || c < 0 {
-				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+			switch strings.ToUpper(args[i].Str) {
+			case "ENTRIESADDED":
+				n, err := strconv.ParseInt(args[i+1].Str, 10, 64)
+				if err != nil {
+					return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+				}
+				if n < 0 {
+					return resp.Value{}, fmt.Errorf("ERR entries_added must be positive")
+				}
+				added := uint64(n)
+				entriesAdded = &added
+			case "MAXDELETEDID":
+				id, err := parseReadStreamID(args[i+1].Str)
+				if err != nil {
+					return resp.Value{}, err
+				}
+				maxDeleted = &id
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
 			}
-			count = c
-			idx += 2
 		}
-		if idx >= len(args) || !strings.EqualFold(args[idx].Str, "STREAMS") {
-			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		stream, err := ds.GetStream(key)
+		if err != nil {
+			return resp.Value{}, err
 		}
-		if len(args) < idx+3 {
-			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'XREAD' command")
+		if stream == nil {
+			return resp.Value{}, fmt.Errorf("ERR no such key")
 		}
-		key := args[idx+1].Str
-		idStr := strings.ToLower(args[idx+2].Str)
-		// Expect explicit ms-seq id; do not implement BLOCK/"$" right now
-		afterID, err := parseExactStreamID(idStr)
+		if err := stream.SetID(last, entriesAdded, maxDeleted); err != nil {
+			return resp.Value{}, err
+		}
+		ds.NotifyKeyEvent("xsetid", key)
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// XREAD [COUNT n] [BLOCK ms] STREAMS key [key ...] id [id ...]
+// An id of "$" reads only entries added after the call and "+" returns the
+// last entry of the stream. With BLOCK the client waits until one of the
+// streams receives an entry newer than its id (BLOCK 0 waits forever).
+func XReadHandler(ds DataStore) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		opts, err := parseXReadOptions(args, false)
 		if err != nil {
 			return resp.Value{}, err
 		}
-		stream := ds.GetOrCreateStream(key)
-		entries := stream.XReadAfter(afterID, count)
-		if len(entries) == 0 {
-			return resp.Value{Type: resp.Array, IsNull: true}, nil
+		n := len(opts.keys)
+		streams := make([]*store.Stream, n)
+		for i, key := range opts.keys {
+			stream, err := ds.GetStream(key)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			streams[i] = stream
+		}
+
+		// Resolve "$" and "+" against the current state of each stream so that
+		// a blocked retry keeps waiting for entries newer than the call
+		after := make([]store.StreamID, n)
+		resolved := make([]string, n)
+		var results []resp.Value
+		for i := 0; i < n; i++ {
+			idStr := opts.ids[i]
+			stream := streams[i]
+			switch idStr {
+			case "$", "+":
+				if stream != nil {
+					after[i] = stream.LastID()
+				}
+				if idStr == "+" && stream != nil {
+					if last, ok := stream.LastEntry(); ok {
+						results = append(results, streamReply(opts.keys[i], []store.StreamEntry{last}))
+						continue
+					}
+				}
+			default:
+				id, err := parseReadStreamID(idStr)
+				if err != nil {
+					return resp.Value{}, err
+				}
+				after[i] = id
+				if stream != nil {
+					if entries := stream.XReadAfter(id, opts.count); len(entries) > 0 {
+						results = append(results, streamReply(opts.keys[i], entries))
+					}
+				}
+			}
+			resolved[i] = after[i].String()
+		}
+
+		if len(results) > 0 {
+			return resp.Value{Type: resp.Array, Array: results}, nil
+		}
+		if !opts.block {
+			return nullArrayReply, nil
+		}
+		retryArgs := make([]resp.Value, len(args))
+		copy(retryArgs, args)
+		for i := 0; i < n; i++ {
+			retryArgs[opts.streamsAt+n+i] = resp.Value{Type: resp.BulkString, Str: resolved[i]}
+		}
+		return resp.Value{}, &BlockedError{
+			Keys:         opts.keys,
+			Timeout:      opts.timeout,
+			TimeoutReply: nullArrayReply,
+			RetryArgs:    retryArgs,
 		}
-		// Build response: [[ key, [ entries... ] ]]
-		entriesResp := entriesToResp(entries)
-		streamKey := resp.Value{Type: resp.BulkString, Str: key}
-		inner := resp.Value{Type: resp.Array, Array: []resp.Value{streamKey, entriesResp}}
-		return resp.Value{Type: resp.Array, Array: []resp.Value{inner}}, nil
 	}
 }
 
+// xreadOptions are the parsed arguments of XREAD and XREADGROUP
+type xreadOptions struct {
+	count     int
+	block     bool
+	timeout   time.Duration
+	noAck     bool
+	group     string
+	consumer  string
+	streamsAt int // index of the first key in the arguments
+	keys      []string
+	ids       []string
+}
+
+// parseXReadOptions parses [GROUP g c] [COUNT n] [BLOCK ms] [NOACK] STREAMS
+// keys... ids...; the GROUP and NOACK options are only accepted for XREADGROUP
+func parseXReadOptions(args []resp.Value, group bool) (*xreadOptions, error) {
+	name := "xread"
+	if group {
+		name = "xreadgroup"
+	}
+	opts := &xreadOptions{}
+	idx := 0
+	for ; idx < len(args); idx++ {
+		opt := strings.ToUpper(args[idx].Str)
+		if opt == "STREAMS" {
+			break
+		}
+		if opt == "NOACK" && group {
+			opts.noAck = true
+			continue
+		}
+		if opt == "GROUP" && idx+2 < len(args) {
+			if !group {
+				return nil, fmt.Errorf("ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+			}
+			opts.group = args[idx+1].Str
+			opts.consumer = args[idx+2].Str
+			idx += 2
+			continue
+		}
+		if idx+1 >= len(args) {
+			return nil, fmt.Errorf("ERR syntax error")
+		}
+		switch opt {
+		case "COUNT":
+			c, err := strconv.Atoi(args[idx+1].Str)
+			if err != nil {
+				return nil, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			if c < 0 {
+				c = 0
+			}
+			opts.count = c
+		case "BLOCK":
+			ms, err := strconv.ParseInt(args[idx+1].Str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ERR timeout is not an integer or out of range")
+			}
+			if ms < 0 {
+				return nil, fmt.Errorf("ERR timeout is negative")
+			}
+			opts.block = true
+			opts.timeout = time.Duration(ms) * time.Millisecond
+		default:
+			return nil, fmt.Errorf("ERR syntax error")
+		}
+		idx++
+	}
+	if idx >= len(args) {
+		return nil, fmt.Errorf("ERR syntax error")
+	}
+	if group && opts.group == "" {
+		return nil, fmt.Errorf("ERR Missing GROUP option for XREADGROUP")
+	}
+	rest := args[idx+1:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		return nil, fmt.Errorf("ERR Unbalanced '%s' list of streams: for each stream key an ID or '$' must be specified.", name)
+	}
+	n := len(rest) / 2
+	opts.streamsAt = idx + 1
+	opts.keys = make([]string, n)
+	opts.ids = make([]string, n)
+	for i := 0; i < n; i++ {
+		opts.keys[i] = rest[i].Str
+		opts.ids[i] = rest[n+i].Str
+	}
+	return opts, nil
+}
+
 // Helpers
 func parseExactStreamID(s string) (store.StreamID, error) {
 	parts := strings.Split(s, "-")
@@ -220,6 +588,16 @@ func parseExactStreamID(s string) (store.StreamID, error) {
 	return store.StreamID{Ms: ms, Seq: seq}, nil
 }
 
+// parseReadStreamID parses an XREAD id; a missing sequence number means 0
+func parseReadStreamID(s string) (store.StreamID, error) {
+	if !strings.Contains(s, "-") {
+		s += "-0"
+	}
+	return parseExactStreamID(s)
+}
+
+// parseRangeID parses an XRANGE bound; an id without a sequence number
+// covers every sequence of that millisecond
 func parseRangeID(s string, isStart bool) (store.StreamID, error) {
 	s = strings.ToLower(s)
 	switch s {
@@ -228,13 +606,36 @@ func parseRangeID(s string, isStart bool) (store.StreamID, error) {
 	case "+":
 		return store.StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}, nil
 	default:
+		if !strings.Contains(s, "-") {
+			if isStart {
+				s += "-0"
+			} else {
+				s += "-" + strconv.FormatUint(math.MaxUint64, 10)
+			}
+		}
 		return parseExactStreamID(s)
 	}
 }
 
+// streamReply builds the [key, [entries...]] reply element for one stream
+func streamReply(key string, entries []store.StreamEntry) resp.Value {
+	return resp.Value{Type: resp.Array, Array: []resp.Value{
+		{Type: resp.BulkString, Str: key},
+		entriesToResp(entries),
+	}}
+}
+
 func entriesToResp(entries []store.StreamEntry) resp.Value {
 	arr := make([]resp.Value, len(entries))
 	for i, e := range entries {
+		if e.Fields == nil {
+			// Entry deleted after it was delivered to a consumer group
+			arr[i] = resp.Value{Type: resp.Array, Array: []resp.Value{
+				{Type: resp.BulkString, Str: e.ID.String()},
+				nullArrayReply,
+			}}
+			continue
+		}
 		// fields array
 		fv := make([]resp.Value, 0, len(e.Fields)*2)
 		for f, v := range e.Fields {
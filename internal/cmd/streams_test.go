@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"gridhouse/internal/resp"
 
@@ -158,3 +160,160 @@ func TestXReadBasic(t *testing.T) {
 	assert.Equal(t, resp.Array, res.Type)
 	assert.True(t, res.IsNull)
 }
+
+func TestXReadMultipleStreams(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	xadd := XAddHandler(ds)
+	xread := XReadHandler(ds)
+
+	for _, args := range [][]string{
+		{"a", "1-1", "f", "1"},
+		{"a", "1-2", "f", "2"},
+		{"b", "5-0", "f", "3"},
+	} {
+		_, err := xadd(bulkArgs(args...))
+		require.NoError(t, err)
+	}
+
+	// Streams without newer entries are left out of the reply
+	res, err := xread(bulkArgs("STREAMS", "a", "b", "missing", "1-1", "5", "0"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "a", res.Array[0].Array[0].Str)
+	assert.Equal(t, "1-2", res.Array[0].Array[1].Array[0].Array[0].Str)
+
+	res, err = xread(bulkArgs("COUNT", "1", "STREAMS", "a", "b", "0-0", "0-0"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 2)
+	require.Len(t, res.Array[0].Array[1].Array, 1)
+	assert.Equal(t, "1-1", res.Array[0].Array[1].Array[0].Array[0].Str)
+	assert.Equal(t, "5-0", res.Array[1].Array[1].Array[0].Array[0].Str)
+
+	// "+" returns the last entry, "$" never returns existing entries
+	res, err = xread(bulkArgs("STREAMS", "a", "b", "+", "$"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "1-2", res.Array[0].Array[1].Array[0].Array[0].Str)
+
+	res, err = xread(bulkArgs("STREAMS", "a", "$"))
+	require.NoError(t, err)
+	assert.True(t, res.IsNull)
+
+	_, err = xread(bulkArgs("STREAMS", "a", "b", "0-0"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unbalanced")
+
+	_, err = xread(bulkArgs("BLOCK", "-1", "STREAMS", "a", "0"))
+	require.EqualError(t, err, "ERR timeout is negative")
+}
+
+func TestXReadBlock(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	xadd := XAddHandler(ds)
+	xread := XReadHandler(ds)
+
+	_, err := xadd(bulkArgs("s", "3-0", "f", "v"))
+	require.NoError(t, err)
+
+	// "$" is resolved to the current last ID for retries
+	_, err = xread(bulkArgs("BLOCK", "250", "COUNT", "10", "STREAMS", "s", "new", "$", "$"))
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, []string{"s", "new"}, blocked.Keys)
+	assert.Equal(t, 250*time.Millisecond, blocked.Timeout)
+	assert.True(t, blocked.TimeoutReply.IsNull)
+	assert.Equal(t, bulkArgs("BLOCK", "250", "COUNT", "10", "STREAMS", "s", "new", "3-0", "0-0"), blocked.RetryArgs)
+
+	_, err = xadd(bulkArgs("new", "*", "f", "v"))
+	require.NoError(t, err)
+	res, err := xread(blocked.RetryArgs)
+	require.NoError(t, err)
+	require.Len(t, res.Array, 1)
+	assert.Equal(t, "new", res.Array[0].Array[0].Str)
+}
+
+func TestXAddOptionsAndXTrimMinID(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	xadd := XAddHandler(ds)
+
+	res, err := xadd(bulkArgs("s", "NOMKSTREAM", "*", "f", "v"))
+	require.NoError(t, err)
+	assert.True(t, res.IsNull)
+	_, _, ok := rewriteXAdd([]string{"s", "NOMKSTREAM", "*", "f", "v"}, res)
+	assert.False(t, ok)
+
+	for _, id := range []string{"1-0", "2", "3-0"} {
+		_, err = xadd(bulkArgs("s", id, "f", "v"))
+		require.NoError(t, err)
+	}
+	res, err = xadd(bulkArgs("s", "3-*", "f", "v"))
+	require.NoError(t, err)
+	assert.Equal(t, "3-1", res.Str)
+
+	_, err = xadd(bulkArgs("s", "0-0", "f", "v"))
+	require.EqualError(t, err, "ERR The ID specified in XADD must be greater than 0-0")
+	_, err = xadd(bulkArgs("newk", "0-0", "f", "v"))
+	require.EqualError(t, err, "ERR The ID specified in XADD must be greater than 0-0")
+	assert.False(t, ds.Exists("newk"))
+	_, err = xadd(bulkArgs("s", "MAXLEN", "=", "2", "LIMIT", "1", "*", "f", "v"))
+	require.EqualError(t, err, "ERR syntax error, LIMIT cannot be used without the special ~ option")
+
+	// Exact MAXLEN trims everything above the threshold
+	args := []string{"s", "MAXLEN", "=", "3", "5-*", "f", "v"}
+	res, err = xadd(bulkArgs(args...))
+	require.NoError(t, err)
+	assert.Equal(t, "5-0", res.Str)
+	length, _ := XLenHandler(ds)(bulkArgs("s"))
+	assert.Equal(t, int64(3), length.Int)
+	name, newArgs, ok := rewriteXAdd(args, res)
+	require.True(t, ok)
+	assert.Equal(t, "XADD", name)
+	assert.Equal(t, []string{"s", "MAXLEN", "=", "3", "5-0", "f", "v"}, newArgs)
+
+	// Approximate trimming removes at most LIMIT entries
+	res, err = xadd(bulkArgs("s", "MINID", "~", "9", "LIMIT", "1", "6-0", "f", "v"))
+	require.NoError(t, err)
+	length, _ = XLenHandler(ds)(bulkArgs("s"))
+	assert.Equal(t, int64(3), length.Int)
+
+	xtrim := XTrimHandler(ds)
+	res, err = xtrim(bulkArgs("s", "MINID", "6"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), res.Int)
+	_, err = xtrim(bulkArgs("s", "MINID", "6", "BOGUS"))
+	require.EqualError(t, err, "ERR syntax error")
+	_, err = xtrim(bulkArgs("s", "MAXLEN", "-1"))
+	require.EqualError(t, err, "ERR The MAXLEN argument must be >= 0.")
+}
+
+func TestXRevRangeAndXSetID(t *testing.T) {
+	ds := newScopedMockDataStore(t)
+	seedGroupStream(t, ds, "s", "1-0", "2-0", "2-1", "3-0")
+
+	res, err := XRevRangeHandler(ds)(bulkArgs("s", "+", "-", "COUNT", "2"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 2)
+	assert.Equal(t, "3-0", res.Array[0].Array[0].Str)
+	assert.Equal(t, "2-1", res.Array[1].Array[0].Str)
+
+	// An id without a sequence covers the whole millisecond
+	res, err = XRevRangeHandler(ds)(bulkArgs("s", "2", "2"))
+	require.NoError(t, err)
+	require.Len(t, res.Array, 2)
+	assert.Equal(t, "2-1", res.Array[0].Array[0].Str)
+	res, err = XRangeHandler(ds)(bulkArgs("s", "2", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, "2-0", res.Array[0].Array[0].Str)
+
+	xsetid := XSetIDHandler(ds)
+	_, err = xsetid(bulkArgs("missing", "1-0"))
+	require.EqualError(t, err, "ERR no such key")
+	_, err = xsetid(bulkArgs("s", "2-0"))
+	require.EqualError(t, err, "ERR The ID specified in XSETID is smaller than the target stream top item")
+	res, err = xsetid(bulkArgs("s", "10-0", "ENTRIESADDED", "7", "MAXDELETEDID", "4-0"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", res.Str)
+
+	_, err = XAddHandler(ds)(bulkArgs("s", "9-0", "f", "v"))
+	require.EqualError(t, err, "ERR The ID specified in XADD is equal or smaller than the target stream top item")
+}
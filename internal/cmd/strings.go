@@ -0,0 +1,455 @@
+package cmd
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringStore is implemented by stores that check and write strings under
+// a single shard lock
+type stringStore interface {
+	SetWithOptions(key, value string, opts store.SetOptions) (store.SetResult, error)
+	GetDel(key string) (string, bool, error)
+	GetEx(key string, expiration time.Time, persist bool) (string, bool, error)
+	SetRange(key string, offset int, value string) (int, error)
+	MSetNX(pairs [][2]string) bool
+}
+
+// stringsOf returns the atomic string operations of s. Stores without them
+// get them emulated on top of Get and Set.
+func stringsOf(s Store) stringStore {
+	if ss, ok := s.(stringStore); ok {
+		return ss
+	}
+	return plainStrings{s}
+}
+
+// plainStrings emulates stringStore for stores that only offer Store
+type plainStrings struct{ Store }
+
+func (p plainStrings) SetWithOptions(key, value string, opts store.SetOptions) (store.SetResult, error) {
+	old, isString := p.Get(key)
+	res := store.SetResult{Old: old, Existed: isString || p.Exists(key)}
+	if opts.Get && res.Existed && !isString {
+		return res, store.ErrWrongType
+	}
+	if (opts.Condition == store.SetIfAbsent && res.Existed) || (opts.Condition == store.SetIfPresent && !res.Existed) {
+		return res, nil
+	}
+	expiration := opts.Expiration
+	if expiration.IsZero() && opts.KeepTTL {
+		expiration = p.expiration(key)
+	}
+	p.Set(key, value, expiration)
+	res.Written = true
+	return res, nil
+}
+
+func (p plainStrings) GetDel(key string) (string, bool, error) {
+	value, ok := p.Get(key)
+	if !ok {
+		return "", false, nil
+	}
+	p.Del(key)
+	return value, true, nil
+}
+
+func (p plainStrings) GetEx(key string, expiration time.Time, persist bool) (string, bool, error) {
+	value, ok := p.Get(key)
+	if ok && (!expiration.IsZero() || persist) {
+		p.Set(key, value, expiration)
+	}
+	return value, ok, nil
+}
+
+func (p plainStrings) SetRange(key string, offset int, value string) (int, error) {
+	if len(value) > 0 && offset+len(value) > store.MaxStringLength {
+		return 0, store.ErrStringTooLong
+	}
+	current, _ := p.Get(key)
+	if len(value) == 0 {
+		return len(current), nil
+	}
+	buf := []byte(current)
+	if end := offset + len(value); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[offset:], value)
+	p.Set(key, string(buf), p.expiration(key))
+	return len(buf), nil
+}
+
+func (p plainStrings) MSetNX(pairs [][2]string) bool {
+	for _, pair := range pairs {
+		if p.Exists(pair[0]) {
+			return false
+		}
+	}
+	for _, pair := range pairs {
+		p.Set(pair[0], pair[1], time.Time{})
+	}
+	return true
+}
+
+// expiration returns when key expires, or the zero time if it does not
+func (p plainStrings) expiration(key string) time.Time {
+	if ms := p.PTTL(key); ms > 0 {
+		return time.Now().Add(time.Duration(ms) * time.Millisecond)
+	}
+	return time.Time{}
+}
+
+// expirationTime converts the argument of an EX, PX, EXAT or PXAT option
+// into an absolute time. Values that are not positive or do not fit a
+// Unix nanosecond timestamp are rejected in the name of command.
+func expirationTime(option string, n int64, command string) (time.Time, error) {
+	unit, base := int64(time.Second), int64(0)
+	if option == "PX" || option == "PXAT" {
+		unit = int64(time.Millisecond)
+	}
+	if option == "EX" || option == "PX" {
+		base = time.Now().UnixNano()
+	}
+	if n <= 0 || n > (math.MaxInt64-base)/unit {
+		return time.Time{}, fmt.Errorf("ERR invalid expire time in '%s' command", command)
+	}
+	return time.Unix(0, base+n*unit), nil
+}
+
+// parseSetOptions parses the [NX|XX] [GET] [EX|PX|EXAT|PXAT time|KEEPTTL]
+// modifiers following SET key value
+func parseSetOptions(args []resp.Value) (store.SetOptions, error) {
+	var opts store.SetOptions
+	expiring := false
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].Str); option {
+		case "NX", "XX":
+			if opts.Condition != store.SetAlways {
+				return opts, fmt.Errorf("ERR syntax error")
+			}
+			opts.Condition = store.SetIfAbsent
+			if option == "XX" {
+				opts.Condition = store.SetIfPresent
+			}
+		case "GET":
+			opts.Get = true
+		case "KEEPTTL":
+			if expiring {
+				return opts, fmt.Errorf("ERR syntax error")
+			}
+			opts.KeepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expiring || opts.KeepTTL || i+1 >= len(args) {
+				return opts, fmt.Errorf("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1].Str, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			if opts.Expiration, err = expirationTime(option, n, "set"); err != nil {
+				return opts, err
+			}
+			expiring = true
+			i++ // Skip the next argument since we consumed it
+		default:
+			return opts, fmt.Errorf("ERR syntax error")
+		}
+	}
+	return opts, nil
+}
+
+// setString runs a SET with opts and reports the key events of a write
+func setString(s Store, key, value string, opts store.SetOptions) (store.SetResult, error) {
+	res, err := stringsOf(s).SetWithOptions(key, value, opts)
+	if err != nil || !res.Written {
+		return res, err
+	}
+	notifyKeyEvent(s, "set", key)
+	if !opts.Expiration.IsZero() {
+		notifyKeyEvent(s, "expire", key)
+	}
+	return res, nil
+}
+
+// rewriteSet propagates a SET that wrote its value with any relative
+// expiration made absolute, so replaying the AOF keeps the deadline
+func rewriteSet(args []string, result resp.Value) (string, []string, bool) {
+	if len(args) == 2 {
+		return "SET", args, !result.IsNull
+	}
+	values := make([]resp.Value, len(args)-2)
+	for i, arg := range args[2:] {
+		values[i] = resp.Value{Type: resp.BulkString, Str: arg}
+	}
+	opts, err := parseSetOptions(values)
+	if err != nil {
+		return "", nil, false
+	}
+	written := !result.IsNull
+	if opts.Get {
+		switch opts.Condition {
+		case store.SetIfAbsent:
+			written = result.IsNull
+		case store.SetAlways:
+			written = true
+		}
+	}
+	if !written {
+		return "", nil, false
+	}
+	return "SET", setArgs(args[0], args[1], opts.Expiration, opts.KeepTTL), true
+}
+
+// setArgs returns the arguments of a SET storing value at key with an
+// absolute expiration, or keeping the current one
+func setArgs(key, value string, expiration time.Time, keepTTL bool) []string {
+	switch {
+	case !expiration.IsZero():
+		return []string{key, value, "PXAT", strconv.FormatInt(expiration.UnixMilli(), 10)}
+	case keepTTL:
+		return []string{key, value, "KEEPTTL"}
+	}
+	return []string{key, value}
+}
+
+// SetNXHandler handles SETNX key value
+func SetNXHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SETNX' command")
+		}
+		res, err := setString(s, args[0].Str, args[1].Str, store.SetOptions{Condition: store.SetIfAbsent})
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !res.Written {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+// rewriteSetNX propagates a SETNX that stored its value as SET
+func rewriteSetNX(args []string, result resp.Value) (string, []string, bool) {
+	return "SET", args, result.Int == 1
+}
+
+// SetEXHandler handles SETEX key seconds value
+func SetEXHandler(s Store) Handler {
+	return setExpiringHandler(s, "setex", "EX")
+}
+
+// PSetEXHandler handles PSETEX key milliseconds value
+func PSetEXHandler(s Store) Handler {
+	return setExpiringHandler(s, "psetex", "PX")
+}
+
+func setExpiringHandler(s Store, name, unit string) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToUpper(name))
+		}
+		n, err := strconv.ParseInt(args[1].Str, 10, 64)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		expiration, err := expirationTime(unit, n, name)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if _, err := setString(s, args[0].Str, args[2].Str, store.SetOptions{Expiration: expiration}); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	}
+}
+
+// rewriteSetExpiring propagates SETEX and PSETEX as SET with an absolute
+// expiration
+func rewriteSetExpiring(unit string) func(args []string, result resp.Value) (string, []string, bool) {
+	return func(args []string, result resp.Value) (string, []string, bool) {
+		if len(args) != 3 {
+			return "", nil, false
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		expiration, err := expirationTime(unit, n, "set")
+		if err != nil {
+			return "", nil, false
+		}
+		return "SET", setArgs(args[0], args[2], expiration, false), true
+	}
+}
+
+// GetSetHandler handles GETSET key value
+func GetSetHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 2 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'GETSET' command")
+		}
+		res, err := setString(s, args[0].Str, args[1].Str, store.SetOptions{Get: true})
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !res.Existed {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		return resp.Value{Type: resp.BulkString, Str: res.Old}, nil
+	}
+}
+
+// rewriteGetSet propagates GETSET as the SET it performed
+func rewriteGetSet(args []string, result resp.Value) (string, []string, bool) {
+	return "SET", args, true
+}
+
+// GetDelHandler handles GETDEL key
+func GetDelHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 1 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'GETDEL' command")
+		}
+		value, ok, err := stringsOf(s).GetDel(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !ok {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		notifyKeyEvent(s, "del", args[0].Str)
+		return resp.Value{Type: resp.BulkString, Str: value}, nil
+	}
+}
+
+// rewriteGetDel propagates a GETDEL that removed its key as DEL
+func rewriteGetDel(args []string, result resp.Value) (string, []string, bool) {
+	return "DEL", args, !result.IsNull
+}
+
+// GetExHandler handles GETEX key [EX seconds|PX ms|EXAT ts|PXAT ts-ms|PERSIST]
+func GetExHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) == 0 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'GETEX' command")
+		}
+		expiration, persist, err := parseGetExOptions(args[1:])
+		if err != nil {
+			return resp.Value{}, err
+		}
+		key := args[0].Str
+		value, ok, err := stringsOf(s).GetEx(key, expiration, persist)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if !ok {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		if !expiration.IsZero() {
+			notifyKeyEvent(s, "expire", key)
+		} else if persist {
+			notifyKeyEvent(s, "persist", key)
+		}
+		return resp.Value{Type: resp.BulkString, Str: value}, nil
+	}
+}
+
+// parseGetExOptions parses the optional expiration change of GETEX
+func parseGetExOptions(args []resp.Value) (expiration time.Time, persist bool, err error) {
+	switch {
+	case len(args) == 0:
+		return time.Time{}, false, nil
+	case len(args) == 1 && strings.EqualFold(args[0].Str, "PERSIST"):
+		return time.Time{}, true, nil
+	case len(args) != 2:
+		return time.Time{}, false, fmt.Errorf("ERR syntax error")
+	}
+	option := strings.ToUpper(args[0].Str)
+	switch option {
+	case "EX", "PX", "EXAT", "PXAT":
+	default:
+		return time.Time{}, false, fmt.Errorf("ERR syntax error")
+	}
+	n, err := strconv.ParseInt(args[1].Str, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	expiration, err = expirationTime(option, n, "getex")
+	return expiration, false, err
+}
+
+// rewriteGetEx propagates a GETEX that changed the expiration as the
+// PEXPIREAT or PERSIST it performed
+func rewriteGetEx(args []string, result resp.Value) (string, []string, bool) {
+	if result.IsNull || len(args) < 2 {
+		return "", nil, false
+	}
+	values := make([]resp.Value, len(args)-1)
+	for i, arg := range args[1:] {
+		values[i] = resp.Value{Type: resp.BulkString, Str: arg}
+	}
+	expiration, persist, err := parseGetExOptions(values)
+	if err != nil {
+		return "", nil, false
+	}
+	if persist {
+		return "PERSIST", args[:1], true
+	}
+	return "PEXPIREAT", []string{args[0], strconv.FormatInt(expiration.UnixMilli(), 10)}, true
+}
+
+// SetRangeHandler handles SETRANGE key offset value
+func SetRangeHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) != 3 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SETRANGE' command")
+		}
+		offset, err := strconv.Atoi(args[1].Str)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		if offset < 0 {
+			return resp.Value{}, fmt.Errorf("ERR offset is out of range")
+		}
+		key := args[0].Str
+		n, err := stringsOf(s).SetRange(key, offset, args[2].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if len(args[2].Str) > 0 {
+			notifyKeyEvent(s, "setrange", key)
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(n)}, nil
+	}
+}
+
+// MSetNXHandler handles MSETNX key value [key value ...]
+func MSetNXHandler(s Store) Handler {
+	return func(args []resp.Value) (resp.Value, error) {
+		if len(args) == 0 || len(args)%2 != 0 {
+			return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'MSETNX' command")
+		}
+		pairs := make([][2]string, 0, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			pairs = append(pairs, [2]string{args[i].Str, args[i+1].Str})
+		}
+		if !stringsOf(s).MSetNX(pairs) {
+			return resp.Value{Type: resp.Integer, Int: 0}, nil
+		}
+		for _, pair := range pairs {
+			notifyKeyEvent(s, "set", pair[0])
+		}
+		return resp.Value{Type: resp.Integer, Int: 1}, nil
+	}
+}
+
+// rewriteMSetNX propagates an MSETNX that stored its keys as MSET
+func rewriteMSetNX(args []string, result resp.Value) (string, []string, bool) {
+	return "MSET", args, result.Int == 1
+}
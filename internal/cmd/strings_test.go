@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOptions(t *testing.T) {
+	db := store.NewUltraOptimizedDB()
+	defer db.Close()
+	set := OptimizedSetHandler(db)
+
+	result, err := set(bulkArgs("lock", "token", "nx", "px", "10000"))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", result.Str)
+	assert.Greater(t, db.PTTL("lock"), int64(9000))
+
+	result, err = set(bulkArgs("lock", "other", "NX", "PX", "10000"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+
+	result, err = set(bulkArgs("lock", "next", "XX", "GET", "KEEPTTL"))
+	require.NoError(t, err)
+	assert.Equal(t, "token", result.Str)
+	assert.Greater(t, db.PTTL("lock"), int64(0))
+
+	result, err = set(bulkArgs("fresh", "v", "GET"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+
+	at := time.Now().Add(time.Hour).Unix()
+	_, err = set(bulkArgs("at", "v", "exat", strconv.FormatInt(at, 10)))
+	require.NoError(t, err)
+	assert.InDelta(t, 3600, db.TTL("at"), 2)
+
+	_, err = set(bulkArgs("k", "v", "EX", "0"))
+	assert.EqualError(t, err, "ERR invalid expire time in 'set' command")
+	_, err = set(bulkArgs("k", "v", "EX", "ten"))
+	assert.EqualError(t, err, "ERR value is not an integer or out of range")
+	for _, bad := range [][]string{
+		{"NX", "XX"}, {"EX", "10", "PX", "10"}, {"EX", "10", "KEEPTTL"}, {"PX"}, {"BOGUS"},
+	} {
+		_, err = set(bulkArgs(append([]string{"k", "v"}, bad...)...))
+		assert.EqualError(t, err, "ERR syntax error", "%v", bad)
+	}
+
+	_, err = db.EnsureList("list")
+	require.NoError(t, err)
+	_, err = set(bulkArgs("list", "v", "GET"))
+	assert.ErrorIs(t, err, store.ErrWrongType)
+}
+
+func TestSetOptionsOnPlainStore(t *testing.T) {
+	s := NewMockStore()
+	set := OptimizedSetHandler(s)
+
+	_, err := set(bulkArgs("k", "v", "EX", "100"))
+	require.NoError(t, err)
+	result, err := set(bulkArgs("k", "w", "NX"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+	result, err = set(bulkArgs("k", "w", "GET", "KEEPTTL"))
+	require.NoError(t, err)
+	assert.Equal(t, "v", result.Str)
+	assert.Greater(t, s.TTL("k"), int64(0))
+}
+
+func TestStringCommands(t *testing.T) {
+	db := store.NewUltraOptimizedDB()
+	defer db.Close()
+
+	result, err := SetNXHandler(db)(bulkArgs("k", "v"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	result, err = SetNXHandler(db)(bulkArgs("k", "w"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+
+	_, err = SetEXHandler(db)(bulkArgs("ex", "100", "v"))
+	require.NoError(t, err)
+	assert.InDelta(t, 100, db.TTL("ex"), 1)
+	_, err = PSetEXHandler(db)(bulkArgs("px", "100000", "v"))
+	require.NoError(t, err)
+	assert.InDelta(t, 100, db.TTL("px"), 1)
+	_, err = SetEXHandler(db)(bulkArgs("ex", "-1", "v"))
+	assert.EqualError(t, err, "ERR invalid expire time in 'setex' command")
+
+	result, err = GetSetHandler(db)(bulkArgs("ex", "new"))
+	require.NoError(t, err)
+	assert.Equal(t, "v", result.Str)
+	assert.Equal(t, int64(-1), db.TTL("ex"))
+	result, err = GetSetHandler(db)(bulkArgs("missing", "v"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+
+	result, err = GetExHandler(db)(bulkArgs("k", "EX", "50"))
+	require.NoError(t, err)
+	assert.Equal(t, "v", result.Str)
+	assert.InDelta(t, 50, db.TTL("k"), 1)
+	_, err = GetExHandler(db)(bulkArgs("k", "PERSIST"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), db.TTL("k"))
+	_, err = GetExHandler(db)(bulkArgs("k", "EX"))
+	assert.EqualError(t, err, "ERR syntax error")
+
+	result, err = GetDelHandler(db)(bulkArgs("k"))
+	require.NoError(t, err)
+	assert.Equal(t, "v", result.Str)
+	result, err = GetDelHandler(db)(bulkArgs("k"))
+	require.NoError(t, err)
+	assert.True(t, result.IsNull)
+
+	result, err = SetRangeHandler(db)(bulkArgs("r", "2", "xy"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Int)
+	_, err = SetRangeHandler(db)(bulkArgs("r", "-1", "xy"))
+	assert.EqualError(t, err, "ERR offset is out of range")
+
+	result, err = MSetNXHandler(db)(bulkArgs("a", "1", "b", "2"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Int)
+	result, err = MSetNXHandler(db)(bulkArgs("b", "3", "c", "4"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Int)
+	assert.False(t, db.Exists("c"))
+}
+
+func TestRewriteSetFamily(t *testing.T) {
+	ok := resp.Value{Type: resp.SimpleString, Str: "OK"}
+	null := resp.Value{Type: resp.BulkString, IsNull: true}
+
+	name, args, propagate := rewriteSet([]string{"k", "v"}, ok)
+	assert.True(t, propagate)
+	assert.Equal(t, "SET", name)
+	assert.Equal(t, []string{"k", "v"}, args)
+
+	_, _, propagate = rewriteSet([]string{"k", "v", "NX"}, null)
+	assert.False(t, propagate)
+
+	// A GET reply tells whether NX and XX let the write through
+	_, _, propagate = rewriteSet([]string{"k", "v", "NX", "GET"}, resp.Value{Type: resp.BulkString, Str: "old"})
+	assert.False(t, propagate)
+	_, _, propagate = rewriteSet([]string{"k", "v", "XX", "GET"}, resp.Value{Type: resp.BulkString, Str: "old"})
+	assert.True(t, propagate)
+
+	_, args, propagate = rewriteSet([]string{"k", "v", "EX", "100"}, ok)
+	require.True(t, propagate)
+	require.Len(t, args, 4)
+	assert.Equal(t, "PXAT", args[2])
+	at, err := strconv.ParseInt(args[3], 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(100*time.Second).UnixMilli(), at, 1000)
+
+	_, args, _ = rewriteSet([]string{"k", "v", "KEEPTTL"}, ok)
+	assert.Equal(t, []string{"k", "v", "KEEPTTL"}, args)
+
+	name, args, propagate = rewriteGetEx([]string{"k", "PERSIST"}, resp.Value{Type: resp.BulkString, Str: "v"})
+	assert.True(t, propagate)
+	assert.Equal(t, "PERSIST", name)
+	assert.Equal(t, []string{"k"}, args)
+	name, args, _ = rewriteGetEx([]string{"k", "PXAT", "123456"}, resp.Value{Type: resp.BulkString, Str: "v"})
+	assert.Equal(t, "PEXPIREAT", name)
+	assert.Equal(t, []string{"k", "123456"}, args)
+	_, _, propagate = rewriteGetEx([]string{"k"}, resp.Value{Type: resp.BulkString, Str: "v"})
+	assert.False(t, propagate)
+
+	name, args, propagate = rewriteMSetNX([]string{"a", "1"}, resp.Value{Type: resp.Integer, Int: 1})
+	assert.True(t, propagate)
+	assert.Equal(t, "MSET", name)
+	assert.Equal(t, []string{"a", "1"}, args)
+}
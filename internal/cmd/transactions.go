@@ -481,6 +481,34 @@ func (wdb *WatchAwareDB) Expire(key string, duration time.Duration) bool {
 	return wdb.db.Expire(key, duration)
 }
 
+// ExpireAt wraps the database ExpireAt method to notify WATCH
+func (wdb *WatchAwareDB) ExpireAt(key string, at time.Time, flags store.ExpireFlags) bool {
+	result := wdb.db.ExpireAt(key, at, flags)
+	if result {
+		wdb.tm.NotifyKeyModified(key)
+	}
+	return result
+}
+
+// Persist wraps the database Persist method to notify WATCH
+func (wdb *WatchAwareDB) Persist(key string) bool {
+	result := wdb.db.Persist(key)
+	if result {
+		wdb.tm.NotifyKeyModified(key)
+	}
+	return result
+}
+
+// ExpireTime delegates to the underlying database
+func (wdb *WatchAwareDB) ExpireTime(key string) int64 {
+	return wdb.db.ExpireTime(key)
+}
+
+// PExpireTime delegates to the underlying database
+func (wdb *WatchAwareDB) PExpireTime(key string) int64 {
+	return wdb.db.PExpireTime(key)
+}
+
 // Keys delegates to the underlying database
 func (wdb *WatchAwareDB) Keys() []string {
 	return wdb.db.Keys()
@@ -518,6 +546,11 @@ func (wdb *WatchAwareDB) Close() {
 	wdb.db.Close()
 }
 
+// NotifyKeyEvent delegates to the underlying database
+func (wdb *WatchAwareDB) NotifyKeyEvent(event, key string) {
+	wdb.db.NotifyKeyEvent(event, key)
+}
+
 // RegisterTransactionCommands registers transaction-related commands
 func RegisterTransactionCommands(registry *OptimizedRegistry, db store.DataStore) *TransactionAwareRegistry {
 	// Create transaction manager
@@ -529,56 +562,67 @@ func RegisterTransactionCommands(registry *OptimizedRegistry, db store.DataStore
 
 	// Register basic commands with watch-aware database
 	registry.Register(&Command{
-		Name:     "SET",
-		Arity:    -1,
-		Handler:  OptimizedSetHandler(watchDB),
-		ReadOnly: false,
+		Name:      "SET",
+		Arity:     -1,
+		Handler:   OptimizedSetHandler(watchDB),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclWrite, AclString, AclSlow},
+		Keys:      FirstKey,
+		Rewrite:   rewriteSet,
 	})
 	registry.Register(&Command{
-		Name:     "GET",
-		Arity:    1,
-		Handler:  OptimizedGetHandler(watchDB),
-		ReadOnly: true,
+		Name:      "GET",
+		Arity:     1,
+		Handler:   OptimizedGetHandler(watchDB),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclRead, AclString, AclFast},
+		Keys:      FirstKey,
 	})
 
 	// MULTI command
 	registry.Register(&Command{
-		Name:     "MULTI",
-		Arity:    0,
-		Handler:  MultiHandler(tm),
-		ReadOnly: false,
+		Name:      "MULTI",
+		Arity:     0,
+		Handler:   MultiHandler(tm),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclFast, AclTransaction},
 	})
 
 	// EXEC command
 	registry.Register(&Command{
-		Name:     "EXEC",
-		Arity:    0,
-		Handler:  ExecHandler(tm, registry),
-		ReadOnly: false,
+		Name:      "EXEC",
+		Arity:     0,
+		Handler:   ExecHandler(tm, registry),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclSlow, AclTransaction},
 	})
 
 	// DISCARD command
 	registry.Register(&Command{
-		Name:     "DISCARD",
-		Arity:    0,
-		Handler:  DiscardHandler(tm),
-		ReadOnly: false,
+		Name:      "DISCARD",
+		Arity:     0,
+		Handler:   DiscardHandler(tm),
+		ReadOnly:  false,
+		ACLGroups: []AclGroup{AclFast, AclTransaction},
 	})
 
 	// WATCH command
 	registry.Register(&Command{
-		Name:     "WATCH",
-		Arity:    -1, // At least 1 key
-		Handler:  WatchHandler(tm),
-		ReadOnly: true,
+		Name:      "WATCH",
+		Arity:     -1, // At least 1 key
+		Handler:   WatchHandler(tm),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclFast, AclTransaction},
+		Keys:      AllKeys,
 	})
 
 	// UNWATCH command
 	registry.Register(&Command{
-		Name:     "UNWATCH",
-		Arity:    0,
-		Handler:  UnwatchHandler(tm),
-		ReadOnly: true,
+		Name:      "UNWATCH",
+		Arity:     0,
+		Handler:   UnwatchHandler(tm),
+		ReadOnly:  true,
+		ACLGroups: []AclGroup{AclFast, AclTransaction},
 	})
 
 	// Store transaction manager in registry for access by other commands
@@ -0,0 +1,110 @@
+package glob
+
+// Match reports whether str matches the Redis-style glob pattern.
+// Supported syntax: '*' (any sequence), '?' (any single byte),
+// '[abc]', '[^abc]', '[a-z]' character classes and '\' escapes.
+func Match(pattern, str string) bool {
+	return match(pattern, str, false)
+}
+
+// MatchNoCase is like Match but compares ASCII letters case-insensitively.
+func MatchNoCase(pattern, str string) bool {
+	return match(pattern, str, true)
+}
+
+func match(pattern, str string, nocase bool) bool {
+	p, s := 0, 0
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			// Collapse consecutive stars
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for i := s; i <= len(str); i++ {
+				if match(pattern[p+1:], str[i:], nocase) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s >= len(str) {
+				return false
+			}
+			s++
+		case '[':
+			if s >= len(str) {
+				return false
+			}
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+			matched := false
+			for p < len(pattern) && pattern[p] != ']' {
+				switch {
+				case pattern[p] == '\\' && p+1 < len(pattern):
+					p++
+					if equalByte(pattern[p], str[s], nocase) {
+						matched = true
+					}
+				case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+					lo, hi := pattern[p], pattern[p+2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					c := str[s]
+					if nocase {
+						lo, hi, c = lower(lo), lower(hi), lower(c)
+					}
+					if c >= lo && c <= hi {
+						matched = true
+					}
+					p += 2
+				default:
+					if equalByte(pattern[p], str[s], nocase) {
+						matched = true
+					}
+				}
+				p++
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			s++
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+		default:
+			if s >= len(str) || !equalByte(pattern[p], str[s], nocase) {
+				return false
+			}
+			s++
+		}
+		p++
+	}
+	return s == len(str)
+}
+
+func equalByte(a, b byte, nocase bool) bool {
+	if nocase {
+		return lower(a) == lower(b)
+	}
+	return a == b
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -34,24 +36,49 @@ func Init(level LogLevel) {
 	})
 
 	// Set log level
+	log.SetLevel(logrusLevel(level))
+}
+
+func logrusLevel(level LogLevel) logrus.Level {
 	switch level {
 	case DebugLevel:
-		log.SetLevel(logrus.DebugLevel)
+		return logrus.DebugLevel
 	case InfoLevel:
-		log.SetLevel(logrus.InfoLevel)
+		return logrus.InfoLevel
 	case WarnLevel:
-		log.SetLevel(logrus.WarnLevel)
+		return logrus.WarnLevel
 	case ErrorLevel:
-		log.SetLevel(logrus.ErrorLevel)
+		return logrus.ErrorLevel
 	case PanicLevel:
-		log.SetLevel(logrus.PanicLevel)
+		return logrus.PanicLevel
 	case FatalLevel:
-		log.SetLevel(logrus.FatalLevel)
+		return logrus.FatalLevel
 	default:
-		log.SetLevel(logrus.InfoLevel)
+		return logrus.InfoLevel
 	}
 }
 
+// ParseLevel parses a log level name. Besides the LogLevel names it accepts
+// the redis.conf loglevel values: verbose, notice and warning.
+func ParseLevel(name string) (LogLevel, error) {
+	switch level := LogLevel(strings.ToLower(name)); level {
+	case DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel:
+		return level, nil
+	case "verbose":
+		return DebugLevel, nil
+	case "notice":
+		return InfoLevel, nil
+	case "warning":
+		return WarnLevel, nil
+	}
+	return "", fmt.Errorf("invalid log level %q", name)
+}
+
+// SetLevel changes the level of the logger
+func SetLevel(level LogLevel) {
+	Get().SetLevel(logrusLevel(level))
+}
+
 // Get returns the logger instance
 func Get() *logrus.Logger {
 	if log == nil {
@@ -121,8 +148,9 @@ func WithFields(fields logrus.Fields) *logrus.Entry {
 	return Get().WithFields(fields)
 }
 
+// GetLevel returns the level of the logger
 func GetLevel() LogLevel {
-	switch logrus.GetLevel() {
+	switch Get().GetLevel() {
 	case logrus.DebugLevel:
 		return DebugLevel
 	case logrus.InfoLevel:
@@ -41,6 +41,23 @@ func TestLoggerInitialization(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	log = nil
+	Init(InfoLevel)
+
+	for name, want := range map[string]LogLevel{"DEBUG": DebugLevel, "warn": WarnLevel, "notice": InfoLevel, "warning": WarnLevel, "verbose": DebugLevel} {
+		level, err := ParseLevel(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, level)
+	}
+	_, err := ParseLevel("loud")
+	assert.Error(t, err)
+
+	SetLevel(ErrorLevel)
+	assert.Equal(t, ErrorLevel, GetLevel())
+	assert.Equal(t, logrus.ErrorLevel, Get().GetLevel())
+}
+
 func TestGetDefaultInitialization(t *testing.T) {
 	// Reset logger to nil
 	log = nil
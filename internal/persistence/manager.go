@@ -22,6 +22,10 @@ type Config struct {
 	AOFRewriteConfig *aof.RewriteConfig
 	RDBEnabled       bool
 	RDBSaveConfig    *RDBSaveConfig
+
+	// SaveRules trigger background RDB saves; RDBSaveConfig, when set,
+	// adds one more rule
+	SaveRules []SaveRule
 }
 
 type RDBSaveConfig struct {
@@ -29,36 +33,76 @@ type RDBSaveConfig struct {
 	MinChanges   int
 }
 
+// SaveRule is a redis.conf "save <seconds> <changes>" rule: a background
+// save starts once Changes writes happened and Interval passed since the
+// last save
+type SaveRule struct {
+	Interval time.Duration
+	Changes  int
+}
+
+// saveCheckInterval is how often the save rules are checked
+const saveCheckInterval = 100 * time.Millisecond
+
 type cmd struct {
+	db   int
 	cmd  string
 	args []string
 }
 
+// ReplayFunc executes a command read back from the AOF
+type ReplayFunc func(name string, args []string) error
+
 type Manager struct {
 	config *Config
-	db     store.DataStore
+	dbs    []store.DataStore
 	aof    *aof.Writer
 	mu     sync.RWMutex
+	replay ReplayFunc
 
 	// RDB state
 	lastSave      time.Time
+	lastSaveErr   error // result of the last save attempt
 	changesSince  int64
 	stopChan      chan struct{}
 	bgSaveRunning int32 // 0 = idle, 1 = running
 
+	// Settings changed at runtime through CONFIG SET
+	settingsMu sync.Mutex
+	saveRules  []SaveRule
+
 	// Pipeline command batching for AOF
 	commandBatch []*cmd
 	batchMu      sync.Mutex
+
+	// Database the AOF stream has selected; -1 forces the next command to
+	// be preceded by a SELECT
+	aofDB int
+	aofMu sync.Mutex
 }
 
 func NewManager(config *Config, db store.DataStore) (*Manager, error) {
+	return NewManagerWithDatabases(config, []store.DataStore{db})
+}
+
+// NewManagerWithDatabases creates a manager persisting several numbered
+// databases; dbs[i] is database i
+func NewManagerWithDatabases(config *Config, dbs []store.DataStore) (*Manager, error) {
 	logger.Infof("Initializing persistence manager with directory: %s", config.Dir)
 
 	m := &Manager{
 		config:       config,
-		db:           db,
+		dbs:          dbs,
 		stopChan:     make(chan struct{}),
 		commandBatch: make([]*cmd, 0),
+		aofDB:        -1,
+	}
+	m.saveRules = append(m.saveRules, config.SaveRules...)
+	if config.RDBSaveConfig != nil {
+		m.saveRules = append(m.saveRules, SaveRule{
+			Interval: config.RDBSaveConfig.SaveInterval,
+			Changes:  config.RDBSaveConfig.MinChanges,
+		})
 	}
 
 	// Create directory if it doesn't exist
@@ -80,8 +124,9 @@ func NewManager(config *Config, db store.DataStore) (*Manager, error) {
 		logger.Info("AOF writer initialized successfully")
 	}
 
-	// Start background RDB save if enabled
-	if config.RDBEnabled && config.RDBSaveConfig != nil {
+	// Start background RDB save if enabled; it keeps running without rules
+	// so that CONFIG SET save can add some
+	if config.RDBEnabled {
 		logger.Info("Starting background RDB save goroutine")
 		go m.backgroundRDBSave()
 	}
@@ -95,6 +140,14 @@ func NewManager(config *Config, db store.DataStore) (*Manager, error) {
 	return m, nil
 }
 
+// SetReplayFunc makes AOF loading execute every command through fn instead
+// of the built-in handling of SET and DEL
+func (m *Manager) SetReplayFunc(fn ReplayFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replay = fn
+}
+
 func (m *Manager) LoadData() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -140,7 +193,7 @@ func (m *Manager) loadFromRDB(path string) error {
 		_ = reader.Close()
 	}()
 
-	return reader.ReadAll(m.db)
+	return reader.ReadAllDatabases(m.dbs)
 }
 
 func (m *Manager) loadFromAOF() error {
@@ -167,17 +220,23 @@ func (m *Manager) loadFromAOF() error {
 
 	// Replay commands
 	return loader.Replay(func(cmd aof.Command) error {
+		if m.replay != nil {
+			if err := m.replay(cmd.Name, cmd.Args); err != nil {
+				logger.Warnf("Failed to replay AOF command %s: %v", cmd.Name, err)
+			}
+			return nil
+		}
 		// Convert AOF command to store operation (case-insensitive)
 		switch strings.ToUpper(cmd.Name) {
 		case "SET":
 			if len(cmd.Args) >= 2 {
 				// For simplicity, we'll set without expiration
 				// In a full implementation, you'd parse EX/PX options
-				m.db.Set(cmd.Args[0], cmd.Args[1], time.Time{})
+				m.dbs[0].Set(cmd.Args[0], cmd.Args[1], time.Time{})
 			}
 		case "DEL":
 			if len(cmd.Args) >= 1 {
-				m.db.Del(cmd.Args[0])
+				m.dbs[0].Del(cmd.Args[0])
 			}
 		}
 		return nil
@@ -185,6 +244,12 @@ func (m *Manager) loadFromAOF() error {
 }
 
 func (m *Manager) AppendMultiCommands(cmdName string, args []string) error {
+	return m.AppendMultiCommandsDB(0, cmdName, args)
+}
+
+// AppendMultiCommandsDB batches a pipelined write command executed in
+// database db until FlushMultiCommand
+func (m *Manager) AppendMultiCommandsDB(db int, cmdName string, args []string) error {
 	if !m.config.AOFEnabled {
 		return nil
 	}
@@ -194,6 +259,7 @@ func (m *Manager) AppendMultiCommands(cmdName string, args []string) error {
 	defer m.batchMu.Unlock()
 
 	m.commandBatch = append(m.commandBatch, &cmd{
+		db:   db,
 		cmd:  cmdName,
 		args: args,
 	})
@@ -202,13 +268,21 @@ func (m *Manager) AppendMultiCommands(cmdName string, args []string) error {
 }
 
 func (m *Manager) AppendCommand(cmd string, args []string) error {
+	return m.AppendCommandDB(0, cmd, args)
+}
+
+// AppendCommandDB logs a write command executed in database db
+func (m *Manager) AppendCommandDB(db int, cmd string, args []string) error {
 	switch {
 
 	case m.config.AOFEnabled && m.config.RDBEnabled:
 		atomic.AddInt64(&m.changesSince, 1)
 		fallthrough
 	case m.config.AOFEnabled:
-		var n = EncodeRESPArrayFast(cmd, args)
+		m.aofMu.Lock()
+		defer m.aofMu.Unlock()
+		var n = m.selectDB(nil, db)
+		n = append(n, EncodeRESPArrayFast(cmd, args)...)
 		if err := m.aof.Append(n); err != nil {
 			return err
 		}
@@ -222,6 +296,16 @@ func (m *Manager) AppendCommand(cmd string, args []string) error {
 	}
 }
 
+// selectDB appends a SELECT to buf when the AOF stream is not in database
+// db yet. The caller must hold aofMu.
+func (m *Manager) selectDB(buf []byte, db int) []byte {
+	if db == m.aofDB {
+		return buf
+	}
+	m.aofDB = db
+	return append(buf, EncodeRESPArrayFast("SELECT", []string{strconv.Itoa(db)})...)
+}
+
 func (m *Manager) FlushMultiCommand() error {
 	if !m.config.AOFEnabled {
 		return nil
@@ -235,9 +319,13 @@ func (m *Manager) FlushMultiCommand() error {
 		return nil
 	}
 
+	m.aofMu.Lock()
+	defer m.aofMu.Unlock()
+
 	// Encode all commands in batch at once - much more efficient
 	var batchData []byte
 	for _, command := range m.commandBatch {
+		batchData = m.selectDB(batchData, command.db)
 		cmdData := EncodeRESPArrayFast(command.cmd, command.args)
 		batchData = append(batchData, cmdData...)
 	}
@@ -255,6 +343,14 @@ func (m *Manager) FlushMultiCommand() error {
 	return nil
 }
 
+// resetAOFDB makes the next logged command select its database again, for
+// when the AOF no longer ends in the database last selected
+func (m *Manager) resetAOFDB() {
+	m.aofMu.Lock()
+	m.aofDB = -1
+	m.aofMu.Unlock()
+}
+
 // ClearData clears all persistence files
 func (m *Manager) ClearData() error {
 	m.mu.Lock()
@@ -267,6 +363,7 @@ func (m *Manager) ClearData() error {
 			return fmt.Errorf("failed to truncate AOF: %w", err)
 		}
 		logger.Info("AOF file truncated due to FLUSHDB")
+		m.resetAOFDB()
 	}
 
 	// Clear RDB file if it exists
@@ -287,24 +384,31 @@ func (m *Manager) ClearData() error {
 }
 
 func (m *Manager) backgroundRDBSave() {
-	ticker := time.NewTicker(m.config.RDBSaveConfig.SaveInterval)
+	ticker := time.NewTicker(saveCheckInterval)
 	defer ticker.Stop()
 
-	logger.Infof("Background RDB save started with interval: %v", m.config.RDBSaveConfig.SaveInterval)
+	logger.Infof("Background RDB save started with rules: %v", m.SaveRules())
 
 	for {
 		select {
 		case <-ticker.C:
 			changes := atomic.LoadInt64(&m.changesSince)
-			if changes >= int64(m.config.RDBSaveConfig.MinChanges) {
-				logger.Debugf("Triggering background RDB save with %d changes", changes)
-				// Call SaveRDB which will handle its own locking
-				if err := m.SaveRDB(); err != nil {
-					// Log error but continue
-					logger.Errorf("Background RDB save failed: %v", err)
-				} else {
-					logger.Info("Background RDB save completed successfully")
-				}
+			if changes == 0 || atomic.LoadInt32(&m.bgSaveRunning) == 1 {
+				continue
+			}
+			m.mu.RLock()
+			elapsed := time.Since(m.lastSave)
+			m.mu.RUnlock()
+			if !m.saveDue(changes, elapsed) {
+				continue
+			}
+			logger.Debugf("Triggering background RDB save with %d changes", changes)
+			// Call SaveRDB which will handle its own locking
+			if err := m.SaveRDB(); err != nil {
+				// Log error but continue
+				logger.Errorf("Background RDB save failed: %v", err)
+			} else {
+				logger.Info("Background RDB save completed successfully")
 			}
 		case <-m.stopChan:
 			logger.Info("Background RDB save stopped")
@@ -313,6 +417,57 @@ func (m *Manager) backgroundRDBSave() {
 	}
 }
 
+// saveDue reports whether a save rule is met
+func (m *Manager) saveDue(changes int64, elapsed time.Duration) bool {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	for _, rule := range m.saveRules {
+		if changes >= int64(rule.Changes) && elapsed >= rule.Interval {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveRules returns the rules triggering background RDB saves
+func (m *Manager) SaveRules() []SaveRule {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	return append([]SaveRule(nil), m.saveRules...)
+}
+
+// SetSaveRules replaces the rules triggering background RDB saves; no rules
+// disable them
+func (m *Manager) SetSaveRules(rules []SaveRule) error {
+	if !m.config.RDBEnabled && len(rules) > 0 {
+		return fmt.Errorf("RDB persistence is disabled")
+	}
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.saveRules = append([]SaveRule(nil), rules...)
+	return nil
+}
+
+// AOFSyncMode returns when the AOF is fsynced
+func (m *Manager) AOFSyncMode() aof.SyncMode {
+	if m.aof != nil {
+		return m.aof.SyncMode()
+	}
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	return m.config.AOFSyncMode
+}
+
+// SetAOFSyncMode changes when the AOF is fsynced
+func (m *Manager) SetAOFSyncMode(mode aof.SyncMode) {
+	m.settingsMu.Lock()
+	m.config.AOFSyncMode = mode
+	m.settingsMu.Unlock()
+	if m.aof != nil {
+		m.aof.SetSyncMode(mode)
+	}
+}
+
 func (m *Manager) backgroundAOFRewriteCheck() {
 	ticker := time.NewTicker(10 * time.Second) // Check every 10 seconds
 	defer ticker.Stop()
@@ -324,10 +479,18 @@ func (m *Manager) backgroundAOFRewriteCheck() {
 		case <-ticker.C:
 			if m.aof != nil {
 				logger.Infof("Background AOF rewrite check started")
-				if err := m.aof.CheckAndRewrite(m.db); err != nil {
+				stores := make([]aof.Store, len(m.dbs))
+				for i, db := range m.dbs {
+					stores[i] = db
+				}
+				// Commands logged while the file is replaced must not rely
+				// on an earlier SELECT
+				m.resetAOFDB()
+				if err := m.aof.CheckAndRewriteDatabases(stores); err != nil {
 					// Log error but continue
 					logger.Errorf("Background AOF rewrite failed: %v", err)
 				}
+				m.resetAOFDB()
 			}
 		case <-m.stopChan:
 			logger.Info("Background AOF rewrite check stopped")
@@ -336,8 +499,9 @@ func (m *Manager) backgroundAOFRewriteCheck() {
 	}
 }
 
-func (m *Manager) saveRDB() error {
+func (m *Manager) saveRDB() (err error) {
 	// Don't lock here since this is called from methods that already hold the lock
+	defer func() { m.lastSaveErr = err }()
 	rdbPath := filepath.Join(m.config.Dir, "dump.rdb")
 	logger.Infof("Saving RDB to %s", rdbPath)
 
@@ -352,93 +516,11 @@ func (m *Manager) saveRDB() error {
 		}
 	}()
 
-	// Get all keys and write them
-	keys := m.db.Keys()
-	logger.Debugf("Writing %d keys to RDB", len(keys))
-
-	var ttlCount = 0
-	for _, key := range keys {
-		ttl := m.db.TTL(key)
-		if ttl > 0 {
-			ttlCount++
-		}
-	}
-
-	// Write header
-	if err := writer.WriteHeader(uint64(len(keys)), uint64(ttlCount)); err != nil {
-		logger.Errorf("Failed to write RDB header: %v", err)
+	keys, err := m.writeDatabases(writer)
+	if err != nil {
 		return err
 	}
 
-	for _, key := range keys {
-		// Get expiration
-		ttl := m.db.TTL(key)
-		var expiration time.Time
-		if ttl > 0 {
-			expiration = time.Now().Add(time.Duration(ttl) * time.Second)
-		}
-
-		// Persist all supported data structures
-		v := m.db.GetDataType(key)
-		switch v {
-		case store.TypeString:
-			if value, exists := m.db.Get(key); exists {
-				if err := writer.WriteString(key, value, expiration); err != nil {
-					logger.Errorf("Failed to write string key '%s' to RDB: %v", key, err)
-					return err
-				}
-			}
-		case store.TypeList:
-			lst := m.db.GetOrCreateList(key)
-			vals := lst.LRange(0, -1)
-			if err := writer.WriteList(key, vals, expiration); err != nil {
-				logger.Errorf("Failed to write list key '%s' to RDB: %v", key, err)
-				return err
-			}
-		case store.TypeSet:
-			set := m.db.GetOrCreateSet(key)
-			members := set.SMembers()
-			if err := writer.WriteSet(key, members, expiration); err != nil {
-				logger.Errorf("Failed to write set key '%s' to RDB: %v", key, err)
-				return err
-			}
-		case store.TypeHash:
-			h := m.db.GetOrCreateHash(key)
-			fields := h.HGetAll()
-			if err := writer.WriteHash(key, fields, expiration); err != nil {
-				logger.Errorf("Failed to write hash key '%s' to RDB: %v", key, err)
-				return err
-			}
-		case store.TypeSortedSet:
-			z := m.db.GetOrCreateSortedSet(key)
-			pairs := make(map[string]float64)
-			arr := z.ZRange(0, -1, true)
-			for i := 0; i+1 < len(arr); i += 2 {
-				if s, err := strconv.ParseFloat(arr[i+1], 64); err == nil {
-					pairs[arr[i]] = s
-				}
-			}
-			if err := writer.WriteZSet(key, pairs, expiration); err != nil {
-				logger.Errorf("Failed to write zset key '%s' to RDB: %v", key, err)
-				return err
-			}
-		case store.TypeStream:
-			st := m.db.GetOrCreateStream(key)
-			entries := st.XRange(store.StreamID{Ms: 0, Seq: 0}, store.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}, 0)
-			wEntries := make([]store.StreamEntry, len(entries))
-			for i, e := range entries {
-				wEntries[i] = store.StreamEntry{ID: e.ID, Fields: e.Fields}
-			}
-			if err := writer.WriteStream(key, wEntries, expiration); err != nil {
-				logger.Errorf("Failed to write stream key '%s' to RDB: %v", key, err)
-				return err
-			}
-		default:
-			fmt.Println("unsupported")
-			// Unknown/unsupported type – skip
-		}
-	}
-
 	// Write EOF
 	if err := writer.WriteEOF(); err != nil {
 		logger.Errorf("Failed to write RDB EOF: %v", err)
@@ -449,7 +531,7 @@ func (m *Manager) saveRDB() error {
 	atomic.StoreInt64(&m.changesSince, 0)
 	m.lastSave = time.Now()
 
-	logger.Infof("RDB save completed successfully with %d keys", len(keys))
+	logger.Infof("RDB save completed successfully with %d keys", keys)
 
 	return nil
 }
@@ -493,92 +575,10 @@ func (m *Manager) GenerateRDBData() ([]byte, error) {
 		}
 	}()
 
-	// Get all keys and write them
-	keys := m.db.Keys()
-	logger.Debugf("Writing %d keys to RDB for replication", len(keys))
-
-	var ttlCount = 0
-	for _, key := range keys {
-		ttl := m.db.TTL(key)
-		if ttl > 0 {
-			ttlCount++
-		}
-	}
-
-	// Write header
-	if err := writer.WriteHeader(uint64(len(keys)), uint64(ttlCount)); err != nil {
-		logger.Errorf("Failed to write RDB header: %v", err)
+	if _, err := m.writeDatabases(writer); err != nil {
 		return nil, err
 	}
 
-	for _, key := range keys {
-		// Get expiration
-		ttl := m.db.TTL(key)
-		var expiration time.Time
-		if ttl > 0 {
-			expiration = time.Now().Add(time.Duration(ttl) * time.Second)
-		}
-
-		// Persist all supported data structures
-		v := m.db.GetDataType(key)
-		switch v {
-		case store.TypeString:
-			if value, exists := m.db.Get(key); exists {
-				if err := writer.WriteString(key, value, expiration); err != nil {
-					logger.Errorf("Failed to write string key '%s' to RDB: %v", key, err)
-					return nil, err
-				}
-			}
-		case store.TypeList:
-			lst := m.db.GetOrCreateList(key)
-			vals := lst.LRange(0, -1)
-			if err := writer.WriteList(key, vals, expiration); err != nil {
-				logger.Errorf("Failed to write list key '%s' to RDB: %v", key, err)
-				return nil, err
-			}
-		case store.TypeSet:
-			set := m.db.GetOrCreateSet(key)
-			members := set.SMembers()
-			if err := writer.WriteSet(key, members, expiration); err != nil {
-				logger.Errorf("Failed to write set key '%s' to RDB: %v", key, err)
-				return nil, err
-			}
-		case store.TypeHash:
-			h := m.db.GetOrCreateHash(key)
-			fields := h.HGetAll()
-			if err := writer.WriteHash(key, fields, expiration); err != nil {
-				logger.Errorf("Failed to write hash key '%s' to RDB: %v", key, err)
-				return nil, err
-			}
-		case store.TypeSortedSet:
-			z := m.db.GetOrCreateSortedSet(key)
-			pairs := make(map[string]float64)
-			arr := z.ZRange(0, -1, true)
-			for i := 0; i+1 < len(arr); i += 2 {
-				if s, err := strconv.ParseFloat(arr[i+1], 64); err == nil {
-					pairs[arr[i]] = s
-				}
-			}
-			if err := writer.WriteZSet(key, pairs, expiration); err != nil {
-				logger.Errorf("Failed to write zset key '%s' to RDB: %v", key, err)
-				return nil, err
-			}
-		case store.TypeStream:
-			st := m.db.GetOrCreateStream(key)
-			entries := st.XRange(store.StreamID{Ms: 0, Seq: 0}, store.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}, 0)
-			wEntries := make([]store.StreamEntry, len(entries))
-			for i, e := range entries {
-				wEntries[i] = store.StreamEntry{ID: e.ID, Fields: e.Fields}
-			}
-			if err := writer.WriteStream(key, wEntries, expiration); err != nil {
-				logger.Errorf("Failed to write stream key '%s' to RDB: %v", key, err)
-				return nil, err
-			}
-		default:
-			logger.Warnf("Unknown data type for key '%s': %v", key, v)
-		}
-	}
-
 	if err := writer.WriteEOF(); err != nil {
 		logger.Errorf("Failed to write RDB EOF: %v", err)
 		return nil, err
@@ -598,6 +598,114 @@ func (m *Manager) GenerateRDBData() ([]byte, error) {
 	return rdbData, nil
 }
 
+// writeDatabases writes the aux fields and every non-empty database to
+// writer, returning the number of keys written
+func (m *Manager) writeDatabases(writer *rdb.Writer) (int, error) {
+	if err := writer.WriteAux(); err != nil {
+		logger.Errorf("Failed to write RDB header: %v", err)
+		return 0, err
+	}
+
+	total := 0
+	for index, db := range m.dbs {
+		keys := db.Keys()
+		if len(keys) == 0 {
+			continue
+		}
+		logger.Debugf("Writing %d keys of database %d to RDB", len(keys), index)
+
+		var ttlCount = 0
+		for _, key := range keys {
+			ttl := db.TTL(key)
+			if ttl > 0 {
+				ttlCount++
+			}
+		}
+
+		if err := writer.WriteDBHeader(index, uint64(len(keys)), uint64(ttlCount)); err != nil {
+			logger.Errorf("Failed to write RDB database header: %v", err)
+			return 0, err
+		}
+
+		for _, key := range keys {
+			if err := writeKey(writer, db, key); err != nil {
+				return 0, err
+			}
+		}
+		total += len(keys)
+	}
+	return total, nil
+}
+
+// writeKey writes key of db with its expiration
+func writeKey(writer *rdb.Writer, db store.DataStore, key string) error {
+	// Get expiration
+	var expiration time.Time
+	if ms := db.PExpireTime(key); ms > 0 {
+		expiration = time.UnixMilli(ms)
+	}
+
+	// Persist all supported data structures
+	v := db.GetDataType(key)
+	switch v {
+	case store.TypeString:
+		if value, exists := db.Get(key); exists {
+			if err := writer.WriteString(key, value, expiration); err != nil {
+				logger.Errorf("Failed to write string key '%s' to RDB: %v", key, err)
+				return err
+			}
+		}
+	case store.TypeList:
+		lst := db.GetOrCreateList(key)
+		vals := lst.LRange(0, -1)
+		if err := writer.WriteList(key, vals, expiration); err != nil {
+			logger.Errorf("Failed to write list key '%s' to RDB: %v", key, err)
+			return err
+		}
+	case store.TypeSet:
+		set := db.GetOrCreateSet(key)
+		members := set.SMembers()
+		if err := writer.WriteSet(key, members, expiration); err != nil {
+			logger.Errorf("Failed to write set key '%s' to RDB: %v", key, err)
+			return err
+		}
+	case store.TypeHash:
+		h := db.GetOrCreateHash(key)
+		fields := h.HGetAll()
+		if err := writer.WriteHash(key, fields, expiration); err != nil {
+			logger.Errorf("Failed to write hash key '%s' to RDB: %v", key, err)
+			return err
+		}
+	case store.TypeSortedSet:
+		z := db.GetOrCreateSortedSet(key)
+		pairs := make(map[string]float64)
+		arr := z.ZRange(0, -1, true)
+		for i := 0; i+1 < len(arr); i += 2 {
+			if s, err := strconv.ParseFloat(arr[i+1], 64); err == nil {
+				pairs[arr[i]] = s
+			}
+		}
+		if err := writer.WriteZSet(key, pairs, expiration); err != nil {
+			logger.Errorf("Failed to write zset key '%s' to RDB: %v", key, err)
+			return err
+		}
+	case store.TypeStream:
+		st := db.GetOrCreateStream(key)
+		entries := st.XRange(store.StreamID{Ms: 0, Seq: 0}, store.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}, 0)
+		wEntries := make([]store.StreamEntry, len(entries))
+		for i, e := range entries {
+			wEntries[i] = store.StreamEntry{ID: e.ID, Fields: e.Fields}
+		}
+		if err := writer.WriteStreamWithGroups(key, wEntries, st.Meta(), st.Groups(), expiration); err != nil {
+			logger.Errorf("Failed to write stream key '%s' to RDB: %v", key, err)
+			return err
+		}
+	default:
+		logger.Warnf("Unknown data type for key '%s': %v", key, v)
+	}
+	return nil
+}
+
 // BGSaveAsync starts an asynchronous background RDB save.
 // Returns error if a background save is already in progress.
 func (m *Manager) BGSaveAsync() error {
@@ -661,6 +769,9 @@ func (m *Manager) Stats() map[string]interface{} {
 		"rdb_enabled":   m.config.RDBEnabled,
 		"changes_since": int(atomic.LoadInt64(&m.changesSince)),
 		"last_save":     m.lastSave,
+
+		"last_save_ok":       m.lastSaveErr == nil,
+		"bgsave_in_progress": atomic.LoadInt32(&m.bgSaveRunning) == 1,
 	}
 
 	if m.aof != nil {
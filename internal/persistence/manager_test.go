@@ -500,6 +500,39 @@ func TestManagerBackgroundRDBSave(t *testing.T) {
 	})
 }
 
+func TestManagerSaveRules(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{Dir: dir, RDBEnabled: true}
+	db := store.NewUltraOptimizedDB()
+	manager, err := NewManager(&config, db)
+	require.NoError(t, err)
+	defer manager.Close()
+	require.Empty(t, manager.SaveRules())
+
+	// Without rules changes are not saved
+	db.Set("k", "v", time.Time{})
+	require.NoError(t, manager.AppendCommand("SET", []string{"k", "v"}))
+	time.Sleep(3 * saveCheckInterval)
+	_, err = os.Stat(filepath.Join(dir, "dump.rdb"))
+	require.True(t, os.IsNotExist(err))
+
+	rules := []SaveRule{{Interval: time.Hour, Changes: 1}, {Interval: 0, Changes: 1}}
+	require.NoError(t, manager.SetSaveRules(rules))
+	assert.Equal(t, rules, manager.SaveRules())
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "dump.rdb"))
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	manager.SetAOFSyncMode(aof.No)
+	assert.Equal(t, aof.No, manager.AOFSyncMode())
+
+	disabled, err := NewManager(&Config{Dir: t.TempDir()}, db)
+	require.NoError(t, err)
+	defer disabled.Close()
+	require.EqualError(t, disabled.SetSaveRules(rules), "RDB persistence is disabled")
+}
+
 func TestManagerBackgroundAOFRewriteCheck(t *testing.T) {
 	t.Run("backgroundAOFRewriteCheck basic test", func(t *testing.T) {
 		config := Config{
@@ -522,3 +555,49 @@ func TestManagerBackgroundAOFRewriteCheck(t *testing.T) {
 		close(manager.stopChan)
 	})
 }
+
+func TestManagerMultipleDatabases(t *testing.T) {
+	dir := t.TempDir()
+	dbs := store.NewDatabases(3)
+	defer dbs.Close()
+	dbs.DB(0).Set("zero", "0", time.Time{})
+	dbs.DB(2).Set("two", "2", time.Time{})
+	_, err := dbs.DB(2).EnsureList("list")
+	require.NoError(t, err)
+	dbs.DB(2).GetOrCreateList("list").RPush("x")
+
+	config := &Config{Dir: dir, AOFEnabled: true, AOFSyncMode: aof.Always, RDBEnabled: true}
+	manager, err := NewManagerWithDatabases(config, dbs.Stores())
+	require.NoError(t, err)
+
+	require.NoError(t, manager.AppendCommandDB(0, "SET", []string{"zero", "0"}))
+	require.NoError(t, manager.AppendCommandDB(2, "SET", []string{"two", "2"}))
+	require.NoError(t, manager.AppendMultiCommandsDB(2, "DEL", []string{"gone"}))
+	require.NoError(t, manager.AppendMultiCommandsDB(1, "DEL", []string{"gone"}))
+	require.NoError(t, manager.FlushMultiCommand())
+	require.NoError(t, manager.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "appendonly.aof"))
+	require.NoError(t, err)
+	want := string(EncodeRESPArrayFast("SELECT", []string{"0"})) +
+		string(EncodeRESPArrayFast("SET", []string{"zero", "0"})) +
+		string(EncodeRESPArrayFast("SELECT", []string{"2"})) +
+		string(EncodeRESPArrayFast("SET", []string{"two", "2"})) +
+		string(EncodeRESPArrayFast("DEL", []string{"gone"})) +
+		string(EncodeRESPArrayFast("SELECT", []string{"1"})) +
+		string(EncodeRESPArrayFast("DEL", []string{"gone"}))
+	assert.Equal(t, want, string(data))
+
+	// The RDB written on close keeps every key in its database
+	loaded := store.NewDatabases(3)
+	defer loaded.Close()
+	reloader, err := NewManagerWithDatabases(&Config{Dir: dir, RDBEnabled: true}, loaded.Stores())
+	require.NoError(t, err)
+	require.NoError(t, reloader.LoadData())
+	keys, _ := loaded.KeyspaceStats()
+	assert.Equal(t, []int{1, 0, 2}, keys)
+	v, ok := loaded.DB(2).Get("two")
+	require.True(t, ok)
+	assert.Equal(t, "2", v)
+	assert.Equal(t, []string{"x"}, loaded.DB(2).GetOrCreateList("list").LRange(0, -1))
+}
@@ -0,0 +1,181 @@
+package pubsub
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// NotifyFlags selects which keyspace notifications are published, using the
+// classes of the notify-keyspace-events setting
+type NotifyFlags uint32
+
+const (
+	NotifyKeyspace NotifyFlags = 1 << iota // K: __keyspace@<db>__:<key>
+	NotifyKeyevent                         // E: __keyevent@<db>__:<event>
+	NotifyGeneric                          // g: DEL, EXPIRE, RENAME, ...
+	NotifyString                           // $
+	NotifyList                             // l
+	NotifySet                              // s
+	NotifyHash                             // h
+	NotifyZSet                             // z
+	NotifyExpired                          // x
+	NotifyEvicted                          // e
+	NotifyStream                           // t
+	NotifyKeyMiss                          // m
+	NotifyModule                           // d
+	NotifyNew                              // n
+
+	// NotifyAll is the A alias; key misses and new keys must be requested explicitly
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash |
+		NotifyZSet | NotifyExpired | NotifyEvicted | NotifyStream | NotifyModule
+)
+
+// ErrInvalidNotifyFlags is returned by ParseNotifyFlags for unknown class characters
+var ErrInvalidNotifyFlags = errors.New("Invalid event class character. Use 'Ag$lshzxeKEtmdn'.")
+
+// classChars lists the class characters in the order String renders them
+var classChars = []struct {
+	c    byte
+	flag NotifyFlags
+}{
+	{'g', NotifyGeneric}, {'$', NotifyString}, {'l', NotifyList}, {'s', NotifySet},
+	{'h', NotifyHash}, {'z', NotifyZSet}, {'x', NotifyExpired}, {'e', NotifyEvicted},
+	{'t', NotifyStream}, {'d', NotifyModule},
+}
+
+// ParseNotifyFlags parses a notify-keyspace-events value such as "KEA" or "Elg"
+func ParseNotifyFlags(s string) (NotifyFlags, error) {
+	var flags NotifyFlags
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 'A':
+			flags |= NotifyAll
+		case 'K':
+			flags |= NotifyKeyspace
+		case 'E':
+			flags |= NotifyKeyevent
+		case 'm':
+			flags |= NotifyKeyMiss
+		case 'n':
+			flags |= NotifyNew
+		default:
+			found := false
+			for _, cc := range classChars {
+				if cc.c == c {
+					flags |= cc.flag
+					found = true
+					break
+				}
+			}
+			if !found {
+				return 0, ErrInvalidNotifyFlags
+			}
+		}
+	}
+	return flags, nil
+}
+
+// String renders the flags the way CONFIG GET notify-keyspace-events reports them
+func (f NotifyFlags) String() string {
+	var b strings.Builder
+	if f&NotifyAll == NotifyAll {
+		b.WriteByte('A')
+	} else {
+		for _, cc := range classChars {
+			if f&cc.flag != 0 {
+				b.WriteByte(cc.c)
+			}
+		}
+	}
+	if f&NotifyKeyspace != 0 {
+		b.WriteByte('K')
+	}
+	if f&NotifyKeyevent != 0 {
+		b.WriteByte('E')
+	}
+	if f&NotifyKeyMiss != 0 {
+		b.WriteByte('m')
+	}
+	if f&NotifyNew != 0 {
+		b.WriteByte('n')
+	}
+	return b.String()
+}
+
+// eventClasses maps the key events reported by the store and the command
+// handlers to their notification class. Events missing here are not published.
+var eventClasses = map[string]NotifyFlags{
+	"del": NotifyGeneric, "expire": NotifyGeneric, "persist": NotifyGeneric,
+	"rename_from": NotifyGeneric, "rename_to": NotifyGeneric,
+	"move_from": NotifyGeneric, "move_to": NotifyGeneric,
+	"copy_to": NotifyGeneric, "restore": NotifyGeneric,
+
+	"set": NotifyString, "setrange": NotifyString, "incrby": NotifyString,
+	"incrbyfloat": NotifyString, "append": NotifyString,
+
+	"lpush": NotifyList, "rpush": NotifyList, "lpop": NotifyList, "rpop": NotifyList,
+	"linsert": NotifyList, "lset": NotifyList, "lrem": NotifyList, "ltrim": NotifyList,
+
+	"sadd": NotifySet, "srem": NotifySet, "spop": NotifySet,
+	"sinterstore": NotifySet, "sunionstore": NotifySet, "sdiffstore": NotifySet,
+
+	"hset": NotifyHash, "hdel": NotifyHash, "hincrby": NotifyHash, "hincrbyfloat": NotifyHash,
+
+	"zadd": NotifyZSet, "zincr": NotifyZSet, "zrem": NotifyZSet,
+	"zpopmin": NotifyZSet, "zpopmax": NotifyZSet,
+	"zremrangebyscore": NotifyZSet, "zremrangebyrank": NotifyZSet, "zremrangebylex": NotifyZSet,
+	"zinterstore": NotifyZSet, "zunionstore": NotifyZSet, "zdiffstore": NotifyZSet,
+	"zrangestore": NotifyZSet,
+
+	"xadd": NotifyStream, "xdel": NotifyStream, "xtrim": NotifyStream, "xsetid": NotifyStream,
+	"xgroup-create": NotifyStream, "xgroup-setid": NotifyStream, "xgroup-destroy": NotifyStream,
+	"xgroup-createconsumer": NotifyStream, "xgroup-delconsumer": NotifyStream,
+
+	"expired": NotifyExpired,
+	"evicted": NotifyEvicted,
+	"keymiss": NotifyKeyMiss,
+	"new":     NotifyNew,
+}
+
+// KeyspaceNotifier publishes key events on the __keyspace@<db>__ and
+// __keyevent@<db>__ channels of a hub. Notifications are disabled until
+// SetFlags enables a class and at least one of K and E.
+type KeyspaceNotifier struct {
+	hub   *Hub
+	flags atomic.Uint32
+}
+
+// NewKeyspaceNotifier creates a disabled notifier publishing to hub
+func NewKeyspaceNotifier(hub *Hub) *KeyspaceNotifier {
+	return &KeyspaceNotifier{hub: hub}
+}
+
+// Flags returns the enabled notification classes
+func (n *KeyspaceNotifier) Flags() NotifyFlags {
+	return NotifyFlags(n.flags.Load())
+}
+
+// SetFlags replaces the enabled notification classes
+func (n *KeyspaceNotifier) SetFlags(flags NotifyFlags) {
+	n.flags.Store(uint32(flags))
+}
+
+// Notify publishes event on key in database db if its class is enabled
+func (n *KeyspaceNotifier) Notify(db int, event, key string) {
+	flags := n.Flags()
+	if flags&(NotifyKeyspace|NotifyKeyevent) == 0 {
+		return
+	}
+	if class, ok := eventClasses[event]; !ok || flags&class == 0 {
+		return
+	}
+	prefix := strconv.Itoa(db) + "__:"
+	if flags&NotifyKeyspace != 0 {
+		n.hub.Publish("__keyspace@"+prefix+key, event)
+	}
+	if flags&NotifyKeyevent != 0 {
+		n.hub.Publish("__keyevent@"+prefix+event, key)
+	}
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotifyFlags(t *testing.T) {
+	flags, err := ParseNotifyFlags("KEA")
+	require.NoError(t, err)
+	assert.Equal(t, NotifyKeyspace|NotifyKeyevent|NotifyAll, flags)
+	assert.Equal(t, "AKE", flags.String())
+
+	flags, err = ParseNotifyFlags("Elg$xn")
+	require.NoError(t, err)
+	assert.Equal(t, "g$lxEn", flags.String())
+
+	flags, err = ParseNotifyFlags("")
+	require.NoError(t, err)
+	assert.Equal(t, NotifyFlags(0), flags)
+
+	_, err = ParseNotifyFlags("KEq")
+	assert.ErrorIs(t, err, ErrInvalidNotifyFlags)
+}
+
+func TestKeyspaceNotifier(t *testing.T) {
+	h := NewHub()
+	sub := NewSubscriber("s", 16)
+	h.PSubscribe(sub, "__key*__:*")
+	n := NewKeyspaceNotifier(h)
+
+	// Disabled by default
+	n.Notify(0, "set", "k")
+	assert.Empty(t, sub.Messages())
+
+	n.SetFlags(NotifyKeyspace | NotifyKeyevent | NotifyString)
+	n.Notify(3, "set", "k")
+	require.Len(t, sub.Messages(), 2)
+	assert.Equal(t, []string{"pmessage", "__key*__:*", "__keyspace@3__:k", "set"}, strs(<-sub.Messages()))
+	assert.Equal(t, []string{"pmessage", "__key*__:*", "__keyevent@3__:set", "k"}, strs(<-sub.Messages()))
+
+	// Classes that are not enabled and unknown events are not published
+	n.Notify(0, "lpush", "list")
+	n.Notify(0, "bogus", "k")
+	assert.Empty(t, sub.Messages())
+
+	// Only the keyevent channel with E alone
+	n.SetFlags(NotifyKeyevent | NotifyExpired)
+	n.Notify(0, "expired", "k")
+	require.Len(t, sub.Messages(), 1)
+	assert.Equal(t, "__keyevent@0__:expired", strs(<-sub.Messages())[2])
+}
@@ -0,0 +1,394 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"gridhouse/internal/glob"
+	"gridhouse/internal/resp"
+)
+
+// DefaultQueueSize is the number of undelivered messages a subscriber may
+// accumulate before it is considered a slow consumer and dropped.
+const DefaultQueueSize = 4096
+
+// Subscriber is a single connection's view of the pub/sub registry.
+// Messages are queued without blocking the publisher; a subscriber whose
+// queue is full is closed and removed from the hub.
+type Subscriber struct {
+	id       string
+	messages chan resp.Value
+	done     chan struct{}
+	once     sync.Once
+	dropped  atomic.Bool
+
+	// Subscription state, guarded by Hub.mu
+	channels      map[string]struct{}
+	patterns      map[string]struct{}
+	shardChannels map[string]struct{}
+}
+
+// NewSubscriber creates a subscriber with the given message queue size
+func NewSubscriber(id string, queueSize int) *Subscriber {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	return &Subscriber{
+		id:            id,
+		messages:      make(chan resp.Value, queueSize),
+		done:          make(chan struct{}),
+		channels:      make(map[string]struct{}),
+		patterns:      make(map[string]struct{}),
+		shardChannels: make(map[string]struct{}),
+	}
+}
+
+// ID returns the subscriber identifier
+func (s *Subscriber) ID() string { return s.id }
+
+// Messages returns the queue of pending messages for the connection writer
+func (s *Subscriber) Messages() <-chan resp.Value { return s.messages }
+
+// Done is closed when the subscriber has been closed
+func (s *Subscriber) Done() <-chan struct{} { return s.done }
+
+// Dropped reports whether the subscriber was closed for falling behind
+func (s *Subscriber) Dropped() bool { return s.dropped.Load() }
+
+// Close marks the subscriber as closed. It is safe to call multiple times.
+func (s *Subscriber) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// deliver enqueues a message without blocking. It reports false if the
+// subscriber is closed or its queue is full.
+func (s *Subscriber) deliver(msg resp.Value) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.messages <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Hub is the server-wide registry of channel, pattern and shard channel subscriptions
+type Hub struct {
+	mu            sync.RWMutex
+	channels      map[string]map[*Subscriber]struct{}
+	patterns      map[string]map[*Subscriber]struct{}
+	shardChannels map[string]map[*Subscriber]struct{}
+	clients       map[*Subscriber]struct{}
+}
+
+// NewHub creates an empty pub/sub registry
+func NewHub() *Hub {
+	return &Hub{
+		channels:      make(map[string]map[*Subscriber]struct{}),
+		patterns:      make(map[string]map[*Subscriber]struct{}),
+		shardChannels: make(map[string]map[*Subscriber]struct{}),
+		clients:       make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe subscribes to channels and returns one confirmation per channel
+func (h *Hub) Subscribe(sub *Subscriber, channels ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	replies := make([]resp.Value, 0, len(channels))
+	for _, ch := range channels {
+		add(h.channels, sub.channels, ch, sub)
+		replies = append(replies, reply("subscribe", ch, sub.count()))
+	}
+	h.track(sub)
+	return replies
+}
+
+// Unsubscribe removes channel subscriptions, or all of them when no channels are given
+func (h *Hub) Unsubscribe(sub *Subscriber, channels ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.remove(h.channels, sub.channels, sub, "unsubscribe", channels, sub.count)
+}
+
+// PSubscribe subscribes to glob patterns and returns one confirmation per pattern
+func (h *Hub) PSubscribe(sub *Subscriber, patterns ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	replies := make([]resp.Value, 0, len(patterns))
+	for _, p := range patterns {
+		add(h.patterns, sub.patterns, p, sub)
+		replies = append(replies, reply("psubscribe", p, sub.count()))
+	}
+	h.track(sub)
+	return replies
+}
+
+// PUnsubscribe removes pattern subscriptions, or all of them when no patterns are given
+func (h *Hub) PUnsubscribe(sub *Subscriber, patterns ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.remove(h.patterns, sub.patterns, sub, "punsubscribe", patterns, sub.count)
+}
+
+// SSubscribe subscribes to shard channels and returns one confirmation per channel
+func (h *Hub) SSubscribe(sub *Subscriber, channels ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	replies := make([]resp.Value, 0, len(channels))
+	for _, ch := range channels {
+		add(h.shardChannels, sub.shardChannels, ch, sub)
+		replies = append(replies, reply("ssubscribe", ch, sub.shardCount()))
+	}
+	h.track(sub)
+	return replies
+}
+
+// SUnsubscribe removes shard channel subscriptions, or all of them when none are given
+func (h *Hub) SUnsubscribe(sub *Subscriber, channels ...string) []resp.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.remove(h.shardChannels, sub.shardChannels, sub, "sunsubscribe", channels, sub.shardCount)
+}
+
+// Remove drops every subscription held by the subscriber
+func (h *Hub) Remove(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub)
+}
+
+func (h *Hub) removeLocked(sub *Subscriber) {
+	for ch := range sub.channels {
+		del(h.channels, sub.channels, ch, sub)
+	}
+	for p := range sub.patterns {
+		del(h.patterns, sub.patterns, p, sub)
+	}
+	for ch := range sub.shardChannels {
+		del(h.shardChannels, sub.shardChannels, ch, sub)
+	}
+	delete(h.clients, sub)
+}
+
+// Active reports whether the subscriber holds any subscription
+func (h *Hub) Active(sub *Subscriber) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return sub.count()+sub.shardCount() > 0
+}
+
+// Counts returns the number of channels, patterns and shard channels sub is
+// subscribed to
+func (h *Hub) Counts(sub *Subscriber) (channels, patterns, shardChannels int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(sub.channels), len(sub.patterns), len(sub.shardChannels)
+}
+
+// Clients returns the number of subscribers holding at least one subscription
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Publish delivers a message to channel and pattern subscribers and returns
+// the number of subscribers that received it. Subscribers that cannot keep up
+// are closed and removed.
+func (h *Hub) Publish(channel, message string) int {
+	h.mu.RLock()
+	var slow []*Subscriber
+	received := 0
+	msg := messageValue("message", channel, message)
+	for sub := range h.channels[channel] {
+		if sub.deliver(msg) {
+			received++
+		} else {
+			slow = append(slow, sub)
+		}
+	}
+	for pattern, subs := range h.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		pmsg := resp.Value{Type: resp.Push, Array: []resp.Value{
+			bulk("pmessage"), bulk(pattern), bulk(channel), bulk(message),
+		}}
+		for sub := range subs {
+			if sub.deliver(pmsg) {
+				received++
+			} else {
+				slow = append(slow, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+	h.drop(slow)
+	return received
+}
+
+// SPublish delivers a message to shard channel subscribers
+func (h *Hub) SPublish(channel, message string) int {
+	h.mu.RLock()
+	var slow []*Subscriber
+	received := 0
+	msg := messageValue("smessage", channel, message)
+	for sub := range h.shardChannels[channel] {
+		if sub.deliver(msg) {
+			received++
+		} else {
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+	h.drop(slow)
+	return received
+}
+
+// Channels returns the active channels matching pattern (all when pattern is empty)
+func (h *Hub) Channels(pattern string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return matching(h.channels, pattern)
+}
+
+// ShardChannels returns the active shard channels matching pattern
+func (h *Hub) ShardChannels(pattern string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return matching(h.shardChannels, pattern)
+}
+
+// NumSub returns the number of subscribers for each channel
+func (h *Hub) NumSub(channels ...string) []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	counts := make([]int, len(channels))
+	for i, ch := range channels {
+		counts[i] = len(h.channels[ch])
+	}
+	return counts
+}
+
+// ShardNumSub returns the number of subscribers for each shard channel
+func (h *Hub) ShardNumSub(channels ...string) []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	counts := make([]int, len(channels))
+	for i, ch := range channels {
+		counts[i] = len(h.shardChannels[ch])
+	}
+	return counts
+}
+
+// NumPat returns the number of distinct subscribed patterns
+func (h *Hub) NumPat() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.patterns)
+}
+
+// drop closes and unregisters slow consumers
+func (h *Hub) drop(slow []*Subscriber) {
+	if len(slow) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range slow {
+		select {
+		case <-sub.done:
+			// Already closed by its connection
+		default:
+			sub.dropped.Store(true)
+			sub.Close()
+		}
+		h.removeLocked(sub)
+	}
+}
+
+func (h *Hub) track(sub *Subscriber) {
+	if sub.count()+sub.shardCount() > 0 {
+		h.clients[sub] = struct{}{}
+	}
+}
+
+func (h *Hub) remove(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, sub *Subscriber, kind string, names []string, count func() int) []resp.Value {
+	if len(names) == 0 {
+		for name := range own {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return []resp.Value{{Type: resp.Push, Array: []resp.Value{
+			bulk(kind), {Type: resp.BulkString, IsNull: true}, {Type: resp.Integer, Int: int64(count())},
+		}}}
+	}
+	replies := make([]resp.Value, 0, len(names))
+	for _, name := range names {
+		del(index, own, name, sub)
+		replies = append(replies, reply(kind, name, count()))
+	}
+	if sub.count()+sub.shardCount() == 0 {
+		delete(h.clients, sub)
+	}
+	return replies
+}
+
+func (s *Subscriber) count() int { return len(s.channels) + len(s.patterns) }
+
+func (s *Subscriber) shardCount() int { return len(s.shardChannels) }
+
+func add(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string, sub *Subscriber) {
+	subs, ok := index[name]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		index[name] = subs
+	}
+	subs[sub] = struct{}{}
+	own[name] = struct{}{}
+}
+
+func del(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string, sub *Subscriber) {
+	delete(own, name)
+	if subs, ok := index[name]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(index, name)
+		}
+	}
+}
+
+func matching(index map[string]map[*Subscriber]struct{}, pattern string) []string {
+	names := make([]string, 0, len(index))
+	for name := range index {
+		if pattern == "" || glob.Match(pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func reply(kind, name string, count int) resp.Value {
+	return resp.Value{Type: resp.Push, Array: []resp.Value{
+		bulk(kind), bulk(name), {Type: resp.Integer, Int: int64(count)},
+	}}
+}
+
+func messageValue(kind, channel, message string) resp.Value {
+	return resp.Value{Type: resp.Push, Array: []resp.Value{
+		bulk(kind), bulk(channel), bulk(message),
+	}}
+}
+
+func bulk(s string) resp.Value {
+	return resp.Value{Type: resp.BulkString, Str: s}
+}
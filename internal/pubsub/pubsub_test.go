@@ -0,0 +1,118 @@
+package pubsub
+
+import (
+	"strconv"
+	"testing"
+
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strs(v resp.Value) []string {
+	out := make([]string, len(v.Array))
+	for i, e := range v.Array {
+		if e.Type == resp.Integer {
+			out[i] = strconv.FormatInt(e.Int, 10)
+			continue
+		}
+		out[i] = e.Str
+	}
+	return out
+}
+
+func TestSubscribeAndPublish(t *testing.T) {
+	h := NewHub()
+	a := NewSubscriber("a", 16)
+	b := NewSubscriber("b", 16)
+
+	replies := h.Subscribe(a, "news", "sport")
+	require.Len(t, replies, 2)
+	assert.Equal(t, []string{"subscribe", "news", "1"}, strs(replies[0]))
+	assert.Equal(t, []string{"subscribe", "sport", "2"}, strs(replies[1]))
+
+	h.PSubscribe(b, "n*")
+	assert.Equal(t, 2, h.Clients())
+	assert.Equal(t, 1, h.NumPat())
+
+	assert.Equal(t, 2, h.Publish("news", "hello"))
+	assert.Equal(t, 0, h.Publish("weather", "sunny"))
+
+	msg := <-a.Messages()
+	assert.Equal(t, []string{"message", "news", "hello"}, strs(msg))
+	pmsg := <-b.Messages()
+	assert.Equal(t, []string{"pmessage", "n*", "news", "hello"}, strs(pmsg))
+
+	assert.Equal(t, []string{"news", "sport"}, h.Channels(""))
+	assert.Equal(t, []string{"sport"}, h.Channels("s*"))
+	assert.Equal(t, []int{1, 0}, h.NumSub("news", "none"))
+}
+
+func TestUnsubscribeAll(t *testing.T) {
+	h := NewHub()
+	a := NewSubscriber("a", 16)
+	h.Subscribe(a, "x", "y")
+
+	replies := h.Unsubscribe(a)
+	require.Len(t, replies, 2)
+	assert.Equal(t, []string{"unsubscribe", "x", "1"}, strs(replies[0]))
+	assert.Equal(t, []string{"unsubscribe", "y", "0"}, strs(replies[1]))
+	assert.False(t, h.Active(a))
+	assert.Equal(t, 0, h.Clients())
+	assert.Empty(t, h.Channels(""))
+
+	// Unsubscribing with no subscriptions still answers once
+	replies = h.Unsubscribe(a)
+	require.Len(t, replies, 1)
+	assert.True(t, replies[0].Array[1].IsNull)
+}
+
+func TestShardChannels(t *testing.T) {
+	h := NewHub()
+	a := NewSubscriber("a", 16)
+	h.Subscribe(a, "orders")
+	replies := h.SSubscribe(a, "orders")
+	// Shard subscriptions are counted separately
+	assert.Equal(t, []string{"ssubscribe", "orders", "1"}, strs(replies[0]))
+
+	assert.Equal(t, 1, h.SPublish("orders", "o1"))
+	msg := <-a.Messages()
+	assert.Equal(t, []string{"smessage", "orders", "o1"}, strs(msg))
+
+	assert.Equal(t, []string{"orders"}, h.ShardChannels(""))
+	assert.Equal(t, []int{1}, h.ShardNumSub("orders"))
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub()
+	slow := NewSubscriber("slow", 1)
+	h.Subscribe(slow, "ch")
+
+	assert.Equal(t, 1, h.Publish("ch", "m1"))
+	// Queue is full: publisher must not block and the subscriber is dropped
+	assert.Equal(t, 0, h.Publish("ch", "m2"))
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow subscriber was not closed")
+	}
+	assert.True(t, slow.Dropped())
+	assert.Equal(t, []int{0}, h.NumSub("ch"))
+	assert.Equal(t, 0, h.Clients())
+}
+
+func TestRemove(t *testing.T) {
+	h := NewHub()
+	a := NewSubscriber("a", 16)
+	h.Subscribe(a, "c")
+	h.PSubscribe(a, "p*")
+	h.SSubscribe(a, "s")
+	h.Remove(a)
+
+	assert.Empty(t, h.Channels(""))
+	assert.Empty(t, h.ShardChannels(""))
+	assert.Equal(t, 0, h.NumPat())
+	assert.Equal(t, 0, h.Clients())
+}
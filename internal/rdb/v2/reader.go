@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hdt3213/rdb/model"
 	"github.com/hdt3213/rdb/parser"
 	"github.com/sirupsen/logrus"
 )
@@ -28,9 +29,30 @@ func NewReader(path string) (*Reader, error) {
 	}, nil
 }
 
+// ReadAll loads every key into db regardless of the database it was saved in
 func (r *Reader) ReadAll(db store.DataStore) error {
+	return r.read(func(int) store.DataStore { return db })
+}
+
+// ReadAllDatabases loads every key into the database it was saved in. Keys
+// of databases beyond len(dbs) are skipped.
+func (r *Reader) ReadAllDatabases(dbs []store.DataStore) error {
+	return r.read(func(index int) store.DataStore {
+		if index < 0 || index >= len(dbs) {
+			logrus.Warnf("skipping key of database %d: only %d databases configured", index, len(dbs))
+			return nil
+		}
+		return dbs[index]
+	})
+}
+
+func (r *Reader) read(dbFor func(index int) store.DataStore) error {
 	decoder := parser.NewDecoder(r.file)
 	return decoder.Parse(func(o parser.RedisObject) bool {
+		db := dbFor(o.GetDBIndex())
+		if db == nil {
+			return true
+		}
 		switch o.GetType() {
 		case parser.StringType:
 			str := o.(*parser.StringObject)
@@ -89,8 +111,29 @@ func (r *Reader) ReadAll(db store.DataStore) error {
 					logrus.Error(err)
 				}
 			})
+			if so.LastId != nil {
+				added := so.AddedEntriesCount
+				if added < uint64(st.XLen()) {
+					added = uint64(st.XLen())
+				}
+				var maxDeleted store.StreamID
+				if so.MaxDeletedId != nil {
+					maxDeleted = store.StreamID{Ms: so.MaxDeletedId.Ms, Seq: so.MaxDeletedId.Sequence}
+				}
+				last := store.StreamID{Ms: so.LastId.Ms, Seq: so.LastId.Sequence}
+				if err := st.SetID(last, &added, &maxDeleted); err != nil {
+					logrus.Error(err)
+				}
+			}
+			for _, g := range so.Groups {
+				st.RestoreGroup(convertGroup(g))
+			}
 			println("stream", so.Key, so.Entries)
 		}
+		// Strings got theirs from Set; collections are given theirs once filled
+		if exp := o.GetExpiration(); exp != nil && o.GetType() != parser.StringType {
+			db.ExpireAt(o.GetKey(), *exp, 0)
+		}
 		// return true to continue, return false to stop the iteration
 		return true
 	})
@@ -100,6 +143,41 @@ func (r *Reader) Close() error {
 	return r.file.Close()
 }
 
+// convertGroup converts an RDB consumer group into a store snapshot
+func convertGroup(g *model.StreamGroup) store.ConsumerGroupInfo {
+	info := store.ConsumerGroupInfo{
+		Name:        g.Name,
+		EntriesRead: int64(g.EntriesRead),
+		Pending:     make([]store.PendingEntry, 0, len(g.Pending)),
+		Consumers:   make([]store.ConsumerInfo, 0, len(g.Consumers)),
+	}
+	if g.LastId != nil {
+		info.LastID = store.StreamID{Ms: g.LastId.Ms, Seq: g.LastId.Sequence}
+	}
+	owners := make(map[store.StreamID]string)
+	for _, c := range g.Consumers {
+		info.Consumers = append(info.Consumers, store.ConsumerInfo{
+			Name:       c.Name,
+			SeenTime:   time.UnixMilli(int64(c.SeenTime)),
+			ActiveTime: time.UnixMilli(int64(c.ActiveTime)),
+			Pending:    len(c.Pending),
+		})
+		for _, id := range c.Pending {
+			owners[store.StreamID{Ms: id.Ms, Seq: id.Sequence}] = c.Name
+		}
+	}
+	for _, nack := range g.Pending {
+		id := store.StreamID{Ms: nack.Id.Ms, Seq: nack.Id.Sequence}
+		info.Pending = append(info.Pending, store.PendingEntry{
+			ID:            id,
+			Consumer:      owners[id],
+			DeliveryTime:  time.UnixMilli(int64(nack.DeliveryTime)),
+			DeliveryCount: nack.DeliveryCount,
+		})
+	}
+	return info
+}
+
 // getStreamKey attempts to read the Key field from parser.StreamObject via reflection
 func getStreamKey(so *parser.StreamObject) string {
 	// Prefer using GetKey method if available via embedding
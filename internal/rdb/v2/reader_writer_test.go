@@ -169,3 +169,122 @@ func TestRDBV2RoundTripBasic(t *testing.T) {
 		assert.Equal(t, "5", entries[1].Fields["amount"])
 	}
 }
+
+func TestRDBV2StreamGroupsRoundTrip(t *testing.T) {
+	rdbPath := filepath.Join(t.TempDir(), "groups.rdb")
+
+	src := store.NewUltraOptimizedDB()
+	st := src.GetOrCreateStream("events")
+	for _, id := range []store.StreamID{{Ms: 1, Seq: 0}, {Ms: 2, Seq: 0}, {Ms: 3, Seq: 0}} {
+		id := id
+		_, err := st.XAdd(&id, map[string]string{"n": id.String()})
+		require.NoError(t, err)
+	}
+	require.NoError(t, st.CreateGroup("workers", store.StreamID{}, 0))
+	require.NoError(t, st.CreateGroup("audit", store.StreamID{Ms: 3}, -1))
+	_, err := st.ReadGroup("workers", "alice", true, store.StreamID{}, 2, false)
+	require.NoError(t, err)
+	_, err = st.ReadGroup("workers", "bob", true, store.StreamID{}, 0, false)
+	require.NoError(t, err)
+	_, err = st.CreateConsumer("workers", "idle")
+	require.NoError(t, err)
+
+	w, err := NewWriter(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteHeader(1, 0))
+	entries := st.XRange(store.StreamID{}, store.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}, 0)
+	require.NoError(t, w.WriteStreamWithGroups("events", entries, st.Meta(), st.Groups(), time.Time{}))
+	require.NoError(t, w.WriteEOF())
+	require.NoError(t, w.Close())
+
+	dst := store.NewUltraOptimizedDB()
+	r, err := NewReader(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, r.ReadAll(dst))
+	require.NoError(t, r.Close())
+
+	want := st.Groups()
+	got := dst.GetOrCreateStream("events").Groups()
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].Name, got[i].Name)
+		assert.Equal(t, want[i].LastID, got[i].LastID)
+		assert.Equal(t, want[i].EntriesRead, got[i].EntriesRead)
+		require.Len(t, got[i].Pending, len(want[i].Pending))
+		for j, pe := range want[i].Pending {
+			assert.Equal(t, pe.ID, got[i].Pending[j].ID)
+			assert.Equal(t, pe.Consumer, got[i].Pending[j].Consumer)
+			assert.Equal(t, pe.DeliveryCount, got[i].Pending[j].DeliveryCount)
+			assert.Equal(t, pe.DeliveryTime.UnixMilli(), got[i].Pending[j].DeliveryTime.UnixMilli())
+		}
+		require.Len(t, got[i].Consumers, len(want[i].Consumers))
+		for j, c := range want[i].Consumers {
+			assert.Equal(t, c.Name, got[i].Consumers[j].Name)
+			assert.Equal(t, c.Pending, got[i].Consumers[j].Pending)
+		}
+	}
+}
+
+func TestRDBV2StreamMetaRoundTrip(t *testing.T) {
+	rdbPath := filepath.Join(t.TempDir(), "meta.rdb")
+
+	st := store.NewStream()
+	for _, id := range []store.StreamID{{Ms: 1, Seq: 0}, {Ms: 2, Seq: 0}, {Ms: 3, Seq: 0}} {
+		id := id
+		_, err := st.XAdd(&id, map[string]string{"n": id.String()})
+		require.NoError(t, err)
+	}
+	st.XDel([]store.StreamID{{Ms: 2, Seq: 0}})
+	require.NoError(t, st.SetID(store.StreamID{Ms: 9, Seq: 0}, nil, nil))
+
+	w, err := NewWriter(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteHeader(1, 0))
+	entries := st.XRange(store.StreamID{}, store.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}, 0)
+	require.NoError(t, w.WriteStreamWithGroups("events", entries, st.Meta(), nil, time.Time{}))
+	require.NoError(t, w.WriteEOF())
+	require.NoError(t, w.Close())
+
+	dst := store.NewUltraOptimizedDB()
+	r, err := NewReader(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, r.ReadAll(dst))
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, store.StreamMeta{
+		Length:       2,
+		FirstID:      store.StreamID{Ms: 1},
+		LastID:       store.StreamID{Ms: 9},
+		MaxDeletedID: store.StreamID{Ms: 2},
+		EntriesAdded: 3,
+	}, dst.GetOrCreateStream("events").Meta())
+}
+
+// TestRDBV2CollectionTTLRoundTrip checks that collections keep their
+// expiration and persistent keys stay persistent
+func TestRDBV2CollectionTTLRoundTrip(t *testing.T) {
+	rdbPath := filepath.Join(t.TempDir(), "ttl.rdb")
+	exp := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	w, err := NewWriter(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteHeader(4, 3))
+	require.NoError(t, w.WriteList("list", []string{"a"}, exp))
+	require.NoError(t, w.WriteHash("hash", map[string]string{"f": "v"}, exp))
+	require.NoError(t, w.WriteZSet("zset", map[string]float64{"m": 1}, exp))
+	require.NoError(t, w.WriteString("plain", "v", time.Time{}))
+	require.NoError(t, w.WriteEOF())
+	require.NoError(t, w.Close())
+
+	dst := store.NewUltraOptimizedDB()
+	defer dst.Close()
+	r, err := NewReader(rdbPath)
+	require.NoError(t, err)
+	require.NoError(t, r.ReadAll(dst))
+	require.NoError(t, r.Close())
+
+	for _, key := range []string{"list", "hash", "zset"} {
+		assert.Equal(t, exp.UnixMilli(), dst.PExpireTime(key), key)
+	}
+	assert.Equal(t, int64(-1), dst.PExpireTime("plain"))
+}
@@ -25,6 +25,14 @@ func (w *Writer) WriteHeader(keyCount, ttlCount uint64) error {
 	if keyCount == 0 {
 		return nil
 	}
+	if err := w.WriteAux(); err != nil {
+		return err
+	}
+	return w.WriteDBHeader(0, keyCount, ttlCount)
+}
+
+// WriteAux writes the auxiliary fields; it must precede the first database
+func (w *Writer) WriteAux() error {
 	auxMap := map[string]string{
 		"redis-ver":    "7.0.0",
 		"redis-bits":   "64",
@@ -36,31 +44,48 @@ func (w *Writer) WriteHeader(keyCount, ttlCount uint64) error {
 			return err
 		}
 	}
+	return nil
+}
 
-	return w.enc.WriteDBHeader(0, keyCount, ttlCount)
+// WriteDBHeader starts database index (a SELECTDB opcode) holding keyCount
+// keys. Empty databases are skipped since the format does not allow them.
+func (w *Writer) WriteDBHeader(index int, keyCount, ttlCount uint64) error {
+	if keyCount == 0 {
+		return nil
+	}
+	return w.enc.WriteDBHeader(uint(index), keyCount, ttlCount)
 }
 
 func (w *Writer) WriteEOF() error {
 	return w.enc.WriteEnd()
 }
 
+// ttlOptions returns the encoder options storing expiration, none for a
+// key that does not expire
+func ttlOptions(expiration time.Time) []interface{} {
+	if expiration.IsZero() {
+		return nil
+	}
+	return []interface{}{encoder.WithTTL(uint64(expiration.UnixMilli()))}
+}
+
 func (w *Writer) WriteString(key string, value string, expiration time.Time) error {
-	return w.enc.WriteStringObject(key, []byte(value), encoder.WithTTL(uint64(expiration.Unix()*1000)))
+	return w.enc.WriteStringObject(key, []byte(value), ttlOptions(expiration)...)
 }
 
-func (w *Writer) WriteList(key string, vals []string, _ time.Time) error {
-	return w.enc.WriteListObject(key, convertStringValsToBytes(vals))
+func (w *Writer) WriteList(key string, vals []string, expiration time.Time) error {
+	return w.enc.WriteListObject(key, convertStringValsToBytes(vals), ttlOptions(expiration)...)
 }
 
-func (w *Writer) WriteSet(key string, members []string, _ time.Time) error {
-	return w.enc.WriteSetObject(key, convertStringValsToBytes(members))
+func (w *Writer) WriteSet(key string, members []string, expiration time.Time) error {
+	return w.enc.WriteSetObject(key, convertStringValsToBytes(members), ttlOptions(expiration)...)
 }
 
-func (w *Writer) WriteHash(key string, fields map[string]string, _ time.Time) error {
-	return w.enc.WriteHashMapObject(key, convertStringMapToBytes(fields))
+func (w *Writer) WriteHash(key string, fields map[string]string, expiration time.Time) error {
+	return w.enc.WriteHashMapObject(key, convertStringMapToBytes(fields), ttlOptions(expiration)...)
 }
 
-func (w *Writer) WriteZSet(key string, pairs map[string]float64, _ time.Time) error {
+func (w *Writer) WriteZSet(key string, pairs map[string]float64, expiration time.Time) error {
 	var zset []*model.ZSetEntry
 	for k, p := range pairs {
 		zset = append(zset, &model.ZSetEntry{
@@ -68,15 +93,26 @@ func (w *Writer) WriteZSet(key string, pairs map[string]float64, _ time.Time) er
 			Score:  p,
 		})
 	}
-	return w.enc.WriteZSetObject(key, zset)
+	return w.enc.WriteZSetObject(key, zset, ttlOptions(expiration)...)
 }
 
 func (w *Writer) WriteStream(key string, entries []store.StreamEntry, exp time.Time) error {
+	meta := store.StreamMeta{Length: len(entries), EntriesAdded: uint64(len(entries))}
+	if len(entries) > 0 {
+		meta.LastID = entries[len(entries)-1].ID
+	}
+	return w.WriteStreamWithGroups(key, entries, meta, nil, exp)
+}
+
+// WriteStreamWithGroups writes a stream together with its ID bookkeeping
+// (last generated ID, max deleted ID, entries added) and its consumer groups
+func (w *Writer) WriteStreamWithGroups(key string, entries []store.StreamEntry, meta store.StreamMeta, groups []store.ConsumerGroupInfo, exp time.Time) error {
+	lastID := meta.LastID
 	// Build model.StreamObject from store entries
 	stream := &model.StreamObject{
 		Version: 2,
 		Entries: make([]*model.StreamEntry, 0, len(entries)),
-		Groups:  nil,
+		Groups:  convertGroups(groups),
 	}
 	// Helper to track first/last IDs
 	var (
@@ -127,19 +163,59 @@ func (w *Writer) WriteStream(key string, entries []store.StreamEntry, exp time.T
 	if len(entries) > 0 {
 		stream.FirstId = &model.StreamId{Ms: minMs, Sequence: minSeq}
 		stream.LastId = &model.StreamId{Ms: maxMs, Sequence: maxSeq}
-		stream.AddedEntriesCount = uint64(len(entries))
+		if lastID.Ms > maxMs || (lastID.Ms == maxMs && lastID.Seq > maxSeq) {
+			stream.LastId = &model.StreamId{Ms: lastID.Ms, Sequence: lastID.Seq}
+		}
 	} else {
 		stream.FirstId = &model.StreamId{Ms: 0, Sequence: 0}
-		stream.LastId = &model.StreamId{Ms: 0, Sequence: 0}
-		stream.AddedEntriesCount = 0
+		stream.LastId = &model.StreamId{Ms: lastID.Ms, Sequence: lastID.Seq}
+	}
+	stream.AddedEntriesCount = meta.EntriesAdded
+	if stream.AddedEntriesCount < stream.Length {
+		stream.AddedEntriesCount = stream.Length
 	}
+	stream.MaxDeletedId = &model.StreamId{Ms: meta.MaxDeletedID.Ms, Sequence: meta.MaxDeletedID.Seq}
+
+	return w.enc.WriteStreamObject(key, stream, ttlOptions(exp)...)
+}
 
-	// Pass TTL option if expiration is set
-	var opts []interface{}
-	if !exp.IsZero() {
-		opts = append(opts, encoder.WithTTL(uint64(exp.Unix()*1000)))
+// convertGroups converts consumer group snapshots into their RDB model.
+// Times are stored as unix milliseconds; an unknown entries-read counter
+// (-1) is stored as the all-ones value like Redis does.
+func convertGroups(groups []store.ConsumerGroupInfo) []*model.StreamGroup {
+	if len(groups) == 0 {
+		return nil
+	}
+	out := make([]*model.StreamGroup, 0, len(groups))
+	for _, g := range groups {
+		mg := &model.StreamGroup{
+			Name:        g.Name,
+			LastId:      &model.StreamId{Ms: g.LastID.Ms, Sequence: g.LastID.Seq},
+			EntriesRead: uint64(g.EntriesRead),
+			Pending:     make([]*model.StreamNAck, 0, len(g.Pending)),
+			Consumers:   make([]*model.StreamConsumer, 0, len(g.Consumers)),
+		}
+		owned := make(map[string][]*model.StreamId)
+		for _, pe := range g.Pending {
+			id := &model.StreamId{Ms: pe.ID.Ms, Sequence: pe.ID.Seq}
+			mg.Pending = append(mg.Pending, &model.StreamNAck{
+				Id:            id,
+				DeliveryTime:  uint64(pe.DeliveryTime.UnixMilli()),
+				DeliveryCount: pe.DeliveryCount,
+			})
+			owned[pe.Consumer] = append(owned[pe.Consumer], id)
+		}
+		for _, c := range g.Consumers {
+			mg.Consumers = append(mg.Consumers, &model.StreamConsumer{
+				Name:       c.Name,
+				SeenTime:   uint64(c.SeenTime.UnixMilli()),
+				ActiveTime: uint64(c.ActiveTime.UnixMilli()),
+				Pending:    owned[c.Name],
+			})
+		}
+		out = append(out, mg)
 	}
-	return w.enc.WriteStreamObject(key, stream, opts...)
+	return out
 }
 
 func NewWriter(path string) (*Writer, error) {
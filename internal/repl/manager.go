@@ -131,6 +131,19 @@ func (m *Manager) ListReplicas() []*Replica {
 	return replicas
 }
 
+// ReplicaOffsets returns the offset each replica was sent up to, by replica
+// ID
+func (m *Manager) ReplicaOffsets() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	offsets := make(map[string]int64, len(m.replicas))
+	for id, replica := range m.replicas {
+		offsets[id] = replica.Offset
+	}
+	return offsets
+}
+
 // ListReplicas returns all connected replicas
 func (m *Manager) Count() int {
 	m.mu.RLock()
@@ -2,11 +2,13 @@ package repl
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"gridhouse/internal/logger"
@@ -18,32 +20,51 @@ import (
 // Slave represents a replication slave that can connect to a master
 type Slave struct {
 	masterAddr string
+	tlsConfig  *tls.Config // nil connects to the master in plaintext
 	conn       net.Conn
 	reader     *bufio.Reader
 	writer     *bufio.Writer
 	runID      string
-	offset     int64
+	offset     int64 // atomic
 	role       Role
+	linkUp     atomic.Bool // set while the command stream from the master is up
 	stopChan   chan struct{}
-	db         store.DataStore // Database instance to apply replicated commands
+	db         store.DataStore   // Database selected by the master's command stream
+	dbs        []store.DataStore // All databases, dbs[i] is database i
 }
 
 // NewSlave creates a new slave instance
 func NewSlave(masterAddr string, db store.DataStore) *Slave {
+	return NewSlaveWithDatabases(masterAddr, []store.DataStore{db})
+}
+
+// NewSlaveWithDatabases creates a slave replicating into several numbered
+// databases; the command stream starts in database 0
+func NewSlaveWithDatabases(masterAddr string, dbs []store.DataStore) *Slave {
 	return &Slave{
 		masterAddr: masterAddr,
 		role:       RoleSlave,
 		stopChan:   make(chan struct{}),
-		db:         db,
+		db:         dbs[0],
+		dbs:        dbs,
 	}
 }
 
+// SetTLSConfig makes Connect dial the master over TLS with config
+func (s *Slave) SetTLSConfig(config *tls.Config) {
+	s.tlsConfig = config
+}
+
 // Connect connects to the master and initiates replication
 func (s *Slave) Connect() error {
 	logger.Infof("Connecting to master at %s", s.masterAddr)
 
 	var err error
-	s.conn, err = net.Dial("tcp", s.masterAddr)
+	if s.tlsConfig != nil {
+		s.conn, err = tls.Dial("tcp", s.masterAddr, s.tlsConfig)
+	} else {
+		s.conn, err = net.Dial("tcp", s.masterAddr)
+	}
 	if err != nil {
 		logger.Errorf("Failed to connect to master %s: %v", s.masterAddr, err)
 		return fmt.Errorf("failed to connect to master: %w", err)
@@ -136,8 +157,8 @@ func (s *Slave) performHandshake() error {
 		return fmt.Errorf("invalid offset in PSYNC response: %w", err)
 	}
 
-	s.offset = offset
-	logger.Infof("PSYNC successful: run_id=%s, offset=%d", s.runID, s.offset)
+	atomic.StoreInt64(&s.offset, offset)
+	logger.Infof("PSYNC successful: run_id=%s, offset=%d", s.runID, offset)
 
 	// Step 5: Read RDB dump
 	logger.Info("Starting to receive RDB dump from master")
@@ -238,6 +259,8 @@ func (s *Slave) receiveRDBDump() error {
 // startCommandStream starts receiving commands from the master
 func (s *Slave) startCommandStream() error {
 	logger.Info("Starting command stream from master")
+	s.linkUp.Store(true)
+	defer s.linkUp.Store(false)
 
 	for {
 		select {
@@ -253,7 +276,7 @@ func (s *Slave) startCommandStream() error {
 			}
 
 			logger.Debugf("Received command from master: %s", command)
-			s.offset += int64(len(command))
+			atomic.AddInt64(&s.offset, int64(len(command)))
 		}
 	}
 }
@@ -311,13 +334,39 @@ func (s *Slave) readCommand() (string, error) {
 
 	// Execute the command on the local database
 	switch cmdNameUpper {
+	case "SELECT":
+		if len(args) == 1 {
+			if index, err := strconv.Atoi(args[0].Str); err == nil && index >= 0 && index < len(s.dbs) {
+				s.db = s.dbs[index]
+				logger.Debugf("Replicated SELECT %d", index)
+			} else {
+				logger.Warnf("Replicated SELECT of unknown database %s", args[0].Str)
+			}
+		}
 	case "SET":
 		if len(args) >= 2 {
 			key := args[0].Str
 			value := args[1].Str
-			s.db.Set(key, value, time.Time{}) // No expiration for replicated SET
+			s.db.Set(key, value, s.replicatedExpiration(key, args[2:]))
 			logger.Debugf("Replicated SET %s = %s", key, value)
 		}
+	case "MSET":
+		for i := 0; i+1 < len(args); i += 2 {
+			s.db.Set(args[i].Str, args[i+1].Str, time.Time{})
+		}
+		logger.Debugf("Replicated MSET with %d keys", len(args)/2)
+	case "SETRANGE":
+		if len(args) >= 3 {
+			offset, err := strconv.Atoi(args[1].Str)
+			if r, ok := s.db.(interface {
+				SetRange(key string, offset int, value string) (int, error)
+			}); ok && err == nil {
+				if _, err := r.SetRange(args[0].Str, offset, args[2].Str); err != nil {
+					logger.Warnf("Failed to replicate SETRANGE %s: %v", args[0].Str, err)
+				}
+			}
+			logger.Debugf("Replicated SETRANGE %s", args[0].Str)
+		}
 	case "DEL":
 		if len(args) >= 1 {
 			key := args[0].Str
@@ -342,6 +391,20 @@ func (s *Slave) readCommand() (string, error) {
 				logger.Debugf("Replicated PEXPIRE %s = %d", key, ttl)
 			}
 		}
+	case "PEXPIREAT":
+		if len(args) >= 2 {
+			key := args[0].Str
+			ms, err := strconv.ParseInt(args[1].Str, 10, 64)
+			if err == nil {
+				s.db.ExpireAt(key, time.UnixMilli(ms), 0)
+				logger.Debugf("Replicated PEXPIREAT %s = %d", key, ms)
+			}
+		}
+	case "PERSIST":
+		if len(args) >= 1 {
+			s.db.Persist(args[0].Str)
+			logger.Debugf("Replicated PERSIST %s", args[0].Str)
+		}
 	case "INCR":
 		if len(args) >= 1 {
 			key := args[0].Str
@@ -415,16 +478,40 @@ func (s *Slave) readCommand() (string, error) {
 		if len(args) >= 1 {
 			key := args[0].Str
 			list := s.db.GetOrCreateList(key)
-			list.LPop()
+			for i := 0; i < popCount(args); i++ {
+				list.LPop()
+			}
 			logger.Debugf("Replicated LPOP %s", key)
 		}
 	case "RPOP":
 		if len(args) >= 1 {
 			key := args[0].Str
 			list := s.db.GetOrCreateList(key)
-			list.RPop()
+			for i := 0; i < popCount(args); i++ {
+				list.RPop()
+			}
 			logger.Debugf("Replicated RPOP %s", key)
 		}
+	case "LMOVE":
+		if len(args) >= 4 {
+			src := s.db.GetOrCreateList(args[0].Str)
+			var element string
+			var ok bool
+			if strings.EqualFold(args[2].Str, "LEFT") {
+				element, ok = src.LPop()
+			} else {
+				element, ok = src.RPop()
+			}
+			if ok {
+				dst := s.db.GetOrCreateList(args[1].Str)
+				if strings.EqualFold(args[3].Str, "LEFT") {
+					dst.LPush(element)
+				} else {
+					dst.RPush(element)
+				}
+			}
+			logger.Debugf("Replicated LMOVE %s %s", args[0].Str, args[1].Str)
+		}
 	case "LREM":
 		if len(args) >= 3 {
 			key := args[0].Str
@@ -447,6 +534,23 @@ func (s *Slave) readCommand() (string, error) {
 				logger.Debugf("Replicated LTRIM %s [%d:%d]", key, start, stop)
 			}
 		}
+	case "LSET":
+		if len(args) >= 3 {
+			key := args[0].Str
+			index, err := strconv.Atoi(args[1].Str)
+			if err == nil {
+				list := s.db.GetOrCreateList(key)
+				list.LSet(index, args[2].Str)
+				logger.Debugf("Replicated LSET %s index %d", key, index)
+			}
+		}
+	case "LINSERT":
+		if len(args) >= 4 {
+			key := args[0].Str
+			list := s.db.GetOrCreateList(key)
+			list.LInsert(strings.EqualFold(args[1].Str, "BEFORE"), args[2].Str, args[3].Str)
+			logger.Debugf("Replicated LINSERT %s", key)
+		}
 	// Set commands
 	case "SADD":
 		if len(args) >= 2 {
@@ -477,6 +581,31 @@ func (s *Slave) readCommand() (string, error) {
 			set.SPop()
 			logger.Debugf("Replicated SPOP %s", key)
 		}
+	case "SMOVE":
+		if len(args) >= 3 {
+			src := s.db.GetOrCreateSet(args[0].Str)
+			dst := s.db.GetOrCreateSet(args[1].Str)
+			store.SetMove(src, dst, args[2].Str)
+			logger.Debugf("Replicated SMOVE %s %s", args[0].Str, args[1].Str)
+		}
+	case "SUNIONSTORE", "SINTERSTORE", "SDIFFSTORE":
+		if len(args) >= 2 {
+			sets := make([]*store.Set, len(args)-1)
+			for i, arg := range args[1:] {
+				sets[i], _ = s.db.GetSet(arg.Str)
+			}
+			var members []string
+			switch cmdNameUpper {
+			case "SUNIONSTORE":
+				members = store.SetUnion(sets...)
+			case "SINTERSTORE":
+				members = store.SetInter(0, sets...)
+			default:
+				members = store.SetDiff(sets[0], sets[1:]...)
+			}
+			s.db.StoreSet(args[0].Str, members)
+			logger.Debugf("Replicated %s %s with %d members", cmdNameUpper, args[0].Str, len(members))
+		}
 	// Hash commands
 	case "HSET":
 		if len(args) >= 3 {
@@ -575,6 +704,13 @@ func (s *Slave) readCommand() (string, error) {
 			s.db.Del(key)
 		}
 		logger.Debugf("Replicated FLUSHDB - cleared %d keys", len(keys))
+	case "FLUSHALL":
+		for _, db := range s.dbs {
+			for _, key := range db.Keys() {
+				db.Del(key)
+			}
+		}
+		logger.Debug("Replicated FLUSHALL")
 	default:
 		logger.Debugf("Replicated command not handled: %s", cmdName)
 	}
@@ -587,6 +723,38 @@ func (s *Slave) readCommand() (string, error) {
 	return buf.String(), nil
 }
 
+// replicatedExpiration returns the expiration of a replicated SET of key
+// from its options, which the master sends as PXAT or KEEPTTL
+func (s *Slave) replicatedExpiration(key string, options []resp.Value) time.Time {
+	for i := 0; i < len(options); i++ {
+		switch strings.ToUpper(options[i].Str) {
+		case "PXAT":
+			if i+1 < len(options) {
+				if ms, err := strconv.ParseInt(options[i+1].Str, 10, 64); err == nil {
+					return time.UnixMilli(ms)
+				}
+			}
+		case "KEEPTTL":
+			if ms := s.db.PTTL(key); ms > 0 {
+				return time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// popCount returns the optional count argument of LPOP/RPOP
+func popCount(args []resp.Value) int {
+	if len(args) < 2 {
+		return 1
+	}
+	n, err := strconv.Atoi(args[1].Str)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
 // loadRDBData loads RDB data into the local database
 func (s *Slave) loadRDBData(rdbData []byte) error {
 	if len(rdbData) == 0 {
@@ -627,7 +795,7 @@ func (s *Slave) loadRDBData(rdbData []byte) error {
 	}()
 
 	// Read all data directly into the database
-	if err := reader.ReadAll(s.db); err != nil {
+	if err := reader.ReadAllDatabases(s.dbs); err != nil {
 		return fmt.Errorf("failed to read RDB data: %w", err)
 	}
 
@@ -653,7 +821,13 @@ func (s *Slave) RunID() string {
 
 // Offset returns the current replication offset
 func (s *Slave) Offset() int64 {
-	return s.offset
+	return atomic.LoadInt64(&s.offset)
+}
+
+// LinkUp reports whether the slave finished the initial sync and is
+// receiving the master's command stream
+func (s *Slave) LinkUp() bool {
+	return s.linkUp.Load()
 }
 
 // Role returns the replication role
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"unsafe"
 )
@@ -16,6 +17,22 @@ const (
 	Integer
 	BulkString
 	Array
+
+	// RESP3 types; UltraEncodeProto downgrades them for RESP2 clients
+	Map       // Array holds alternating keys and values
+	Set       // Array holds the members
+	Double    // Str holds the formatted number, see FormatDouble
+	Boolean   // Int is 1 for true and 0 for false
+	Null      // sent as a null bulk string to RESP2 clients
+	BigNumber // Str holds the digits
+	Verbatim  // Str holds the text, sent with the txt format
+	Push      // Array holds an out-of-band frame such as a pub/sub message
+)
+
+// Protocol versions negotiated with HELLO
+const (
+	RESP2 = 2
+	RESP3 = 3
 )
 
 type Value struct {
@@ -26,6 +43,19 @@ type Value struct {
 	IsNull bool
 }
 
+// FormatDouble formats f the way Double replies and RESP2 float replies carry it
+func FormatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 // Pre-computed byte slices for common responses
 var (
 	OkResponse         = []byte("+OK\r\n")
@@ -37,10 +67,27 @@ var (
 	colonByte          = []byte(":")
 	dollarByte         = []byte("$")
 	asteriskByte       = []byte("*")
+	resp3NullResponse  = []byte("_\r\n")
+	trueResponse       = []byte("#t\r\n")
+	falseResponse      = []byte("#f\r\n")
 )
 
-// UltraEncode is fastest RESP encoder with minimal allocations
+// UltraEncode is fastest RESP encoder with minimal allocations. Every type is
+// written as is; use UltraEncodeProto to write for a negotiated protocol.
 func UltraEncode(w io.Writer, v Value) error {
+	return ultraEncode(w, v, 0)
+}
+
+// UltraEncodeProto encodes v for a client speaking proto. RESP2 clients get
+// RESP3 types in their RESP2 shape (maps, sets and pushes as arrays, doubles
+// as bulk strings, booleans as integers); RESP3 clients get null bulk strings
+// and arrays as the RESP3 null.
+func UltraEncodeProto(w io.Writer, v Value, proto int) error {
+	return ultraEncode(w, v, proto)
+}
+
+// ultraEncode encodes v for proto, where 0 keeps every type as is
+func ultraEncode(w io.Writer, v Value, proto int) error {
 	switch v.Type {
 	case SimpleString:
 		return ultraEncodeSimpleString(w, v.Str)
@@ -49,9 +96,63 @@ func UltraEncode(w io.Writer, v Value) error {
 	case Integer:
 		return ultraEncodeInteger(w, v.Int)
 	case BulkString:
+		if v.IsNull && proto == RESP3 {
+			_, err := w.Write(resp3NullResponse)
+			return err
+		}
 		return ultraEncodeBulkString(w, v.Str, v.IsNull)
 	case Array:
-		return ultraEncodeArray(w, v.Array, v.IsNull)
+		if v.IsNull && proto == RESP3 {
+			_, err := w.Write(resp3NullResponse)
+			return err
+		}
+		return ultraEncodeAggregate(w, '*', v.Array, len(v.Array), v.IsNull, proto)
+	case Map:
+		if proto == RESP2 {
+			return ultraEncodeAggregate(w, '*', v.Array, len(v.Array), false, proto)
+		}
+		return ultraEncodeAggregate(w, '%', v.Array, len(v.Array)/2, false, proto)
+	case Set:
+		if proto == RESP2 {
+			return ultraEncodeAggregate(w, '*', v.Array, len(v.Array), false, proto)
+		}
+		return ultraEncodeAggregate(w, '~', v.Array, len(v.Array), false, proto)
+	case Push:
+		if proto == RESP2 {
+			return ultraEncodeAggregate(w, '*', v.Array, len(v.Array), false, proto)
+		}
+		return ultraEncodeAggregate(w, '>', v.Array, len(v.Array), false, proto)
+	case Double, BigNumber:
+		if proto == RESP2 {
+			return ultraEncodeBulkString(w, v.Str, false)
+		}
+		prefix := byte(',')
+		if v.Type == BigNumber {
+			prefix = '('
+		}
+		return ultraEncodeLine(w, prefix, v.Str)
+	case Verbatim:
+		if proto == RESP2 {
+			return ultraEncodeBulkString(w, v.Str, false)
+		}
+		return ultraEncodeVerbatim(w, v.Str)
+	case Boolean:
+		if proto == RESP2 {
+			return ultraEncodeInteger(w, v.Int)
+		}
+		if v.Int != 0 {
+			_, err := w.Write(trueResponse)
+			return err
+		}
+		_, err := w.Write(falseResponse)
+		return err
+	case Null:
+		if proto == RESP2 {
+			_, err := w.Write(nullResponse)
+			return err
+		}
+		_, err := w.Write(resp3NullResponse)
+		return err
 	default:
 		return Encode(w, v) // Fallback
 	}
@@ -91,6 +192,43 @@ func Encode(w io.Writer, v Value) error {
 			}
 		}
 		return bw.Flush()
+	case Map, Set, Push:
+		prefix, n := byte('~'), len(v.Array)
+		switch v.Type {
+		case Map:
+			prefix, n = '%', n/2
+		case Push:
+			prefix = '>'
+		}
+		bw := bufio.NewWriter(w)
+		if _, err := fmt.Fprintf(bw, "%c%d\r\n", prefix, n); err != nil {
+			return err
+		}
+		for _, el := range v.Array {
+			if err := Encode(bw, el); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	case Double:
+		_, err := fmt.Fprintf(w, ",%s\r\n", v.Str)
+		return err
+	case BigNumber:
+		_, err := fmt.Fprintf(w, "(%s\r\n", v.Str)
+		return err
+	case Verbatim:
+		_, err := fmt.Fprintf(w, "=%d\r\ntxt:%s\r\n", len(v.Str)+4, v.Str)
+		return err
+	case Boolean:
+		b := 't'
+		if v.Int == 0 {
+			b = 'f'
+		}
+		_, err := fmt.Fprintf(w, "#%c\r\n", b)
+		return err
+	case Null:
+		_, err := io.WriteString(w, "_\r\n")
+		return err
 	default:
 		return fmt.Errorf("unknown type: %v", v.Type)
 	}
@@ -176,8 +314,9 @@ func ultraEncodeBulkString(w io.Writer, s string, isNull bool) error {
 	return err
 }
 
-// ultraEncodeArray encodes arrays with minimal allocs
-func ultraEncodeArray(w io.Writer, arr []Value, isNull bool) error {
+// ultraEncodeAggregate encodes arrays, maps, sets and pushes with minimal
+// allocs; n is the count written in the header
+func ultraEncodeAggregate(w io.Writer, prefix byte, arr []Value, n int, isNull bool, proto int) error {
 	if isNull {
 		// RESP null array is encoded as *-1\r\n
 		if _, err := w.Write(asteriskByte); err != nil {
@@ -190,19 +329,15 @@ func ultraEncodeArray(w io.Writer, arr []Value, isNull bool) error {
 		return err
 	}
 
-	if len(arr) == 0 {
+	if n == 0 && prefix == '*' {
 		_, err := w.Write(emptyArrayResponse)
 		return err
 	}
 
-	// Write array length prefix
-	if _, err := w.Write(asteriskByte); err != nil {
-		return err
-	}
-
-	// Use pre-allocated buffer for length conversion
-	var abuf [20]byte
-	buf := strconv.AppendInt(abuf[:0], int64(len(arr)), 10)
+	// Write length prefix
+	var abuf [21]byte
+	buf := append(abuf[:0], prefix)
+	buf = strconv.AppendInt(buf, int64(n), 10)
 	if _, err := w.Write(buf); err != nil {
 		return err
 	}
@@ -211,9 +346,9 @@ func ultraEncodeArray(w io.Writer, arr []Value, isNull bool) error {
 		return err
 	}
 
-	// Write array elements
+	// Write elements
 	for _, el := range arr {
-		if err := UltraEncode(w, el); err != nil {
+		if err := ultraEncode(w, el, proto); err != nil {
 			return err
 		}
 	}
@@ -221,6 +356,36 @@ func ultraEncodeArray(w io.Writer, arr []Value, isNull bool) error {
 	return nil
 }
 
+// ultraEncodeLine encodes a single line type such as a double or a big number
+func ultraEncodeLine(w io.Writer, prefix byte, s string) error {
+	if _, err := w.Write([]byte{prefix}); err != nil {
+		return err
+	}
+	if _, err := w.Write(unsafe.Slice(unsafe.StringData(s), len(s))); err != nil {
+		return err
+	}
+	_, err := w.Write(crlfBytes)
+	return err
+}
+
+// ultraEncodeVerbatim encodes a verbatim string in the txt format
+func ultraEncodeVerbatim(w io.Writer, s string) error {
+	var lbuf [21]byte
+	buf := append(lbuf[:0], '=')
+	buf = strconv.AppendInt(buf, int64(len(s)+4), 10)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "\r\ntxt:"); err != nil {
+		return err
+	}
+	if _, err := w.Write(unsafe.Slice(unsafe.StringData(s), len(s))); err != nil {
+		return err
+	}
+	_, err := w.Write(crlfBytes)
+	return err
+}
+
 // UltraEncodeOK is an ultra-fast encoder for OK responses
 func UltraEncodeOK(w io.Writer) error {
 	_, err := w.Write(OkResponse)
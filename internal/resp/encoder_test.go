@@ -2,6 +2,7 @@ package resp
 
 import (
 	"bytes"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -157,3 +158,57 @@ func TestUltraEncode_Wrappers_Error(t *testing.T) {
 	require.NoError(t, UltraEncodeError(&buf, "ERR boom"))
 	require.Equal(t, "-ERR boom\r\n", buf.String())
 }
+
+func TestUltraEncodeProto_RESP3(t *testing.T) {
+	cases := []struct {
+		v    Value
+		want string
+	}{
+		{Value{Type: Map, Array: []Value{{Type: BulkString, Str: "a"}, {Type: Integer, Int: 1}}}, "%1\r\n$1\r\na\r\n:1\r\n"},
+		{Value{Type: Set, Array: []Value{{Type: BulkString, Str: "x"}}}, "~1\r\n$1\r\nx\r\n"},
+		{Value{Type: Push, Array: []Value{{Type: BulkString, Str: "message"}}}, ">1\r\n$7\r\nmessage\r\n"},
+		{Value{Type: Double, Str: FormatDouble(3.14)}, ",3.14\r\n"},
+		{Value{Type: Double, Str: FormatDouble(math.Inf(-1))}, ",-inf\r\n"},
+		{Value{Type: Boolean, Int: 1}, "#t\r\n"},
+		{Value{Type: Boolean}, "#f\r\n"},
+		{Value{Type: Null}, "_\r\n"},
+		{Value{Type: BigNumber, Str: "12345678901234567890"}, "(12345678901234567890\r\n"},
+		{Value{Type: Verbatim, Str: "hi"}, "=6\r\ntxt:hi\r\n"},
+		{Value{Type: BulkString, IsNull: true}, "_\r\n"},
+		{Value{Type: Array, IsNull: true}, "_\r\n"},
+		{Value{Type: Map, Array: []Value{}}, "%0\r\n"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		require.NoError(t, UltraEncodeProto(&buf, c.v, RESP3))
+		require.Equal(t, c.want, buf.String())
+
+		// Encode writes RESP3 types the same way
+		if !c.v.IsNull {
+			buf.Reset()
+			require.NoError(t, Encode(&buf, c.v))
+			require.Equal(t, c.want, buf.String())
+		}
+	}
+}
+
+func TestUltraEncodeProto_RESP2Downgrade(t *testing.T) {
+	cases := []struct {
+		v    Value
+		want string
+	}{
+		{Value{Type: Map, Array: []Value{{Type: BulkString, Str: "a"}, {Type: Double, Str: "1.5"}}}, "*2\r\n$1\r\na\r\n$3\r\n1.5\r\n"},
+		{Value{Type: Set, Array: []Value{}}, "*0\r\n"},
+		{Value{Type: Push, Array: []Value{{Type: BulkString, Str: "pong"}}}, "*1\r\n$4\r\npong\r\n"},
+		{Value{Type: Boolean, Int: 1}, ":1\r\n"},
+		{Value{Type: Null}, "$-1\r\n"},
+		{Value{Type: Verbatim, Str: "hi"}, "$2\r\nhi\r\n"},
+		{Value{Type: BulkString, IsNull: true}, "$-1\r\n"},
+		{Value{Type: Array, IsNull: true}, "*-1\r\n"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		require.NoError(t, UltraEncodeProto(&buf, c.v, RESP2))
+		require.Equal(t, c.want, buf.String())
+	}
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/resp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// connectionCommands describes the commands the server serves itself
+// rather than a database registry, so that ACL rules and ACL CAT cover them.
+// Their handlers are never called.
+func connectionCommands() *cmd.Registry {
+	registry := cmd.NewRegistry()
+	for _, c := range []*cmd.Command{
+		{Name: "AUTH", ACLGroups: []cmd.AclGroup{cmd.AclFast, cmd.AclConnection}},
+		{Name: "HELLO", ACLGroups: []cmd.AclGroup{cmd.AclFast, cmd.AclConnection}},
+		{Name: "CLIENT", ACLGroups: []cmd.AclGroup{cmd.AclSlow, cmd.AclConnection}},
+		{Name: "ACL", ACLGroups: []cmd.AclGroup{cmd.AclAdmin, cmd.AclSlow, cmd.AclDangerous}},
+		{Name: "SLOWLOG", ACLGroups: []cmd.AclGroup{cmd.AclAdmin, cmd.AclSlow, cmd.AclDangerous}},
+		{Name: "MONITOR", ACLGroups: []cmd.AclGroup{cmd.AclAdmin, cmd.AclSlow, cmd.AclDangerous}},
+		{Name: "MULTI", ACLGroups: []cmd.AclGroup{cmd.AclFast, cmd.AclTransaction}},
+		{Name: "EXEC", ACLGroups: []cmd.AclGroup{cmd.AclSlow, cmd.AclTransaction}},
+		{Name: "DISCARD", ACLGroups: []cmd.AclGroup{cmd.AclFast, cmd.AclTransaction}},
+		{Name: "SUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}, Channels: cmd.AllKeys},
+		{Name: "SSUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}, Channels: cmd.AllKeys},
+		{Name: "PSUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}, Channels: cmd.AllKeys},
+		{Name: "UNSUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}},
+		{Name: "SUNSUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}},
+		{Name: "PUNSUBSCRIBE", ACLGroups: []cmd.AclGroup{cmd.AclPubSub, cmd.AclSlow}},
+	} {
+		c.Arity = -1
+		registry.Register(c)
+	}
+	return registry
+}
+
+// commandSpec returns the description of a command, whether a registry or
+// the server serves it. Every database registry has the same commands.
+func (s *Server) commandSpec(name string) (*cmd.Command, bool) {
+	if info, ok := s.registry.Get(name); ok {
+		return info, true
+	}
+	return s.connCommands.Get(name)
+}
+
+// aclCatalog validates the command and category names of ACL rules
+type aclCatalog struct {
+	s *Server
+}
+
+func (c aclCatalog) HasCommand(name string) bool {
+	_, ok := c.s.commandSpec(name)
+	return ok
+}
+
+func (c aclCatalog) HasCategory(name string) bool {
+	for _, group := range cmd.AclGroups {
+		if string(group) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setDefaultPassword makes password, or no password when empty,
+// authenticate the default user, the way requirepass does
+func (s *Server) setDefaultPassword(password string) {
+	if password == "" {
+		s.acl.SetUser("default", "nopass")
+	} else {
+		s.acl.SetUser("default", "resetpass", ">"+password)
+	}
+}
+
+// defaultAuthed reports whether new connections start authenticated as the
+// default user
+func (s *Server) defaultAuthed() bool {
+	if s.acl == nil {
+		// Servers assembled by hand in tests have no ACL
+		return s.requirePass() == ""
+	}
+	u := s.acl.Get("default")
+	return u != nil && u.Enabled() && u.NoPass()
+}
+
+// authenticate logs client in as username for command, AUTH or HELLO
+func (s *Server) authenticate(client *Client, command, username, password string) error {
+	if s.acl.Authenticate(username, password) == nil {
+		s.aclLog.Add("auth", aclContext(client), "AUTH", username, clientInfo(client, command, ""))
+		return fmt.Errorf("WRONGPASS invalid username-password pair or user is disabled.")
+	}
+	client.authed, client.user = true, username
+	return nil
+}
+
+// auth handles AUTH [username] password
+func (s *Server) auth(client *Client, args []resp.Value) (resp.Value, error) {
+	switch len(args) {
+	case 1:
+		if s.defaultAuthed() {
+			return resp.Value{}, fmt.Errorf("ERR AUTH <password> called without any password configured for the default user. " +
+				"Are you sure your configuration is correct?")
+		}
+		if err := s.authenticate(client, "auth", "default", args[0].Str); err != nil {
+			return resp.Value{}, err
+		}
+	case 2:
+		if err := s.authenticate(client, "auth", args[0].Str, args[1].Str); err != nil {
+			return resp.Value{}, err
+		}
+	case 0:
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'AUTH' command")
+	default:
+		return resp.Value{}, fmt.Errorf("ERR syntax error")
+	}
+	return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+}
+
+// authorize checks that client may run command with args: it must be
+// authenticated, and its user allowed the command and every key and channel
+// the command names. Denials are recorded in the ACL LOG.
+func (s *Server) authorize(client *Client, command string, args []resp.Value) error {
+	if strings.EqualFold(command, "AUTH") {
+		return nil
+	}
+	if !client.authed {
+		return fmt.Errorf("NOAUTH Authentication required.")
+	}
+	if s.acl == nil {
+		return nil
+	}
+	user := s.acl.Get(client.user)
+	if user != nil && user.Unrestricted() {
+		return nil
+	}
+	info, ok := s.commandSpec(command)
+	if !ok {
+		// Unknown commands fail on their own
+		return nil
+	}
+
+	name := strings.ToLower(info.Name)
+	var sub string
+	if hasSubcommands(name) && len(args) > 0 {
+		sub = strings.ToLower(args[0].Str)
+	}
+	categories := make([]string, len(info.ACLGroups))
+	for i, group := range info.ACLGroups {
+		categories[i] = string(group)
+	}
+	if user == nil || !user.CanRun(name, sub, categories) {
+		object := name
+		if sub != "" {
+			object += "|" + sub
+		}
+		return s.deny(client, info, sub, "command", object,
+			fmt.Errorf("NOPERM User %s has no permissions to run the '%s' command", client.user, object))
+	}
+	if info.Keys != nil {
+		for _, key := range info.Keys(args) {
+			if !user.CanAccessKey(key, !info.ReadOnly) {
+				return s.deny(client, info, sub, "key", key, fmt.Errorf("NOPERM No permissions to access a key"))
+			}
+		}
+	}
+	if info.Channels != nil {
+		pattern := name == "psubscribe"
+		for _, channel := range info.Channels(args) {
+			if !user.CanAccessChannel(channel, pattern) {
+				return s.deny(client, info, sub, "channel", channel, fmt.Errorf("NOPERM No permissions to access a channel"))
+			}
+		}
+	}
+	return nil
+}
+
+// authorizeArgs is authorize for the commands the server reads as strings
+func (s *Server) authorizeArgs(client *Client, command string, args []string) error {
+	respArgs := make([]resp.Value, len(args))
+	for i, arg := range args {
+		respArgs[i] = resp.Value{Type: resp.BulkString, Str: arg}
+	}
+	return s.authorize(client, command, respArgs)
+}
+
+// deny records a denied command, with its subcommand sub if any, in the ACL
+// LOG and the command stats
+func (s *Server) deny(client *Client, info *cmd.Command, sub, reason, object string, err error) error {
+	s.aclLog.Add(reason, aclContext(client), object, client.user, clientInfo(client, info.Name, sub))
+	if s.stats != nil {
+		s.stats.GetStats().RecordRejectedCommand(strings.ToUpper(info.Name))
+	}
+	return err
+}
+
+// aclContext tells ACL LOG whether a command ran on its own or in EXEC
+func aclContext(client *Client) string {
+	if client.txMode {
+		return "multi"
+	}
+	return "toplevel"
+}
+
+// clientInfo formats client for ACL LOG. The published state still names the
+// previous command, so cmd= reports command and sub, the one being refused.
+func clientInfo(client *Client, command, sub string) string {
+	st := client.snapshot()
+	st.cmd, st.arg = command, sub
+	return strings.TrimSuffix(client.formatInfo(st, time.Now()), "\n")
+}
+
+// killUserClients closes the connections authenticated as users that no
+// longer exist
+func (s *Server) killUserClients(client *Client) {
+	for _, c := range s.clients.list() {
+		if user := c.snapshot().user; user != "" && s.acl.Get(user) == nil {
+			s.kill(client, c)
+		}
+	}
+}
+
+// aclCommand handles ACL CAT, DELUSER, GETUSER, LIST, USERS, SETUSER,
+// WHOAMI, LOG, SAVE, LOAD and HELP
+func (s *Server) aclCommand(client *Client, args []resp.Value) (resp.Value, error) {
+	if len(args) == 0 {
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'ACL' command")
+	}
+	name := args[0].Str
+	sub := strings.ToUpper(name)
+	args = args[1:]
+	arity := func(ok bool) error {
+		if !ok {
+			return fmt.Errorf("ERR wrong number of arguments for 'ACL|%s' command", strings.ToLower(sub))
+		}
+		return nil
+	}
+	bulks := func(list []string) resp.Value {
+		out := make([]resp.Value, len(list))
+		for i, s := range list {
+			out[i] = resp.Value{Type: resp.BulkString, Str: s}
+		}
+		return resp.Value{Type: resp.Array, Array: out}
+	}
+
+	switch sub {
+	case "CAT":
+		if err := arity(len(args) <= 1); err != nil {
+			return resp.Value{}, err
+		}
+		if len(args) == 0 {
+			names := make([]string, len(cmd.AclGroups))
+			for i, group := range cmd.AclGroups {
+				names[i] = string(group)
+			}
+			return bulks(names), nil
+		}
+		category := strings.ToLower(args[0].Str)
+		if !(aclCatalog{s}).HasCategory(category) {
+			return resp.Value{}, fmt.Errorf("ERR Unknown category '%s'", args[0].Str)
+		}
+		var names []string
+		for _, registry := range []*cmd.Registry{s.registry, s.connCommands} {
+			for _, n := range registry.List() {
+				if info, ok := registry.Get(n); ok && info.InGroup(cmd.AclGroup(category)) {
+					names = append(names, strings.ToLower(n))
+				}
+			}
+		}
+		sort.Strings(names)
+		return bulks(names), nil
+	case "SETUSER":
+		if err := arity(len(args) >= 1); err != nil {
+			return resp.Value{}, err
+		}
+		rules := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			rules[i] = arg.Str
+		}
+		if err := s.acl.SetUser(args[0].Str, rules...); err != nil {
+			return resp.Value{}, fmt.Errorf("ERR %v", err)
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "DELUSER":
+		if err := arity(len(args) >= 1); err != nil {
+			return resp.Value{}, err
+		}
+		names := make([]string, len(args))
+		for i, arg := range args {
+			names[i] = arg.Str
+		}
+		deleted, err := s.acl.Delete(names...)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR %v", err)
+		}
+		s.killUserClients(client)
+		return resp.Value{Type: resp.Integer, Int: int64(deleted)}, nil
+	case "GETUSER":
+		if err := arity(len(args) == 1); err != nil {
+			return resp.Value{}, err
+		}
+		u := s.acl.Get(args[0].Str)
+		if u == nil {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		return resp.Value{Type: resp.Map, Array: []resp.Value{
+			{Type: resp.BulkString, Str: "flags"}, bulks(u.Flags()),
+			{Type: resp.BulkString, Str: "passwords"}, bulks(u.Passwords()),
+			{Type: resp.BulkString, Str: "commands"}, {Type: resp.BulkString, Str: u.CommandRules()},
+			{Type: resp.BulkString, Str: "keys"}, {Type: resp.BulkString, Str: u.KeyPatterns()},
+			{Type: resp.BulkString, Str: "channels"}, {Type: resp.BulkString, Str: u.ChannelPatterns()},
+			{Type: resp.BulkString, Str: "selectors"}, {Type: resp.Array, Array: []resp.Value{}},
+		}}, nil
+	case "LIST":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		var lines []string
+		for _, u := range s.acl.List() {
+			lines = append(lines, "user "+u.Name+" "+u.Describe())
+		}
+		return bulks(lines), nil
+	case "USERS":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		var names []string
+		for _, u := range s.acl.List() {
+			names = append(names, u.Name)
+		}
+		return bulks(names), nil
+	case "WHOAMI":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.BulkString, Str: client.user}, nil
+	case "LOG":
+		if err := arity(len(args) <= 1); err != nil {
+			return resp.Value{}, err
+		}
+		count := 10
+		if len(args) == 1 {
+			if strings.EqualFold(args[0].Str, "RESET") {
+				s.aclLog.Reset()
+				return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+			}
+			n, err := strconv.Atoi(args[0].Str)
+			if err != nil || n < 0 {
+				return resp.Value{}, fmt.Errorf("ERR value is out of range, must be positive")
+			}
+			count = n
+		}
+		now := time.Now()
+		entries := s.aclLog.Entries(count)
+		out := make([]resp.Value, len(entries))
+		for i, e := range entries {
+			out[i] = resp.Value{Type: resp.Map, Array: []resp.Value{
+				{Type: resp.BulkString, Str: "count"}, {Type: resp.Integer, Int: int64(e.Count)},
+				{Type: resp.BulkString, Str: "reason"}, {Type: resp.BulkString, Str: e.Reason},
+				{Type: resp.BulkString, Str: "context"}, {Type: resp.BulkString, Str: e.Context},
+				{Type: resp.BulkString, Str: "object"}, {Type: resp.BulkString, Str: e.Object},
+				{Type: resp.BulkString, Str: "username"}, {Type: resp.BulkString, Str: e.Username},
+				{Type: resp.BulkString, Str: "age-seconds"}, {Type: resp.Double, Str: resp.FormatDouble(now.Sub(e.Created).Seconds())},
+				{Type: resp.BulkString, Str: "client-info"}, {Type: resp.BulkString, Str: e.ClientInfo},
+				{Type: resp.BulkString, Str: "entry-id"}, {Type: resp.Integer, Int: e.ID},
+				{Type: resp.BulkString, Str: "timestamp-created"}, {Type: resp.Integer, Int: e.Created.UnixMilli()},
+				{Type: resp.BulkString, Str: "timestamp-last-updated"}, {Type: resp.Integer, Int: e.Updated.UnixMilli()},
+			}}
+		}
+		return resp.Value{Type: resp.Array, Array: out}, nil
+	case "SAVE", "LOAD":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		if s.cfg.ACLFile == "" {
+			return resp.Value{}, fmt.Errorf("ERR This Redis instance is not configured to use an ACL file. " +
+				"You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE " +
+				"(assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+		}
+		if sub == "SAVE" {
+			if err := s.acl.Save(s.cfg.ACLFile); err != nil {
+				return resp.Value{}, fmt.Errorf("ERR There was an error trying to save the ACLs. Please check the server logs for more information")
+			}
+		} else {
+			if err := s.acl.Load(s.cfg.ACLFile); err != nil {
+				return resp.Value{}, fmt.Errorf("ERR %v", err)
+			}
+			s.killUserClients(client)
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "HELP":
+		lines := []string{
+			"ACL <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"CAT [<category>]",
+			"    List all commands that belong to <category>, or all command categories",
+			"    when no category is specified.",
+			"DELUSER <username> [<username> ...]",
+			"    Delete a list of users.",
+			"GETUSER <username>",
+			"    Get the user's details.",
+			"LIST",
+			"    Show users details in config file format.",
+			"LOAD",
+			"    Reload users from the ACL file.",
+			"LOG [<count> | RESET]",
+			"    Show the ACL log entries.",
+			"SAVE",
+			"    Save the current config to the ACL file.",
+			"SETUSER <username> <attribute> [<attribute> ...]",
+			"    Create or modify a user with the specified attributes.",
+			"USERS",
+			"    List all the registered usernames.",
+			"WHOAMI",
+			"    Return the current connection username.",
+			"HELP",
+			"    Print this help.",
+		}
+		out := make([]resp.Value, len(lines))
+		for i, line := range lines {
+			out[i] = resp.Value{Type: resp.SimpleString, Str: line}
+		}
+		return resp.Value{Type: resp.Array, Array: out}, nil
+	default:
+		return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s'. Try ACL HELP.", name)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"gridhouse/internal/cmd"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readArray reads an array reply, each element flattened to its scalars
+// joined by spaces
+func (c *testConn) readArray(t *testing.T) []string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var read func() string
+	read = func() string {
+		line, err := c.r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\r\n")
+		n, _ := strconv.Atoi(line[1:])
+		switch line[0] {
+		case '$':
+			body := make([]byte, n+2)
+			_, err := io.ReadFull(c.r, body)
+			require.NoError(t, err)
+			return string(body[:n])
+		case '*':
+			parts := make([]string, n)
+			for i := range parts {
+				parts[i] = read()
+			}
+			return strings.Join(parts, " ")
+		}
+		return line[1:]
+	}
+	line, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, "*"), line)
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	require.NoError(t, err)
+	out := make([]string, n)
+	for i := range out {
+		out[i] = read()
+	}
+	return out
+}
+
+func TestACLPermissions(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	admin := dialTest(t, srv)
+	admin.send(t, "ACL", "SETUSER", "app", "on", ">pw", "~app:*", "%R~shared:*", "&news.*", "+@read", "+@write", "+@pubsub", "+@transaction", "-flushall")
+	admin.expect(t, "+OK\r\n")
+
+	c := dialTest(t, srv)
+	c.send(t, "AUTH", "app", "wrong")
+	c.expect(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+	c.send(t, "AUTH", "app", "pw")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "ACL", "WHOAMI")
+	c.expect(t, "-NOPERM User app has no permissions to run the 'acl|whoami' command\r\n")
+
+	c.send(t, "SET", "app:1", "v")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "GET", "shared:1")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "SET", "shared:1", "v")
+	c.expect(t, "-NOPERM No permissions to access a key\r\n")
+	c.send(t, "MSET", "app:2", "v", "other", "v")
+	c.expect(t, "-NOPERM No permissions to access a key\r\n")
+	c.send(t, "FLUSHALL")
+	c.expect(t, "-NOPERM User app has no permissions to run the 'flushall' command\r\n")
+	c.send(t, "PUBLISH", "news.tech", "hi")
+	c.expect(t, ":0\r\n")
+	c.send(t, "PUBLISH", "sports", "hi")
+	c.expect(t, "-NOPERM No permissions to access a channel\r\n")
+	c.send(t, "PSUBSCRIBE", "news.tech.*")
+	c.expect(t, "-NOPERM No permissions to access a channel\r\n")
+
+	// Commands inside MULTI and pipelines are checked too
+	c.send(t, "MULTI")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "app:3", "v")
+	c.expect(t, "+QUEUED\r\n")
+	c.send(t, "DEL", "other")
+	c.expect(t, "+QUEUED\r\n")
+	c.send(t, "EXEC")
+	c.expect(t, "*2\r\n+OK\r\n-NOPERM No permissions to access a key\r\n")
+	_, err := c.Write([]byte("*2\r\n$3\r\nGET\r\n$5\r\napp:1\r\n*2\r\n$3\r\nGET\r\n$5\r\nother\r\n"))
+	require.NoError(t, err)
+	c.expect(t, "$1\r\nv\r\n-NOPERM No permissions to access a key\r\n")
+
+	admin.send(t, "ACL", "LOG", "2")
+	log := admin.readArray(t)
+	require.Len(t, log, 2)
+	assert.Contains(t, log[0], "reason key context toplevel object other username app ")
+	assert.Contains(t, log[1], "reason key context multi object other username app ")
+
+	admin.send(t, "CLIENT", "LIST")
+	assert.Contains(t, admin.readBulk(t), " user=app ")
+
+	// Deleting the user closes its connections
+	admin.send(t, "ACL", "DELUSER", "app", "nobody")
+	admin.expect(t, ":1\r\n")
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = c.r.ReadByte()
+	assert.Error(t, err)
+}
+
+func TestACLHelloAndMemoryUsage(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	admin := dialTest(t, srv)
+	admin.send(t, "ACL", "SETUSER", "getter", "on", ">pw", "~app:*", "-@all", "+get", "+memory")
+	admin.expect(t, "+OK\r\n")
+
+	// HELLO AUTH authenticates, after which HELLO needs the permission
+	c := dialTest(t, srv)
+	c.send(t, "HELLO", "2", "AUTH", "getter", "pw")
+	c.readArray(t)
+	c.send(t, "HELLO", "2")
+	c.expect(t, "-NOPERM User getter has no permissions to run the 'hello' command\r\n")
+
+	c.send(t, "MEMORY", "USAGE", "other")
+	c.expect(t, "-NOPERM No permissions to access a key\r\n")
+	c.send(t, "MEMORY", "USAGE", "app:1")
+	c.expect(t, "$-1\r\n")
+
+	// ACL LOG names the refused command, not the one the client ran before
+	c.send(t, "GET", "app:1")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "PING")
+	c.expect(t, "-NOPERM User getter has no permissions to run the 'ping' command\r\n")
+	c.send(t, "AUTH", "getter", "wrong")
+	c.expect(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+	admin.send(t, "ACL", "LOG", "3")
+	log := admin.readArray(t)
+	require.Len(t, log, 3)
+	assert.Contains(t, log[0], " cmd=auth ")
+	assert.Contains(t, log[1], " cmd=ping ")
+	assert.Contains(t, log[2], " cmd=memory|usage ")
+}
+
+func TestACLDefaultUser(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+	c.send(t, "AUTH", "pw")
+	c.expect(t, "-ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?\r\n")
+	c.send(t, "ACL", "WHOAMI")
+	c.expect(t, "$7\r\ndefault\r\n")
+	c.send(t, "ACL", "LIST")
+	c.expect(t, "*1\r\n$34\r\nuser default on nopass ~* &* +@all\r\n")
+
+	// requirepass is the default user's password
+	c.send(t, "CONFIG", "SET", "requirepass", "pw")
+	c.expect(t, "+OK\r\n")
+	c2 := dialTest(t, srv)
+	c2.send(t, "ACL", "WHOAMI")
+	c2.expect(t, "-NOAUTH Authentication required.\r\n")
+	c2.send(t, "AUTH", "default", "pw")
+	c2.expect(t, "+OK\r\n")
+
+	c.send(t, "ACL", "SETUSER", "default", "+bogus")
+	c.expect(t, "-ERR Error in ACL SETUSER modifier '+bogus': Unknown command or category name in ACL\r\n")
+	c.send(t, "ACL", "DELUSER", "default")
+	c.expect(t, "-ERR The 'default' user cannot be removed\r\n")
+	c.send(t, "ACL", "GETUSER", "nobody")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "ACL", "CAT", "bogus")
+	c.expect(t, "-ERR Unknown category 'bogus'\r\n")
+	c.send(t, "ACL", "SAVE")
+	c.expect(t, "-ERR This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.\r\n")
+}
+
+func TestACLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	require.NoError(t, os.WriteFile(path, []byte("user reader on nopass ~* +@read\n"), 0600))
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, ACLFile: path})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+
+	c := dialTest(t, srv)
+	c.send(t, "AUTH", "reader", "x")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "-NOPERM User reader has no permissions to run the 'set' command\r\n")
+
+	admin := dialTest(t, srv)
+	admin.send(t, "ACL", "SETUSER", "writer", "on", ">pw", "~*", "+@write")
+	admin.expect(t, "+OK\r\n")
+	admin.send(t, "ACL", "SAVE")
+	admin.expect(t, "+OK\r\n")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "user writer on #")
+
+	// Users the reloaded file lacks lose their connections
+	require.NoError(t, os.WriteFile(path, []byte("user writer on nopass ~* +@write\n"), 0600))
+	admin.send(t, "ACL", "LOAD")
+	admin.expect(t, "+OK\r\n")
+	admin.send(t, "ACL", "USERS")
+	admin.expect(t, "*2\r\n$7\r\ndefault\r\n$6\r\nwriter\r\n")
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = c.r.ReadByte()
+	assert.Error(t, err)
+}
+
+func TestEveryCommandHasACLCategories(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	for _, registry := range []*cmd.Registry{srv.registry, srv.connCommands} {
+		for _, name := range registry.List() {
+			info, _ := registry.Get(name)
+			assert.NotEmpty(t, info.ACLGroups, name)
+		}
+	}
+}
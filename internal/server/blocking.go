@@ -0,0 +1,154 @@
+package server
+
+import (
+	"errors"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/repl"
+	"gridhouse/internal/resp"
+	"net"
+	"strings"
+	"time"
+)
+
+// execute runs a command through the registry. Blocking commands that have
+// nothing to serve park the client until they can be served or time out.
+// propagated reports that a served blocking command was already propagated.
+func (s *Server) execute(client *Client, command string, args []resp.Value) (result resp.Value, propagated bool, err error) {
+	result, err = s.dispatch(client, command, args)
+	if blocked, ok := asBlocked(err); ok {
+		return s.waitBlocked(client, command, args, blocked)
+	}
+	return result, false, err
+}
+
+// dispatch runs a command the client's ACL user is allowed and maxmemory
+// leaves room for: connection commands are served by the server and
+// everything else by the registry of the client's database. The execution
+// time goes to the command stats and the slow log.
+func (s *Server) dispatch(client *Client, command string, args []resp.Value) (resp.Value, error) {
+	if err := s.authorize(client, command, args); err != nil {
+		return resp.Value{}, err
+	}
+	if err := s.freeMemory(command); err != nil {
+		return resp.Value{}, err
+	}
+	start := time.Now()
+	var result resp.Value
+	var err error
+	switch {
+	case strings.EqualFold(command, "AUTH"):
+		result, err = s.auth(client, args)
+	case strings.EqualFold(command, "ACL"):
+		result, err = s.aclCommand(client, args)
+	case strings.EqualFold(command, "CLIENT"):
+		result, err = s.clientCommand(client, args)
+	case strings.EqualFold(command, "SLOWLOG"):
+		result, err = s.slowlogCommand(args)
+	default:
+		result, err = s.registryFor(client).Execute(command, args)
+	}
+	s.recordCommand(client, command, args, time.Since(start), err)
+	return result, err
+}
+
+// asBlocked reports whether err asks for the client to be parked
+func asBlocked(err error) (*cmd.BlockedError, bool) {
+	var blocked *cmd.BlockedError
+	if err != nil && errors.As(err, &blocked) {
+		return blocked, true
+	}
+	return nil, false
+}
+
+// waitBlocked parks the client on the keys of a blocked command. The command
+// is re-run through dispatch, in FIFO order with other blocked clients,
+// whenever one of the keys is modified. A served command is propagated right
+// away by the goroutine serving it, which already propagated the write that
+// woke the client, so the AOF and replicas see both in order; served reports
+// that it was.
+func (s *Server) waitBlocked(client *Client, command string, args []resp.Value, blocked *cmd.BlockedError) (result resp.Value, served bool, err error) {
+	db, registry := client.db, s.registryFor(client)
+	propArgs := make([]string, len(args))
+	for i, arg := range args {
+		propArgs[i] = arg.Str
+	}
+	if blocked.RetryArgs != nil {
+		args = blocked.RetryArgs
+	}
+	retry := func() (resp.Value, bool, error) {
+		result, err := s.dispatch(client, command, args)
+		if _, ok := asBlocked(err); ok {
+			return resp.Value{}, true, nil
+		}
+		if info, ok := registry.Get(command); ok && err == nil && !info.ReadOnly {
+			if name, newArgs, ok := propagation(info, command, propArgs, result); ok {
+				s.propagate(db, name, newArgs)
+			}
+		}
+		return result, false, err
+	}
+
+	gone, stopWatching := client.watchDisconnect()
+	s.stats.GetStats().IncrementBlockedClients()
+	client.blocked.Store(true)
+	result, ok, err := s.blocking.Wait(blocked.Keys, blocked.Timeout, retry, gone)
+	client.blocked.Store(false)
+	s.stats.GetStats().DecrementBlockedClients()
+	stopWatching()
+
+	if !ok {
+		// Served commands were recorded by dispatch, the others end here
+		s.recordCommand(client, command, args, 0, nil)
+		return blocked.TimeoutReply, false, nil
+	}
+	return result, true, err
+}
+
+// propagation returns the command to append to the AOF and forward to
+// replicas for a successful write command
+func propagation(info *cmd.Command, command string, args []string, result resp.Value) (string, []string, bool) {
+	if info.Rewrite != nil {
+		return info.Rewrite(args, result)
+	}
+	return command, args, true
+}
+
+// propagate appends a write command run in database db to the AOF and
+// forwards it to the replicas. The AOF writer queues appends, so calling it
+// in line costs no I/O and keeps commands in the order they ran.
+func (s *Server) propagate(db int, command string, args []string) {
+	if s.persist != nil {
+		if err := s.persist.AppendCommandDB(db, command, args); err != nil {
+			logger.Error(err)
+		}
+	}
+	if s.replManager.Count() > 0 && s.replManager.Role() == repl.RoleMaster {
+		logger.Debugf("Forwarding write command %s to replicas", command)
+		s.replicate(db, command, args)
+	}
+}
+
+// watchDisconnect reports when the peer closes the connection while the
+// handler goroutine is parked. stop must be called before the client is read
+// from again.
+func (c *Client) watchDisconnect() (<-chan struct{}, func()) {
+	gone := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		if _, err := c.reader.Peek(1); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return
+			}
+			close(gone)
+		}
+	}()
+	stop := func() {
+		c.conn.SetReadDeadline(time.Now())
+		<-finished
+		c.conn.SetReadDeadline(time.Time{})
+	}
+	return gone, stop
+}
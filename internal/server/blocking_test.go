@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gridhouse/internal/aof"
+	"gridhouse/internal/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConn is a raw RESP connection used by the blocking command tests
+type testConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func dialTest(t *testing.T, srv *Server) *testConn {
+	t.Helper()
+	c, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return &testConn{Conn: c, r: bufio.NewReader(c)}
+}
+
+func (c *testConn) send(t *testing.T, args ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+	}
+	_, err := c.Write([]byte(b.String()))
+	require.NoError(t, err)
+}
+
+func (c *testConn) expect(t *testing.T, want string) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got := make([]byte, len(want))
+	_, err := io.ReadFull(c.r, got)
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+}
+
+func (c *testConn) expectNothing(t *testing.T) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err := c.r.Peek(1)
+	require.Error(t, err)
+	c.SetReadDeadline(time.Time{})
+}
+
+func startBlockingTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func waitBlockedClients(t *testing.T, srv *Server, n int64) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return srv.stats.GetStats().GetBlockedClients() == n
+	}, 2*time.Second, time.Millisecond)
+}
+
+func TestBlockingPopWakesOnPush(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	waiter := dialTest(t, srv)
+	pusher := dialTest(t, srv)
+
+	waiter.send(t, "BLPOP", "jobs", "0")
+	waitBlockedClients(t, srv, 1)
+
+	pusher.send(t, "INFO", "clients")
+	header, err := pusher.r.ReadString('\n')
+	require.NoError(t, err)
+	size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	require.NoError(t, err)
+	body := make([]byte, size+2)
+	_, err = io.ReadFull(pusher.r, body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "blocked_clients:1\r\n")
+
+	pusher.send(t, "RPUSH", "jobs", "j1")
+	pusher.expect(t, ":1\r\n")
+	waiter.expect(t, "*2\r\n$4\r\njobs\r\n$2\r\nj1\r\n")
+	waitBlockedClients(t, srv, 0)
+}
+
+func TestBlockingPopFIFO(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	first := dialTest(t, srv)
+	second := dialTest(t, srv)
+	pusher := dialTest(t, srv)
+
+	first.send(t, "BRPOP", "q", "0")
+	waitBlockedClients(t, srv, 1)
+	second.send(t, "BRPOP", "q", "0")
+	waitBlockedClients(t, srv, 2)
+
+	pusher.send(t, "LPUSH", "q", "a")
+	pusher.expect(t, ":1\r\n")
+	first.expect(t, "*2\r\n$1\r\nq\r\n$1\r\na\r\n")
+	second.expectNothing(t)
+
+	pusher.send(t, "LPUSH", "q", "b")
+	pusher.expect(t, ":1\r\n")
+	second.expect(t, "*2\r\n$1\r\nq\r\n$1\r\nb\r\n")
+}
+
+func TestBlockingPopTimeout(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+
+	start := time.Now()
+	c.send(t, "BLPOP", "none", "0.05")
+	c.expect(t, "*-1\r\n")
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	c.send(t, "BLMOVE", "none", "dst", "LEFT", "RIGHT", "0.01")
+	c.expect(t, "$-1\r\n")
+
+	// The connection is usable after a timeout
+	c.send(t, "PING")
+	c.expect(t, "+PONG\r\n")
+}
+
+func TestBlockingMoveAndMPop(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	waiter := dialTest(t, srv)
+	pusher := dialTest(t, srv)
+
+	waiter.send(t, "BLMOVE", "src", "dst", "LEFT", "LEFT", "0")
+	waitBlockedClients(t, srv, 1)
+	pusher.send(t, "RPUSH", "src", "x")
+	pusher.expect(t, ":1\r\n")
+	waiter.expect(t, "$1\r\nx\r\n")
+
+	pusher.send(t, "LRANGE", "dst", "0", "-1")
+	pusher.expect(t, "*1\r\n$1\r\nx\r\n")
+
+	waiter.send(t, "BLMPOP", "0", "2", "a", "b", "LEFT", "COUNT", "2")
+	waitBlockedClients(t, srv, 1)
+	pusher.send(t, "RPUSH", "b", "1", "2", "3")
+	pusher.expect(t, ":3\r\n")
+	waiter.expect(t, "*2\r\n$1\r\nb\r\n*2\r\n$1\r\n1\r\n$1\r\n2\r\n")
+}
+
+func TestBlockingPopInsideTransaction(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	waiter := dialTest(t, srv)
+	tx := dialTest(t, srv)
+
+	waiter.send(t, "BLPOP", "k", "0")
+	waitBlockedClients(t, srv, 1)
+
+	// The transaction sees its own push; the blocked client is only served
+	// once EXEC has finished
+	tx.send(t, "MULTI")
+	tx.expect(t, "+OK\r\n")
+	tx.send(t, "LPUSH", "k", "mine")
+	tx.expect(t, "+QUEUED\r\n")
+	tx.send(t, "LPOP", "k")
+	tx.expect(t, "+QUEUED\r\n")
+	tx.send(t, "LPUSH", "k", "theirs")
+	tx.expect(t, "+QUEUED\r\n")
+	tx.send(t, "EXEC")
+	tx.expect(t, "*3\r\n:1\r\n$4\r\nmine\r\n:1\r\n")
+
+	waiter.expect(t, "*2\r\n$1\r\nk\r\n$6\r\ntheirs\r\n")
+
+	// Blocking commands inside MULTI answer immediately
+	tx.send(t, "MULTI")
+	tx.expect(t, "+OK\r\n")
+	tx.send(t, "BLPOP", "empty", "0")
+	tx.expect(t, "+QUEUED\r\n")
+	tx.send(t, "EXEC")
+	tx.expect(t, "*1\r\n*-1\r\n")
+}
+
+func TestBlockedClientDisconnect(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	waiter := dialTest(t, srv)
+	pusher := dialTest(t, srv)
+
+	waiter.send(t, "BLPOP", "k", "0")
+	waitBlockedClients(t, srv, 1)
+	waiter.Close()
+	waitBlockedClients(t, srv, 0)
+
+	// The element is not consumed by the departed client
+	pusher.send(t, "RPUSH", "k", "v")
+	pusher.expect(t, ":1\r\n")
+	pusher.send(t, "LLEN", "k")
+	pusher.expect(t, ":1\r\n")
+}
+
+func TestXReadBlockWakesOnXAdd(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	reader := dialTest(t, srv)
+	writer := dialTest(t, srv)
+
+	writer.send(t, "XADD", "s1", "1-0", "f", "old")
+	writer.expect(t, "$3\r\n1-0\r\n")
+
+	reader.send(t, "XREAD", "BLOCK", "0", "STREAMS", "s1", "s2", "$", "$")
+	waitBlockedClients(t, srv, 1)
+
+	// Unrelated writes do not wake the reader
+	writer.send(t, "XADD", "other", "1-0", "f", "v")
+	writer.expect(t, "$3\r\n1-0\r\n")
+	reader.expectNothing(t)
+
+	writer.send(t, "XADD", "s2", "7-0", "f", "new")
+	writer.expect(t, "$3\r\n7-0\r\n")
+	reader.expect(t, "*1\r\n*2\r\n$2\r\ns2\r\n*1\r\n*2\r\n$3\r\n7-0\r\n*2\r\n$1\r\nf\r\n$3\r\nnew\r\n")
+	waitBlockedClients(t, srv, 0)
+
+	reader.send(t, "XREAD", "BLOCK", "20", "STREAMS", "s1", "$")
+	reader.expect(t, "*-1\r\n")
+}
+
+func TestXReadGroupBlockWakesOnXAdd(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	reader := dialTest(t, srv)
+	writer := dialTest(t, srv)
+
+	writer.send(t, "XGROUP", "CREATE", "jobs", "workers", "$", "MKSTREAM")
+	writer.expect(t, "+OK\r\n")
+
+	reader.send(t, "XREADGROUP", "GROUP", "workers", "alice", "BLOCK", "0", "STREAMS", "jobs", ">")
+	waitBlockedClients(t, srv, 1)
+
+	writer.send(t, "XADD", "jobs", "1-0", "task", "a")
+	writer.expect(t, "$3\r\n1-0\r\n")
+	reader.expect(t, "*1\r\n*2\r\n$4\r\njobs\r\n*1\r\n*2\r\n$3\r\n1-0\r\n*2\r\n$4\r\ntask\r\n$1\r\na\r\n")
+
+	writer.send(t, "XPENDING", "jobs", "workers")
+	writer.expect(t, "*4\r\n:1\r\n$3\r\n1-0\r\n$3\r\n1-0\r\n*1\r\n*2\r\n$5\r\nalice\r\n$1\r\n1\r\n")
+
+	// Destroying the group releases blocked readers with an error
+	reader.send(t, "XREADGROUP", "GROUP", "workers", "alice", "BLOCK", "0", "STREAMS", "jobs", ">")
+	waitBlockedClients(t, srv, 1)
+	writer.send(t, "XGROUP", "DESTROY", "jobs", "workers")
+	writer.expect(t, ":1\r\n")
+	reader.expect(t, "-NOGROUP No such key 'jobs' or consumer group 'workers' in XREADGROUP with GROUP option\r\n")
+}
+
+func TestServedBlockingPopFollowsPush(t *testing.T) {
+	dir := t.TempDir()
+	srv := New(Config{
+		Addr:           "127.0.0.1:0",
+		MaxConnections: 10,
+		Persistence:    &persistence.Config{Dir: dir, AOFEnabled: true, AOFSyncMode: aof.Always},
+	})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	waiter := dialTest(t, srv)
+	pusher := dialTest(t, srv)
+
+	waiter.send(t, "BLPOP", "jobs", "0")
+	waitBlockedClients(t, srv, 1)
+	pusher.send(t, "RPUSH", "jobs", "j1")
+	pusher.expect(t, ":1\r\n")
+	waiter.expect(t, "*2\r\n$4\r\njobs\r\n$2\r\nj1\r\n")
+
+	// The served pop is propagated after the push that woke it
+	push := string(persistence.EncodeRESPArrayFast("RPUSH", []string{"jobs", "j1"}))
+	pop := string(persistence.EncodeRESPArrayFast("LPOP", []string{"jobs"}))
+	var content string
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filepath.Join(dir, "appendonly.aof"))
+		content = string(data)
+		return strings.Contains(content, pop)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Less(t, strings.Index(content, push), strings.Index(content, pop))
+
+	// The blocked call is recorded once, when it is served
+	pusher.send(t, "INFO", "commandstats")
+	assert.Contains(t, pusher.readBulk(t), "cmdstat_blpop:calls=1,")
+}
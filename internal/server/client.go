@@ -3,10 +3,13 @@ package server
 import (
 	"bufio"
 	"fmt"
+	"gridhouse/internal/pubsub"
 	"gridhouse/internal/resp"
 	"net"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // QueuedCommand represents a command queued during a transaction
@@ -23,18 +26,57 @@ type Client struct {
 	server *Server
 	connID string
 
-	// Auth state
+	// id is the numeric connection ID reported by HELLO
+	id connectionID
+
+	// Protocol version negotiated with HELLO and the name set by SETNAME
+	proto int
+	name  string
+
+	// Auth state: user is the ACL user the connection runs as
 	authed bool
+	user   string
+
+	// Selected database
+	db int
 
 	// Transaction state
 	txMode     bool
 	queuedCmds []QueuedCommand
 
+	// Pub/Sub state: subscribed is true while the client is in subscriber mode
+	sub        *pubsub.Subscriber
+	subscribed bool
+	pumpDone   chan struct{}
+
+	// monitor is set once the client ran MONITOR
+	monitor *monitor
+
 	// Performance: Reusable response buffer
 	responseBuf *[]byte
 
 	// Per-client writer mutex to minimize lock contention scope
 	writerMu sync.Mutex
+
+	// CLIENT LIST bookkeeping: lastCmd and lastArg are the last command read
+	// and its first argument, replica is set once the connection ran PSYNC
+	// and noEvict by CLIENT NO-EVICT
+	created time.Time
+	lastCmd string
+	lastArg string
+	replica bool
+	noEvict bool
+	blocked atomic.Bool
+	stateMu sync.Mutex
+	state   clientState
+
+	// CLIENT REPLY state: mute drops the replies of the running command
+	replyOff bool
+	skipNext bool
+	mute     bool
+
+	// closeAfterReply closes the connection once the running command replied
+	closeAfterReply bool
 }
 
 // newClient creates a new client instance
@@ -42,13 +84,24 @@ func newClient(conn net.Conn, server *Server, connID string) *Client {
 	responseBuf := responsePool.Get().(*[]byte)
 	*responseBuf = (*responseBuf)[:0] // Reset buffer
 
+	var id connectionID
+	if tracker, ok := conn.(*connectionTracker); ok {
+		id = tracker.id
+	} else {
+		id = connectionID(atomic.AddUint64(&nextConnectionID, 1))
+	}
+
 	return &Client{
 		conn:        conn,
 		reader:      bufio.NewReaderSize(conn, server.cfg.ReadBuffer),
 		writer:      bufio.NewWriterSize(conn, server.cfg.WriteBuffer),
 		server:      server,
 		connID:      connID,
-		authed:      server.cfg.Password == "",
+		id:          id,
+		proto:       resp.RESP2,
+		created:     time.Now(),
+		authed:      server.defaultAuthed(),
+		user:        "default",
 		txMode:      false,
 		queuedCmds:  make([]QueuedCommand, 0),
 		responseBuf: responseBuf,
@@ -64,9 +117,19 @@ func (c *Client) readCommand() (string, []string, error) {
 	return command, args, nil
 }
 
-// writeResponse writes a response without flushing
+// startCommand records the command for CLIENT LIST and applies CLIENT REPLY
+func (c *Client) startCommand(command string, args []string) {
+	c.lastCmd, c.lastArg = command, ""
+	if len(args) > 0 {
+		c.lastArg = args[0]
+	}
+	c.mute = c.replyOff || c.skipNext
+	c.skipNext = false
+}
+
+// writeResponse writes a response in the client's protocol without flushing
 func (c *Client) writeResponse(response resp.Value) error {
-	return resp.UltraEncode(c.writer, response)
+	return resp.UltraEncodeProto(c.writer, response, c.proto)
 }
 
 // writeResponseOK writes an OK response without flushing
@@ -113,8 +176,21 @@ func (c *Client) flushProtected() error {
 	return c.writer.Flush()
 }
 
-// writeAndFlush writes a response and flushes immediately
+// sharesWriter reports whether a pub/sub or MONITOR pump goroutine writes to
+// the connection too. RESP3 subscribers and monitors keep running commands.
+func (c *Client) sharesWriter() bool {
+	return c.sub != nil || c.monitor != nil
+}
+
+// writeAndFlush writes a response and flushes immediately, under the writer
+// mutex when the writer is shared
 func (c *Client) writeAndFlush(response resp.Value) error {
+	if c.mute {
+		return nil
+	}
+	if c.sharesWriter() {
+		return c.writeAndFlushProtected(response)
+	}
 	if err := c.writeResponse(response); err != nil {
 		return err
 	}
@@ -123,6 +199,12 @@ func (c *Client) writeAndFlush(response resp.Value) error {
 
 // writeAndFlushOK writes an OK response and flushes immediately
 func (c *Client) writeAndFlushOK() error {
+	if c.mute {
+		return nil
+	}
+	if c.sharesWriter() {
+		return c.writeAndFlushProtected(resp.Value{Type: resp.SimpleString, Str: "OK"})
+	}
 	if err := c.writeResponseOK(); err != nil {
 		return err
 	}
@@ -131,12 +213,32 @@ func (c *Client) writeAndFlushOK() error {
 
 // writeAndFlushError writes an error response and flushes immediately
 func (c *Client) writeAndFlushError(errMsg string) error {
+	if c.mute {
+		return nil
+	}
+	if c.sharesWriter() {
+		return c.writeAndFlushProtected(resp.Value{Type: resp.Error, Str: errMsg})
+	}
 	if err := c.writeResponseError(errMsg); err != nil {
 		return err
 	}
 	return c.flush()
 }
 
+// writeAndFlushProtected writes a response and flushes under the writer mutex,
+// for connections that share their writer with a pub/sub message pump
+func (c *Client) writeAndFlushProtected(response resp.Value) error {
+	if c.mute {
+		return nil
+	}
+	c.writerMu.Lock()
+	defer c.writerMu.Unlock()
+	if err := c.writeResponse(response); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
 func (c *Client) writeRawAndFlush(response []byte) error {
 	if _, err := c.writer.Write(response); err != nil {
 		return err
@@ -200,29 +302,32 @@ func (c *Client) execTransaction() error {
 		return fmt.Errorf("EXEC without MULTI")
 	}
 
-	// Execute all queued commands
+	// Execute all queued commands. Blocked clients are served only once the
+	// whole transaction has run, and blocking commands never block inside it.
 	results := make([]resp.Value, len(c.queuedCmds))
+	if b := c.server.blocking; b != nil {
+		b.Hold()
+		defer b.ServeReady()
+		defer b.Release()
+	}
 	for i, queuedCmd := range c.queuedCmds {
+		c.server.feedMonitors(c, queuedCmd.Command, queuedCmd.Args)
 		result, err := c.executeCommand(queuedCmd.Command, queuedCmd.Args)
-		if err != nil {
-			results[i] = resp.Value{Type: resp.Error, Str: err.Error()}
+		if blocked, ok := asBlocked(err); ok {
+			results[i] = blocked.TimeoutReply
+		} else if err != nil {
+			results[i] = resp.Value{Type: resp.Error, Str: errorReply(err)}
 		} else {
 			results[i] = result
 		}
 	}
 
-	// Write all responses
-	response := resp.Value{Type: resp.Array, Array: results}
-	if err := c.writeResponse(response); err != nil {
-		return err
-	}
-
 	// Exit transaction mode
 	c.txMode = false
 	c.queuedCmds = c.queuedCmds[:0]
 
-	// Use protected flush for transactions
-	return c.flushProtected()
+	// Write all responses, protected for transactions
+	return c.writeAndFlushProtected(resp.Value{Type: resp.Array, Array: results})
 }
 
 // discardTransaction discards all queued commands and exits transaction mode
@@ -257,10 +362,11 @@ func (c *Client) executeCommand(command string, args []string) (resp.Value, erro
 	switch command {
 	default:
 		// Use registry for other commands
-		response, err := c.server.registry.Execute(command, respArgs)
+		response, err := c.server.dispatch(c, command, respArgs)
 		if err != nil {
 			return resp.Value{}, err
 		}
+		c.selectDB(command, args)
 		return response, nil
 	}
 }
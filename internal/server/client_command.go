@@ -0,0 +1,281 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientCommand handles the CLIENT subcommands. They act on connections, so
+// the server serves them instead of a registry.
+func (s *Server) clientCommand(client *Client, args []resp.Value) (resp.Value, error) {
+	if len(args) == 0 {
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'CLIENT' command")
+	}
+	name := args[0].Str
+	sub := strings.ToUpper(name)
+	args = args[1:]
+	arity := func(ok bool) error {
+		if !ok {
+			return fmt.Errorf("ERR wrong number of arguments for 'CLIENT|%s' command", strings.ToLower(sub))
+		}
+		return nil
+	}
+
+	switch sub {
+	case "ID":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(client.id)}, nil
+	case "INFO":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		client.publishState()
+		return resp.Value{Type: resp.Verbatim, Str: client.info(time.Now())}, nil
+	case "LIST":
+		return s.clientList(client, args)
+	case "KILL":
+		if err := arity(len(args) > 0); err != nil {
+			return resp.Value{}, err
+		}
+		return s.clientKill(client, args)
+	case "GETNAME":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		if client.name == "" {
+			return resp.Value{Type: resp.BulkString, IsNull: true}, nil
+		}
+		return resp.Value{Type: resp.BulkString, Str: client.name}, nil
+	case "SETNAME":
+		if err := arity(len(args) == 1); err != nil {
+			return resp.Value{}, err
+		}
+		if !validClientName(args[0].Str) {
+			return resp.Value{}, fmt.Errorf("ERR Client names cannot contain spaces, newlines or special characters.")
+		}
+		client.name = args[0].Str
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "PAUSE":
+		if err := arity(len(args) == 1 || len(args) == 2); err != nil {
+			return resp.Value{}, err
+		}
+		ms, err := strconv.ParseInt(args[0].Str, 10, 64)
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR timeout is not an integer or out of range")
+		}
+		if ms < 0 {
+			return resp.Value{}, fmt.Errorf("ERR timeout is negative")
+		}
+		mode := pauseAll
+		if len(args) == 2 {
+			switch strings.ToUpper(args[1].Str) {
+			case "WRITE":
+				mode = pauseWrite
+			case "ALL":
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+		}
+		s.pause.pause(time.Duration(ms)*time.Millisecond, mode)
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "UNPAUSE":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		s.pause.lift()
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "NO-EVICT":
+		if err := arity(len(args) == 1); err != nil {
+			return resp.Value{}, err
+		}
+		on, err := parseOnOff(args[0].Str)
+		if err != nil {
+			return resp.Value{}, err
+		}
+		client.noEvict = on
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "REPLY":
+		if err := arity(len(args) == 1); err != nil {
+			return resp.Value{}, err
+		}
+		switch strings.ToUpper(args[0].Str) {
+		case "ON":
+			client.replyOff, client.mute = false, false
+		case "OFF":
+			client.replyOff, client.mute = true, true
+		case "SKIP":
+			if !client.replyOff {
+				client.skipNext, client.mute = true, true
+			}
+		default:
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		}
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	default:
+		return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s' for 'CLIENT' command", name)
+	}
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "ON":
+		return true, nil
+	case "OFF":
+		return false, nil
+	}
+	return false, fmt.Errorf("ERR syntax error")
+}
+
+// clientList handles CLIENT LIST [TYPE normal|master|replica|pubsub] [ID id ...]
+func (s *Server) clientList(client *Client, args []resp.Value) (resp.Value, error) {
+	var kind string
+	var ids map[connectionID]bool
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.EqualFold(args[i].Str, "TYPE") && i+1 < len(args):
+			k, err := parseClientType(args[i+1].Str)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			kind = k
+			i++
+		case strings.EqualFold(args[i].Str, "ID") && i+1 < len(args):
+			ids = make(map[connectionID]bool)
+			for i++; i < len(args); i++ {
+				id, err := strconv.ParseUint(args[i].Str, 10, 64)
+				if err != nil || id == 0 {
+					return resp.Value{}, fmt.Errorf("ERR Invalid client ID")
+				}
+				ids[connectionID(id)] = true
+			}
+		default:
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		}
+	}
+
+	client.publishState()
+	now := time.Now()
+	var b strings.Builder
+	for _, c := range s.clients.list() {
+		if kind != "" && c.snapshot().kind != kind {
+			continue
+		}
+		if ids != nil && !ids[c.id] {
+			continue
+		}
+		b.WriteString(c.info(now))
+	}
+	return resp.Value{Type: resp.Verbatim, Str: b.String()}, nil
+}
+
+// parseClientType parses the TYPE filter of CLIENT LIST and CLIENT KILL
+func parseClientType(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "normal":
+		return "normal", nil
+	case "replica", "slave":
+		return "replica", nil
+	case "pubsub":
+		return "pubsub", nil
+	case "master":
+		return "master", nil
+	}
+	return "", fmt.Errorf("ERR Unknown client type '%s'", s)
+}
+
+// clientKill handles CLIENT KILL addr:port and the filter form
+// CLIENT KILL [ID id] [TYPE type] [USER user] [ADDR addr] [LADDR addr]
+// [SKIPME yes|no] [MAXAGE seconds]
+func (s *Server) clientKill(client *Client, args []resp.Value) (resp.Value, error) {
+	// The old form kills a single client by address and replies OK
+	if len(args) == 1 {
+		for _, c := range s.clients.list() {
+			if c.addr() == args[0].Str {
+				s.kill(client, c)
+				return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+			}
+		}
+		return resp.Value{}, fmt.Errorf("ERR No such client")
+	}
+	if len(args)%2 != 0 {
+		return resp.Value{}, fmt.Errorf("ERR syntax error")
+	}
+
+	var id connectionID
+	var kind, user, addr, laddr string
+	var maxAge int64
+	skipMe := true
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1].Str
+		switch strings.ToUpper(args[i].Str) {
+		case "ID":
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || n == 0 {
+				return resp.Value{}, fmt.Errorf("ERR client-id should be greater than 0")
+			}
+			id = connectionID(n)
+		case "TYPE":
+			k, err := parseClientType(value)
+			if err != nil {
+				return resp.Value{}, err
+			}
+			kind = k
+		case "USER":
+			user = value
+		case "ADDR":
+			addr = value
+		case "LADDR":
+			laddr = value
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				skipMe = true
+			case "no":
+				skipMe = false
+			default:
+				return resp.Value{}, fmt.Errorf("ERR syntax error")
+			}
+		case "MAXAGE":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return resp.Value{}, fmt.Errorf("ERR value is not an integer or out of range")
+			}
+			maxAge = n
+		default:
+			return resp.Value{}, fmt.Errorf("ERR syntax error")
+		}
+	}
+
+	now := time.Now()
+	killed := int64(0)
+	for _, c := range s.clients.list() {
+		switch {
+		case id != 0 && c.id != id,
+			kind != "" && c.snapshot().kind != kind,
+			user != "" && c.snapshot().user != user,
+			addr != "" && c.addr() != addr,
+			laddr != "" && c.laddr() != laddr,
+			maxAge > 0 && now.Sub(c.created) < time.Duration(maxAge)*time.Second,
+			skipMe && c == client:
+			continue
+		}
+		s.kill(client, c)
+		killed++
+	}
+	return resp.Value{Type: resp.Integer, Int: killed}, nil
+}
+
+// kill closes the connection of target. A client killing itself gets its
+// reply before the connection is closed.
+func (s *Server) kill(client, target *Client) {
+	if target == client {
+		client.closeAfterReply = true
+		return
+	}
+	target.conn.Close()
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clientRegistry tracks the connected clients for CLIENT LIST and CLIENT
+// KILL. The zero value is ready to use.
+type clientRegistry struct {
+	mu      sync.RWMutex
+	clients map[connectionID]*Client
+}
+
+func (r *clientRegistry) add(c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clients == nil {
+		r.clients = make(map[connectionID]*Client)
+	}
+	r.clients[c.id] = c
+}
+
+func (r *clientRegistry) remove(c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, c.id)
+}
+
+// list returns the connected clients ordered by ID
+func (r *clientRegistry) list() []*Client {
+	r.mu.RLock()
+	out := make([]*Client, 0, len(r.clients))
+	for _, c := range r.clients {
+		out = append(out, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].id < out[j].id })
+	return out
+}
+
+// clientState is what CLIENT LIST reports about a client. The connection
+// goroutine owns the Client fields and publishes a copy between commands.
+type clientState struct {
+	name       string
+	user       string
+	db         int
+	cmd        string
+	arg        string
+	lastActive time.Time
+	flags      string
+	kind       string // normal, replica or pubsub
+	sub        int
+	psub       int
+	ssub       int
+	multi      int
+	qbuf       int
+	obl        int
+	proto      int
+}
+
+// publishState snapshots the connection state for other goroutines
+func (c *Client) publishState() {
+	st := clientState{
+		name:       c.name,
+		user:       c.user,
+		db:         c.db,
+		cmd:        c.lastCmd,
+		arg:        c.lastArg,
+		lastActive: time.Now(),
+		kind:       "normal",
+		multi:      -1,
+		qbuf:       c.reader.Buffered(),
+		proto:      c.proto,
+	}
+	var flags strings.Builder
+	if c.replica {
+		flags.WriteByte('S')
+		st.kind = "replica"
+	}
+	if c.subscribed {
+		flags.WriteByte('P')
+		st.kind = "pubsub"
+	}
+	if c.txMode {
+		flags.WriteByte('x')
+		st.multi = len(c.queuedCmds)
+	}
+	if c.noEvict {
+		flags.WriteByte('e')
+	}
+	if c.monitor != nil {
+		flags.WriteByte('O')
+	}
+	if c.unixSocket() {
+		flags.WriteByte('U')
+	}
+	st.flags = flags.String()
+	if c.sub != nil {
+		st.sub, st.psub, st.ssub = c.server.hub.Counts(c.sub)
+	}
+	if c.sharesWriter() {
+		c.writerMu.Lock()
+		st.obl = c.writer.Buffered()
+		c.writerMu.Unlock()
+	} else {
+		st.obl = c.writer.Buffered()
+	}
+
+	c.stateMu.Lock()
+	c.state = st
+	c.stateMu.Unlock()
+}
+
+// snapshot returns the last published state
+func (c *Client) snapshot() clientState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+// info formats the client the way CLIENT LIST and CLIENT INFO report it
+func (c *Client) info(now time.Time) string {
+	return c.formatInfo(c.snapshot(), now)
+}
+
+// formatInfo formats the client from st
+func (c *Client) formatInfo(st clientState, now time.Time) string {
+	flags := st.flags
+	if c.blocked.Load() {
+		flags += "b"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+	cmd := strings.ToLower(st.cmd)
+	switch {
+	case cmd == "":
+		cmd = "NULL"
+	case hasSubcommands(cmd) && st.arg != "":
+		cmd += "|" + strings.ToLower(st.arg)
+	}
+	qbufFree := c.reader.Size() - st.qbuf
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d sub=%d psub=%d ssub=%d "+
+		"multi=%d qbuf=%d qbuf-free=%d obl=%d oll=0 omem=%d tot-mem=%d cmd=%s user=%s resp=%d\n",
+		c.id, c.addr(), c.laddr(), st.name,
+		int64(now.Sub(c.created).Seconds()), int64(now.Sub(st.lastActive).Seconds()), flags, st.db,
+		st.sub, st.psub, st.ssub, st.multi, st.qbuf, qbufFree, st.obl, st.obl,
+		c.reader.Size()+c.writer.Size(), cmd, st.user, st.proto)
+}
+
+// unixSocket reports whether the client connected through the Unix socket
+func (c *Client) unixSocket() bool {
+	laddr := c.conn.LocalAddr()
+	return laddr != nil && laddr.Network() == "unix"
+}
+
+// addr returns the address of the client; clients of the Unix socket have
+// the socket path with port 0, as in Redis
+func (c *Client) addr() string {
+	if c.unixSocket() {
+		return c.laddr()
+	}
+	return c.conn.RemoteAddr().String()
+}
+
+// laddr returns the address the client connected to
+func (c *Client) laddr() string {
+	if c.unixSocket() {
+		return c.conn.LocalAddr().String() + ":0"
+	}
+	return c.conn.LocalAddr().String()
+}
+
+// hasSubcommands reports whether the first argument of the lower case
+// command names a subcommand, which CLIENT LIST and ACL rules tell apart
+func hasSubcommands(command string) bool {
+	switch command {
+	case "acl", "client", "command", "config", "latency", "memory", "object", "pubsub",
+		"script", "slowlog", "xgroup", "xinfo":
+		return true
+	}
+	return false
+}
+
+// Pause modes of CLIENT PAUSE
+const (
+	pauseNone int32 = iota
+	pauseWrite
+	pauseAll
+)
+
+// pauseState implements CLIENT PAUSE and UNPAUSE. The zero value is unpaused.
+type pauseState struct {
+	mode   atomic.Int32
+	mu     sync.Mutex
+	until  time.Time
+	timer  *time.Timer
+	lifted chan struct{} // closed when the pause ends
+}
+
+// pause holds commands of mode for d. A pause in progress is extended, never
+// shortened, and takes the new mode.
+func (p *pauseState) pause(d time.Duration, mode int32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lifted == nil {
+		p.lifted = make(chan struct{})
+	}
+	if until := time.Now().Add(d); until.After(p.until) {
+		p.until = until
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		p.timer = time.AfterFunc(d, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if !time.Now().Before(p.until) {
+				p.liftLocked()
+			}
+		})
+	}
+	p.mode.Store(mode)
+}
+
+// lift ends the pause in progress, if any
+func (p *pauseState) lift() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.liftLocked()
+}
+
+func (p *pauseState) liftLocked() {
+	if p.lifted == nil {
+		return
+	}
+	p.mode.Store(pauseNone)
+	p.until = time.Time{}
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	close(p.lifted)
+	p.lifted = nil
+}
+
+// paused reports whether commands are held; write tells whether the command
+// may write and is only called while a pause is in progress
+func (p *pauseState) paused(write func() bool) bool {
+	switch p.mode.Load() {
+	case pauseAll:
+		return true
+	case pauseWrite:
+		return write()
+	}
+	return false
+}
+
+// wait blocks until the pause in progress is lifted
+func (p *pauseState) wait() {
+	p.mu.Lock()
+	lifted := p.lifted
+	p.mu.Unlock()
+	if lifted != nil {
+		<-lifted
+	}
+}
+
+// holdIfPaused blocks the client while CLIENT PAUSE holds one of commands.
+// Replicas are never paused.
+func (s *Server) holdIfPaused(client *Client, commands ...string) {
+	if s.pause.mode.Load() == pauseNone || client.replica {
+		return
+	}
+	write := func() bool {
+		for _, command := range commands {
+			if s.mayWrite(client, command) {
+				return true
+			}
+		}
+		return false
+	}
+	for s.pause.paused(write) {
+		s.pause.wait()
+	}
+}
+
+// mayWrite reports whether command is held by CLIENT PAUSE WRITE
+func (s *Server) mayWrite(client *Client, command string) bool {
+	switch strings.ToUpper(command) {
+	case "PUBLISH", "SPUBLISH":
+		return true
+	}
+	info, ok := s.registryFor(client).Get(command)
+	return ok && !info.ReadOnly
+}
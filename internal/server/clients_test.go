@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readBulk reads a bulk string reply
+func (c *testConn) readBulk(t *testing.T) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(header, "$"), header)
+	n, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	require.NoError(t, err)
+	body := make([]byte, n+2)
+	_, err = io.ReadFull(c.r, body)
+	require.NoError(t, err)
+	return string(body[:n])
+}
+
+// readInt reads an integer reply
+func (c *testConn) readInt(t *testing.T) int64 {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, ":"), line)
+	n, err := strconv.ParseInt(strings.TrimSpace(line[1:]), 10, 64)
+	require.NoError(t, err)
+	return n
+}
+
+func TestClientListAndKill(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+	other := dialTest(t, srv)
+
+	c.send(t, "CLIENT", "GETNAME")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "CLIENT", "SETNAME", "bad name")
+	c.expect(t, "-ERR Client names cannot contain spaces, newlines or special characters.\r\n")
+	c.send(t, "CLIENT", "SETNAME", "worker")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CLIENT", "GETNAME")
+	c.expect(t, "$6\r\nworker\r\n")
+	c.send(t, "CLIENT", "ID")
+	id := c.readInt(t)
+
+	other.send(t, "SELECT", "3")
+	other.expect(t, "+OK\r\n")
+	other.send(t, "SUBSCRIBE", "news")
+	other.expect(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n")
+
+	c.send(t, "CLIENT", "INFO")
+	info := c.readBulk(t)
+	require.Contains(t, info, "id="+strconv.FormatInt(id, 10)+" ")
+	require.Contains(t, info, " name=worker ")
+	require.Contains(t, info, " flags=N ")
+	require.Contains(t, info, " cmd=client|info ")
+	require.True(t, strings.HasSuffix(info, "\n"))
+
+	require.Eventually(t, func() bool {
+		c.send(t, "CLIENT", "LIST", "TYPE", "pubsub")
+		list := c.readBulk(t)
+		return strings.Count(list, "\n") == 1 && strings.Contains(list, " flags=P ") &&
+			strings.Contains(list, " db=3 ") && strings.Contains(list, " sub=1 psub=0 ")
+	}, 2*time.Second, 10*time.Millisecond)
+	c.send(t, "CLIENT", "LIST")
+	require.Equal(t, 2, strings.Count(c.readBulk(t), "\n"))
+	c.send(t, "CLIENT", "LIST", "TYPE", "bogus")
+	c.expect(t, "-ERR Unknown client type 'bogus'\r\n")
+
+	// SKIPME defaults to yes, so killing every normal client spares the caller
+	c.send(t, "CLIENT", "KILL", "TYPE", "normal")
+	c.expect(t, ":0\r\n")
+	c.send(t, "CLIENT", "KILL", "TYPE", "pubsub")
+	c.expect(t, ":1\r\n")
+	other.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := other.r.ReadByte()
+	require.Error(t, err)
+
+	c.send(t, "CLIENT", "KILL", "127.0.0.1:1")
+	c.expect(t, "-ERR No such client\r\n")
+	c.send(t, "CLIENT", "KILL", "ID", strconv.FormatInt(id, 10), "SKIPME", "no")
+	c.expect(t, ":1\r\n")
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = c.r.ReadByte()
+	require.Error(t, err)
+}
+
+func TestClientReply(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+
+	c.send(t, "CLIENT", "REPLY", "OFF")
+	c.send(t, "SET", "k", "1")
+	c.send(t, "CLIENT", "REPLY", "ON")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CLIENT", "REPLY", "SKIP")
+	c.send(t, "INCR", "k")
+	c.send(t, "INCR", "k")
+	c.expect(t, ":3\r\n")
+
+	// EXEC replies are dropped like any other. Each command is sent on its
+	// own so that the transaction does not run as part of a pipeline.
+	for _, command := range [][]string{{"CLIENT", "REPLY", "OFF"}, {"MULTI"}, {"SET", "t", "1"}, {"EXEC"}} {
+		c.send(t, command...)
+		c.expectNothing(t)
+	}
+	c.send(t, "CLIENT", "REPLY", "ON")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "GET", "t")
+	c.expect(t, "$1\r\n1\r\n")
+}
+
+func TestExecErrorReplies(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10})
+	srv.registry.Register(&cmd.Command{
+		Name:  "FAILPLAIN",
+		Arity: 0,
+		Handler: func(args []resp.Value) (resp.Value, error) {
+			return resp.Value{}, fmt.Errorf("value is broken")
+		},
+	})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	c := dialTest(t, srv)
+
+	// Errors without a code get the ERR prefix inside EXEC as well
+	c.send(t, "FAILPLAIN")
+	c.expect(t, "-ERR value is broken\r\n")
+	c.send(t, "MULTI")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "FAILPLAIN")
+	c.expect(t, "+QUEUED\r\n")
+	c.send(t, "EXEC")
+	c.expect(t, "*1\r\n-ERR value is broken\r\n")
+}
+
+func TestClientPause(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	admin := dialTest(t, srv)
+	c := dialTest(t, srv)
+
+	admin.send(t, "CLIENT", "PAUSE", "-1")
+	admin.expect(t, "-ERR timeout is negative\r\n")
+	admin.send(t, "CLIENT", "PAUSE", "10000", "WRITE")
+	admin.expect(t, "+OK\r\n")
+
+	// Reads go through, writes wait for UNPAUSE
+	c.send(t, "GET", "k")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "SET", "k", "v")
+	c.expectNothing(t)
+	admin.send(t, "CLIENT", "UNPAUSE")
+	admin.expect(t, "+OK\r\n")
+	c.expect(t, "+OK\r\n")
+
+	// Pauses also end when they time out
+	admin.send(t, "CLIENT", "PAUSE", "100")
+	admin.expect(t, "+OK\r\n")
+	start := time.Now()
+	c.send(t, "GET", "k")
+	c.expect(t, "$1\r\nv\r\n")
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
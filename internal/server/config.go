@@ -0,0 +1,195 @@
+package server
+
+import (
+	"errors"
+	"gridhouse/internal/aof"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/persistence"
+	"gridhouse/internal/pubsub"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errPersistenceDisabled = errors.New("persistence is disabled")
+
+// configParams returns the settings CONFIG GET and CONFIG SET operate on.
+// The names and value formats are those of redis.conf, so a config file can
+// use them too.
+func (s *Server) configParams(notifier *pubsub.KeyspaceNotifier) cmd.ConfigParams {
+	params := cmd.ConfigParams{
+		"port": {Get: func() string {
+			// The listener knows the port when the address asked for any
+			if s.addr != "" {
+				return configPort(s.addr)
+			}
+			return configPort(s.cfg.Addr)
+		}},
+		"bind":      cmd.ImmutableConfigParam(configBind(s.cfg.Addr)),
+		"databases": cmd.ImmutableConfigParam(strconv.Itoa(s.dbs.Count())),
+		"dir": {Get: func() string {
+			if s.cfg.Persistence == nil {
+				return ""
+			}
+			return s.cfg.Persistence.Dir
+		}},
+		"appendonly": cmd.BoolConfigParam(func() bool {
+			return s.cfg.Persistence != nil && s.cfg.Persistence.AOFEnabled
+		}, nil),
+		"metrics-addr":   cmd.ImmutableConfigParam(s.cfg.MetricsAddr),
+		"aclfile":        cmd.ImmutableConfigParam(s.cfg.ACLFile),
+		"unixsocket":     cmd.ImmutableConfigParam(s.cfg.UnixSocket),
+		"unixsocketperm": cmd.ImmutableConfigParam(formatUnixSocketPerm(s.cfg.UnixSocketPerm)),
+
+		"requirepass": {
+			Get: s.requirePass,
+			Set: func(value string) error {
+				s.cfgMu.Lock()
+				defer s.cfgMu.Unlock()
+				s.cfg.Password = value
+				s.setDefaultPassword(value)
+				return nil
+			},
+		},
+		"maxclients": cmd.IntConfigParam(1, math.MaxInt64, s.maxClients, func(n int64) error {
+			s.cfgMu.Lock()
+			s.cfg.MaxConnections = n
+			s.cfgMu.Unlock()
+			s.stats.GetStats().SetMaxConnections(n)
+			return nil
+		}),
+		"appendfsync": cmd.EnumConfigParam([]string{"always", "everysec", "no"}, func() string {
+			if s.persist == nil {
+				return ""
+			}
+			return s.persist.AOFSyncMode().String()
+		}, func(value string) error {
+			if s.persist == nil {
+				return errPersistenceDisabled
+			}
+			mode, err := aof.ParseSyncMode(value)
+			if err != nil {
+				return err
+			}
+			s.persist.SetAOFSyncMode(mode)
+			return nil
+		}),
+		"save": {
+			Get: func() string {
+				if s.persist == nil {
+					return ""
+				}
+				return formatSaveRules(s.persist.SaveRules())
+			},
+			Set: func(value string) error {
+				if s.persist == nil {
+					return errPersistenceDisabled
+				}
+				rules, err := parseSaveRules(strings.Fields(value))
+				if err != nil {
+					return err
+				}
+				return s.persist.SetSaveRules(rules)
+			},
+		},
+		"loglevel": {
+			Get: func() string { return string(logger.GetLevel()) },
+			Set: func(value string) error {
+				level, err := logger.ParseLevel(value)
+				if err != nil {
+					return err
+				}
+				logger.SetLevel(level)
+				return nil
+			},
+		},
+		"notify-keyspace-events": {
+			Get: func() string { return notifier.Flags().String() },
+			Set: func(value string) error {
+				flags, err := pubsub.ParseNotifyFlags(value)
+				if err != nil {
+					return err
+				}
+				notifier.SetFlags(flags)
+				return nil
+			},
+		},
+		"slowlog-log-slower-than": cmd.IntConfigParam(math.MinInt64, math.MaxInt64, s.slowlog.slowerThan.Load, func(n int64) error {
+			s.slowlog.slowerThan.Store(n)
+			return nil
+		}),
+		"slowlog-max-len": cmd.IntConfigParam(0, math.MaxInt32, s.slowlog.maxLen.Load, func(n int64) error {
+			s.slowlog.setMaxLen(int(n))
+			return nil
+		}),
+	}
+	for name, param := range s.tlsParams() {
+		params[name] = param
+	}
+	for name, param := range s.maxMemoryParams() {
+		params[name] = param
+	}
+	return params
+}
+
+// requirePass returns the password clients authenticate with, empty when
+// none is required
+func (s *Server) requirePass() string {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg.Password
+}
+
+// maxClients returns the number of connections above which new ones are
+// rejected
+func (s *Server) maxClients() int64 {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg.MaxConnections
+}
+
+// configPort returns the port of a listen address
+func configPort(addr string) string {
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		return port
+	}
+	return ""
+}
+
+// configBind returns the host of a listen address, empty for all interfaces
+func configBind(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return ""
+}
+
+// parseSaveRules parses "<seconds> <changes>" pairs; no pairs disable
+// background saves
+func parseSaveRules(args []string) ([]persistence.SaveRule, error) {
+	if len(args)%2 != 0 {
+		return nil, errors.New("Invalid save parameters")
+	}
+	rules := make([]persistence.SaveRule, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		seconds, err1 := strconv.Atoi(args[i])
+		changes, err2 := strconv.Atoi(args[i+1])
+		if err1 != nil || err2 != nil || seconds < 0 || changes < 0 {
+			return nil, errors.New("Invalid save parameters")
+		}
+		rules = append(rules, persistence.SaveRule{Interval: time.Duration(seconds) * time.Second, Changes: changes})
+	}
+	return rules, nil
+}
+
+// formatSaveRules formats rules as the value of the save parameter
+func formatSaveRules(rules []persistence.SaveRule) string {
+	parts := make([]string, 0, 2*len(rules))
+	for _, rule := range rules {
+		parts = append(parts, strconv.FormatInt(int64(rule.Interval/time.Second), 10), strconv.Itoa(rule.Changes))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"gridhouse/internal/aof"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/persistence"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gridhouse.conf")
+	require.NoError(t, os.WriteFile(path, []byte(`# Network
+bind 127.0.0.1 ::1
+port 0
+requirepass "se cret\x21"
+aclfile users.acl
+unixsocket /run/gridhouse.sock
+unixsocketperm 770
+maxclients 50
+maxmemory 100mb
+maxmemory-policy ALLKEYS-LRU
+maxmemory-samples 10
+
+databases 4
+dir '/tmp/grid house'
+appendonly yes
+appendfsync no
+save 900 1 300 10
+save 60 10000
+slowlog-log-slower-than 0
+loglevel notice
+replicaof 10.0.0.1 6379
+tls-port 6390
+tls-cert-file server.crt
+tls-key-file server.key
+tls-ca-cert-file ca.crt
+tls-auth-clients optional
+tls-auth-clients-user CN
+tls-replication yes
+`), 0644))
+
+	cfg := Config{Addr: ":6380", MaxConnections: 1000}
+	require.NoError(t, LoadConfigFile(path, &cfg))
+	assert.Equal(t, "127.0.0.1:0", cfg.Addr)
+	assert.Equal(t, "se cret!", cfg.Password)
+	assert.Equal(t, "users.acl", cfg.ACLFile)
+	assert.Equal(t, "/run/gridhouse.sock", cfg.UnixSocket)
+	assert.Equal(t, os.FileMode(0770), cfg.UnixSocketPerm)
+	assert.Equal(t, int64(50), cfg.MaxConnections)
+	assert.Equal(t, int64(100<<20), cfg.MaxMemory)
+	assert.Equal(t, "allkeys-lru", cfg.MaxMemoryPolicy)
+	assert.Equal(t, 10, cfg.MaxMemorySamples)
+	assert.Equal(t, 4, cfg.Databases)
+	assert.Equal(t, "/tmp/grid house", cfg.Persistence.Dir)
+	assert.True(t, cfg.Persistence.AOFEnabled)
+	assert.Equal(t, aof.No, cfg.Persistence.AOFSyncMode)
+	assert.True(t, cfg.Persistence.RDBEnabled)
+	assert.Equal(t, []persistence.SaveRule{
+		{Interval: 900 * time.Second, Changes: 1},
+		{Interval: 300 * time.Second, Changes: 10},
+		{Interval: 60 * time.Second, Changes: 10000},
+	}, cfg.Persistence.SaveRules)
+	assert.Equal(t, &SlowlogConfig{SlowerThan: 0, MaxLen: defaultSlowlogMaxLen}, cfg.Slowlog)
+	assert.Equal(t, logger.InfoLevel, cfg.LogLevel)
+	assert.Equal(t, "10.0.0.1:6379", cfg.SlaveOf)
+	assert.Equal(t, &TLSConfig{
+		Addr: "127.0.0.1:6390", CertFile: "server.crt", KeyFile: "server.key", CAFile: "ca.crt",
+		AuthClients: "optional", AuthClientsUser: "cn", Replication: true,
+	}, cfg.TLS)
+	assert.Equal(t, path, cfg.ConfigFile)
+
+	for _, tc := range []struct{ line, err string }{
+		{"bogus yes", "bad directive or wrong number of arguments: bogus yes"},
+		{"port", "bad directive or wrong number of arguments: port"},
+		{"appendonly maybe", "argument must be 'yes' or 'no': appendonly maybe"},
+		{"unixsocketperm 999", "Invalid socket file permissions: unixsocketperm 999"},
+		{"tls-auth-clients maybe", "invalid tls-auth-clients \"maybe\", must be no, optional or yes: tls-auth-clients maybe"},
+		{"maxmemory-policy lru", "invalid maxmemory-policy \"lru\": maxmemory-policy lru"},
+		{"maxclients 0", "argument must be between 1 and 9223372036854775807 inclusive: maxclients 0"},
+		{"save 900", "Invalid save parameters: save 900"},
+		{`requirepass "open`, `unbalanced quotes: requirepass "open`},
+	} {
+		require.NoError(t, os.WriteFile(path, []byte("port 6380\n"+tc.line+"\n"), 0644))
+		require.EqualError(t, LoadConfigFile(path, &Config{}), path+":2: "+tc.err)
+	}
+}
+
+func TestConfigSetAndRewrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gridhouse.conf")
+	require.NoError(t, os.WriteFile(path, []byte("# Keep me\nport 0\nsave 900 1\nsave 300 10\nmaxclients 100\n"), 0600))
+
+	cfg := Config{Addr: "127.0.0.1:0", Persistence: &persistence.Config{Dir: dir}}
+	require.NoError(t, LoadConfigFile(path, &cfg))
+	srv := New(cfg)
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+
+	c := dialTest(t, srv)
+	c.send(t, "CONFIG", "GET", "maxc*", "save")
+	c.expect(t, "*4\r\n$10\r\nmaxclients\r\n$3\r\n100\r\n$4\r\nsave\r\n$12\r\n900 1 300 10\r\n")
+	c.send(t, "CONFIG", "SET", "maxclients", "200", "save", "60 5", "requirepass", "p w")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "SET", "appendfsync", "no")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "SET", "port", "7000")
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config\r\n")
+	c.send(t, "CONFIG", "REWRITE")
+	c.expect(t, "+OK\r\n")
+
+	assert.Equal(t, int64(200), srv.maxClients())
+	assert.Equal(t, []persistence.SaveRule{{Interval: 60 * time.Second, Changes: 5}}, srv.persist.SaveRules())
+
+	// The existing lines are updated, the changed settings the file lacked
+	// are appended and the file keeps its mode
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# Keep me\nport 0\nsave 60 5\nmaxclients 200\n\n"+rewriteMarker+"\nappendfsync no\nrequirepass \"p w\"\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The password applies to new connections and the rewritten file loads
+	c2 := dialTest(t, srv)
+	c2.send(t, "GET", "k")
+	c2.expect(t, "-NOAUTH Authentication required.\r\n")
+	c2.send(t, "AUTH", "p w")
+	c2.expect(t, "+OK\r\n")
+
+	reloaded := Config{}
+	require.NoError(t, LoadConfigFile(path, &reloaded))
+	assert.Equal(t, "p w", reloaded.Password)
+	assert.Equal(t, aof.No, reloaded.Persistence.AOFSyncMode)
+}
+
+func TestConfigRewriteWithoutFile(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+	c.send(t, "CONFIG", "REWRITE")
+	c.expect(t, "-ERR The server is running without a config file\r\n")
+	c.send(t, "CONFIG", "SET", "save", "60 1")
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'save') - persistence is disabled\r\n")
+}
+
+func TestQuoteConfigArg(t *testing.T) {
+	for _, s := range []string{"plain", "", "two words", `q"uote`, "back\\slash", "new\nline", "\x00\xff", "#hash", "it's"} {
+		args, err := splitConfigArgs("name " + quoteConfigArg(s))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", s}, args)
+	}
+}
@@ -0,0 +1,514 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gridhouse/internal/aof"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/persistence"
+	"gridhouse/internal/pubsub"
+	"gridhouse/internal/store"
+	"gridhouse/internal/tlsconfig"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultPort is used when a config file sets bind but no port
+const defaultPort = "6380"
+
+// rewriteMarker precedes the settings CONFIG REWRITE appends to the file
+const rewriteMarker = "# Generated by CONFIG REWRITE"
+
+// configDirective applies one redis.conf style directive to a Config
+type configDirective struct {
+	arity int // number of arguments, -n for at least n
+	apply func(cfg *Config, args []string) error
+}
+
+var configDirectives = map[string]configDirective{
+	"port": {1, func(cfg *Config, args []string) error {
+		port, err := cmd.ParseConfigInt(args[0], 0, 65535)
+		if err != nil {
+			return err
+		}
+		cfg.Addr = net.JoinHostPort(configBind(cfg.Addr), strconv.FormatInt(port, 10))
+		return nil
+	}},
+	"bind": {-1, func(cfg *Config, args []string) error {
+		// Only the first address is listened on
+		host := strings.TrimPrefix(args[0], "-")
+		if host == "*" {
+			host = ""
+		}
+		port := configPort(cfg.Addr)
+		if port == "" {
+			port = defaultPort
+		}
+		cfg.Addr = net.JoinHostPort(host, port)
+		if cfg.TLS != nil && cfg.TLS.Addr != "" {
+			cfg.TLS.Addr = net.JoinHostPort(host, configPort(cfg.TLS.Addr))
+		}
+		return nil
+	}},
+	"requirepass": {1, func(cfg *Config, args []string) error {
+		cfg.Password = args[0]
+		return nil
+	}},
+	"aclfile": {1, func(cfg *Config, args []string) error {
+		cfg.ACLFile = args[0]
+		return nil
+	}},
+	"unixsocket": {1, func(cfg *Config, args []string) error {
+		cfg.UnixSocket = args[0]
+		return nil
+	}},
+	"unixsocketperm": {1, func(cfg *Config, args []string) error {
+		perm, err := parseUnixSocketPerm(args[0])
+		cfg.UnixSocketPerm = perm
+		return err
+	}},
+	"maxclients": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], 1, math.MaxInt64)
+		cfg.MaxConnections = n
+		return err
+	}},
+	"databases": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], 1, math.MaxInt32)
+		cfg.Databases = int(n)
+		return err
+	}},
+	"dir": {1, func(cfg *Config, args []string) error {
+		persistenceConfig(cfg).Dir = args[0]
+		return nil
+	}},
+	"appendonly": {1, func(cfg *Config, args []string) error {
+		enabled, err := cmd.ParseConfigBool(args[0])
+		persistenceConfig(cfg).AOFEnabled = enabled
+		return err
+	}},
+	"appendfsync": {1, func(cfg *Config, args []string) error {
+		mode, err := aof.ParseSyncMode(args[0])
+		persistenceConfig(cfg).AOFSyncMode = mode
+		return err
+	}},
+	"auto-aof-rewrite-percentage": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], 0, math.MaxInt32)
+		rewrite := aofRewriteConfig(cfg)
+		rewrite.RewritePercentage = int(n)
+		rewrite.Enabled = n > 0
+		return err
+	}},
+	"auto-aof-rewrite-min-size": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigMemory(args[0])
+		aofRewriteConfig(cfg).MinRewriteSize = n
+		return err
+	}},
+	"maxmemory": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigMemory(args[0])
+		cfg.MaxMemory = n
+		return err
+	}},
+	"maxmemory-policy": {1, func(cfg *Config, args []string) error {
+		policy, err := store.ParseEvictionPolicy(args[0])
+		cfg.MaxMemoryPolicy = policy.String()
+		return err
+	}},
+	"maxmemory-samples": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], 1, 64)
+		cfg.MaxMemorySamples = int(n)
+		return err
+	}},
+	"save": {-1, func(cfg *Config, args []string) error {
+		persist := persistenceConfig(cfg)
+		if len(args) == 1 && args[0] == "" {
+			persist.SaveRules = nil
+			return nil
+		}
+		rules, err := parseSaveRules(args)
+		if err != nil {
+			return err
+		}
+		persist.RDBEnabled = true
+		persist.SaveRules = append(persist.SaveRules, rules...)
+		return nil
+	}},
+	"slowlog-log-slower-than": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], math.MinInt64, math.MaxInt64)
+		slowlogConfig(cfg).SlowerThan = n
+		return err
+	}},
+	"slowlog-max-len": {1, func(cfg *Config, args []string) error {
+		n, err := cmd.ParseConfigInt(args[0], 0, math.MaxInt32)
+		slowlogConfig(cfg).MaxLen = int(n)
+		return err
+	}},
+	"loglevel": {1, func(cfg *Config, args []string) error {
+		level, err := logger.ParseLevel(args[0])
+		cfg.LogLevel = level
+		return err
+	}},
+	"notify-keyspace-events": {1, func(cfg *Config, args []string) error {
+		if _, err := pubsub.ParseNotifyFlags(args[0]); err != nil {
+			return err
+		}
+		cfg.NotifyKeyspaceEvents = args[0]
+		return nil
+	}},
+	"replicaof": {2, applyReplicaOf},
+	"slaveof":   {2, applyReplicaOf},
+	"metrics-addr": {1, func(cfg *Config, args []string) error {
+		cfg.MetricsAddr = args[0]
+		return nil
+	}},
+	"tls-port": {1, func(cfg *Config, args []string) error {
+		port, err := cmd.ParseConfigInt(args[0], 0, 65535)
+		if err != nil {
+			return err
+		}
+		if port == 0 {
+			tlsConfig(cfg).Addr = ""
+		} else {
+			tlsConfig(cfg).Addr = net.JoinHostPort(configBind(cfg.Addr), strconv.FormatInt(port, 10))
+		}
+		return nil
+	}},
+	"tls-cert-file": {1, func(cfg *Config, args []string) error {
+		tlsConfig(cfg).CertFile = args[0]
+		return nil
+	}},
+	"tls-key-file": {1, func(cfg *Config, args []string) error {
+		tlsConfig(cfg).KeyFile = args[0]
+		return nil
+	}},
+	"tls-ca-cert-file": {1, func(cfg *Config, args []string) error {
+		tlsConfig(cfg).CAFile = args[0]
+		return nil
+	}},
+	"tls-auth-clients": {1, func(cfg *Config, args []string) error {
+		if _, err := tlsconfig.ParseClientAuth(args[0]); err != nil {
+			return err
+		}
+		tlsConfig(cfg).AuthClients = strings.ToLower(args[0])
+		return nil
+	}},
+	"tls-auth-clients-user": {1, func(cfg *Config, args []string) error {
+		value := strings.ToLower(args[0])
+		if value != "off" && value != "cn" {
+			return errors.New("argument must be 'off' or 'cn'")
+		}
+		tlsConfig(cfg).AuthClientsUser = value
+		return nil
+	}},
+	"tls-replication": {1, func(cfg *Config, args []string) error {
+		enabled, err := cmd.ParseConfigBool(args[0])
+		tlsConfig(cfg).Replication = enabled
+		return err
+	}},
+}
+
+func applyReplicaOf(cfg *Config, args []string) error {
+	if strings.EqualFold(args[0], "no") && strings.EqualFold(args[1], "one") {
+		cfg.SlaveOf = ""
+		return nil
+	}
+	if _, err := cmd.ParseConfigInt(args[1], 0, 65535); err != nil {
+		return err
+	}
+	cfg.SlaveOf = net.JoinHostPort(args[0], args[1])
+	return nil
+}
+
+func persistenceConfig(cfg *Config) *persistence.Config {
+	if cfg.Persistence == nil {
+		cfg.Persistence = &persistence.Config{}
+	}
+	return cfg.Persistence
+}
+
+func aofRewriteConfig(cfg *Config) *aof.RewriteConfig {
+	persist := persistenceConfig(cfg)
+	if persist.AOFRewriteConfig == nil {
+		persist.AOFRewriteConfig = &aof.RewriteConfig{Enabled: true}
+	}
+	return persist.AOFRewriteConfig
+}
+
+func tlsConfig(cfg *Config) *TLSConfig {
+	if cfg.TLS == nil {
+		cfg.TLS = &TLSConfig{}
+	}
+	return cfg.TLS
+}
+
+func slowlogConfig(cfg *Config) *SlowlogConfig {
+	if cfg.Slowlog == nil {
+		cfg.Slowlog = &SlowlogConfig{SlowerThan: defaultSlowlogSlowerThan, MaxLen: defaultSlowlogMaxLen}
+	}
+	return cfg.Slowlog
+}
+
+// LoadConfigFile applies the directives of a redis.conf style file to cfg:
+// one directive per line followed by its arguments, which may be quoted,
+// and # comments. Settings the file doesn't mention keep their value in cfg.
+func LoadConfigFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		args, err := splitConfigArgs(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v: %s", path, lineno, err, line)
+		}
+		directive, ok := configDirectives[strings.ToLower(args[0])]
+		n := len(args) - 1
+		if !ok || (directive.arity >= 0 && n != directive.arity) || n < -directive.arity {
+			return fmt.Errorf("%s:%d: bad directive or wrong number of arguments: %s", path, lineno, line)
+		}
+		if err := directive.apply(cfg, args[1:]); err != nil {
+			return fmt.Errorf("%s:%d: %v: %s", path, lineno, err, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	cfg.ConfigFile = path
+	return nil
+}
+
+// splitConfigArgs splits a config line into arguments like Redis: they are
+// separated by spaces and may be "double quoted", with \n, \r, \t, \", \\
+// and \xHH escapes, or 'single quoted', with \' only
+func splitConfigArgs(line string) ([]string, error) {
+	var args []string
+	for i := 0; ; {
+		for i < len(line) && isConfigSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+		if c := line[i]; c == '"' || c == '\'' {
+			arg, n, err := quotedConfigArg(line[i:])
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			i += n
+			continue
+		}
+		start := i
+		for i < len(line) && !isConfigSpace(line[i]) {
+			i++
+		}
+		args = append(args, line[start:i])
+	}
+}
+
+// quotedConfigArg reads the quoted argument s starts with and returns it
+// along with the number of bytes read
+func quotedConfigArg(s string) (string, int, error) {
+	quote := s[0]
+	var arg []byte
+	for i := 1; i < len(s); {
+		c := s[i]
+		switch {
+		case c == quote:
+			if i+1 < len(s) && !isConfigSpace(s[i+1]) {
+				return "", 0, errors.New("closing quote must be followed by a space")
+			}
+			return string(arg), i + 1, nil
+		case c == '\\' && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			arg = append(arg, '\'')
+			i += 2
+		case c == '\\' && quote == '"' && i+3 < len(s) && s[i+1] == 'x' && isHex(s[i+2]) && isHex(s[i+3]):
+			b, _ := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			arg = append(arg, byte(b))
+			i += 4
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			switch e := s[i+1]; e {
+			case 'n':
+				arg = append(arg, '\n')
+			case 'r':
+				arg = append(arg, '\r')
+			case 't':
+				arg = append(arg, '\t')
+			case 'b':
+				arg = append(arg, '\b')
+			case 'a':
+				arg = append(arg, '\a')
+			default:
+				arg = append(arg, e)
+			}
+			i += 2
+		default:
+			arg = append(arg, c)
+			i++
+		}
+	}
+	return "", 0, errors.New("unbalanced quotes")
+}
+
+func isConfigSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// quoteConfigArg returns s as a config file argument, quoting it when needed
+func quoteConfigArg(s string) string {
+	plain := s != ""
+	for i := 0; i < len(s) && plain; i++ {
+		c := s[i]
+		plain = c > ' ' && c < 0x7f && c != '"' && c != '\'' && c != '\\' && c != '#'
+	}
+	if plain {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// configLines returns the config file lines setting name to value
+func configLines(name, value string) []string {
+	if name == "save" && value != "" {
+		// One line per rule
+		fields := strings.Fields(value)
+		lines := make([]string, 0, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			lines = append(lines, "save "+fields[i]+" "+fields[i+1])
+		}
+		return lines
+	}
+	return []string{name + " " + quoteConfigArg(value)}
+}
+
+// rewriteConfig writes the settings CONFIG SET can change back to the
+// config file. Lines setting them are updated in place, other lines are
+// kept, and settings the file lacks are appended when they differ from the
+// values the server started with.
+func (s *Server) rewriteConfig() error {
+	s.rewriteMu.Lock()
+	defer s.rewriteMu.Unlock()
+
+	path := s.cfg.ConfigFile
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	out := make([]string, 0, len(lines))
+	written := make(map[string]bool)
+	marked := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		marked = marked || trimmed == rewriteMarker
+		args, err := splitConfigArgs(trimmed)
+		if err != nil || len(args) == 0 {
+			out = append(out, line)
+			continue
+		}
+		name := strings.ToLower(args[0])
+		param, ok := s.params[name]
+		if !ok || param.Set == nil {
+			out = append(out, line)
+			continue
+		}
+		// Later lines of a setting are folded into the first one
+		if !written[name] {
+			out = append(out, configLines(name, param.Get())...)
+			written[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(s.params))
+	for name := range s.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		param := s.params[name]
+		if written[name] || param.Set == nil {
+			continue
+		}
+		value := param.Get()
+		if value == s.startConfig[name] {
+			continue
+		}
+		if !marked {
+			out = append(out, "", rewriteMarker)
+			marked = true
+		}
+		out = append(out, configLines(name, value)...)
+	}
+
+	return writeFileAtomic(path, []byte(strings.Join(out, "\n")+"\n"))
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers see
+// either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"gridhouse/internal/blocking"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/store"
+	"strconv"
+	"strings"
+)
+
+// signallingDatabases wakes the blocked clients after SWAPDB, since the keys
+// they wait on may hold values in the database swapped in
+type signallingDatabases struct {
+	*store.Databases
+	blocking *blocking.Manager
+}
+
+func (d signallingDatabases) Swap(a, b int) {
+	d.Databases.Swap(a, b)
+	d.blocking.SignalAll()
+}
+
+// registryFor returns the registry of the database the client has selected.
+// Servers assembled without registries per database only have registry.
+func (s *Server) registryFor(c *Client) *cmd.Registry {
+	if c.db < len(s.registries) {
+		return s.registries[c.db]
+	}
+	return s.registry
+}
+
+// selectDB switches the client to the database of a successful SELECT
+func (c *Client) selectDB(command string, args []string) {
+	if index, ok := selectedDB(command, args); ok {
+		c.db = index
+	}
+}
+
+// selectedDB returns the database a SELECT command switches to
+func selectedDB(command string, args []string) (int, bool) {
+	if len(args) != 1 || !strings.EqualFold(command, "SELECT") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// replicate forwards a write command executed in database db to the
+// replicas, selecting the database first when the stream is elsewhere
+func (s *Server) replicate(db int, command string, args []string) {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+
+	var buf strings.Builder
+	if db != s.replDB {
+		selectCmd := resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.BulkString, Str: "SELECT"},
+			{Type: resp.BulkString, Str: strconv.Itoa(db)},
+		}}
+		if err := resp.Encode(&buf, selectCmd); err != nil {
+			logger.Errorf("Failed to encode SELECT for replication: %v", err)
+			return
+		}
+	}
+
+	// Encode command as RESP array for replication
+	respArray := make([]resp.Value, 1+len(args))
+	respArray[0] = resp.Value{Type: resp.BulkString, Str: command}
+	for i, arg := range args {
+		respArray[i+1] = resp.Value{Type: resp.BulkString, Str: arg}
+	}
+	if err := resp.Encode(&buf, resp.Value{Type: resp.Array, Array: respArray}); err != nil {
+		logger.Errorf("Failed to encode command for replication: %v", err)
+		return
+	}
+
+	// Forward to all connected replicas
+	s.replManager.AppendCommand([]byte(buf.String()))
+	s.replDB = db
+	logger.Debugf("Successfully forwarded write command %s to replicas", command)
+}
+
+// resetReplicationDB makes the next replicated command select its database,
+// for replicas that just attached and start in database 0
+func (s *Server) resetReplicationDB() {
+	s.replMu.Lock()
+	s.replDB = -1
+	s.replMu.Unlock()
+}
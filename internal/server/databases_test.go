@@ -0,0 +1,105 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gridhouse/internal/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectIsPerConnection(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, Databases: 4})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	a := dialTest(t, srv)
+	b := dialTest(t, srv)
+
+	a.send(t, "SELECT", "2")
+	a.expect(t, "+OK\r\n")
+	a.send(t, "SET", "k", "two")
+	a.expect(t, "+OK\r\n")
+	a.send(t, "SELECT", "4")
+	a.expect(t, "-ERR DB index is out of range\r\n")
+
+	b.send(t, "GET", "k")
+	b.expect(t, "$-1\r\n")
+	b.send(t, "DBSIZE")
+	b.expect(t, ":0\r\n")
+
+	// A pipeline switches databases between its commands
+	_, err := b.Write([]byte("*2\r\n$6\r\nSELECT\r\n$1\r\n2\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n*3\r\n$4\r\nMOVE\r\n$1\r\nk\r\n$1\r\n1\r\n"))
+	require.NoError(t, err)
+	b.expect(t, "+OK\r\n$3\r\ntwo\r\n:1\r\n")
+
+	// SELECT inside MULTI applies to the rest of the transaction
+	a.send(t, "MULTI")
+	a.expect(t, "+OK\r\n")
+	a.send(t, "SELECT", "1")
+	a.expect(t, "+QUEUED\r\n")
+	a.send(t, "GET", "k")
+	a.expect(t, "+QUEUED\r\n")
+	a.send(t, "EXEC")
+	a.expect(t, "*2\r\n+OK\r\n$3\r\ntwo\r\n")
+
+	a.send(t, "CONFIG", "GET", "databases")
+	a.expect(t, "*2\r\n$9\r\ndatabases\r\n$1\r\n4\r\n")
+
+	a.send(t, "INFO", "keyspace")
+	header := readLines(t, a.Conn, a.r, 1)
+	require.True(t, strings.HasPrefix(header, "$"))
+	assert.Equal(t, "# Keyspace\r\ndb1:keys=1,expires=0,avg_ttl=0\r\n", readLines(t, a.Conn, a.r, 2))
+}
+
+func TestAOFReplayFollowsSelect(t *testing.T) {
+	dir := t.TempDir()
+	var aof []byte
+	for _, c := range [][]string{
+		{"SET", "k", "zero"},
+		{"SELECT", "3"},
+		{"SET", "k", "three"},
+		{"RPUSH", "l", "a", "b"},
+		{"SELECT", "1"},
+		{"SET", "k", "one"},
+		{"SWAPDB", "1", "2"},
+	} {
+		aof = append(aof, persistence.EncodeRESPArrayFast(c[0], c[1:])...)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "appendonly.aof"), aof, 0644))
+
+	srv := New(Config{
+		Addr:           "127.0.0.1:0",
+		MaxConnections: 10,
+		Persistence:    &persistence.Config{Dir: dir, AOFEnabled: true},
+	})
+	t.Cleanup(func() { srv.Close() })
+
+	keys, _ := srv.dbs.KeyspaceStats()
+	assert.Equal(t, []int{1, 0, 1, 2}, keys[:4])
+	v, _ := srv.dbs.DB(2).Get("k")
+	assert.Equal(t, "one", v)
+	v, _ = srv.dbs.DB(3).Get("k")
+	assert.Equal(t, "three", v)
+}
+
+func TestSwapDBWakesBlockedClients(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	waiter := dialTest(t, srv)
+	other := dialTest(t, srv)
+
+	other.send(t, "SELECT", "1")
+	other.expect(t, "+OK\r\n")
+	other.send(t, "RPUSH", "jobs", "j1")
+	other.expect(t, ":1\r\n")
+
+	waiter.send(t, "BLPOP", "jobs", "0")
+	waitBlockedClients(t, srv, 1)
+	other.send(t, "SWAPDB", "0", "1")
+	other.expect(t, "+OK\r\n")
+	waiter.expect(t, "*2\r\n$4\r\njobs\r\n$2\r\nj1\r\n")
+	waitBlockedClients(t, srv, 0)
+}
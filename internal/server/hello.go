@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/repl"
+	"gridhouse/internal/resp"
+	"gridhouse/internal/stats"
+	"strconv"
+	"strings"
+)
+
+// isHelloCommand reports whether command is HELLO. HELLO is handled before the
+// AUTH gate since it can authenticate the connection itself.
+func isHelloCommand(client *Client, command string) bool {
+	// RESP2 subscribers are limited to the subscriber commands
+	return strings.EqualFold(command, "HELLO") && !(client.subscribed && client.proto == resp.RESP2)
+}
+
+// hello handles HELLO [protover [AUTH username password] [SETNAME clientname]].
+// Nothing changes unless the whole command is valid; the reply describes the
+// connection and is written in the newly selected protocol. Authenticated
+// clients need the ACL permission to run it like any other command.
+func (s *Server) hello(client *Client, args []string) (resp.Value, error) {
+	if client.authed {
+		if err := s.authorizeArgs(client, "HELLO", args); err != nil {
+			return resp.Value{}, err
+		}
+	}
+	proto := client.proto
+	if len(args) > 0 {
+		ver, err := strconv.Atoi(args[0])
+		if err != nil {
+			return resp.Value{}, fmt.Errorf("ERR Protocol version is not an integer or out of range")
+		}
+		if ver != resp.RESP2 && ver != resp.RESP3 {
+			return resp.Value{}, fmt.Errorf("NOPROTO unsupported protocol version")
+		}
+		proto = ver
+	}
+
+	var auth bool
+	var user, pass, name string
+	var setName bool
+	for i := 1; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "AUTH" && i+2 < len(args):
+			auth, user, pass = true, args[i+1], args[i+2]
+			i += 2
+		case opt == "SETNAME" && i+1 < len(args):
+			setName, name = true, args[i+1]
+			i++
+		default:
+			return resp.Value{}, fmt.Errorf("ERR Syntax error in HELLO option '%s'", args[i])
+		}
+	}
+
+	if setName && !validClientName(name) {
+		return resp.Value{}, fmt.Errorf("ERR Client names cannot contain spaces, newlines or special characters.")
+	}
+	if auth {
+		if err := s.authenticate(client, "hello", user, pass); err != nil {
+			return resp.Value{}, err
+		}
+	}
+	if !client.authed {
+		return resp.Value{}, fmt.Errorf("NOAUTH HELLO must be called with the client already authenticated, " +
+			"otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and " +
+			"select the RESP protocol version at the same time")
+	}
+	if setName {
+		client.name = name
+	}
+	client.proto = proto
+
+	role := "master"
+	if s.replManager.Role() == repl.RoleSlave {
+		role = "replica"
+	}
+	return resp.Value{Type: resp.Map, Array: []resp.Value{
+		{Type: resp.BulkString, Str: "server"}, {Type: resp.BulkString, Str: "redis"},
+		{Type: resp.BulkString, Str: "version"}, {Type: resp.BulkString, Str: stats.Version},
+		{Type: resp.BulkString, Str: "proto"}, {Type: resp.Integer, Int: int64(proto)},
+		{Type: resp.BulkString, Str: "id"}, {Type: resp.Integer, Int: int64(client.id)},
+		{Type: resp.BulkString, Str: "mode"}, {Type: resp.BulkString, Str: "standalone"},
+		{Type: resp.BulkString, Str: "role"}, {Type: resp.BulkString, Str: role},
+		{Type: resp.BulkString, Str: "modules"}, {Type: resp.Array, Array: []resp.Value{}},
+	}}, nil
+}
+
+// writeHello runs HELLO and flushes its reply or error
+func (s *Server) writeHello(client *Client, args []string) error {
+	result, err := s.hello(client, args)
+	if err != nil {
+		return client.writeAndFlushError(errorReply(err))
+	}
+	return client.writeAndFlush(result)
+}
+
+// validClientName reports whether name only has printable characters without
+// spaces, as CLIENT SETNAME requires
+func validClientName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
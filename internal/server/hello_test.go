@@ -0,0 +1,71 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// expectHello reads a HELLO reply in proto, skipping the connection ID
+func (c *testConn) expectHello(t *testing.T, proto string) {
+	t.Helper()
+	header, body := "*14\r\n", "$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"
+	if proto == "3" {
+		header = "%7\r\n"
+	}
+	c.expect(t, header+"$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.0.0\r\n$5\r\nproto\r\n:"+proto+"\r\n$2\r\nid\r\n")
+	id, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	require.Regexp(t, `^:\d+\r\n$`, id)
+	c.expect(t, body)
+}
+
+func TestHelloNegotiatesRESP3(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, Password: "secret"})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	c := dialTest(t, srv)
+
+	c.send(t, "HELLO", "3")
+	c.expect(t, "-NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time\r\n")
+	c.send(t, "HELLO", "4")
+	c.expect(t, "-NOPROTO unsupported protocol version\r\n")
+	c.send(t, "HELLO", "3", "AUTH", "default", "wrong")
+	c.expect(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+	c.send(t, "HELLO", "3", "SETNAME")
+	c.expect(t, "-ERR Syntax error in HELLO option 'SETNAME'\r\n")
+	c.send(t, "HELLO", "3", "AUTH", "default", "secret", "SETNAME", "app")
+	c.expectHello(t, "3")
+
+	// Maps, doubles and nulls are native in RESP3
+	c.send(t, "HSET", "h", "f", "v")
+	c.expect(t, ":1\r\n")
+	c.send(t, "HGETALL", "h")
+	c.expect(t, "%1\r\n$1\r\nf\r\n$1\r\nv\r\n")
+	c.send(t, "ZADD", "z", "1.5", "m")
+	c.expect(t, ":1\r\n")
+	c.send(t, "ZSCORE", "z", "m")
+	c.expect(t, ",1.5\r\n")
+	c.send(t, "GET", "missing")
+	c.expect(t, "_\r\n")
+
+	// Subscribers get push frames and can still run commands
+	c.send(t, "SUBSCRIBE", "ch")
+	c.expect(t, ">3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n")
+	c.send(t, "ZSCORE", "z", "m")
+	c.expect(t, ",1.5\r\n")
+
+	// RESP2 clients keep the flat replies
+	p := dialTest(t, srv)
+	p.send(t, "AUTH", "secret")
+	p.expect(t, "+OK\r\n")
+	p.send(t, "HELLO")
+	p.expectHello(t, "2")
+	p.send(t, "HGETALL", "h")
+	p.expect(t, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n")
+	p.send(t, "ZSCORE", "z", "m")
+	p.expect(t, "$3\r\n1.5\r\n")
+	p.send(t, "PUBLISH", "ch", "hi")
+	p.expect(t, ":1\r\n")
+	c.expect(t, ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n")
+}
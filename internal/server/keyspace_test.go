@@ -0,0 +1,58 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyspaceNotifications(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, Databases: 2})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	sub := dialTest(t, srv)
+	c := dialTest(t, srv)
+
+	sub.send(t, "PSUBSCRIBE", "__key*@1__:*")
+	sub.expect(t, "*3\r\n$10\r\npsubscribe\r\n$12\r\n__key*@1__:*\r\n:1\r\n")
+
+	// Disabled by default
+	c.send(t, "CONFIG", "GET", "notify-keyspace-events")
+	c.expect(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$0\r\n\r\n")
+	c.send(t, "SELECT", "1")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "+OK\r\n")
+	sub.expectNothing(t)
+
+	c.send(t, "CONFIG", "SET", "notify-keyspace-events", "KEq")
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'notify-keyspace-events') - Invalid event class character. Use 'Ag$lshzxeKEtmdn'.\r\n")
+	c.send(t, "CONFIG", "SET", "notify-keyspace-events", "Elx")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "GET", "notify-keyspace-events")
+	c.expect(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$3\r\nlxE\r\n")
+
+	// Strings are not selected; lists and expirations are
+	c.send(t, "SET", "k", "v2")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "RPUSH", "l", "a")
+	c.expect(t, ":1\r\n")
+	sub.expect(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@1__:*\r\n$20\r\n__keyevent@1__:rpush\r\n$1\r\nl\r\n")
+
+	c.send(t, "SET", "t", "v", "PX", "1")
+	c.expect(t, "+OK\r\n")
+	time.Sleep(5 * time.Millisecond)
+	c.send(t, "GET", "t")
+	c.expect(t, "$-1\r\n")
+	sub.expect(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@1__:*\r\n$22\r\n__keyevent@1__:expired\r\n$1\r\nt\r\n")
+
+	// K adds the keyspace channel
+	c.send(t, "CONFIG", "SET", "notify-keyspace-events", "KEA")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "DEL", "k")
+	c.expect(t, ":1\r\n")
+	sub.expect(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@1__:*\r\n$16\r\n__keyspace@1__:k\r\n$3\r\ndel\r\n")
+	sub.expect(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@1__:*\r\n$18\r\n__keyevent@1__:del\r\n$1\r\nk\r\n")
+	sub.expectNothing(t)
+}
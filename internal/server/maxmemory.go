@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/store"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxMemorySamples is the number of keys an eviction samples unless
+// maxmemory-samples says otherwise
+const defaultMaxMemorySamples = 5
+
+// freesMemory lists the write commands that only ever remove keys, which
+// run even when nothing more can be evicted
+var freesMemory = map[string]bool{"DEL": true, "UNLINK": true, "FLUSHDB": true, "FLUSHALL": true}
+
+// setMaxMemory applies the memory limit settings of cfg
+func (s *Server) setMaxMemory(cfg Config) {
+	s.maxMemory.Store(cfg.MaxMemory)
+	if cfg.MaxMemoryPolicy != "" {
+		policy, err := store.ParseEvictionPolicy(cfg.MaxMemoryPolicy)
+		if err != nil {
+			logger.Warnf("Ignoring maxmemory-policy: %v", err)
+		}
+		s.maxMemoryPolicy.Store(int32(policy))
+	}
+	samples := int64(cfg.MaxMemorySamples)
+	if samples <= 0 {
+		samples = defaultMaxMemorySamples
+	}
+	s.maxMemorySamples.Store(samples)
+}
+
+// freeMemory makes room under maxmemory for command. Write commands evict
+// keys as maxmemory-policy says until the keys fit, and fail with OOM when
+// nothing more can be evicted; other commands never allocate keys and run
+// regardless.
+func (s *Server) freeMemory(command string) error {
+	limit := s.maxMemory.Load()
+	if limit <= 0 || s.dbs.UsedMemory() <= limit {
+		return nil
+	}
+	info, ok := s.commandSpec(command)
+	if !ok || info.ReadOnly || !slices.Contains(info.ACLGroups, cmd.AclWrite) {
+		return nil
+	}
+	name := strings.ToUpper(info.Name)
+	if freesMemory[name] {
+		return nil
+	}
+	if !s.evict(limit) {
+		if s.stats != nil {
+			s.stats.GetStats().RecordRejectedCommand(name)
+		}
+		return fmt.Errorf("OOM command not allowed when used memory > 'maxmemory'.")
+	}
+	return nil
+}
+
+// evict evicts keys until they fit in limit, reporting false if the policy
+// runs out of keys first. Each eviction is propagated as a DEL, so the AOF
+// and replicas drop the key too.
+func (s *Server) evict(limit int64) bool {
+	policy := store.EvictionPolicy(s.maxMemoryPolicy.Load())
+	samples := int(s.maxMemorySamples.Load())
+	for s.dbs.UsedMemory() > limit {
+		db, key, ok := s.dbs.Evict(policy, samples)
+		if !ok {
+			return false
+		}
+		s.propagate(db, "DEL", []string{key})
+		if s.stats != nil {
+			s.stats.GetStats().IncrementEvictedKeys()
+		}
+	}
+	return true
+}
+
+// maxMemoryParams returns the maxmemory settings served by CONFIG. Lowering
+// maxmemory evicts right away rather than on the next write.
+func (s *Server) maxMemoryParams() cmd.ConfigParams {
+	return cmd.ConfigParams{
+		"maxmemory": {
+			Get: func() string { return strconv.FormatInt(s.maxMemory.Load(), 10) },
+			Set: func(value string) error {
+				n, err := cmd.ParseConfigMemory(value)
+				if err != nil {
+					return err
+				}
+				s.maxMemory.Store(n)
+				return nil
+			},
+			Apply: func() error {
+				if limit := s.maxMemory.Load(); limit > 0 {
+					s.evict(limit)
+				}
+				return nil
+			},
+		},
+		"maxmemory-policy": cmd.EnumConfigParam(store.EvictionPolicyNames(), func() string {
+			return store.EvictionPolicy(s.maxMemoryPolicy.Load()).String()
+		}, func(value string) error {
+			policy, err := store.ParseEvictionPolicy(value)
+			if err != nil {
+				return err
+			}
+			s.maxMemoryPolicy.Store(int32(policy))
+			return nil
+		}),
+		"maxmemory-samples": cmd.IntConfigParam(1, 64, s.maxMemorySamples.Load, func(n int64) error {
+			s.maxMemorySamples.Store(n)
+			return nil
+		}),
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gridhouse/internal/aof"
+	"gridhouse/internal/persistence"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxMemory(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+	big := strings.Repeat("x", 2000)
+
+	c.send(t, "CONFIG", "SET", "maxmemory", "1kb")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "GET", "maxmemory*")
+	assert.Equal(t, []string{"maxmemory", "1024", "maxmemory-policy", "noeviction", "maxmemory-samples", "5"}, c.readArray(t))
+
+	// Under noeviction writes fail once the keys outgrow maxmemory, while
+	// reads and deletes still run
+	c.send(t, "SET", "a", big)
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "b", "v")
+	c.expect(t, "-OOM command not allowed when used memory > 'maxmemory'.\r\n")
+	c.send(t, "RPUSH", "l", "v")
+	c.expect(t, "-OOM command not allowed when used memory > 'maxmemory'.\r\n")
+	c.send(t, "GET", "b")
+	c.expect(t, "$-1\r\n")
+	c.send(t, "DEL", "a")
+	c.expect(t, ":1\r\n")
+	c.send(t, "SET", "b", "v")
+	c.expect(t, "+OK\r\n")
+
+	// Other policies evict keys to make room instead
+	c.send(t, "CONFIG", "SET", "maxmemory-policy", "volatile-ttl")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "a", big, "EX", "100")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "c", "v")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "EXISTS", "a", "b", "c")
+	c.expect(t, ":2\r\n")
+	assert.Equal(t, int64(1), srv.stats.GetStats().GetEvictedKeys())
+
+	// Lowering maxmemory evicts right away
+	c.send(t, "CONFIG", "SET", "maxmemory-policy", "allkeys-random", "maxmemory", "1")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "DBSIZE")
+	c.expect(t, ":0\r\n")
+
+	c.send(t, "CONFIG", "SET", "maxmemory-policy", "lru")
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'maxmemory-policy') - argument(s) must be one of the following: noeviction, allkeys-lru, volatile-lru, allkeys-lfu, volatile-lfu, allkeys-random, volatile-random, volatile-ttl\r\n")
+}
+
+func TestEvictionPropagatesDel(t *testing.T) {
+	dir := t.TempDir()
+	srv := New(Config{
+		Addr:           "127.0.0.1:0",
+		MaxConnections: 10,
+		Persistence:    &persistence.Config{Dir: dir, AOFEnabled: true, AOFSyncMode: aof.Always},
+	})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	c := dialTest(t, srv)
+
+	c.send(t, "SELECT", "1")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "a", strings.Repeat("x", 2000), "EX", "100")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "SET", "maxmemory-policy", "volatile-ttl", "maxmemory", "1kb")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SELECT", "0")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "c", "v")
+	c.expect(t, "+OK\r\n")
+
+	// The eviction reaches the AOF as a DEL in the key's database, still
+	// selected by the write of a, ahead of the write it made room for
+	want := string(persistence.EncodeRESPArrayFast("DEL", []string{"a"})) +
+		string(persistence.EncodeRESPArrayFast("SELECT", []string{"0"})) +
+		string(persistence.EncodeRESPArrayFast("SET", []string{"c", "v"}))
+	var content string
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filepath.Join(dir, "appendonly.aof"))
+		content = string(data)
+		return strings.Contains(content, "$1\r\nc\r\n")
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, strings.HasSuffix(content, want))
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/repl"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startMetrics serves /metrics, /healthz and /readyz on cfg.MetricsAddr
+func (s *Server) startMetrics() error {
+	ln, err := net.Listen("tcp", s.cfg.MetricsAddr)
+	if err != nil {
+		return fmt.Errorf("metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", s.serveReady)
+
+	s.metricsLn = ln
+	s.metrics = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Infof("Metrics listening on %s", ln.Addr())
+	go func() {
+		if err := s.metrics.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Metrics server failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// MetricsAddr returns the address of the metrics listener, empty if it is
+// disabled
+func (s *Server) MetricsAddr() string {
+	if s.metricsLn == nil {
+		return ""
+	}
+	return s.metricsLn.Addr().String()
+}
+
+// ready reports whether the server can serve traffic, and why not: data is
+// still loading or a replica's link to its master is down
+func (s *Server) ready() (bool, string) {
+	if s.loading.Load() {
+		return false, "loading"
+	}
+	if s.slave != nil && !s.slave.LinkUp() {
+		return false, "master link down"
+	}
+	return true, "ok"
+}
+
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	ok, reason := s.ready()
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, reason)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var p promWriter
+	s.writeMetrics(&p)
+	w.Write([]byte(p.b.String()))
+}
+
+// writeMetrics renders the server stats in the Prometheus text format
+func (s *Server) writeMetrics(p *promWriter) {
+	s.stats.UpdateKeyspaceStats()
+	s.stats.UpdateMemoryStats()
+	snap := s.stats.GetStats().GetSnapshot()
+
+	p.gauge("gridhouse_uptime_seconds", "Seconds since the server started.", float64(snap.Uptime))
+	p.gauge("gridhouse_loading", "Whether the server is loading its data.", boolValue(s.loading.Load()))
+
+	// Clients
+	p.gauge("gridhouse_connected_clients", "Client connections.", float64(snap.ActiveConnections))
+	p.gauge("gridhouse_max_clients", "Maximum client connections.", float64(snap.MaxConnections))
+	p.gauge("gridhouse_blocked_clients", "Clients parked on blocking commands.", float64(snap.BlockedClients))
+	p.gauge("gridhouse_pubsub_clients", "Clients in subscriber mode.", float64(snap.PubSubClients))
+	p.counter("gridhouse_connections_received_total", "Connections accepted.", float64(snap.TotalConnectionsReceived))
+	p.counter("gridhouse_rejected_connections_total", "Connections rejected over the client limit.", float64(snap.RejectedConnections))
+
+	// Commands
+	p.counter("gridhouse_commands_processed_total", "Commands processed.", float64(snap.TotalCommandsProcessed))
+	names := make([]string, 0, len(snap.CommandStats))
+	for name := range snap.CommandStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p.header("gridhouse_commands_total", "counter", "Calls per command.")
+	for _, name := range names {
+		p.sample("gridhouse_commands_total", float64(snap.CommandStats[name].Calls), "cmd", strings.ToLower(name))
+	}
+	p.header("gridhouse_commands_failed_total", "counter", "Calls per command that replied with an error.")
+	for _, name := range names {
+		p.sample("gridhouse_commands_failed_total", float64(snap.CommandStats[name].FailedCalls), "cmd", strings.ToLower(name))
+	}
+	p.header("gridhouse_commands_rejected_total", "counter", "Calls per command refused before running.")
+	for _, name := range names {
+		p.sample("gridhouse_commands_rejected_total", float64(snap.CommandStats[name].RejectedCalls), "cmd", strings.ToLower(name))
+	}
+	p.header("gridhouse_command_duration_seconds", "summary", "Execution time per command.")
+	for _, name := range names {
+		st, cmd := snap.CommandStats[name], strings.ToLower(name)
+		if st.Calls == 0 {
+			continue
+		}
+		p.sample("gridhouse_command_duration_seconds", st.P50.Seconds(), "cmd", cmd, "quantile", "0.5")
+		p.sample("gridhouse_command_duration_seconds", st.P99.Seconds(), "cmd", cmd, "quantile", "0.99")
+		p.sample("gridhouse_command_duration_seconds", st.P999.Seconds(), "cmd", cmd, "quantile", "0.999")
+		p.sample("gridhouse_command_duration_seconds_sum", float64(st.Usec)/1e6, "cmd", cmd)
+		p.sample("gridhouse_command_duration_seconds_count", float64(st.Calls), "cmd", cmd)
+	}
+
+	// Keyspace
+	p.counter("gridhouse_keyspace_hits_total", "Key lookups that found the key.", float64(snap.KeyspaceHits))
+	p.counter("gridhouse_keyspace_misses_total", "Key lookups that missed.", float64(snap.KeyspaceMisses))
+	p.counter("gridhouse_expired_keys_total", "Keys removed because they expired.", float64(snap.ExpiredKeys))
+	p.counter("gridhouse_evicted_keys_total", "Keys evicted to stay under the memory limit.", float64(snap.EvictedKeys))
+	dbs := make([]int, 0, len(snap.DatabaseKeys))
+	for db := range snap.DatabaseKeys {
+		dbs = append(dbs, db)
+	}
+	sort.Ints(dbs)
+	p.header("gridhouse_db_keys", "gauge", "Keys per database.")
+	for _, db := range dbs {
+		p.sample("gridhouse_db_keys", float64(snap.DatabaseKeys[db]), "db", strconv.Itoa(db))
+	}
+	p.header("gridhouse_db_expiring_keys", "gauge", "Keys with a TTL per database.")
+	for _, db := range dbs {
+		p.sample("gridhouse_db_expiring_keys", float64(snap.DatabaseExpires[db]), "db", strconv.Itoa(db))
+	}
+
+	// Memory, CPU and network
+	p.gauge("gridhouse_memory_used_bytes", "Bytes allocated by the server.", float64(snap.UsedMemory))
+	p.gauge("gridhouse_memory_peak_bytes", "Peak of gridhouse_memory_used_bytes.", float64(snap.PeakMemory))
+	p.counter("gridhouse_cpu_sys_seconds_total", "System CPU time used.", snap.UsedCPUSys)
+	p.counter("gridhouse_cpu_user_seconds_total", "User CPU time used.", snap.UsedCPUUser)
+	p.counter("gridhouse_net_input_bytes_total", "Bytes read from clients.", float64(snap.TotalNetInputBytes))
+	p.counter("gridhouse_net_output_bytes_total", "Bytes written to clients.", float64(snap.TotalNetOutputBytes))
+
+	s.writeReplicationMetrics(p)
+	s.writePersistenceMetrics(p)
+}
+
+func (s *Server) writeReplicationMetrics(p *promWriter) {
+	if s.slave != nil {
+		p.gauge("gridhouse_master_link_up", "Whether the replica is streaming from its master.", boolValue(s.slave.LinkUp()))
+		p.gauge("gridhouse_repl_offset", "Replication offset.", float64(s.slave.Offset()))
+		return
+	}
+	if s.replManager == nil || s.replManager.Role() != repl.RoleMaster {
+		return
+	}
+	offset := s.replManager.Offset()
+	p.gauge("gridhouse_repl_offset", "Replication offset.", float64(offset))
+	replicas := s.replManager.ReplicaOffsets()
+	ids := make([]string, 0, len(replicas))
+	for id := range replicas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	p.gauge("gridhouse_connected_replicas", "Replicas attached to this master.", float64(len(ids)))
+	p.header("gridhouse_replica_offset", "gauge", "Offset each replica was sent up to.")
+	for _, id := range ids {
+		p.sample("gridhouse_replica_offset", float64(replicas[id]), "replica", id)
+	}
+	p.header("gridhouse_replica_lag_bytes", "gauge", "Bytes of the replication stream each replica is behind.")
+	for _, id := range ids {
+		p.sample("gridhouse_replica_lag_bytes", float64(offset-replicas[id]), "replica", id)
+	}
+}
+
+func (s *Server) writePersistenceMetrics(p *promWriter) {
+	if s.persist == nil {
+		return
+	}
+	st := s.persist.Stats()
+	if v, ok := st["rdb_enabled"].(bool); ok && v {
+		p.gauge("gridhouse_rdb_changes_since_last_save", "Changes since the last RDB save.", numberValue(st["changes_since"]))
+		p.gauge("gridhouse_rdb_last_save_timestamp_seconds", "Time of the last successful RDB save.", numberValue(st["last_save"]))
+		p.gauge("gridhouse_rdb_last_save_ok", "Whether the last RDB save succeeded.", numberValue(st["last_save_ok"]))
+		p.gauge("gridhouse_rdb_bgsave_in_progress", "Whether a background save is running.", numberValue(st["bgsave_in_progress"]))
+	}
+	// The AOF does not track whether a rewrite is running, so no gauge
+	// claims to report it
+	if _, ok := st["aof_size"]; ok {
+		p.gauge("gridhouse_aof_size_bytes", "Size of the AOF.", numberValue(st["aof_size"]))
+		p.gauge("gridhouse_aof_base_size_bytes", "Size of the AOF after the last rewrite.", numberValue(st["aof_base_size"]))
+		p.gauge("gridhouse_aof_last_rewrite_timestamp_seconds", "Time of the last AOF rewrite.", numberValue(st["aof_last_rewrite_time_sec"]))
+	}
+}
+
+// promWriter builds a Prometheus text exposition
+type promWriter struct {
+	b strings.Builder
+}
+
+func (p *promWriter) header(name, kind, help string) {
+	fmt.Fprintf(&p.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample; labels are name, value pairs
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	p.b.WriteString(name)
+	if len(labels) > 0 {
+		p.b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				p.b.WriteByte(',')
+			}
+			p.b.WriteString(labels[i])
+			p.b.WriteString(`="`)
+			p.b.WriteString(labelEscaper.Replace(labels[i+1]))
+			p.b.WriteByte('"')
+		}
+		p.b.WriteByte('}')
+	}
+	p.b.WriteByte(' ')
+	p.b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	p.b.WriteByte('\n')
+}
+
+func (p *promWriter) gauge(name, help string, value float64) {
+	p.header(name, "gauge", help)
+	p.sample(name, value)
+}
+
+func (p *promWriter) counter(name, help string, value float64) {
+	p.header(name, "counter", help)
+	p.sample(name, value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// numberValue converts the values of the persistence stats map
+func numberValue(v interface{}) float64 {
+	switch v := v.(type) {
+	case bool:
+		return boolValue(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case time.Time:
+		if v.IsZero() {
+			return 0
+		}
+		return float64(v.Unix())
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// httpGet fetches path from the metrics listener
+func httpGet(t *testing.T, srv *Server, path string) (int, string) {
+	t.Helper()
+	res, err := http.Get("http://" + srv.MetricsAddr() + path)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, MetricsAddr: "127.0.0.1:0"})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+
+	c := dialTest(t, srv)
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "GET", "missing")
+	c.expect(t, "$-1\r\n")
+
+	status, body := httpGet(t, srv, "/metrics")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, body, "# TYPE gridhouse_connected_clients gauge\ngridhouse_connected_clients 1\n")
+	require.Contains(t, body, "# TYPE gridhouse_commands_total counter\n")
+	require.Contains(t, body, "gridhouse_commands_total{cmd=\"set\"} 1\n")
+	require.Contains(t, body, "gridhouse_command_duration_seconds_count{cmd=\"get\"} 1\n")
+	require.Contains(t, body, "gridhouse_command_duration_seconds{cmd=\"get\",quantile=\"0.99\"} ")
+	require.Contains(t, body, "gridhouse_db_keys{db=\"0\"} 1\n")
+	require.Contains(t, body, "gridhouse_repl_offset 0\n")
+	require.Contains(t, body, "gridhouse_loading 0\n")
+
+	status, body = httpGet(t, srv, "/healthz")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "ok\n", body)
+	status, _ = httpGet(t, srv, "/readyz")
+	require.Equal(t, http.StatusOK, status)
+
+	srv.loading.Store(true)
+	status, body = httpGet(t, srv, "/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "loading\n", body)
+}
+
+func TestPromWriterEscapesLabels(t *testing.T) {
+	var p promWriter
+	p.sample("m", 1.5, "k", "a\"b\\c\nd")
+	require.Equal(t, "m{k=\"a\\\"b\\\\c\\nd\"} 1.5\n", p.b.String())
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/resp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// monitorQueueSize is the number of lines a monitor may fall behind before
+// it is disconnected
+const monitorQueueSize = 4096
+
+// monitor is a connection that ran MONITOR. Lines are queued without
+// blocking and written by the connection's pump goroutine.
+type monitor struct {
+	lines   chan string
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Bool
+}
+
+func newMonitor() *monitor {
+	return &monitor{lines: make(chan string, monitorQueueSize), done: make(chan struct{})}
+}
+
+func (m *monitor) close() {
+	m.once.Do(func() { close(m.done) })
+}
+
+// monitorFeed fans the executed commands out to the monitors. The zero value
+// has no monitors.
+type monitorFeed struct {
+	mu       sync.RWMutex
+	monitors map[*monitor]struct{}
+	count    atomic.Int32
+}
+
+func (f *monitorFeed) add(m *monitor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.monitors == nil {
+		f.monitors = make(map[*monitor]struct{})
+	}
+	f.monitors[m] = struct{}{}
+	f.count.Store(int32(len(f.monitors)))
+}
+
+func (f *monitorFeed) remove(m *monitor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.monitors, m)
+	f.count.Store(int32(len(f.monitors)))
+}
+
+// publish queues line on every monitor. A monitor whose queue is full is
+// dropped instead of stalling the command path.
+func (f *monitorFeed) publish(line string) {
+	var slow []*monitor
+	f.mu.RLock()
+	for m := range f.monitors {
+		select {
+		case m.lines <- line:
+		default:
+			slow = append(slow, m)
+		}
+	}
+	f.mu.RUnlock()
+	for _, m := range slow {
+		m.dropped.Store(true)
+		f.remove(m)
+		m.close()
+	}
+}
+
+// feedMonitors streams a command run by client to the monitors, in the
+// format of Redis: +<unix time> [<db> <addr>] "arg" ...
+func (s *Server) feedMonitors(client *Client, command string, args []string) {
+	if s.monitors.count.Load() == 0 {
+		return
+	}
+	var b strings.Builder
+	now := time.Now()
+	fmt.Fprintf(&b, "%d.%06d [%d %s] ", now.Unix(), now.Nanosecond()/1000, client.db, client.addr())
+	quoteArg(&b, command)
+	from, to := redactedArgs(command, args)
+	for i, arg := range args {
+		b.WriteByte(' ')
+		if i >= from && i < to {
+			quoteArg(&b, "(redacted)")
+		} else {
+			quoteArg(&b, arg)
+		}
+	}
+	s.monitors.publish(b.String())
+}
+
+// redactedArgs returns the range of args that carry credentials
+func redactedArgs(command string, args []string) (from, to int) {
+	switch {
+	case strings.EqualFold(command, "AUTH"):
+		return 0, len(args)
+	case strings.EqualFold(command, "ACL"):
+		if len(args) > 0 && strings.EqualFold(args[0], "SETUSER") {
+			return 2, len(args)
+		}
+	case strings.EqualFold(command, "HELLO"):
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(args[i], "AUTH") {
+				return i + 1, i + 3
+			}
+		}
+	}
+	return 0, 0
+}
+
+// quoteArg writes s as a double quoted string with non-printable bytes
+// escaped, like Redis's sdscatrepr
+func quoteArg(b *strings.Builder, s string) {
+	const hex = "0123456789abcdef"
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c < ' ' || c > '~' {
+				b.WriteString(`\x`)
+				b.WriteByte(hex[c>>4])
+				b.WriteByte(hex[c&0xf])
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// isMonitorCommand reports whether command is MONITOR
+func isMonitorCommand(command string) bool {
+	return strings.EqualFold(command, "MONITOR")
+}
+
+// startMonitor turns the connection into a monitor. The OK goes out before
+// the monitor is registered so that no line can overtake it.
+func (s *Server) startMonitor(client *Client) error {
+	if err := s.authorizeArgs(client, "MONITOR", nil); err != nil {
+		return client.writeAndFlushError(errorReply(err))
+	}
+	if client.monitor != nil {
+		return client.writeAndFlushOK()
+	}
+	m := newMonitor()
+	client.monitor = m
+	client.writerMu.Lock()
+	var err error
+	if !client.mute {
+		if err = client.writeResponseOK(); err == nil {
+			err = client.flush()
+		}
+	}
+	s.monitors.add(m)
+	client.writerMu.Unlock()
+	go client.pumpMonitor(m)
+	return err
+}
+
+// pumpMonitor writes monitor lines until the monitor is closed, closing the
+// connection of a monitor that fell behind
+func (c *Client) pumpMonitor(m *monitor) {
+	for {
+		select {
+		case line := <-m.lines:
+			if err := c.writeMonitorLines(m, line); err != nil {
+				logger.Debugf("MONITOR write error for %s: %v", c.connID, err)
+				c.server.monitors.remove(m)
+				m.close()
+				c.conn.Close()
+				return
+			}
+		case <-m.done:
+			if m.dropped.Load() {
+				logger.Warnf("Closing slow monitor %s", c.connID)
+				c.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// writeMonitorLines writes line and everything already queued behind it in
+// one flush
+func (c *Client) writeMonitorLines(m *monitor, line string) error {
+	c.writerMu.Lock()
+	defer c.writerMu.Unlock()
+	err := resp.UltraEncodeSimpleString(c.writer, line)
+	for err == nil && len(m.lines) > 0 {
+		err = resp.UltraEncodeSimpleString(c.writer, <-m.lines)
+	}
+	if err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// releaseMonitor stops the monitor when the connection ends
+func (s *Server) releaseMonitor(client *Client) {
+	if client.monitor == nil {
+		return
+	}
+	s.monitors.remove(client.monitor)
+	client.monitor.close()
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var monitorLine = regexp.MustCompile(`^\+\d+\.\d{6} \[(\d+) 127\.0\.0\.1:\d+\] (.*)\r\n$`)
+
+// expectMonitor reads a MONITOR line and checks its db and arguments
+func (c *testConn) expectMonitor(t *testing.T, db, args string) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	require.NoError(t, err)
+	m := monitorLine.FindStringSubmatch(line)
+	require.NotNil(t, m, line)
+	require.Equal(t, db, m[1])
+	require.Equal(t, args, m[2])
+}
+
+func TestMonitor(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	mon := dialTest(t, srv)
+	c := dialTest(t, srv)
+
+	mon.send(t, "MONITOR")
+	mon.expect(t, "+OK\r\n")
+
+	c.send(t, "SET", "k", "a \"b\"\n\x01")
+	c.expect(t, "+OK\r\n")
+	mon.expectMonitor(t, "0", `"SET" "k" "a \"b\"\n\x01"`)
+
+	c.send(t, "AUTH", "user", "secret")
+	c.r.ReadString('\n')
+	mon.expectMonitor(t, "0", `"AUTH" "(redacted)" "(redacted)"`)
+
+	// Pipelines and transactions are streamed command by command
+	_, err := c.Write([]byte("*2\r\n$6\r\nSELECT\r\n$1\r\n2\r\n*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n"))
+	require.NoError(t, err)
+	c.expect(t, "+OK\r\n:1\r\n")
+	mon.expectMonitor(t, "0", `"SELECT" "2"`)
+	mon.expectMonitor(t, "2", `"INCR" "n"`)
+
+	c.send(t, "MULTI")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "INCR", "n")
+	c.expect(t, "+QUEUED\r\n")
+	c.send(t, "EXEC")
+	c.expect(t, "*1\r\n:2\r\n")
+	mon.expectMonitor(t, "2", `"MULTI"`)
+	mon.expectMonitor(t, "2", `"INCR" "n"`)
+	mon.expectMonitor(t, "2", `"EXEC"`)
+	mon.expectNothing(t)
+
+	// Monitors can still run commands
+	mon.send(t, "CLIENT", "LIST")
+	list := mon.readBulk(t)
+	require.Contains(t, list, " flags=O ")
+	mon.expectMonitor(t, "0", `"CLIENT" "LIST"`)
+}
+
+func TestMonitorDropsSlowMonitor(t *testing.T) {
+	var feed monitorFeed
+	m := newMonitor()
+	feed.add(m)
+	for i := 0; i < monitorQueueSize; i++ {
+		feed.publish("line")
+	}
+	require.False(t, m.dropped.Load())
+
+	feed.publish("overflow")
+	require.True(t, m.dropped.Load())
+	require.Zero(t, feed.count.Load())
+	select {
+	case <-m.done:
+	default:
+		t.Fatal("slow monitor was not closed")
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	var b strings.Builder
+	quoteArg(&b, "a\\b\t\r\xff")
+	require.Equal(t, `"a\\b\t\r\xff"`, b.String())
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gridhouse/internal/persistence"
+	"gridhouse/internal/resp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAOFReplayRestoresConsumerGroups(t *testing.T) {
+	dir := t.TempDir()
+	var aof []byte
+	for _, c := range [][]string{
+		{"XADD", "s", "1-0", "f", "a"},
+		{"XADD", "s", "2-0", "f", "b"},
+		{"XADD", "s", "3-0", "f", "c"},
+		{"XGROUP", "CREATE", "s", "g", "0"},
+		{"XREADGROUP", "GROUP", "g", "alice", "STREAMS", "s", ">"},
+		{"XACK", "s", "g", "1-0"},
+		{"XCLAIM", "s", "g", "bob", "0", "3-0"},
+	} {
+		aof = append(aof, persistence.EncodeRESPArrayFast(c[0], c[1:])...)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "appendonly.aof"), aof, 0644))
+
+	srv := New(Config{
+		Addr:           "127.0.0.1:0",
+		MaxConnections: 10,
+		Persistence:    &persistence.Config{Dir: dir, AOFEnabled: true},
+	})
+	t.Cleanup(func() { srv.Close() })
+
+	res, err := srv.registry.Execute("XPENDING", []resp.Value{
+		{Type: resp.BulkString, Str: "s"}, {Type: resp.BulkString, Str: "g"},
+		{Type: resp.BulkString, Str: "-"}, {Type: resp.BulkString, Str: "+"},
+		{Type: resp.BulkString, Str: "10"},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Array, 2)
+	assert.Equal(t, "2-0", res.Array[0].Array[0].Str)
+	assert.Equal(t, "alice", res.Array[0].Array[1].Str)
+	assert.Equal(t, "3-0", res.Array[1].Array[0].Str)
+	assert.Equal(t, "bob", res.Array[1].Array[1].Str)
+	assert.Equal(t, int64(2), res.Array[1].Array[3].Int)
+}
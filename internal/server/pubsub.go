@@ -0,0 +1,172 @@
+package server
+
+import (
+	"errors"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/pubsub"
+	"gridhouse/internal/resp"
+	"strings"
+)
+
+// errClientQuit signals that the client asked to close the connection
+var errClientQuit = errors.New("client quit")
+
+// isSubscribeCommand reports whether command changes pub/sub subscriptions
+func isSubscribeCommand(command string) bool {
+	switch strings.ToUpper(command) {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE", "SSUBSCRIBE", "SUNSUBSCRIBE":
+		return true
+	}
+	return false
+}
+
+// handleSubscriberCommand handles subscription commands and enforces subscriber
+// mode. It reports false when the command should go through the registry.
+// RESP3 clients get messages as push frames and may run any command.
+func (s *Server) handleSubscriberCommand(client *Client, command string, args []string) (bool, error) {
+	if isSubscribeCommand(command) {
+		if err := s.authorizeArgs(client, command, args); err != nil {
+			return true, client.writeAndFlushProtected(resp.Value{Type: resp.Error, Str: errorReply(err)})
+		}
+		return true, s.handleSubscribe(client, strings.ToUpper(command), args)
+	}
+	if !client.subscribed || client.proto == resp.RESP3 {
+		return false, nil
+	}
+
+	switch strings.ToUpper(command) {
+	case "PING":
+		if len(args) > 1 {
+			return true, client.writeAndFlushProtected(resp.Value{Type: resp.Error, Str: "ERR wrong number of arguments for 'ping' command"})
+		}
+		payload := ""
+		if len(args) == 1 {
+			payload = args[0]
+		}
+		return true, client.writeAndFlushProtected(resp.Value{Type: resp.Array, Array: []resp.Value{
+			{Type: resp.BulkString, Str: "pong"},
+			{Type: resp.BulkString, Str: payload},
+		}})
+	case "QUIT":
+		client.writeAndFlushProtected(resp.Value{Type: resp.SimpleString, Str: "OK"})
+		return true, errClientQuit
+	default:
+		return true, client.writeAndFlushProtected(resp.Value{Type: resp.Error, Str: "ERR Can't execute '" + strings.ToLower(command) +
+			"': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are allowed in this context"})
+	}
+}
+
+// handleSubscribe applies a (P|S)(UN)SUBSCRIBE command and writes one reply per channel
+func (s *Server) handleSubscribe(client *Client, command string, args []string) error {
+	if len(args) == 0 && !strings.Contains(command, "UNSUBSCRIBE") {
+		return client.writeAndFlushProtected(resp.Value{Type: resp.Error, Str: "ERR wrong number of arguments for '" + strings.ToLower(command) + "' command"})
+	}
+
+	if client.sub == nil {
+		client.sub = pubsub.NewSubscriber(client.connID, pubsub.DefaultQueueSize)
+		client.pumpDone = make(chan struct{})
+		go client.pumpMessages()
+	}
+
+	// Hold the writer lock while registering so that published messages can
+	// not overtake the subscription confirmations
+	client.writerMu.Lock()
+	var replies []resp.Value
+	switch command {
+	case "SUBSCRIBE":
+		replies = s.hub.Subscribe(client.sub, args...)
+	case "UNSUBSCRIBE":
+		replies = s.hub.Unsubscribe(client.sub, args...)
+	case "PSUBSCRIBE":
+		replies = s.hub.PSubscribe(client.sub, args...)
+	case "PUNSUBSCRIBE":
+		replies = s.hub.PUnsubscribe(client.sub, args...)
+	case "SSUBSCRIBE":
+		replies = s.hub.SSubscribe(client.sub, args...)
+	case "SUNSUBSCRIBE":
+		replies = s.hub.SUnsubscribe(client.sub, args...)
+	}
+	var err error
+	for _, r := range replies {
+		if err = client.writeResponse(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = client.flush()
+	}
+	client.writerMu.Unlock()
+
+	active := s.hub.Active(client.sub)
+	if active && !client.subscribed {
+		s.stats.GetStats().IncrementPubSubClients()
+	} else if !active && client.subscribed {
+		s.stats.GetStats().DecrementPubSubClients()
+	}
+	client.subscribed = active
+
+	// Leaving subscriber mode: stop the pump so regular replies own the writer again
+	if !active {
+		client.sub.Close()
+		<-client.pumpDone
+		client.sub = nil
+	}
+	return err
+}
+
+// pumpMessages writes published messages to the connection until the
+// subscriber is closed. A subscriber dropped as a slow consumer loses its
+// connection, like Redis does when the pub/sub output buffer limit is hit.
+func (c *Client) pumpMessages() {
+	sub := c.sub
+	defer close(c.pumpDone)
+	for {
+		select {
+		case msg := <-sub.Messages():
+			if err := c.writeMessages(sub, msg); err != nil {
+				logger.Debugf("Pub/Sub write error for %s: %v", c.connID, err)
+				sub.Close()
+				c.conn.Close()
+				return
+			}
+		case <-sub.Done():
+			if sub.Dropped() {
+				logger.Warnf("Closing slow pub/sub subscriber %s", c.connID)
+				c.conn.Close()
+				return
+			}
+			// Deliver what was published before the client unsubscribed
+			if len(sub.Messages()) > 0 {
+				c.writeMessages(sub, <-sub.Messages())
+			}
+			return
+		}
+	}
+}
+
+// writeMessages writes msg and everything already queued behind it in one flush
+func (c *Client) writeMessages(sub *pubsub.Subscriber, msg resp.Value) error {
+	c.writerMu.Lock()
+	defer c.writerMu.Unlock()
+	err := c.writeResponse(msg)
+	for err == nil && len(sub.Messages()) > 0 {
+		err = c.writeResponse(<-sub.Messages())
+	}
+	if err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// releaseSubscriber drops all subscriptions when the connection ends
+func (s *Server) releaseSubscriber(client *Client) {
+	if client.sub == nil {
+		return
+	}
+	s.hub.Remove(client.sub)
+	client.sub.Close()
+	if client.subscribed {
+		s.stats.GetStats().DecrementPubSubClients()
+		client.subscribed = false
+	}
+}
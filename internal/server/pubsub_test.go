@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readLines reads n CRLF-terminated lines from the connection
+func readLines(t *testing.T, c net.Conn, r *bufio.Reader, n int) string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func TestPubSubSubscribePublish(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10})
+	require.NoError(t, srv.Start())
+	defer srv.Close()
+
+	sub, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer sub.Close()
+	sr := bufio.NewReader(sub)
+
+	pub, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer pub.Close()
+	pr := bufio.NewReader(pub)
+
+	// SUBSCRIBE news
+	_, err = sub.Write([]byte("*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", readLines(t, sub, sr, 6))
+
+	// PSUBSCRIBE n*
+	_, err = sub.Write([]byte("*2\r\n$10\r\nPSUBSCRIBE\r\n$2\r\nn*\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:2\r\n", readLines(t, sub, sr, 6))
+
+	// PUBLISH news hi reaches both subscriptions
+	_, err = pub.Write([]byte("*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$2\r\nhi\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, ":2\r\n", readLines(t, pub, pr, 1))
+
+	require.Equal(t, "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n", readLines(t, sub, sr, 7))
+	require.Equal(t, "*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$2\r\nhi\r\n", readLines(t, sub, sr, 9))
+
+	// PUBSUB NUMSUB news / NUMPAT
+	_, err = pub.Write([]byte("*3\r\n$6\r\nPUBSUB\r\n$6\r\nNUMSUB\r\n$4\r\nnews\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "*2\r\n$4\r\nnews\r\n:1\r\n", readLines(t, pub, pr, 4))
+	_, err = pub.Write([]byte("*2\r\n$6\r\nPUBSUB\r\n$6\r\nNUMPAT\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, ":1\r\n", readLines(t, pub, pr, 1))
+
+	// INFO clients reports the subscriber
+	_, err = pub.Write([]byte("*2\r\n$4\r\nINFO\r\n$7\r\nclients\r\n"))
+	require.NoError(t, err)
+	header := readLines(t, pub, pr, 1)
+	require.True(t, strings.HasPrefix(header, "$"))
+	info := readLines(t, pub, pr, 8)
+	require.Contains(t, info, "pubsub_clients:1\r\n")
+}
+
+func TestPubSubSubscriberMode(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10})
+	require.NoError(t, srv.Start())
+	defer srv.Close()
+
+	c, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	_, err = c.Write([]byte("*2\r\n$9\r\nSUBSCRIBE\r\n$1\r\na\r\n"))
+	require.NoError(t, err)
+	readLines(t, c, r, 6)
+
+	// Regular commands are rejected while subscribed
+	_, err = c.Write([]byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n", readLines(t, c, r, 1))
+
+	// PING answers in the pub/sub form
+	_, err = c.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "*2\r\n$4\r\npong\r\n$0\r\n\r\n", readLines(t, c, r, 5))
+
+	// Unsubscribing from everything leaves subscriber mode
+	_, err = c.Write([]byte("*1\r\n$11\r\nUNSUBSCRIBE\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:0\r\n", readLines(t, c, r, 6))
+
+	_, err = c.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "+PONG\r\n", readLines(t, c, r, 1))
+	require.Equal(t, int64(0), srv.stats.GetStats().GetPubSubClients())
+}
+
+func TestPubSubPipelinedSubscribe(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10})
+	require.NoError(t, srv.Start())
+	defer srv.Close()
+
+	c, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	// SET, SUBSCRIBE and PING in a single write
+	_, err = c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n" +
+		"*2\r\n$9\r\nSUBSCRIBE\r\n$1\r\na\r\n" +
+		"*1\r\n$4\r\nPING\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "+OK\r\n", readLines(t, c, r, 1))
+	require.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n", readLines(t, c, r, 6))
+	require.Equal(t, "*2\r\n$4\r\npong\r\n$0\r\n\r\n", readLines(t, c, r, 5))
+}
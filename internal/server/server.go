@@ -2,13 +2,18 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
+	"gridhouse/internal/acl"
+	"gridhouse/internal/blocking"
 	"gridhouse/internal/cmd"
 	"gridhouse/internal/logger"
 	"gridhouse/internal/persistence"
+	"gridhouse/internal/pubsub"
 	"gridhouse/internal/repl"
 	"gridhouse/internal/resp"
 	"gridhouse/internal/store"
+	"gridhouse/internal/tlsconfig"
 	"io"
 	"net"
 	"net/http"
@@ -17,6 +22,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Response buffer pool for zero-copy - HUGE buffers for pipelines
@@ -36,6 +42,8 @@ var pipelineResponsePool = sync.Pool{
 }
 
 type Config struct {
+	// Addr is the TCP listen address. Port 0 picks a free port, or
+	// disables the TCP listener when TLS or the Unix socket is enabled.
 	Addr           string
 	Persistence    *persistence.Config
 	Password       string // Optional password for AUTH command
@@ -43,106 +51,316 @@ type Config struct {
 	WriteBuffer    int
 	ReadBuffer     int
 	MaxConnections int64
+	Databases      int // Number of databases, cmd.DefaultDatabases if unset
+
+	// NotifyKeyspaceEvents is the initial notify-keyspace-events value,
+	// e.g. "KEA"; empty disables keyspace notifications
+	NotifyKeyspaceEvents string
+
+	// MetricsAddr is the address of the HTTP listener serving /metrics,
+	// /healthz and /readyz; empty disables it
+	MetricsAddr string
+
+	// Slowlog overrides the slowlog-log-slower-than and slowlog-max-len
+	// defaults when set
+	Slowlog *SlowlogConfig
+
+	// LogLevel is applied to the logger when set
+	LogLevel logger.LogLevel
+
+	// ConfigFile is the file the config was loaded from; CONFIG REWRITE
+	// is only available when it is set
+	ConfigFile string
+
+	// ACLFile is the file ACL users are loaded from at startup and by ACL
+	// LOAD, and saved to by ACL SAVE; empty keeps users in memory only
+	ACLFile string
+
+	// TLS configures the TLS listener, nil disables it
+	TLS *TLSConfig
+
+	// UnixSocket is the path of a Unix socket clients can connect to,
+	// empty disables it; UnixSocketPerm, when set, is the socket's mode
+	UnixSocket     string
+	UnixSocketPerm os.FileMode
+
+	// MaxMemory limits the memory of the keys, 0 for no limit. Writes past
+	// it evict keys as MaxMemoryPolicy says, noeviction when empty, which
+	// picks the best of MaxMemorySamples keys, 5 when unset.
+	MaxMemory        int64
+	MaxMemoryPolicy  string
+	MaxMemorySamples int
+}
+
+// SlowlogConfig holds the slowlog settings
+type SlowlogConfig struct {
+	SlowerThan int64 // microseconds, negative disables the log
+	MaxLen     int
 }
 
 type Server struct {
-	cfg         Config
-	ln          net.Listener
-	addr        string
-	db          store.DataStore
-	registry    *cmd.Registry
-	persist     *persistence.Manager
-	stats       *ServerStats
-	slave       *repl.Slave   // Slave instance for replication
-	replManager *repl.Manager // Replication manager
+	cfg        Config
+	cfgMu      sync.RWMutex // Guards the cfg fields CONFIG SET changes
+	ln         net.Listener
+	addr       string
+	tlsLn      net.Listener               // TLS listener, nil if disabled
+	tlsAddr    string                     // Address of tlsLn
+	tlsConfig  atomic.Pointer[tls.Config] // Certificates new TLS connections use
+	unixLn     net.Listener               // Unix socket listener, nil if disabled
+	dbs        *store.Databases
+	registries []*cmd.Registry // registries[i] executes commands in database i
+	db         store.DataStore // Database 0
+	registry   *cmd.Registry   // Registry of database 0
+	// connCommands describes the commands the server serves itself
+	connCommands *cmd.Registry
+	persist      *persistence.Manager
+	stats        *ServerStats
+	slave        *repl.Slave       // Slave instance for replication
+	replManager  *repl.Manager     // Replication manager
+	hub          *pubsub.Hub       // Pub/Sub channel and pattern registry
+	blocking     *blocking.Manager // Clients parked on blocking commands
+	clients      clientRegistry    // Connected clients for CLIENT LIST and KILL
+	pause        pauseState        // CLIENT PAUSE state
+	monitors     monitorFeed       // Connections that ran MONITOR
+	slowlog      *slowlog          // Commands slower than slowlog-log-slower-than
+	acl          *acl.Users        // ACL users, requirepass being the default user's password
+	aclLog       acl.Log           // Denied commands and failed authentications
+	loading      atomic.Bool       // Set while the persisted data is loaded
+
+	// Settings served by CONFIG, their values at startup and the lock
+	// serializing CONFIG REWRITE
+	params      cmd.ConfigParams
+	startConfig map[string]string
+	rewriteMu   sync.Mutex
+
+	// HTTP listener for Prometheus and health checks, nil if disabled
+	metrics   *http.Server
+	metricsLn net.Listener
 
 	// Connection management
 	connSemaphore chan struct{} // Semaphore to limit concurrent connections
 
-	// Memory management
-	memoryLimit int64 // Memory limit in bytes
+	// Memory limit: maxmemory, 0 for none, the store.EvictionPolicy
+	// freeing memory and the keys it samples
+	maxMemory        atomic.Int64
+	maxMemoryPolicy  atomic.Int32
+	maxMemorySamples atomic.Int64
 
 	// Transaction management
 	tm *cmd.TransactionManager // Transaction manager for ACID compliance
+
+	// Database the replication stream has selected, -1 if unknown
+	replDB int
+	replMu sync.Mutex
 }
 
 func New(cfg Config) *Server {
+	databases := cfg.Databases
+	if databases <= 0 {
+		databases = cmd.DefaultDatabases
+	}
+
 	// Use ultra-optimized DB for maximum performance
-	db := store.NewUltraOptimizedDB()
-	registry := cmd.NewRegistry()
-	cmd.RegisterOptimizedCommands(registry, db)
+	dbs := store.NewDatabases(databases)
+	db := dbs.DB(0)
 
 	// Initialize transaction manager for ACID compliance
 	tm := cmd.NewTransactionManager(db)
 
 	// Initialize server stats
 	stats := NewServerStats(&cfg)
+	stats.SetDatabases(dbs)
+
+	hub := pubsub.NewHub()
+	blocker := blocking.NewManager()
+
+	notifier := pubsub.NewKeyspaceNotifier(hub)
+	if flags, err := pubsub.ParseNotifyFlags(cfg.NotifyKeyspaceEvents); err != nil {
+		logger.Warnf("Ignoring notify-keyspace-events %q: %v", cfg.NotifyKeyspaceEvents, err)
+	} else {
+		notifier.SetFlags(flags)
+	}
+	slowlog := newSlowlog()
+	if cfg.Slowlog != nil {
+		slowlog.slowerThan.Store(cfg.Slowlog.SlowerThan)
+		slowlog.setMaxLen(cfg.Slowlog.MaxLen)
+	}
+	if cfg.LogLevel != "" {
+		logger.SetLevel(cfg.LogLevel)
+	}
+
+	// Determine role based on configuration
+	role := repl.RoleMaster
+	if cfg.SlaveOf != "" {
+		role = repl.RoleSlave
+	}
+
+	// Initialize replication manager with correct role
+	replManager := repl.NewManager(role, 1024*1024) // 1MB backlog
 
 	server := &Server{
 		cfg:           cfg,
+		dbs:           dbs,
 		db:            db,
-		registry:      registry,
 		stats:         stats,
+		hub:           hub,
+		blocking:      blocker,
+		slowlog:       slowlog,
+		replManager:   replManager,
+		replDB:        -1,
 		tm:            tm,                         // Transaction manager for ACID compliance
 		connSemaphore: make(chan struct{}, 50000), // Increased to 50K connections
 		// REMOVED: workerPool - eliminated to prevent scaling bottleneck
-		memoryLimit: 4 * 1024 * 1024 * 1024, // 4GB memory limit for large test runs
 	}
+	server.setMaxMemory(cfg)
+	if cfg.TLS != nil {
+		// CONFIG SET changes the server's copy of the TLS settings
+		settings := *cfg.TLS
+		server.cfg.TLS = &settings
+	}
+	server.connCommands = connectionCommands()
+	server.acl = acl.NewUsers(aclCatalog{server})
+	server.setDefaultPassword(cfg.Password)
+	server.params = server.configParams(notifier)
+	var rewrite func() error
+	if cfg.ConfigFile != "" {
+		rewrite = server.rewriteConfig
+	}
+
+	// Every database gets its own registry so handlers stay bound to it
+	registries := make([]*cmd.Registry, dbs.Count())
+	for i := range registries {
+		registry := cmd.NewRegistry()
+		cmd.RegisterOptimizedCommands(registry, dbs.DB(i))
+		cmd.RegisterPubSubCommands(registry, hub)
+		// Register server commands (INFO, AUTH) with dynamic stats
+		cmd.RegisterServerCommands(registry, stats, server.params, rewrite)
+		cmd.RegisterReplicationCommands(registry, replManager)
+		registries[i] = registry
+
+		// Wake clients blocked on keys whenever a command modifies them and
+		// publish the keyspace notifications
+		dbs.DB(i).OnKeyEvent(func(event, key string) {
+			blocker.Signal(key)
+			notifier.Notify(i, event, key)
+		})
+	}
+	server.registries = registries
+	server.registry = registries[0]
 
 	// Initialize persistence if configured
+	var persistCmds cmd.PersistenceManager
 	if cfg.Persistence != nil {
-		persist, err := persistence.NewManager(cfg.Persistence, db)
+		persist, err := persistence.NewManagerWithDatabases(cfg.Persistence, dbs.Stores())
 		if err == nil {
 			server.persist = persist
-			// Load existing data
-			if err := persist.LoadData(); err != nil {
-				// Log error but continue
-				// In production, you might want to handle this differently
-				_ = err // Suppress unused variable warning
-			}
+			persistCmds = persist
 			// Register persistence commands
-			cmd.RegisterPersistenceCommands(registry, persist, db)
+			for i, registry := range registries {
+				cmd.RegisterPersistenceCommands(registry, persist, dbs.DB(i))
+			}
 		}
 	}
-
-	// Determine role based on configuration
-	role := repl.RoleMaster
-	if cfg.SlaveOf != "" {
-		role = repl.RoleSlave
+	for i, registry := range registries {
+		cmd.RegisterDatabaseCommands(registry, signallingDatabases{dbs, blocker}, i, persistCmds)
 	}
 
-	// Initialize replication manager with correct role
-	replManager := repl.NewManager(role, 1024*1024) // 1MB backlog
-	server.replManager = replManager
-
-	// Register server commands (INFO, AUTH) with dynamic stats
-	cmd.RegisterServerCommands(registry, stats)
-	cmd.RegisterReplicationCommands(registry, replManager)
+	if server.persist != nil {
+		// Replay the AOF through the command handlers, following its SELECTs
+		current := 0
+		server.persist.SetReplayFunc(func(name string, args []string) error {
+			respArgs := make([]resp.Value, len(args))
+			for i, arg := range args {
+				respArgs[i] = resp.Value{Type: resp.BulkString, Str: arg}
+			}
+			_, err := registries[current].Execute(name, respArgs)
+			if index, ok := selectedDB(name, args); ok && err == nil {
+				current = index
+			}
+			return err
+		})
+		// Load existing data
+		server.loading.Store(true)
+		if err := server.persist.LoadData(); err != nil {
+			// Log error but continue
+			// In production, you might want to handle this differently
+			_ = err // Suppress unused variable warning
+		}
+		server.loading.Store(false)
+	}
 
 	// If configured as slave, start replication
 	if cfg.SlaveOf != "" {
 		logger.Infof("Starting as slave, connecting to master at %s", cfg.SlaveOf)
-		server.slave = repl.NewSlave(cfg.SlaveOf, db)
+		server.slave = repl.NewSlaveWithDatabases(cfg.SlaveOf, dbs.Stores())
 		go func() {
+			if cfg.TLS != nil && cfg.TLS.Replication {
+				config, err := tlsconfig.Client(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile, false)
+				if err != nil {
+					logger.Errorf("Failed to load TLS settings for replication: %v", err)
+					return
+				}
+				server.slave.SetTLSConfig(config)
+			}
 			if err := server.slave.Connect(); err != nil {
 				logger.Errorf("Failed to connect to master: %v", err)
 			}
 		}()
 	}
 
+	// CONFIG REWRITE appends the settings changed since startup
+	server.startConfig = make(map[string]string, len(server.params))
+	for name, param := range server.params {
+		server.startConfig[name] = param.Get()
+	}
+
 	return server
 }
 
 func (s *Server) Start() error {
-	ln, err := net.Listen("tcp", s.cfg.Addr)
-	if err != nil {
-		logger.Errorf("Failed to start server on %s: %v", s.cfg.Addr, err)
-		return err
+	if s.cfg.ACLFile != "" {
+		if err := s.acl.Load(s.cfg.ACLFile); err != nil {
+			logger.Errorf("Failed to load ACL file %s: %v", s.cfg.ACLFile, err)
+			return err
+		}
+	}
+
+	tlsEnabled := s.cfg.TLS != nil && s.cfg.TLS.Addr != ""
+	if configPort(s.cfg.Addr) != "0" || (!tlsEnabled && s.cfg.UnixSocket == "") {
+		ln, err := net.Listen("tcp", s.cfg.Addr)
+		if err != nil {
+			logger.Errorf("Failed to start server on %s: %v", s.cfg.Addr, err)
+			return err
+		}
+		s.ln = ln
+		s.addr = ln.Addr().String()
+		logger.Infof("Server listening on %s", s.addr)
+		go s.serve()
+	}
+
+	if tlsEnabled {
+		if err := s.startTLS(); err != nil {
+			logger.Errorf("Failed to start TLS on %s: %v", s.cfg.TLS.Addr, err)
+			s.closeListeners()
+			return err
+		}
+	}
+
+	if s.cfg.UnixSocket != "" {
+		if err := s.startUnixSocket(); err != nil {
+			logger.Errorf("Failed to listen on Unix socket %s: %v", s.cfg.UnixSocket, err)
+			s.closeListeners()
+			return err
+		}
+	}
+
+	if s.cfg.MetricsAddr != "" {
+		if err := s.startMetrics(); err != nil {
+			logger.Errorf("Failed to start metrics on %s: %v", s.cfg.MetricsAddr, err)
+			s.closeListeners()
+			return err
+		}
 	}
-	s.ln = ln
-	s.addr = ln.Addr().String()
-	logger.Infof("Server listening on %s", s.addr)
-	go s.serve()
 
 	if v, ok := os.LookupEnv("PROFILING_ENABLED"); ok && (v == "1" || v == "true") {
 		go func() {
@@ -153,21 +371,28 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Addr returns the address of the plaintext listener, empty if it is
+// disabled
 func (s *Server) Addr() string { return s.addr }
 
+// closeListeners stops accepting connections
+func (s *Server) closeListeners() error {
+	if s.tlsLn != nil {
+		s.tlsLn.Close()
+	}
+	if s.unixLn != nil {
+		s.unixLn.Close()
+	}
+	if s.ln != nil {
+		return s.ln.Close()
+	}
+	return nil
+}
+
 // shouldRejectConnection checks if we should reject connection
 func (s *Server) shouldRejectConnection() bool {
-	// Check memory usage
-	// var m runtime.MemStats
-	// runtime.ReadMemStats(&m)
-	// Reject if memory usage exceeds limit
-	// if m.Alloc > uint64(s.memoryLimit) {
-	// 	logger.Warnf("Memory limit exceeded: %d MB", m.Alloc/(1024*1024))
-	// 	return true
-	// }
-
 	// Reject if too many active connections
-	if s.stats.GetStats().GetActiveConnections() > s.cfg.MaxConnections {
+	if s.stats.GetStats().GetActiveConnections() > s.maxClients() {
 		logger.Warnf("Too many active connections: %d", s.stats.GetStats().GetActiveConnections())
 		return true
 	}
@@ -183,22 +408,30 @@ func (s *Server) Close() error {
 		s.slave.Stop()
 	}
 
+	if s.metrics != nil {
+		if err := s.metrics.Close(); err != nil {
+			logger.Errorf("Failed to close metrics listener: %v", err)
+		}
+	}
+
 	if s.persist != nil {
 		if err := s.persist.Close(); err != nil {
 			logger.Errorf("Failed to close persistence manager: %v", err)
 		}
 	}
-	if s.db != nil {
-		s.db.Close()
+	if s.dbs != nil {
+		s.dbs.Close()
 	}
-	if s.ln != nil {
-		logger.Info("Server closed successfully")
-		return s.ln.Close()
-	}
-	return nil
+	logger.Info("Server closed successfully")
+	return s.closeListeners()
 }
 
 func (s *Server) serve() {
+	s.serveListener(s.ln)
+}
+
+// serveListener accepts the connections of ln until it is closed
+func (s *Server) serveListener(ln net.Listener) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Errorf("Panic in serve loop: %v", r)
@@ -206,7 +439,7 @@ func (s *Server) serve() {
 	}()
 
 	for {
-		c, err := s.ln.Accept()
+		c, err := ln.Accept()
 		if err != nil {
 			logger.Debugf("Failed to accept connection: %v", err)
 			return
@@ -287,10 +520,30 @@ func (s *Server) handle(conn net.Conn) {
 		tcpConn.SetWriteBuffer(s.cfg.WriteBuffer) // Increase write buffer
 	}
 
+	// Finish the TLS handshake first, the client certificate may name the
+	// user the client is authenticated as
+	var certUser string
+	if tracker, ok := conn.(*connectionTracker); ok {
+		if tlsConn, ok := tracker.Conn.(*tls.Conn); ok {
+			var err error
+			if certUser, err = s.tlsHandshake(tlsConn); err != nil {
+				logger.Debugf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+				return
+			}
+		}
+	}
+
 	// Create client instance
 	client := newClient(conn, s, connID)
+	if certUser != "" {
+		client.authed, client.user = true, certUser
+	}
+	s.clients.add(client)
 
 	defer func() {
+		s.clients.remove(client)
+		s.releaseMonitor(client)
+		s.releaseSubscriber(client)
 		// Return response buffer to pool when client connection ends
 		if client.responseBuf != nil {
 			responsePool.Put(client.responseBuf)
@@ -298,6 +551,12 @@ func (s *Server) handle(conn net.Conn) {
 	}()
 
 	for {
+		// Publish the state left by the last command for CLIENT LIST
+		if client.closeAfterReply {
+			break
+		}
+		client.publishState()
+
 		// Check if in transaction mode
 		if client.txMode {
 			// Read next command
@@ -306,6 +565,7 @@ func (s *Server) handle(conn net.Conn) {
 				logger.Debugf("Parse error in transaction: %v", err)
 				break
 			}
+			client.startCommand(command, args)
 
 			// Handle transaction commands
 			switch command {
@@ -315,11 +575,17 @@ func (s *Server) handle(conn net.Conn) {
 						break
 					}
 				} else {
+					queued := make([]string, len(client.queuedCmds))
+					for i, q := range client.queuedCmds {
+						queued[i] = q.Command
+					}
+					s.holdIfPaused(client, queued...)
 					if err := client.execTransaction(); err != nil {
 						if err := client.writeAndFlushError("ERR " + err.Error()); err != nil {
 							break
 						}
 					}
+					s.feedMonitors(client, command, args)
 				}
 			case "DISCARD", "discard":
 				if len(args) != 0 {
@@ -327,6 +593,7 @@ func (s *Server) handle(conn net.Conn) {
 						break
 					}
 				} else {
+					s.feedMonitors(client, command, args)
 					if err := client.discardTransaction(); err != nil {
 						if err := client.writeAndFlushError("ERR " + err.Error()); err != nil {
 							break
@@ -340,12 +607,9 @@ func (s *Server) handle(conn net.Conn) {
 			default:
 				// Queue the command and respond with QUEUED
 				client.queueCommand(command, args)
-				if err := client.writeResponse(resp.Value{Type: resp.SimpleString, Str: "QUEUED"}); err != nil {
-					break
-				}
 				// Must flush QUEUED response immediately
 				logger.Debugf("Flushing QUEUED response for command: %s", command)
-				if err := client.flush(); err != nil {
+				if err := client.writeAndFlush(resp.Value{Type: resp.SimpleString, Str: "QUEUED"}); err != nil {
 					logger.Debugf("Failed to flush QUEUED response: %v", err)
 					break
 				}
@@ -360,44 +624,52 @@ func (s *Server) handle(conn net.Conn) {
 			logger.Debugf("Parse error: %v", err)
 			break
 		}
+		client.startCommand(command, args)
+		if client.authed && !isMonitorCommand(command) {
+			s.feedMonitors(client, command, args)
+		}
 
-		// AUTH handling and NOAUTH enforcement
-		if client.server.cfg.Password != "" {
-			// Only allow AUTH until authenticated
-			if strings.EqualFold(command, "AUTH") {
-				// AUTH arity check and validation
-				if len(args) < 1 {
-					if err := client.writeAndFlushError("ERR wrong number of arguments for 'AUTH' command"); err != nil {
-						break
-					}
-					continue
-				}
+		// HELLO negotiates the protocol and may authenticate, so it goes first
+		if isHelloCommand(client, command) {
+			if err := s.writeHello(client, args); err != nil {
+				break
+			}
+			continue
+		}
 
-				var pass = args[len(args)-1]
+		// Reject any command but AUTH until the client authenticated; AUTH
+		// itself runs like any other command
+		if !client.authed && !strings.EqualFold(command, "AUTH") {
+			if err := client.writeAndFlushError("NOAUTH Authentication required."); err != nil {
+				break
+			}
+			continue
+		}
 
-				// If no password configured, accept any (for completeness)
-				if client.server.cfg.Password == "" || pass == client.server.cfg.Password {
-					client.authed = true
-					if err := client.writeAndFlushOK(); err != nil {
-						break
-					}
-				} else {
-					if err := client.writeAndFlushError("ERR invalid password"); err != nil {
-						break
-					}
-				}
-				continue
+		// Subscriptions and subscriber mode are connection state
+		if handled, err := s.handleSubscriberCommand(client, command, args); handled {
+			if err != nil {
+				break
 			}
-			if !client.authed {
-				// Reject any other command until AUTH succeeds
-				if err := client.writeAndFlushError("NOAUTH Authentication required."); err != nil {
+			continue
+		}
+
+		if isMonitorCommand(command) {
+			if err := s.startMonitor(client); err != nil {
+				break
+			}
+			continue
+		}
+
+		// Check for transaction and replication commands
+		if strings.EqualFold(command, "PSYNC") || strings.EqualFold(command, "MULTI") {
+			if err := s.authorizeArgs(client, command, args); err != nil {
+				if err := client.writeAndFlushError(errorReply(err)); err != nil {
 					break
 				}
 				continue
 			}
 		}
-
-		// Check for transaction and replication commands
 		switch command {
 		case "PSYNC", "psync":
 			if err := s.handlePSyncCommand(client, args); err != nil {
@@ -490,7 +762,61 @@ func (s *Server) handle(conn net.Conn) {
 			}
 
 			// Execute all commands and write responses directly to buffer
-			for _, cmd := range commands {
+			closeConn := false
+			for i, cmd := range commands {
+				if closeConn {
+					break
+				}
+				if i > 0 {
+					client.startCommand(cmd.command, cmd.args)
+					if !isMonitorCommand(cmd.command) {
+						s.feedMonitors(client, cmd.command, cmd.args)
+					}
+				}
+				if client.subscribed || isSubscribeCommand(cmd.command) {
+					// Flush replies so far to keep them ordered with pub/sub output
+					if err := client.writeFullBuffer(pipelineBuf); err != nil {
+						closeConn = true
+						break
+					}
+					pipelineBuf = pipelineBuf[:0]
+					if err := client.flushProtected(); err != nil {
+						closeConn = true
+						break
+					}
+					if handled, err := s.handleSubscriberCommand(client, cmd.command, cmd.args); handled {
+						closeConn = err != nil
+						continue
+					}
+				}
+				if isHelloCommand(client, cmd.command) || isMonitorCommand(cmd.command) {
+					if err := client.writeFullBuffer(pipelineBuf); err != nil {
+						closeConn = true
+						break
+					}
+					pipelineBuf = pipelineBuf[:0]
+					if isMonitorCommand(cmd.command) {
+						closeConn = s.startMonitor(client) != nil
+					} else {
+						closeConn = s.writeHello(client, cmd.args) != nil
+					}
+					continue
+				}
+
+				if s.pause.paused(func() bool { return s.mayWrite(client, cmd.command) }) {
+					// Send the replies so far before holding the client
+					if err := client.writeFullBuffer(pipelineBuf); err != nil {
+						closeConn = true
+						break
+					}
+					pipelineBuf = pipelineBuf[:0]
+					if err := client.flushProtected(); err != nil {
+						closeConn = true
+						break
+					}
+					s.holdIfPaused(client, cmd.command)
+				}
+
 				// Execute command using ultra-fast path when possible
 				var responseBytes []byte
 				var err error
@@ -503,52 +829,66 @@ func (s *Server) handle(conn net.Conn) {
 				}
 
 				// Fallback to generic execution
-				result, execErr := s.registry.Execute(cmd.command, respArgs)
+				db, registry := client.db, s.registryFor(client)
+				result, execErr := s.dispatch(client, cmd.command, respArgs)
+				propagated := false
+				if blocked, ok := asBlocked(execErr); ok {
+					// Send the replies so far before parking the client
+					if err := client.writeFullBuffer(pipelineBuf); err != nil {
+						closeConn = true
+						break
+					}
+					pipelineBuf = pipelineBuf[:0]
+					if err := client.flushProtected(); err != nil {
+						closeConn = true
+						break
+					}
+					result, propagated, execErr = s.waitBlocked(client, cmd.command, respArgs, blocked)
+				}
 				err = execErr
+				propCmd, propArgs := cmd.command, cmd.args
 				if err != nil {
-					responseBytes = []byte("-ERR " + err.Error() + "\r\n")
+					responseBytes = []byte("-" + errorReply(err) + "\r\n")
 					errorResponses++
 				} else {
 					// Convert resp.Value to bytes efficiently
 					var respBuf bytes.Buffer
-					resp.UltraEncode(&respBuf, result)
+					resp.UltraEncodeProto(&respBuf, result, client.proto)
 					responseBytes = respBuf.Bytes()
 
 					// Check if write command for AOF
-					if cmdInfo, exists := s.registry.Get(cmd.command); exists && !cmdInfo.ReadOnly {
-						isWriteCommand = true
+					if cmdInfo, exists := registry.Get(cmd.command); exists && !cmdInfo.ReadOnly && !propagated {
+						propCmd, propArgs, isWriteCommand = propagation(cmdInfo, cmd.command, cmd.args, result)
 					}
+					client.selectDB(cmd.command, cmd.args)
 				}
 
 				// Append response directly to pipeline buffer
-				pipelineBuf = append(pipelineBuf, responseBytes...)
+				if !client.mute {
+					pipelineBuf = append(pipelineBuf, responseBytes...)
+				}
+				closeConn = client.closeAfterReply
 
 				// Async AOF logging for write commands
 				if isWriteCommand && s.persist != nil {
-					if err := s.persist.AppendMultiCommands(cmd.command, cmd.args); err != nil {
+					if err := s.persist.AppendMultiCommandsDB(db, propCmd, propArgs); err != nil {
 						logger.Error(err)
 					}
 				}
 
 				// Forward write commands to replicas for ongoing replication (pipeline)
 				if isWriteCommand && s.replManager.Count() > 0 && s.replManager.Role() == repl.RoleMaster {
-					logger.Debugf("Forwarding pipeline write command %s to replicas", cmd.command)
-					// Encode command as RESP array for replication
-					respArray := make([]resp.Value, 1+len(cmd.args))
-					respArray[0] = resp.Value{Type: resp.BulkString, Str: cmd.command}
-					for i, arg := range cmd.args {
-						respArray[i+1] = resp.Value{Type: resp.BulkString, Str: arg}
-					}
+					logger.Debugf("Forwarding pipeline write command %s to replicas", propCmd)
+					s.replicate(db, propCmd, propArgs)
+				}
 
-					var buf strings.Builder
-					if err := resp.Encode(&buf, resp.Value{Type: resp.Array, Array: respArray}); err == nil {
-						// Forward to all connected replicas
-						s.replManager.AppendCommand([]byte(buf.String()))
-						logger.Debugf("Successfully forwarded pipeline write command %s to replicas", cmd.command)
-					} else {
-						logger.Errorf("Failed to encode pipeline command for replication: %v", err)
-					}
+				// Serve clients blocked on keys this command pushed to. Served
+				// commands are appended to the AOF as they run, so the batch
+				// goes first to keep the AOF in order.
+				if s.persist != nil && s.blocking.Blocked() > 0 {
+					s.persist.FlushMultiCommand()
 				}
+				s.blocking.ServeReady()
 			}
 
 			// Single write of entire pipeline response buffer
@@ -571,6 +911,9 @@ func (s *Server) handle(conn net.Conn) {
 				logger.Warnf("Pipeline flush error: %v", err)
 				break
 			}
+			if closeConn {
+				break
+			}
 		} else {
 			// Single command mode: execute and flush immediately
 			logger.Debugf("Executing single command via registry: %s, args: %v", command, args)
@@ -584,59 +927,60 @@ func (s *Server) handle(conn net.Conn) {
 			}
 
 			// Fallback to generic path
-			result, err := s.registry.Execute(command, respArgs)
+			s.holdIfPaused(client, command)
+			db, registry := client.db, s.registryFor(client)
+			result, propagated, err := s.execute(client, command, respArgs)
+			propCmd, propArgs := command, args
 			if err != nil {
 				logger.Debugf("Single command error, flushing error response")
-				fastPathErr = client.writeAndFlushError("ERR " + err.Error())
+				fastPathErr = client.writeAndFlushError(errorReply(err))
 			} else {
 				logger.Debugf("Single command success, flushing result")
 				fastPathErr = client.writeAndFlush(result)
 
 				// Check if it's a write command for AOF
-				if cmdInfo, exists := s.registry.Get(command); exists && !cmdInfo.ReadOnly {
-					isWriteCommand = true
+				if cmdInfo, exists := registry.Get(command); exists && !cmdInfo.ReadOnly && !propagated {
+					propCmd, propArgs, isWriteCommand = propagation(cmdInfo, command, args, result)
 					logger.Debugf("Detected write command: %s (ReadOnly: %v)", command, cmdInfo.ReadOnly)
 				} else {
 					logger.Debugf("Command %s is read-only or not found (exists: %v) with args: %v", command, exists, args)
 				}
+				client.selectDB(command, args)
 			}
 
 			if fastPathErr != nil {
 				break
 			}
 
-			// Log to AOF if it's a write command that succeeded (ASYNC)
-			if isWriteCommand && s.persist != nil {
-				// PERFORMANCE FIX: Make AOF logging asynchronous to avoid blocking
-				go func(cmd string, args []string) {
-					if err := s.persist.AppendCommand(cmd, args); err != nil {
-						logger.Error(err)
-					}
-				}(command, args)
+			// Propagate before serving blocked clients, whose served
+			// commands follow the write that woke them
+			if isWriteCommand {
+				s.propagate(db, propCmd, propArgs)
 			}
 
-			// Forward write commands to replicas for ongoing replication
-			logger.Debugf("Checking replication forwarding: isWriteCommand=%v, role=%v", isWriteCommand, s.replManager.Role())
-			if isWriteCommand && s.replManager.Count() > 0 && s.replManager.Role() == repl.RoleMaster {
-				logger.Debugf("Forwarding write command %s to replicas", command)
-				// Encode command as RESP array for replication
-				respArray := make([]resp.Value, 1+len(args))
-				respArray[0] = resp.Value{Type: resp.BulkString, Str: command}
-				for i, arg := range args {
-					respArray[i+1] = resp.Value{Type: resp.BulkString, Str: arg}
-				}
+			// Serve clients blocked on keys this command pushed to
+			s.blocking.ServeReady()
+		}
+	}
+}
 
-				var buf strings.Builder
-				if err := resp.Encode(&buf, resp.Value{Type: resp.Array, Array: respArray}); err == nil {
-					// Forward to all connected replicas
-					s.replManager.AppendCommand([]byte(buf.String()))
-					logger.Debugf("Successfully forwarded write command %s to replicas", command)
-				} else {
-					logger.Errorf("Failed to encode command for replication: %v", err)
-				}
-			}
+// errorReply formats a command error for the wire. Messages that already carry
+// a Redis error code (ERR, WRONGTYPE, ...) are sent as-is, others get "ERR ".
+func errorReply(err error) string {
+	msg := err.Error()
+	code := msg
+	if i := strings.IndexByte(msg, ' '); i >= 0 {
+		code = msg[:i]
+	}
+	if code == "" {
+		return "ERR " + msg
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return "ERR " + msg
 		}
 	}
+	return msg
 }
 
 // handlePSyncCommand handles PSYNC command specially to send RDB data
@@ -651,6 +995,10 @@ func (s *Server) handlePSyncCommand(client *Client, args []string) error {
 		respArgs[i] = resp.Value{Type: resp.BulkString, Str: arg}
 	}
 
+	// The replica starts in database 0 after the full sync
+	s.resetReplicationDB()
+	client.replica = true
+
 	// Use the special PSYNC handler that sends RDB data
 	if s.persist != nil {
 		psyncHandler := cmd.PSyncHandlerWithRDB(s.replManager, s.persist, s.replManager)
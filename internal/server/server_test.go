@@ -318,7 +318,7 @@ func TestServerNew(t *testing.T) {
 		assert.NotNil(t, server.stats)
 		assert.NotNil(t, server.tm)
 		assert.NotNil(t, server.connSemaphore)
-		assert.Equal(t, int64(4*1024*1024*1024), server.memoryLimit) // 4GB
+		assert.Zero(t, server.maxMemory.Load()) // No memory limit
 	})
 
 	t.Run("New server with persistence", func(t *testing.T) {
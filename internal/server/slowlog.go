@@ -0,0 +1,261 @@
+package server
+
+import (
+	"fmt"
+	"gridhouse/internal/resp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of slowlog-log-slower-than (microseconds) and slowlog-max-len
+const (
+	defaultSlowlogSlowerThan = 10000
+	defaultSlowlogMaxLen     = 128
+)
+
+// Limits on what an entry keeps of the arguments, as in Redis
+const (
+	slowlogMaxArgc   = 32
+	slowlogMaxString = 128
+)
+
+// slowlogEntry is a command that ran slower than slowlog-log-slower-than
+type slowlogEntry struct {
+	id       int64
+	time     time.Time
+	duration time.Duration
+	args     []string // command name first, truncated
+	addr     string
+	name     string
+}
+
+// slowlog is the bounded ring behind SLOWLOG. The thresholds are atomics so
+// that fast commands are checked without taking the lock.
+type slowlog struct {
+	slowerThan atomic.Int64 // microseconds, negative disables the log
+	maxLen     atomic.Int64
+
+	mu      sync.Mutex
+	entries []slowlogEntry // ring, next is the slot of the next entry
+	next    int
+	count   int
+	nextID  int64
+}
+
+func newSlowlog() *slowlog {
+	l := &slowlog{}
+	l.slowerThan.Store(defaultSlowlogSlowerThan)
+	l.maxLen.Store(defaultSlowlogMaxLen)
+	return l
+}
+
+// slow reports whether a command that took d is logged
+func (l *slowlog) slow(d time.Duration) bool {
+	threshold := l.slowerThan.Load()
+	return threshold >= 0 && d.Microseconds() >= threshold
+}
+
+// add records an entry, dropping the oldest one once the log is full
+func (l *slowlog) add(e slowlogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.id = l.nextID
+	l.nextID++
+	max := int(l.maxLen.Load())
+	if max == 0 {
+		l.entries, l.next, l.count = nil, 0, 0
+		return
+	}
+	if len(l.entries) != max {
+		l.resizeLocked(max)
+	}
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % max
+	if l.count < max {
+		l.count++
+	}
+}
+
+// setMaxLen changes the length of the log, keeping the newest entries
+func (l *slowlog) setMaxLen(max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxLen.Store(int64(max))
+	l.resizeLocked(max)
+}
+
+func (l *slowlog) resizeLocked(max int) {
+	kept := l.newestLocked(max)
+	l.entries = make([]slowlogEntry, max)
+	l.count = len(kept)
+	for i, e := range kept {
+		l.entries[l.count-1-i] = e
+	}
+	l.next = 0
+	if max > 0 {
+		l.next = l.count % max
+	}
+}
+
+// newest returns up to n entries, newest first; a negative n returns all
+func (l *slowlog) newest(n int) []slowlogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.newestLocked(n)
+}
+
+func (l *slowlog) newestLocked(n int) []slowlogEntry {
+	if n < 0 || n > l.count {
+		n = l.count
+	}
+	out := make([]slowlogEntry, n)
+	for i := range out {
+		out[i] = l.entries[(l.next-1-i+2*len(l.entries))%len(l.entries)]
+	}
+	return out
+}
+
+func (l *slowlog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+func (l *slowlog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.entries {
+		l.entries[i] = slowlogEntry{}
+	}
+	l.next, l.count = 0, 0
+}
+
+// slowlogArgs copies the arguments of a command for an entry, truncating
+// them like Redis and hiding credentials
+func slowlogArgs(command string, args []resp.Value) []string {
+	argc := len(args) + 1
+	if argc > slowlogMaxArgc {
+		argc = slowlogMaxArgc
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = arg.Str
+	}
+	from, to := redactedArgs(command, strs)
+
+	out := make([]string, argc)
+	out[0] = command
+	for i := 1; i < argc; i++ {
+		if i == slowlogMaxArgc-1 && len(args)+1 > slowlogMaxArgc {
+			out[i] = fmt.Sprintf("... (%d more arguments)", len(args)+1-i)
+			break
+		}
+		arg := strs[i-1]
+		switch {
+		case i-1 >= from && i-1 < to:
+			arg = "(redacted)"
+		case len(arg) > slowlogMaxString:
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxString], len(arg)-slowlogMaxString)
+		}
+		out[i] = arg
+	}
+	return out
+}
+
+// logSlow records the command in the slow log if it took long enough
+func (s *Server) logSlow(client *Client, command string, args []resp.Value, d time.Duration) {
+	if s.slowlog == nil || !s.slowlog.slow(d) {
+		return
+	}
+	s.slowlog.add(slowlogEntry{
+		time:     time.Now(),
+		duration: d,
+		args:     slowlogArgs(command, args),
+		addr:     client.addr(),
+		name:     client.name,
+	})
+}
+
+// slowlogCommand handles SLOWLOG GET [count], LEN, RESET and HELP
+func (s *Server) slowlogCommand(args []resp.Value) (resp.Value, error) {
+	if len(args) == 0 {
+		return resp.Value{}, fmt.Errorf("ERR wrong number of arguments for 'SLOWLOG' command")
+	}
+	name := args[0].Str
+	sub := strings.ToUpper(name)
+	args = args[1:]
+	arity := func(ok bool) error {
+		if !ok {
+			return fmt.Errorf("ERR wrong number of arguments for 'SLOWLOG|%s' command", strings.ToLower(sub))
+		}
+		return nil
+	}
+
+	switch sub {
+	case "GET":
+		if err := arity(len(args) <= 1); err != nil {
+			return resp.Value{}, err
+		}
+		count := 10
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0].Str)
+			if err != nil || n < -1 {
+				return resp.Value{}, fmt.Errorf("ERR count should be greater than or equal to -1")
+			}
+			count = n
+		}
+		entries := s.slowlog.newest(count)
+		out := make([]resp.Value, len(entries))
+		for i, e := range entries {
+			argv := make([]resp.Value, len(e.args))
+			for j, arg := range e.args {
+				argv[j] = resp.Value{Type: resp.BulkString, Str: arg}
+			}
+			out[i] = resp.Value{Type: resp.Array, Array: []resp.Value{
+				{Type: resp.Integer, Int: e.id},
+				{Type: resp.Integer, Int: e.time.Unix()},
+				{Type: resp.Integer, Int: e.duration.Microseconds()},
+				{Type: resp.Array, Array: argv},
+				{Type: resp.BulkString, Str: e.addr},
+				{Type: resp.BulkString, Str: e.name},
+			}}
+		}
+		return resp.Value{Type: resp.Array, Array: out}, nil
+	case "LEN":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		return resp.Value{Type: resp.Integer, Int: int64(s.slowlog.len())}, nil
+	case "RESET":
+		if err := arity(len(args) == 0); err != nil {
+			return resp.Value{}, err
+		}
+		s.slowlog.reset()
+		return resp.Value{Type: resp.SimpleString, Str: "OK"}, nil
+	case "HELP":
+		lines := []string{
+			"SLOWLOG <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"GET [<count>]",
+			"    Return top <count> entries from the slowlog (default: 10, -1 mean all).",
+			"    Entries are made of:",
+			"    id, timestamp, time in microseconds, arguments array, client IP and port,",
+			"    client name",
+			"LEN",
+			"    Return the length of the slowlog.",
+			"RESET",
+			"    Reset the slowlog.",
+			"HELP",
+			"    Print this help.",
+		}
+		out := make([]resp.Value, len(lines))
+		for i, line := range lines {
+			out[i] = resp.Value{Type: resp.SimpleString, Str: line}
+		}
+		return resp.Value{Type: resp.Array, Array: out}, nil
+	default:
+		return resp.Value{}, fmt.Errorf("ERR unknown subcommand '%s'. Try SLOWLOG HELP.", name)
+	}
+}
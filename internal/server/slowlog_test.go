@@ -0,0 +1,97 @@
+package server
+
+import (
+	"gridhouse/internal/resp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowlog(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+
+	c.send(t, "CONFIG", "GET", "slowlog-max-len")
+	c.expect(t, "*2\r\n$15\r\nslowlog-max-len\r\n$3\r\n128\r\n")
+	c.send(t, "CONFIG", "SET", "slowlog-max-len", "-1")
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'slowlog-max-len') - argument must be between 0 and 2147483647 inclusive\r\n")
+
+	// With a zero threshold every command is logged, including the CONFIG
+	// SET that lowered it; -1 stops logging
+	c.send(t, "CONFIG", "SET", "slowlog-log-slower-than", "0")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CLIENT", "SETNAME", "worker")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "SET", "slowlog-log-slower-than", "-1")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "GET", "k")
+	c.expect(t, "$1\r\nv\r\n")
+
+	c.send(t, "SLOWLOG", "LEN")
+	c.expect(t, ":3\r\n")
+	c.send(t, "SLOWLOG", "GET", "1")
+	c.expect(t, "*1\r\n*6\r\n:2\r\n")
+	require.Positive(t, c.readInt(t))
+	require.GreaterOrEqual(t, c.readInt(t), int64(0))
+	c.expect(t, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")
+	require.Equal(t, c.LocalAddr().String(), c.readBulk(t))
+	c.expect(t, "$6\r\nworker\r\n")
+
+	c.send(t, "SLOWLOG", "GET", "-2")
+	c.expect(t, "-ERR count should be greater than or equal to -1\r\n")
+	c.send(t, "SLOWLOG", "RESET")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "SLOWLOG", "GET")
+	c.expect(t, "*0\r\n")
+	c.send(t, "SLOWLOG", "BOGUS")
+	c.expect(t, "-ERR unknown subcommand 'BOGUS'. Try SLOWLOG HELP.\r\n")
+}
+
+func TestSlowlogRing(t *testing.T) {
+	l := newSlowlog()
+	l.setMaxLen(3)
+	for i := 0; i < 5; i++ {
+		l.add(slowlogEntry{})
+	}
+	ids := func() []int64 {
+		var out []int64
+		for _, e := range l.newest(-1) {
+			out = append(out, e.id)
+		}
+		return out
+	}
+	require.Equal(t, 3, l.len())
+	require.Equal(t, []int64{4, 3, 2}, ids())
+
+	// Shrinking keeps the newest entries
+	l.setMaxLen(2)
+	require.Equal(t, []int64{4, 3}, ids())
+	l.add(slowlogEntry{})
+	require.Equal(t, []int64{5, 4}, ids())
+	require.Len(t, l.newest(1), 1)
+
+	l.setMaxLen(0)
+	l.add(slowlogEntry{})
+	require.Zero(t, l.len())
+}
+
+func TestSlowlogArgs(t *testing.T) {
+	args := make([]resp.Value, 40)
+	for i := range args {
+		args[i] = resp.Value{Type: resp.BulkString, Str: "x"}
+	}
+	args[0].Str = strings.Repeat("a", 130)
+	got := slowlogArgs("RPUSH", args)
+	require.Len(t, got, slowlogMaxArgc)
+	require.Equal(t, "RPUSH", got[0])
+	require.Equal(t, strings.Repeat("a", 128)+"... (2 more bytes)", got[1])
+	require.Equal(t, "... (10 more arguments)", got[31])
+
+	got = slowlogArgs("AUTH", []resp.Value{{Str: "user"}, {Str: "secret"}})
+	require.Equal(t, []string{"AUTH", "(redacted)", "(redacted)"}, got)
+	got = slowlogArgs("ACL", []resp.Value{{Str: "SETUSER"}, {Str: "bob"}, {Str: "on"}, {Str: ">secret"}})
+	require.Equal(t, []string{"ACL", "SETUSER", "bob", "(redacted)", "(redacted)"}, got)
+}
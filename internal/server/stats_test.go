@@ -299,3 +299,33 @@ func TestServerStatsEdgeCases(t *testing.T) {
 		assert.Equal(t, int64(1), statsManager.GetEvictedKeys())
 	})
 }
+
+func TestCommandStats(t *testing.T) {
+	srv := startBlockingTestServer(t)
+	c := dialTest(t, srv)
+
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "get", "k")
+	c.expect(t, "$1\r\nv\r\n")
+	c.send(t, "GET", "k", "extra")
+	c.expect(t, "-ERR wrong number of arguments for 'GET' command\r\n")
+	c.send(t, "INCR", "k")
+	c.expect(t, "-ERR value is not an integer or out of range\r\n")
+
+	c.send(t, "INFO", "commandstats")
+	info := c.readBulk(t)
+	assert.Regexp(t, `cmdstat_get:calls=1,usec=\d+,usec_per_call=[\d.]+,rejected_calls=1,failed_calls=0\r\n`, info)
+	assert.Regexp(t, `cmdstat_incr:calls=1,usec=\d+,usec_per_call=[\d.]+,rejected_calls=0,failed_calls=1\r\n`, info)
+	assert.Regexp(t, `cmdstat_set:calls=1,`, info)
+	assert.NotContains(t, info, "cmdstat_info")
+
+	c.send(t, "INFO", "latencystats")
+	assert.Regexp(t, `latency_percentiles_usec_get:p50=[\d.]+,p99=[\d.]+,p99\.9=[\d.]+\r\n`, c.readBulk(t))
+
+	c.send(t, "CONFIG", "RESETSTAT")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "INFO", "commandstats")
+	// Only the RESETSTAT itself is left
+	assert.Regexp(t, `^# Commandstats\r\ncmdstat_config:calls=1,[^\r]*\r\n\r\n$`, c.readBulk(t))
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/tls"
+	"gridhouse/internal/cmd"
+	"gridhouse/internal/logger"
+	"gridhouse/internal/tlsconfig"
+	"net"
+	"strings"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the TLS handshake of new connections
+const tlsHandshakeTimeout = 10 * time.Second
+
+// TLSConfig holds the settings of the TLS listener
+type TLSConfig struct {
+	Addr     string // Listen address, e.g. ":6390"; empty disables TLS
+	CertFile string // Certificate presented to clients and, for replication, to the master
+	KeyFile  string
+	CAFile   string // CA certificates peer certificates are verified against
+
+	// AuthClients says whether clients must present a certificate: no,
+	// optional or yes, the default
+	AuthClients string
+
+	// AuthClientsUser set to "cn" authenticates clients as the ACL user
+	// named by the common name of their certificate; empty or "off"
+	// leaves them the default user
+	AuthClientsUser string
+
+	// Replication makes a replica dial its master over TLS
+	Replication bool
+}
+
+// startTLS loads the certificates and listens on the TLS address. Each
+// handshake uses the certificates last loaded, so CONFIG SET can replace
+// them without restarting the listener.
+func (s *Server) startTLS() error {
+	if err := s.reloadTLS(); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.cfg.TLS.Addr)
+	if err != nil {
+		return err
+	}
+	s.tlsLn = tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.tlsConfig.Load(), nil
+		},
+	})
+	s.tlsAddr = ln.Addr().String()
+	logger.Infof("Server listening for TLS on %s", s.tlsAddr)
+	go s.serveListener(s.tlsLn)
+	return nil
+}
+
+// TLSAddr returns the address of the TLS listener, empty if it is disabled
+func (s *Server) TLSAddr() string { return s.tlsAddr }
+
+// reloadTLS reads the certificate files again; the connections accepted
+// afterwards use them
+func (s *Server) reloadTLS() error {
+	settings := s.tlsSettings()
+	clientAuth, err := tlsconfig.ParseClientAuth(settings.AuthClients)
+	if err != nil {
+		return err
+	}
+	config, err := tlsconfig.Server(settings.CertFile, settings.KeyFile, settings.CAFile, clientAuth)
+	if err != nil {
+		return err
+	}
+	s.tlsConfig.Store(config)
+	return nil
+}
+
+// tlsSettings returns a copy of the current TLS settings
+func (s *Server) tlsSettings() TLSConfig {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	if s.cfg.TLS == nil {
+		return TLSConfig{}
+	}
+	return *s.cfg.TLS
+}
+
+// updateTLS changes the TLS settings
+func (s *Server) updateTLS(update func(settings *TLSConfig)) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	if s.cfg.TLS == nil {
+		s.cfg.TLS = &TLSConfig{}
+	}
+	update(s.cfg.TLS)
+}
+
+// tlsHandshake completes the handshake of a TLS connection. With
+// tls-auth-clients-user cn it returns the enabled ACL user the common name
+// of the client certificate names, if any.
+func (s *Server) tlsHandshake(conn *tls.Conn) (string, error) {
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		return "", err
+	}
+	conn.SetDeadline(time.Time{})
+
+	if !strings.EqualFold(s.tlsSettings().AuthClientsUser, "cn") || s.acl == nil {
+		return "", nil
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", nil
+	}
+	name := certs[0].Subject.CommonName
+	if user := s.acl.Get(name); user != nil && user.Enabled() {
+		return name, nil
+	}
+	return "", nil
+}
+
+// tlsParams returns the tls-* settings served by CONFIG. Changing the
+// certificate files or tls-auth-clients reloads the certificates, and
+// setting a file to its current value reloads it too, which picks up
+// renewed certificates.
+func (s *Server) tlsParams() cmd.ConfigParams {
+	reload := func() error {
+		if s.tlsLn == nil {
+			return nil
+		}
+		return s.reloadTLS()
+	}
+	file := func(field func(settings *TLSConfig) *string) cmd.ConfigParam {
+		return cmd.ConfigParam{
+			Get: func() string {
+				settings := s.tlsSettings()
+				return *field(&settings)
+			},
+			Set: func(value string) error {
+				s.updateTLS(func(settings *TLSConfig) { *field(settings) = value })
+				return nil
+			},
+			Apply: reload,
+		}
+	}
+
+	authClients := cmd.EnumConfigParam([]string{tlsconfig.ClientAuthNo, tlsconfig.ClientAuthOptional, tlsconfig.ClientAuthYes},
+		func() string {
+			if value := s.tlsSettings().AuthClients; value != "" {
+				return value
+			}
+			return tlsconfig.ClientAuthYes
+		}, func(value string) error {
+			s.updateTLS(func(settings *TLSConfig) { settings.AuthClients = value })
+			return nil
+		})
+	authClients.Apply = reload
+
+	return cmd.ConfigParams{
+		"tls-port": {Get: func() string {
+			if s.tlsAddr != "" {
+				return configPort(s.tlsAddr)
+			}
+			if port := configPort(s.tlsSettings().Addr); port != "" {
+				return port
+			}
+			return "0"
+		}},
+		"tls-cert-file":    file(func(settings *TLSConfig) *string { return &settings.CertFile }),
+		"tls-key-file":     file(func(settings *TLSConfig) *string { return &settings.KeyFile }),
+		"tls-ca-cert-file": file(func(settings *TLSConfig) *string { return &settings.CAFile }),
+		"tls-auth-clients": authClients,
+		"tls-auth-clients-user": cmd.EnumConfigParam([]string{"off", "cn"}, func() string {
+			if value := s.tlsSettings().AuthClientsUser; value != "" {
+				return value
+			}
+			return "off"
+		}, func(value string) error {
+			s.updateTLS(func(settings *TLSConfig) { settings.AuthClientsUser = value })
+			return nil
+		}),
+		"tls-replication": cmd.BoolConfigParam(func() bool { return s.tlsSettings().Replication }, nil),
+	}
+}
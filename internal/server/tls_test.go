@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"gridhouse/internal/persistence"
+	"gridhouse/internal/tlsconfig"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for TLS tests and writes them as PEM files
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string // The CA certificate
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	ca := &testCA{dir: t.TempDir()}
+	ca.cert, ca.key, ca.file, _ = ca.issue(t, "ca", &x509.Certificate{
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	})
+	return ca
+}
+
+// issue signs a certificate for commonName, self-signed while the CA has
+// none, and returns it along with its certificate and key files
+func (ca *testCA) issue(t *testing.T, commonName string, template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serial
+	template.Subject = pkix.Name{CommonName: commonName}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if ca.cert != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(ca.dir, commonName+".crt")
+	keyFile := filepath.Join(ca.dir, commonName+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return cert, key, certFile, keyFile
+}
+
+// server issues a certificate for 127.0.0.1
+func (ca *testCA) server(t *testing.T, commonName string) (string, string) {
+	_, _, certFile, keyFile := ca.issue(t, commonName, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	return certFile, keyFile
+}
+
+// client issues a client certificate
+func (ca *testCA) client(t *testing.T, commonName string) (string, string) {
+	_, _, certFile, keyFile := ca.issue(t, commonName, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return certFile, keyFile
+}
+
+// dialTLS connects to the TLS listener, presenting the client certificate
+// in certFile and keyFile when set
+func dialTLS(t *testing.T, srv *Server, ca *testCA, certFile, keyFile string) (*testConn, error) {
+	t.Helper()
+	config, err := tlsconfig.Client(ca.file, certFile, keyFile, false)
+	require.NoError(t, err)
+	c, err := tls.Dial("tcp", srv.TLSAddr(), config)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { c.Close() })
+	return &testConn{Conn: c, r: bufio.NewReader(c)}, nil
+}
+
+func startTLSTestServer(t *testing.T, ca *testCA, settings TLSConfig) *Server {
+	t.Helper()
+	settings.Addr = "127.0.0.1:0"
+	settings.CertFile, settings.KeyFile = ca.server(t, "server")
+	settings.CAFile = ca.file
+	// Persistence sends replicas the RDB snapshot
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, TLS: &settings,
+		Persistence: &persistence.Config{Dir: t.TempDir()}})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestTLSListener(t *testing.T) {
+	ca := newTestCA(t)
+	srv := startTLSTestServer(t, ca, TLSConfig{AuthClientsUser: "cn"})
+
+	// Port 0 with TLS enabled leaves only the TLS listener
+	assert.Empty(t, srv.Addr())
+	assert.NotEmpty(t, srv.TLSAddr())
+
+	// Clients must present a certificate
+	c, err := dialTLS(t, srv, ca, "", "")
+	if err == nil {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = c.r.ReadByte()
+	}
+	assert.Error(t, err)
+
+	certFile, keyFile := ca.client(t, "app")
+	c, err = dialTLS(t, srv, ca, certFile, keyFile)
+	require.NoError(t, err)
+	c.send(t, "ACL", "WHOAMI")
+	c.expect(t, "$7\r\ndefault\r\n")
+	c.send(t, "ACL", "SETUSER", "app", "on", "~app:*", "+@all")
+	c.expect(t, "+OK\r\n")
+	c.send(t, "CONFIG", "GET", "tls-auth-clients*")
+	assert.Equal(t, []string{"tls-auth-clients", "yes", "tls-auth-clients-user", "cn"}, c.readArray(t))
+
+	// The common name of the certificate names the user
+	c, err = dialTLS(t, srv, ca, certFile, keyFile)
+	require.NoError(t, err)
+	c.send(t, "ACL", "WHOAMI")
+	c.expect(t, "$3\r\napp\r\n")
+	c.send(t, "SET", "other", "v")
+	c.expect(t, "-NOPERM No permissions to access a key\r\n")
+}
+
+func TestTLSReloadCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	srv := startTLSTestServer(t, ca, TLSConfig{AuthClients: "no"})
+	c, err := dialTLS(t, srv, ca, "", "")
+	require.NoError(t, err)
+
+	certFile, keyFile := ca.server(t, "renewed")
+	c.send(t, "CONFIG", "SET", "tls-cert-file", certFile)
+	c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'tls-cert-file') - failed to load certificate: tls: private key does not match public key\r\n")
+	c.send(t, "CONFIG", "SET", "tls-cert-file", certFile, "tls-key-file", keyFile)
+	c.expect(t, "+OK\r\n")
+
+	// New connections get the new certificate, existing ones keep working
+	renewed, err := dialTLS(t, srv, ca, "", "")
+	require.NoError(t, err)
+	renewed.send(t, "PING")
+	renewed.expect(t, "+PONG\r\n")
+	assert.Equal(t, "renewed", renewed.Conn.(*tls.Conn).ConnectionState().PeerCertificates[0].Subject.CommonName)
+	c.send(t, "PING")
+	c.expect(t, "+PONG\r\n")
+
+	// Requiring client certificates applies to new connections
+	c.send(t, "CONFIG", "SET", "tls-auth-clients", "yes")
+	c.expect(t, "+OK\r\n")
+	if c, err := dialTLS(t, srv, ca, "", ""); err == nil {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = c.r.ReadByte()
+		assert.Error(t, err)
+	}
+}
+
+func TestTLSReplication(t *testing.T) {
+	ca := newTestCA(t)
+	master := startTLSTestServer(t, ca, TLSConfig{})
+	certFile, keyFile := ca.client(t, "replica")
+	replica := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, SlaveOf: master.TLSAddr(), TLS: &TLSConfig{
+		CertFile: certFile, KeyFile: keyFile, CAFile: ca.file, Replication: true,
+	}})
+	require.NoError(t, replica.Start())
+	t.Cleanup(func() { replica.Close() })
+
+	require.Eventually(t, func() bool { return replica.slave.LinkUp() }, 5*time.Second, 10*time.Millisecond)
+	masterCert, masterKey := ca.client(t, "admin")
+	c, err := dialTLS(t, master, ca, masterCert, masterKey)
+	require.NoError(t, err)
+	c.send(t, "SET", "k", "v")
+	c.expect(t, "+OK\r\n")
+	require.Eventually(t, func() bool {
+		v, ok := replica.db.Get("k")
+		return ok && v == "v"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"gridhouse/internal/logger"
+	"net"
+	"os"
+	"strconv"
+)
+
+// startUnixSocket listens on the Unix socket, replacing the socket file a
+// previous run may have left behind
+func (s *Server) startUnixSocket() error {
+	path := s.cfg.UnixSocket
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if s.cfg.UnixSocketPerm != 0 {
+		if err := os.Chmod(path, s.cfg.UnixSocketPerm); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+	s.unixLn = ln
+	logger.Infof("Server listening on Unix socket %s", path)
+	go s.serveListener(ln)
+	return nil
+}
+
+// UnixSocket returns the path of the Unix socket listener, empty if it is
+// disabled
+func (s *Server) UnixSocket() string {
+	if s.unixLn == nil {
+		return ""
+	}
+	return s.cfg.UnixSocket
+}
+
+// parseUnixSocketPerm parses octal socket permissions like "770"
+func parseUnixSocketPerm(value string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, errors.New("Invalid socket file permissions")
+	}
+	return os.FileMode(perm), nil
+}
+
+// formatUnixSocketPerm formats socket permissions the way the
+// unixsocketperm setting takes them
+func formatUnixSocketPerm(perm os.FileMode) string {
+	return strconv.FormatUint(uint64(perm.Perm()), 8)
+}
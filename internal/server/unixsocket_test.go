@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gridhouse.sock")
+	// A socket file left behind by a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := New(Config{Addr: "127.0.0.1:0", MaxConnections: 10, UnixSocket: path, UnixSocketPerm: 0770})
+	require.NoError(t, srv.Start())
+
+	// Port 0 with the Unix socket enabled leaves only the socket
+	assert.Empty(t, srv.Addr())
+	assert.Equal(t, path, srv.UnixSocket())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0770), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	c := &testConn{Conn: conn, r: bufio.NewReader(conn)}
+	c.send(t, "PING")
+	c.expect(t, "+PONG\r\n")
+	c.send(t, "CLIENT", "INFO")
+	clientInfo := c.readBulk(t)
+	assert.Contains(t, clientInfo, " addr="+path+":0 laddr="+path+":0 ")
+	assert.Contains(t, clientInfo, " flags=U ")
+	c.send(t, "CONFIG", "GET", "unixsocket*")
+	assert.Equal(t, []string{"unixsocket", path, "unixsocketperm", "770"}, c.readArray(t))
+	assert.Equal(t, int64(1), srv.stats.GetStats().GetActiveConnections())
+
+	// Closing the server removes the socket file
+	require.NoError(t, srv.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -60,3 +60,33 @@ func TestZSetBasic(t *testing.T) {
 		_ = l2
 	}
 }
+
+func TestWrongTypeReply(t *testing.T) {
+	srv := New(Config{Addr: "127.0.0.1:0"})
+	require.NoError(t, srv.Start())
+	defer srv.Close()
+
+	c, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer c.Close()
+	r := bufio.NewReader(c)
+	c.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+
+	_, err = c.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"))
+	require.NoError(t, err)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "+OK\r\n", line)
+
+	_, err = c.Write([]byte("*3\r\n$5\r\nLPUSH\r\n$1\r\nk\r\n$1\r\nx\r\n"))
+	require.NoError(t, err)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", line)
+
+	_, err = c.Write([]byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	require.NoError(t, err)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "$1\r\n", line)
+}
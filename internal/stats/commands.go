@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latencies are bucketed log-linearly: values below histLinear nanoseconds
+// get a bucket each, larger ones histSubBuckets buckets per power of two,
+// which keeps percentiles within 12.5% of the recorded value
+const (
+	histSubBits    = 3
+	histSubBuckets = 1 << histSubBits
+	histLinear     = 2 * histSubBuckets
+	histBuckets    = histLinear + (64-histSubBits-1)*histSubBuckets
+)
+
+// latencyHistogram records latencies with one atomic add per sample
+type latencyHistogram struct {
+	sum     atomic.Int64 // nanoseconds
+	buckets [histBuckets]atomic.Int64
+}
+
+func histBucket(ns uint64) int {
+	if ns < histLinear {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 1
+	sub := int(ns>>(exp-histSubBits)) & (histSubBuckets - 1)
+	return histLinear + (exp-histSubBits-1)*histSubBuckets + sub
+}
+
+// histValue returns the highest value that falls into bucket i
+func histValue(i int) uint64 {
+	if i < histLinear {
+		return uint64(i)
+	}
+	i -= histLinear
+	exp := i/histSubBuckets + histSubBits + 1
+	shift := exp - histSubBits
+	low := uint64(histSubBuckets+i%histSubBuckets) << shift
+	return low + 1<<shift - 1
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.sum.Add(int64(d))
+	h.buckets[histBucket(uint64(d))].Add(1)
+}
+
+// counts returns the number of samples and the bucket counts
+func (h *latencyHistogram) counts() (int64, []int64) {
+	counts := make([]int64, histBuckets)
+	var total int64
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		total += counts[i]
+	}
+	return total, counts
+}
+
+// percentile returns the latency below which p percent of the samples fall
+func percentile(total int64, counts []int64, p float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := int64(p / 100 * float64(total))
+	if float64(rank) < p/100*float64(total) {
+		rank++
+	}
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return time.Duration(histValue(i))
+		}
+	}
+	return time.Duration(histValue(histBuckets - 1))
+}
+
+// commandStats are the counters of one command
+type commandStats struct {
+	calls    atomic.Int64
+	rejected atomic.Int64
+	failed   atomic.Int64
+	latency  latencyHistogram
+}
+
+// CommandStat is a point-in-time copy of a command's counters, as reported
+// by INFO commandstats and latencystats
+type CommandStat struct {
+	Calls         int64
+	Usec          int64
+	RejectedCalls int64
+	FailedCalls   int64
+
+	// Latency percentiles
+	P50  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+}
+
+// commandTable holds the counters of every command seen. Lookups of known
+// commands don't lock.
+type commandTable struct {
+	commands sync.Map // name -> *commandStats
+}
+
+func (t *commandTable) get(name string) *commandStats {
+	if c, ok := t.commands.Load(name); ok {
+		return c.(*commandStats)
+	}
+	c, _ := t.commands.LoadOrStore(name, &commandStats{})
+	return c.(*commandStats)
+}
+
+func (t *commandTable) reset() {
+	t.commands.Range(func(name, _ any) bool {
+		t.commands.Delete(name)
+		return true
+	})
+}
+
+// RecordCommand accounts one execution of a command that took latency;
+// failed tells whether it replied with an error
+func (s *OptimizedStatsManager) RecordCommand(commandType string, latency time.Duration, failed bool) {
+	c := s.commands.get(commandType)
+	c.calls.Add(1)
+	c.latency.record(latency)
+	if failed {
+		c.failed.Add(1)
+	}
+}
+
+// RecordRejectedCommand accounts a command refused before it ran, such as a
+// call with the wrong number of arguments
+func (s *OptimizedStatsManager) RecordRejectedCommand(commandType string) {
+	s.commands.get(commandType).rejected.Add(1)
+}
+
+// GetCommandStats returns the counters of every command seen since the last
+// reset
+func (s *OptimizedStatsManager) GetCommandStats() map[string]CommandStat {
+	out := make(map[string]CommandStat)
+	s.commands.commands.Range(func(name, value any) bool {
+		c := value.(*commandStats)
+		total, counts := c.latency.counts()
+		out[name.(string)] = CommandStat{
+			Calls:         c.calls.Load(),
+			Usec:          c.latency.sum.Load() / int64(time.Microsecond),
+			RejectedCalls: c.rejected.Load(),
+			FailedCalls:   c.failed.Load(),
+			P50:           percentile(total, counts, 50),
+			P99:           percentile(total, counts, 99),
+			P999:          percentile(total, counts, 99.9),
+		}
+		return true
+	})
+	return out
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	// Every value falls into a bucket whose highest value is within 12.5%
+	for _, ns := range []uint64{0, 1, 15, 16, 17, 100, 1000, 123456, 1 << 40, 1<<63 - 1} {
+		i := histBucket(ns)
+		assert.Less(t, i, histBuckets)
+		high := histValue(i)
+		assert.GreaterOrEqual(t, high, ns)
+		assert.LessOrEqual(t, float64(high-ns), float64(ns)/8, "value %d", ns)
+		if i > 0 {
+			assert.Less(t, histValue(i-1), ns)
+		}
+	}
+}
+
+func TestCommandStats(t *testing.T) {
+	stats := NewOptimizedStatsManager()
+	for i := 1; i <= 1000; i++ {
+		stats.RecordCommand("GET", time.Duration(i)*time.Microsecond, i%100 == 0)
+	}
+	stats.RecordRejectedCommand("GET")
+	stats.RecordRejectedCommand("SET")
+
+	cmds := stats.GetCommandStats()
+	get := cmds["GET"]
+	assert.Equal(t, int64(1000), get.Calls)
+	assert.Equal(t, int64(500500), get.Usec)
+	assert.Equal(t, int64(10), get.FailedCalls)
+	assert.Equal(t, int64(1), get.RejectedCalls)
+	assert.InEpsilon(t, float64(500*time.Microsecond), float64(get.P50), 0.125)
+	assert.InEpsilon(t, float64(990*time.Microsecond), float64(get.P99), 0.125)
+	assert.InEpsilon(t, float64(999*time.Microsecond), float64(get.P999), 0.125)
+	assert.Equal(t, CommandStat{RejectedCalls: 1}, cmds["SET"])
+	assert.Equal(t, 500500*time.Nanosecond, stats.GetAverageLatency("GET"))
+
+	stats.IncrementCommandsProcessed()
+	stats.ResetStats()
+	assert.Empty(t, stats.GetCommandStats())
+	assert.Zero(t, stats.GetTotalCommandsProcessed())
+}
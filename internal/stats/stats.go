@@ -26,8 +26,11 @@ type StatsSnapshot struct {
 	TotalConnectionsReceived int64
 	ActiveConnections        int64
 	RejectedConnections      int64
+	PubSubClients            int64
+	BlockedClients           int64
 	TotalCommandsProcessed   int64
 	CommandsByType           map[string]int64
+	CommandStats             map[string]CommandStat
 	DatabaseKeys             map[int]int64
 	DatabaseExpires          map[int]int64
 	ExpiredKeys              int64
@@ -59,6 +62,8 @@ type OptimizedStatsManager struct {
 	totalConnectionsReceived int64
 	activeConnections        int64
 	rejectedConnections      int64
+	pubsubClients            int64
+	blockedClients           int64
 	totalCommandsProcessed   int64
 	expiredKeys              int64
 	evictedKeys              int64
@@ -78,15 +83,13 @@ type OptimizedStatsManager struct {
 	port                     int
 	role                     string
 	replicating              bool
-	commandsByType           map[string]int64
 	memoryFragmentationRatio float64
 	databaseKeys             map[int]int64
 	databaseExpires          map[int]int64
 	startTime                time.Time
 
-	// Latency tracking with lock-free ring buffer
-	latencyMu        sync.RWMutex
-	latencyByCommand map[string]*ringBuffer
+	// Per-command calls, errors and latency histograms
+	commands commandTable
 
 	// CPU tracking fields - cumulative CPU time in seconds
 	cpuMu                 sync.RWMutex
@@ -99,74 +102,17 @@ type OptimizedStatsManager struct {
 	usedCPUUserMainThread float64
 }
 
-// ringBuffer is a lock-free circular buffer for latency samples
-type ringBuffer struct {
-	data   []time.Duration
-	size   int
-	pos    int64 // atomic position
-	filled int64 // atomic filled indicator
-}
-
-// newRingBuffer creates a new ring buffer
-func newRingBuffer(size int) *ringBuffer {
-	return &ringBuffer{
-		data: make([]time.Duration, size),
-		size: size,
-	}
-}
-
-// add adds a value to the ring buffer
-func (rb *ringBuffer) add(value time.Duration) {
-	pos := atomic.AddInt64(&rb.pos, 1) % int64(rb.size)
-	rb.data[pos] = value
-
-	// Mark as filled once we've wrapped around
-	if pos == 0 {
-		atomic.StoreInt64(&rb.filled, 1)
-	}
-}
-
-// getAverage calculates the average of all samples
-func (rb *ringBuffer) getAverage() time.Duration {
-	filled := atomic.LoadInt64(&rb.filled)
-	pos := atomic.LoadInt64(&rb.pos)
-
-	var sum time.Duration
-	var count int
-
-	if filled == 1 {
-		// Buffer is full, use all samples
-		count = rb.size
-		for _, v := range rb.data {
-			sum += v
-		}
-	} else {
-		// Buffer not full, use only filled samples
-		count = int(pos)
-		for i := 0; i < count; i++ {
-			sum += rb.data[i]
-		}
-	}
-
-	if count == 0 {
-		return 0
-	}
-	return sum / time.Duration(count)
-}
-
 // NewOptimizedStatsManager creates a new high-performance stats manager
 func NewOptimizedStatsManager() *OptimizedStatsManager {
 	now := time.Now()
 
 	var osm = &OptimizedStatsManager{
-		redisVersion:     "7.0.0",
-		os:               runtime.GOOS + " " + runtime.GOARCH,
-		commandsByType:   make(map[string]int64),
-		databaseKeys:     make(map[int]int64),
-		databaseExpires:  make(map[int]int64),
-		startTime:        now,
-		latencyByCommand: make(map[string]*ringBuffer),
-		lastCPUUpdate:    now,
+		redisVersion:    "7.0.0",
+		os:              runtime.GOOS + " " + runtime.GOARCH,
+		databaseKeys:    make(map[int]int64),
+		databaseExpires: make(map[int]int64),
+		startTime:       now,
+		lastCPUUpdate:   now,
 	}
 
 	return osm
@@ -205,6 +151,30 @@ func (s *OptimizedStatsManager) GetRejectedConnections() int64 {
 	return atomic.LoadInt64(&s.rejectedConnections)
 }
 
+func (s *OptimizedStatsManager) IncrementPubSubClients() {
+	atomic.AddInt64(&s.pubsubClients, 1)
+}
+
+func (s *OptimizedStatsManager) DecrementPubSubClients() {
+	atomic.AddInt64(&s.pubsubClients, -1)
+}
+
+func (s *OptimizedStatsManager) GetPubSubClients() int64 {
+	return atomic.LoadInt64(&s.pubsubClients)
+}
+
+func (s *OptimizedStatsManager) IncrementBlockedClients() {
+	atomic.AddInt64(&s.blockedClients, 1)
+}
+
+func (s *OptimizedStatsManager) DecrementBlockedClients() {
+	atomic.AddInt64(&s.blockedClients, -1)
+}
+
+func (s *OptimizedStatsManager) GetBlockedClients() int64 {
+	return atomic.LoadInt64(&s.blockedClients)
+}
+
 // Command tracking methods - OPTIMIZED
 func (s *OptimizedStatsManager) IncrementCommandsProcessed() {
 	atomic.AddInt64(&s.totalCommandsProcessed, 1)
@@ -215,31 +185,15 @@ func (s *OptimizedStatsManager) GetTotalCommandsProcessed() int64 {
 }
 
 func (s *OptimizedStatsManager) IncrementCommandByType(commandType string) {
-	// Use read lock first for common case (command already exists)
-	s.mu.RLock()
-	if _, exists := s.commandsByType[commandType]; exists {
-		s.mu.RUnlock()
-		s.mu.Lock()
-		s.commandsByType[commandType]++
-		s.mu.Unlock()
-		return
-	}
-	s.mu.RUnlock()
-
-	// Command doesn't exist, need write lock to create it
-	s.mu.Lock()
-	s.commandsByType[commandType]++
-	s.mu.Unlock()
+	s.commands.get(commandType).calls.Add(1)
 }
 
 func (s *OptimizedStatsManager) GetCommandsByType() map[string]int64 {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	result := make(map[string]int64, len(s.commandsByType))
-	for cmd, count := range s.commandsByType {
-		result[cmd] = count
-	}
+	result := make(map[string]int64)
+	s.commands.commands.Range(func(name, value any) bool {
+		result[name.(string)] = value.(*commandStats).calls.Load()
+		return true
+	})
 	return result
 }
 
@@ -336,44 +290,44 @@ func (s *OptimizedStatsManager) SetMemoryFragmentationRatio(ratio float64) {
 	s.memoryFragmentationRatio = ratio
 }
 
+// SetDatabaseStats records the key and expiring-key counts of database db;
+// empty databases are dropped from the keyspace stats
+func (s *OptimizedStatsManager) SetDatabaseStats(db int, keys, expires int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if keys == 0 {
+		delete(s.databaseKeys, db)
+		delete(s.databaseExpires, db)
+		return
+	}
+	s.databaseKeys[db] = keys
+	s.databaseExpires[db] = expires
+}
+
 func (s *OptimizedStatsManager) GetMemoryFragmentationRatio() float64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.memoryFragmentationRatio
 }
 
-// Latency tracking methods - OPTIMIZED with lock-free ring buffer
+// Latency tracking methods - one atomic add per sample into the command's
+// histogram
 func (s *OptimizedStatsManager) RecordCommandLatency(commandType string, latency time.Duration) {
-	// Try to get existing ring buffer with read lock
-	s.latencyMu.RLock()
-	rb, exists := s.latencyByCommand[commandType]
-	s.latencyMu.RUnlock()
-
-	if !exists {
-		// Create new ring buffer with write lock
-		s.latencyMu.Lock()
-		if rb, exists = s.latencyByCommand[commandType]; !exists {
-			rb = newRingBuffer(1000)
-			s.latencyByCommand[commandType] = rb
-		}
-		s.latencyMu.Unlock()
-	}
-
-	// Add to ring buffer (lock-free)
-	rb.add(latency)
+	s.commands.get(commandType).latency.record(latency)
 }
 
 // GetAverageLatency calculates the average latency for a command type
 func (s *OptimizedStatsManager) GetAverageLatency(commandType string) time.Duration {
-	s.latencyMu.RLock()
-	rb, exists := s.latencyByCommand[commandType]
-	s.latencyMu.RUnlock()
-
-	if !exists {
+	c, ok := s.commands.commands.Load(commandType)
+	if !ok {
 		return 0
 	}
-
-	return rb.getAverage()
+	h := &c.(*commandStats).latency
+	total, _ := h.counts()
+	if total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum.Load() / total)
 }
 
 // CPU tracking methods - OPTIMIZED with periodic updates
@@ -457,6 +411,22 @@ func (s *OptimizedStatsManager) GetUsedCPUUserMainThread() float64 {
 	return s.usedCPUUserMainThread
 }
 
+// ResetStats clears the counters CONFIG RESETSTAT resets: the command, key
+// space and network counters and the per-command statistics
+func (s *OptimizedStatsManager) ResetStats() {
+	atomic.StoreInt64(&s.totalConnectionsReceived, 0)
+	atomic.StoreInt64(&s.rejectedConnections, 0)
+	atomic.StoreInt64(&s.totalCommandsProcessed, 0)
+	atomic.StoreInt64(&s.expiredKeys, 0)
+	atomic.StoreInt64(&s.evictedKeys, 0)
+	atomic.StoreInt64(&s.keyspaceHits, 0)
+	atomic.StoreInt64(&s.keyspaceMisses, 0)
+	atomic.StoreInt64(&s.totalNetInputBytes, 0)
+	atomic.StoreInt64(&s.totalNetOutputBytes, 0)
+	atomic.StoreInt64(&s.peakMemory, atomic.LoadInt64(&s.usedMemory))
+	s.commands.reset()
+}
+
 // GetSnapshot returns a snapshot of all statistics - OPTIMIZED
 func (s *OptimizedStatsManager) GetSnapshot() StatsSnapshot {
 	// Get atomic values first (no locking needed)
@@ -465,6 +435,8 @@ func (s *OptimizedStatsManager) GetSnapshot() StatsSnapshot {
 		TotalConnectionsReceived: atomic.LoadInt64(&s.totalConnectionsReceived),
 		ActiveConnections:        atomic.LoadInt64(&s.activeConnections),
 		RejectedConnections:      atomic.LoadInt64(&s.rejectedConnections),
+		PubSubClients:            atomic.LoadInt64(&s.pubsubClients),
+		BlockedClients:           atomic.LoadInt64(&s.blockedClients),
 		TotalCommandsProcessed:   atomic.LoadInt64(&s.totalCommandsProcessed),
 		ExpiredKeys:              atomic.LoadInt64(&s.expiredKeys),
 		EvictedKeys:              atomic.LoadInt64(&s.evictedKeys),
@@ -488,11 +460,6 @@ func (s *OptimizedStatsManager) GetSnapshot() StatsSnapshot {
 	snapshot.MemoryFragmentationRatio = s.memoryFragmentationRatio
 
 	// Copy maps
-	snapshot.CommandsByType = make(map[string]int64, len(s.commandsByType))
-	for cmd, count := range s.commandsByType {
-		snapshot.CommandsByType[cmd] = count
-	}
-
 	snapshot.DatabaseKeys = make(map[int]int64, len(s.databaseKeys))
 	for db, count := range s.databaseKeys {
 		snapshot.DatabaseKeys[db] = count
@@ -506,6 +473,12 @@ func (s *OptimizedStatsManager) GetSnapshot() StatsSnapshot {
 	uptime := time.Since(s.startTime)
 	s.mu.RUnlock()
 
+	snapshot.CommandStats = s.GetCommandStats()
+	snapshot.CommandsByType = make(map[string]int64, len(snapshot.CommandStats))
+	for cmd, stat := range snapshot.CommandStats {
+		snapshot.CommandsByType[cmd] = stat.Calls
+	}
+
 	snapshot.Uptime = int64(uptime.Seconds())
 	snapshot.UptimeInDays = uptime.Hours() / 24.0
 
@@ -265,29 +265,30 @@ func BenchmarkAtomicInt64Store(b *testing.B) {
 	})
 }
 
-// Ring buffer benchmarks
-func BenchmarkRingBufferAdd(b *testing.B) {
-	rb := newRingBuffer(1000)
+// Latency histogram benchmarks
+func BenchmarkLatencyHistogramRecord(b *testing.B) {
+	var h latencyHistogram
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		i := 0
 		for pb.Next() {
-			rb.add(time.Duration(i) * time.Microsecond)
+			h.record(time.Duration(i) * time.Microsecond)
 			i++
 		}
 	})
 }
 
-func BenchmarkRingBufferGetAverage(b *testing.B) {
-	rb := newRingBuffer(1000)
+func BenchmarkLatencyHistogramPercentiles(b *testing.B) {
+	var h latencyHistogram
 	// Pre-populate
 	for i := 0; i < 1000; i++ {
-		rb.add(time.Duration(i) * time.Microsecond)
+		h.record(time.Duration(i) * time.Microsecond)
 	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		rb.getAverage()
+		total, counts := h.counts()
+		percentile(total, counts, 99)
 	}
 }
@@ -0,0 +1,111 @@
+package store
+
+import "sync"
+
+// Databases is the fixed set of numbered logical databases served by one
+// server. Every database keeps its identity for the lifetime of the set, so
+// anything bound to database i (handlers, listeners) stays valid across
+// SWAPDB.
+type Databases struct {
+	dbs []*UltraOptimizedDB
+
+	// swapMu serialises swaps, which lock the shards of two databases
+	swapMu sync.Mutex
+}
+
+// NewDatabases creates n empty databases. Only database 0 gets preallocated
+// shard maps; the others grow on demand since most deployments never select
+// them.
+func NewDatabases(n int) *Databases {
+	if n < 1 {
+		n = 1
+	}
+	d := &Databases{dbs: make([]*UltraOptimizedDB, n)}
+	d.dbs[0] = NewUltraOptimizedDB()
+	for i := 1; i < n; i++ {
+		d.dbs[i] = newUltraOptimizedDB(0)
+	}
+	return d
+}
+
+// Count returns the number of databases
+func (d *Databases) Count() int { return len(d.dbs) }
+
+// DB returns database index, which must be in [0, Count())
+func (d *Databases) DB(index int) *UltraOptimizedDB { return d.dbs[index] }
+
+// Stores returns every database in index order
+func (d *Databases) Stores() []DataStore {
+	out := make([]DataStore, len(d.dbs))
+	for i, db := range d.dbs {
+		out[i] = db
+	}
+	return out
+}
+
+// Swap exchanges the contents of databases a and b
+func (d *Databases) Swap(a, b int) {
+	d.swapMu.Lock()
+	defer d.swapMu.Unlock()
+	d.dbs[a].SwapWith(d.dbs[b])
+}
+
+// Move moves key from database from to database to. It reports false when
+// the key does not exist in the source or already exists in the destination.
+func (d *Databases) Move(key string, from, to int) bool {
+	src, dst := d.dbs[from], d.dbs[to]
+	if dst.Exists(key) {
+		return false
+	}
+	it, ok := src.take(key)
+	if !ok {
+		return false
+	}
+	if !dst.putIfAbsent(key, it) {
+		// The destination was written concurrently; give the key back
+		src.putIfAbsent(key, it)
+		return false
+	}
+	src.NotifyKeyEvent("move_from", key)
+	dst.NotifyKeyEvent("move_to", key)
+	return true
+}
+
+// Flush removes every key from database index
+func (d *Databases) Flush(index int) { d.dbs[index].Flush() }
+
+// FlushAll removes every key from every database
+func (d *Databases) FlushAll() {
+	for _, db := range d.dbs {
+		db.Flush()
+	}
+}
+
+// Empty reports whether no database holds keys. It reads the key counters,
+// so it never scans.
+func (d *Databases) Empty() bool {
+	for _, db := range d.dbs {
+		if db.keys.Load() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyspaceStats returns the key and expiring-key counts of every database
+// in index order
+func (d *Databases) KeyspaceStats() (keys, expires []int) {
+	keys = make([]int, len(d.dbs))
+	expires = make([]int, len(d.dbs))
+	for i, db := range d.dbs {
+		keys[i], expires[i] = db.KeyspaceStats()
+	}
+	return keys, expires
+}
+
+// Close stops the background work of every database
+func (d *Databases) Close() {
+	for _, db := range d.dbs {
+		db.Close()
+	}
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabases(t *testing.T) {
+	dbs := NewDatabases(4)
+	defer dbs.Close()
+	require.Equal(t, 4, dbs.Count())
+
+	dbs.DB(0).Set("a", "0", time.Time{})
+	dbs.DB(1).Set("b", "1", time.Now().Add(time.Hour))
+	_, err := dbs.DB(1).EnsureList("l")
+	require.NoError(t, err)
+
+	// Swap exchanges contents but databases keep their identity
+	first := dbs.DB(0)
+	dbs.Swap(0, 1)
+	assert.Same(t, first, dbs.DB(0))
+	assert.True(t, dbs.DB(0).Exists("b"))
+	assert.True(t, dbs.DB(1).Exists("a"))
+	keys, expires := dbs.KeyspaceStats()
+	assert.Equal(t, []int{2, 1, 0, 0}, keys)
+	assert.Equal(t, []int{1, 0, 0, 0}, expires)
+
+	var events []string
+	dbs.DB(2).OnKeyEvent(func(event, key string) { events = append(events, event+":"+key) })
+	require.True(t, dbs.Move("b", 0, 2))
+	assert.False(t, dbs.DB(0).Exists("b"))
+	assert.Greater(t, dbs.DB(2).TTL("b"), int64(0))
+	assert.Equal(t, []string{"move_to:b"}, events)
+
+	// Missing source and existing destination keys are not moved
+	assert.False(t, dbs.Move("b", 0, 2))
+	dbs.DB(1).Set("b", "other", time.Time{})
+	assert.False(t, dbs.Move("b", 1, 2))
+	v, _ := dbs.DB(1).Get("b")
+	assert.Equal(t, "other", v)
+
+	keys, _ = dbs.KeyspaceStats()
+	assert.Equal(t, []int{1, 2, 1, 0}, keys)
+	dbs.Flush(1)
+	assert.False(t, dbs.Empty())
+	dbs.FlushAll()
+	assert.True(t, dbs.Empty())
+}
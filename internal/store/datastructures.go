@@ -3,10 +3,12 @@ package store
 import (
 	"fmt"
 	"gridhouse/internal/logger"
+	"math/rand/v2"
 	"sort"
 	"strconv"
 	"sync"
 	"time"
+	"unsafe"
 )
 
 // DataType represents the type of data structure
@@ -94,6 +96,23 @@ func (l *OptimizedList) LPush(elements ...string) int {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	length := l.pushLeft(elements...)
+	logger.Debugf("LPUSH added %d elements, list length: %d", len(elements), length)
+	return length
+}
+
+// RPush adds elements to the right (tail) of the list - OPTIMIZED
+func (l *OptimizedList) RPush(elements ...string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	length := l.pushRight(elements...)
+	logger.Debugf("RPUSH added %d elements, list length: %d", len(elements), length)
+	return length
+}
+
+// pushLeft adds elements to the head; the caller holds the write lock
+func (l *OptimizedList) pushLeft(elements ...string) int {
 	// Check if we need to grow or shift
 	if l.head-len(elements) < 0 {
 		l.grow(len(elements))
@@ -104,17 +123,11 @@ func (l *OptimizedList) LPush(elements ...string) int {
 		l.head--
 		l.items[l.head] = elements[i]
 	}
-
-	length := l.tail - l.head
-	logger.Debugf("LPUSH added %d elements, list length: %d", len(elements), length)
-	return length
+	return l.tail - l.head
 }
 
-// RPush adds elements to the right (tail) of the list - OPTIMIZED
-func (l *OptimizedList) RPush(elements ...string) int {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
+// pushRight adds elements to the tail; the caller holds the write lock
+func (l *OptimizedList) pushRight(elements ...string) int {
 	// Check if we need to grow
 	if l.tail+len(elements) > l.cap {
 		l.grow(len(elements))
@@ -125,10 +138,7 @@ func (l *OptimizedList) RPush(elements ...string) int {
 		l.items[l.tail] = element
 		l.tail++
 	}
-
-	length := l.tail - l.head
-	logger.Debugf("RPUSH added %d elements, list length: %d", len(elements), length)
-	return length
+	return l.tail - l.head
 }
 
 // grow expands the list capacity and recenters elements
@@ -158,6 +168,31 @@ func (l *OptimizedList) LPop() (string, bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	element, ok := l.popLeft()
+	if !ok {
+		return "", false
+	}
+
+	logger.Debugf("LPOP returned '%s', list length: %d", element, l.tail-l.head)
+	return element, true
+}
+
+// RPop removes and returns the rightmost element - OPTIMIZED
+func (l *OptimizedList) RPop() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.popRight()
+	if !ok {
+		return "", false
+	}
+
+	logger.Debugf("RPOP returned '%s', list length: %d", element, l.tail-l.head)
+	return element, true
+}
+
+// popLeft removes the head element; the caller holds the write lock
+func (l *OptimizedList) popLeft() (string, bool) {
 	if l.head >= l.tail {
 		return "", false
 	}
@@ -168,16 +203,11 @@ func (l *OptimizedList) LPop() (string, bool) {
 
 	// Shrink if needed
 	l.maybeShrink()
-
-	logger.Debugf("LPOP returned '%s', list length: %d", element, l.tail-l.head)
 	return element, true
 }
 
-// RPop removes and returns the rightmost element - OPTIMIZED
-func (l *OptimizedList) RPop() (string, bool) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
+// popRight removes the tail element; the caller holds the write lock
+func (l *OptimizedList) popRight() (string, bool) {
 	if l.head >= l.tail {
 		return "", false
 	}
@@ -188,8 +218,6 @@ func (l *OptimizedList) RPop() (string, bool) {
 
 	// Shrink if needed
 	l.maybeShrink()
-
-	logger.Debugf("RPOP returned '%s', list length: %d", element, l.tail-l.head)
 	return element, true
 }
 
@@ -414,6 +442,102 @@ func (l *OptimizedList) LTrim(start, stop int) {
 	l.tail = len(trimmedItems)
 }
 
+// LInsert inserts element before or after the first occurrence of pivot,
+// returning the new length or -1 when pivot is not in the list
+func (l *OptimizedList) LInsert(before bool, pivot, element string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := l.head; i < l.tail; i++ {
+		if l.items[i] != pivot {
+			continue
+		}
+		at := i - l.head
+		if !before {
+			at++
+		}
+		// Make room at the tail, then shift everything after at by one
+		length := l.pushRight("")
+		items := l.items[l.head:l.tail]
+		copy(items[at+1:], items[at:])
+		items[at] = element
+		return length
+	}
+	return -1
+}
+
+// LPos returns the indexes of up to count occurrences of element, or of
+// all of them when count is 0. The first |rank|-1 occurrences are skipped
+// and a negative rank searches from the tail. A positive maxLen limits how
+// many elements are compared.
+func (l *OptimizedList) LPos(element string, rank, count, maxLen int) []int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	length := l.tail - l.head
+	if maxLen <= 0 || maxLen > length {
+		maxLen = length
+	}
+	skip, step, i := rank-1, 1, 0
+	if rank < 0 {
+		skip, step, i = -rank-1, -1, length-1
+	}
+
+	var matches []int
+	for n := 0; n < maxLen; n, i = n+1, i+step {
+		if l.items[l.head+i] != element {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		matches = append(matches, i)
+		if len(matches) == count {
+			break
+		}
+	}
+	return matches
+}
+
+// ListMove pops an element from one end of src and pushes it onto one end
+// of dst while holding both lists, so no reader ever sees the element in
+// neither or both. src and dst may be the same list, which rotates it.
+func ListMove(src, dst *List, fromLeft, toLeft bool) (string, bool) {
+	a, b := src.OptimizedList, dst.OptimizedList
+	if a == b {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+	} else {
+		// Lock in address order so that opposite moves cannot deadlock
+		first, second := a, b
+		if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+			first, second = second, first
+		}
+		first.mu.Lock()
+		defer first.mu.Unlock()
+		second.mu.Lock()
+		defer second.mu.Unlock()
+	}
+
+	var element string
+	var ok bool
+	if fromLeft {
+		element, ok = a.popLeft()
+	} else {
+		element, ok = a.popRight()
+	}
+	if !ok {
+		return "", false
+	}
+	if toLeft {
+		b.pushLeft(element)
+	} else {
+		b.pushRight(element)
+	}
+	return element, true
+}
+
 // OptimizedSet represents a high-performance Redis set
 type OptimizedSet struct {
 	mu    sync.RWMutex
@@ -507,6 +631,169 @@ func (s *OptimizedSet) SPop() (string, bool) {
 	return "", false
 }
 
+// SMIsMember reports for each element whether it is a member of the set
+func (s *OptimizedSet) SMIsMember(elements ...string) []bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make([]bool, len(elements))
+	for i, element := range elements {
+		_, found[i] = s.items[element]
+	}
+	return found
+}
+
+// SPopN removes and returns up to count random elements from the set
+func (s *OptimizedSet) SPopN(count int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if count > len(s.items) {
+		count = len(s.items)
+	}
+	popped := make([]string, 0, count)
+	for element := range s.items {
+		if len(popped) == count {
+			break
+		}
+		delete(s.items, element)
+		popped = append(popped, element)
+	}
+	return popped
+}
+
+// SRandMember returns random elements without removing them. A positive
+// count returns up to count distinct elements; a negative count returns
+// exactly -count elements that may repeat.
+func (s *OptimizedSet) SRandMember(count int) []string {
+	s.mu.RLock()
+	members := make([]string, 0, len(s.items))
+	for member := range s.items {
+		members = append(members, member)
+	}
+	s.mu.RUnlock()
+
+	if len(members) == 0 || count == 0 {
+		return []string{}
+	}
+	if count < 0 {
+		picked := make([]string, -count)
+		for i := range picked {
+			picked[i] = members[rand.IntN(len(members))]
+		}
+		return picked
+	}
+	if count >= len(members) {
+		return members
+	}
+	// Partial Fisher-Yates shuffle of the first count slots
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:count]
+}
+
+// filter returns the elements whose membership in the set equals keep
+func (s *OptimizedSet) filter(elements []string, keep bool) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kept := elements[:0]
+	for _, element := range elements {
+		if _, ok := s.items[element]; ok == keep {
+			kept = append(kept, element)
+		}
+	}
+	return kept
+}
+
+// SetMove moves member from src to dst while holding both sets, so no
+// reader ever sees it in neither or both. It reports whether src held it.
+func SetMove(src, dst *Set, member string) bool {
+	a, b := src.OptimizedSet, dst.OptimizedSet
+	if a == b {
+		return a.SIsMember(member)
+	}
+	// Lock in address order so that opposite moves cannot deadlock
+	first, second := a, b
+	if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+		first, second = second, first
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if _, ok := a.items[member]; !ok {
+		return false
+	}
+	delete(a.items, member)
+	b.items[member] = struct{}{}
+	return true
+}
+
+// SetUnion returns the members of any of sets. Nil sets count as empty.
+func SetUnion(sets ...*Set) []string {
+	seen := make(map[string]struct{})
+	var members []string
+	for _, set := range sets {
+		if set == nil {
+			continue
+		}
+		for _, member := range set.SMembers() {
+			if _, ok := seen[member]; !ok {
+				seen[member] = struct{}{}
+				members = append(members, member)
+			}
+		}
+	}
+	return members
+}
+
+// SetInter returns the members of all of sets, at most limit of them when
+// limit is positive. Nil sets count as empty.
+func SetInter(limit int, sets ...*Set) []string {
+	if len(sets) == 0 {
+		return nil
+	}
+	// Start from the smallest set to test as few members as possible
+	smallest := 0
+	for i, set := range sets {
+		if set == nil {
+			return nil
+		}
+		if set.SCard() < sets[smallest].SCard() {
+			smallest = i
+		}
+	}
+	members := sets[smallest].SMembers()
+	for i, set := range sets {
+		if i != smallest && len(members) > 0 {
+			members = set.filter(members, true)
+		}
+	}
+	if limit > 0 && len(members) > limit {
+		members = members[:limit]
+	}
+	return members
+}
+
+// SetDiff returns the members of first that are in none of others. Nil
+// sets count as empty.
+func SetDiff(first *Set, others ...*Set) []string {
+	if first == nil {
+		return nil
+	}
+	members := first.SMembers()
+	for _, set := range others {
+		if set != nil && len(members) > 0 {
+			members = set.filter(members, false)
+		}
+	}
+	return members
+}
+
 // OptimizedHash represents a high-performance Redis hash
 type OptimizedHash struct {
 	mu     sync.RWMutex
@@ -532,6 +819,18 @@ func (h *OptimizedHash) HSet(field, value string) bool {
 	return !exists // Return true if field was new
 }
 
+// HSetNX sets a field only if it does not exist yet and reports whether it did
+func (h *OptimizedHash) HSetNX(field, value string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.fields[field]; exists {
+		return false
+	}
+	h.fields[field] = value
+	return true
+}
+
 // HGet gets a field from the hash - OPTIMIZED
 func (h *OptimizedHash) HGet(field string) (string, bool) {
 	h.mu.RLock()
@@ -541,6 +840,52 @@ func (h *OptimizedHash) HGet(field string) (string, bool) {
 	return value, exists
 }
 
+// HMGet gets several fields at once; found[i] reports whether fields[i]
+// exists
+func (h *OptimizedHash) HMGet(fields ...string) (values []string, found []bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	values = make([]string, len(fields))
+	found = make([]bool, len(fields))
+	for i, field := range fields {
+		values[i], found[i] = h.fields[field]
+	}
+	return values, found
+}
+
+// HRandField returns random field-value pairs without removing them. A
+// positive count returns up to count distinct fields; a negative count
+// returns exactly -count fields that may repeat.
+func (h *OptimizedHash) HRandField(count int) [][2]string {
+	h.mu.RLock()
+	pairs := make([][2]string, 0, len(h.fields))
+	for field, value := range h.fields {
+		pairs = append(pairs, [2]string{field, value})
+	}
+	h.mu.RUnlock()
+
+	if len(pairs) == 0 || count == 0 {
+		return [][2]string{}
+	}
+	if count < 0 {
+		picked := make([][2]string, -count)
+		for i := range picked {
+			picked[i] = pairs[rand.IntN(len(pairs))]
+		}
+		return picked
+	}
+	if count >= len(pairs) {
+		return pairs
+	}
+	// Partial Fisher-Yates shuffle of the first count slots
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(pairs)-i)
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs[:count]
+}
+
 // HDel removes fields from the hash - OPTIMIZED
 func (h *OptimizedHash) HDel(fields ...string) int {
 	h.mu.Lock()
@@ -859,6 +1204,19 @@ type Stream struct {
 	entries []StreamEntry
 	lastMs  uint64
 	lastSeq uint64
+	groups  map[string]*consumerGroup
+
+	entriesAdded uint64   // entries added over the lifetime of the stream
+	maxDeleted   StreamID // greatest ID removed by XDEL
+}
+
+// StreamMeta describes a stream's bookkeeping beyond its entries
+type StreamMeta struct {
+	Length       int
+	FirstID      StreamID // 0-0 when empty
+	LastID       StreamID
+	MaxDeletedID StreamID
+	EntriesAdded uint64
 }
 
 func NewStream() *Stream {
@@ -886,16 +1244,103 @@ func (s *Stream) XAdd(provided *StreamID, fields map[string]string) (StreamID, e
 	} else {
 		// custom id
 		id = *provided
+		if id.Ms == 0 && id.Seq == 0 {
+			return StreamID{}, fmt.Errorf("ERR The ID specified in XADD must be greater than 0-0")
+		}
 		// must be greater than last
 		if id.Ms < s.lastMs || (id.Ms == s.lastMs && id.Seq <= s.lastSeq) {
 			return StreamID{}, fmt.Errorf("ERR The ID specified in XADD is equal or smaller than the target stream top item")
 		}
 	}
 
+	s.appendLocked(id, fields)
+	return id, nil
+}
+
+// XAddAutoSeq appends an entry with the given milliseconds part and the next
+// free sequence number for it (the "ms-*" form of XADD)
+func (s *Stream) XAddAutoSeq(ms uint64, fields map[string]string) (StreamID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id StreamID
+	switch {
+	case ms > s.lastMs:
+		id = StreamID{Ms: ms}
+	case ms == s.lastMs && s.lastSeq < ^uint64(0):
+		id = StreamID{Ms: ms, Seq: s.lastSeq + 1}
+	default:
+		return StreamID{}, fmt.Errorf("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	}
+	if id.Ms == 0 && id.Seq == 0 {
+		id.Seq = 1
+	}
+	s.appendLocked(id, fields)
+	return id, nil
+}
+
+func (s *Stream) appendLocked(id StreamID, fields map[string]string) {
 	s.entries = append(s.entries, StreamEntry{ID: id, Fields: fields})
 	s.lastMs = id.Ms
 	s.lastSeq = id.Seq
-	return id, nil
+	s.entriesAdded++
+}
+
+// Meta returns the stream's length and ID bookkeeping
+func (s *Stream) Meta() StreamMeta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := StreamMeta{
+		Length:       len(s.entries),
+		LastID:       StreamID{Ms: s.lastMs, Seq: s.lastSeq},
+		MaxDeletedID: s.maxDeleted,
+		EntriesAdded: s.entriesAdded,
+	}
+	if len(s.entries) > 0 {
+		meta.FirstID = s.entries[0].ID
+	}
+	return meta
+}
+
+// SetID moves the last generated ID of the stream forward (XSETID).
+// entriesAdded and maxDeleted are only changed when non-nil.
+func (s *Stream) SetID(last StreamID, entriesAdded *uint64, maxDeleted *StreamID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) > 0 && compareStreamID(last, s.entries[len(s.entries)-1].ID) < 0 {
+		return fmt.Errorf("ERR The ID specified in XSETID is smaller than the target stream top item")
+	}
+	if entriesAdded != nil && *entriesAdded < uint64(len(s.entries)) {
+		return fmt.Errorf("ERR The entries_added specified in XSETID is smaller than the target stream length")
+	}
+	if maxDeleted != nil && compareStreamID(last, *maxDeleted) < 0 {
+		return fmt.Errorf("ERR The ID specified in XSETID is smaller than the provided max_deleted_entry_id")
+	}
+	s.lastMs, s.lastSeq = last.Ms, last.Seq
+	if entriesAdded != nil {
+		s.entriesAdded = *entriesAdded
+	}
+	if maxDeleted != nil {
+		s.maxDeleted = *maxDeleted
+	}
+	return nil
+}
+
+// LastID returns the ID of the most recently added entry, or 0-0 for a
+// stream that never had one. Deleting entries does not move it back.
+func (s *Stream) LastID() StreamID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StreamID{Ms: s.lastMs, Seq: s.lastSeq}
+}
+
+// LastEntry returns the entry with the greatest ID, if any
+func (s *Stream) LastEntry() (StreamEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) == 0 {
+		return StreamEntry{}, false
+	}
+	return s.entries[len(s.entries)-1], true
 }
 
 // XLen returns number of entries in the stream
@@ -976,6 +1421,23 @@ func (s *Stream) XRange(start, end StreamID, count int) []StreamEntry {
 	return out
 }
 
+// XRevRange returns entries with start <= id <= end from the newest to the
+// oldest, optionally limited by count if >0
+func (s *Stream) XRevRange(end, start StreamID, count int) []StreamEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	startIdx := s.findFirstAtLeast(start)
+	endIdx := s.findLastAtMost(end)
+	out := []StreamEntry{}
+	for i := endIdx; i >= startIdx && i >= 0; i-- {
+		if count > 0 && len(out) == count {
+			break
+		}
+		out = append(out, s.entries[i])
+	}
+	return out
+}
+
 // XReadAfter returns up to count entries with ID > after
 func (s *Stream) XReadAfter(after StreamID, count int) []StreamEntry {
 	s.mu.Lock()
@@ -1010,6 +1472,9 @@ func (s *Stream) XDel(ids []StreamID) int {
 	for _, e := range s.entries {
 		if _, ok := target[e.ID.String()]; ok {
 			removed++
+			if compareStreamID(e.ID, s.maxDeleted) > 0 {
+				s.maxDeleted = e.ID
+			}
 			continue
 		}
 		newEntries = append(newEntries, e)
@@ -1020,13 +1485,36 @@ func (s *Stream) XDel(ids []StreamID) int {
 
 // XTrimMaxLen trims the stream to keep at most maxLen newest entries. Returns removed count.
 func (s *Stream) XTrimMaxLen(maxLen int) int {
+	return s.TrimMaxLen(maxLen, 0)
+}
+
+// TrimMaxLen trims the oldest entries so that at most maxLen remain, removing
+// no more than limit entries when limit > 0. Returns removed count.
+func (s *Stream) TrimMaxLen(maxLen, limit int) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	n := len(s.entries)
 	if maxLen < 0 || n <= maxLen {
 		return 0
 	}
-	toRemove := n - maxLen
+	return s.trimHeadLocked(n-maxLen, limit)
+}
+
+// TrimMinID removes entries with IDs lower than minID, no more than limit
+// entries when limit > 0. Returns removed count.
+func (s *Stream) TrimMinID(minID StreamID, limit int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trimHeadLocked(s.findFirstAtLeast(minID), limit)
+}
+
+func (s *Stream) trimHeadLocked(toRemove, limit int) int {
+	if limit > 0 && toRemove > limit {
+		toRemove = limit
+	}
+	if toRemove <= 0 {
+		return 0
+	}
 	// remove from the beginning (oldest)
 	s.entries = append([]StreamEntry(nil), s.entries[toRemove:]...)
 	return toRemove
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -236,6 +237,69 @@ func TestListLTrim(t *testing.T) {
 	assert.Equal(t, 0, list.LLen())
 }
 
+func TestListLInsert(t *testing.T) {
+	list := NewList()
+	list.RPush("a", "b")
+
+	assert.Equal(t, 3, list.LInsert(true, "a", "x"))
+	assert.Equal(t, 4, list.LInsert(false, "b", "y"))
+	assert.Equal(t, -1, list.LInsert(true, "missing", "z"))
+	assert.Equal(t, []string{"x", "a", "b", "y"}, list.LRange(0, -1))
+
+	// Inserting past the capacity grows the list
+	for i := 0; i < 20; i++ {
+		list.LInsert(false, "a", "n")
+	}
+	assert.Equal(t, 24, list.LLen())
+	assert.Equal(t, "y", list.LRange(-1, -1)[0])
+}
+
+func TestListLPos(t *testing.T) {
+	list := NewList()
+	list.RPush("a", "c", "b", "c", "c")
+
+	assert.Equal(t, []int{1}, list.LPos("c", 1, 1, 0))
+	assert.Equal(t, []int{1, 3, 4}, list.LPos("c", 1, 0, 0))
+	assert.Equal(t, []int{3, 4}, list.LPos("c", 2, 0, 0))
+	assert.Equal(t, []int{4, 3}, list.LPos("c", -1, 2, 0))
+	assert.Equal(t, []int{1}, list.LPos("c", 1, 0, 3))
+	assert.Empty(t, list.LPos("z", 1, 0, 0))
+}
+
+func TestListMove(t *testing.T) {
+	a, b := NewList(), NewList()
+	a.RPush("1", "2")
+
+	element, ok := ListMove(a, b, true, false)
+	require.True(t, ok)
+	assert.Equal(t, "1", element)
+	assert.Equal(t, []string{"2"}, a.LRange(0, -1))
+	assert.Equal(t, []string{"1"}, b.LRange(0, -1))
+
+	// Moves in opposite directions neither deadlock nor lose elements
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				ListMove(a, b, true, false)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				ListMove(b, a, false, true)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, a.LLen()+b.LLen())
+
+	_, ok = ListMove(NewList(), b, true, true)
+	assert.False(t, ok)
+}
+
 // Set Tests
 
 func TestNewSet(t *testing.T) {
@@ -348,6 +412,96 @@ func TestSetSPop(t *testing.T) {
 	assert.Equal(t, 1, set.SCard())
 }
 
+func TestSetSPopN(t *testing.T) {
+	set := NewSet()
+	set.SAdd("a", "b", "c")
+
+	popped := set.SPopN(2)
+	assert.Len(t, popped, 2)
+	assert.Equal(t, 1, set.SCard())
+	for _, member := range popped {
+		assert.False(t, set.SIsMember(member))
+	}
+
+	assert.Len(t, set.SPopN(5), 1)
+	assert.Empty(t, set.SPopN(1))
+}
+
+func TestSetSRandMember(t *testing.T) {
+	set := NewSet()
+	assert.Empty(t, set.SRandMember(3))
+	assert.Empty(t, set.SRandMember(-3))
+
+	set.SAdd("a", "b", "c")
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, set.SRandMember(10))
+
+	picked := set.SRandMember(2)
+	assert.Len(t, picked, 2)
+	assert.NotEqual(t, picked[0], picked[1])
+
+	// A negative count may repeat members and returns exactly that many
+	picked = set.SRandMember(-10)
+	assert.Len(t, picked, 10)
+	for _, member := range picked {
+		assert.Contains(t, []string{"a", "b", "c"}, member)
+	}
+	assert.Equal(t, 3, set.SCard())
+}
+
+func TestSetSMIsMember(t *testing.T) {
+	set := NewSet()
+	set.SAdd("a", "c")
+	assert.Equal(t, []bool{true, false, true}, set.SMIsMember("a", "b", "c"))
+}
+
+func TestSetMove(t *testing.T) {
+	a, b := NewSet(), NewSet()
+	a.SAdd("x", "y")
+
+	assert.True(t, SetMove(a, b, "x"))
+	assert.False(t, SetMove(a, b, "x"))
+	assert.ElementsMatch(t, []string{"y"}, a.SMembers())
+	assert.ElementsMatch(t, []string{"x"}, b.SMembers())
+	assert.True(t, SetMove(a, a, "y"))
+	assert.Equal(t, 1, a.SCard())
+
+	// Moves in opposite directions neither deadlock nor lose members
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				SetMove(a, b, "x")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				SetMove(b, a, "x")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, a.SCard()+b.SCard())
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a, b, c := NewSet(), NewSet(), NewSet()
+	a.SAdd("a", "b", "c", "d")
+	b.SAdd("c")
+	c.SAdd("a", "c", "e")
+
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, SetUnion(a, nil, b, c))
+	assert.ElementsMatch(t, []string{"c"}, SetInter(0, a, b, c))
+	assert.ElementsMatch(t, []string{"a", "c"}, SetInter(0, a, c))
+	assert.Len(t, SetInter(1, a, c), 1)
+	assert.Empty(t, SetInter(0, a, nil))
+	assert.ElementsMatch(t, []string{"b", "d"}, SetDiff(a, b, c))
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, SetDiff(a, nil))
+	assert.Empty(t, SetDiff(nil, a))
+}
+
 // Hash Tests
 
 func TestNewHash(t *testing.T) {
@@ -540,6 +694,49 @@ func TestHashHIncrByFloat(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestHashHSetNX(t *testing.T) {
+	hash := NewHash()
+
+	assert.True(t, hash.HSetNX("field", "a"))
+	assert.False(t, hash.HSetNX("field", "b"))
+	value, _ := hash.HGet("field")
+	assert.Equal(t, "a", value)
+}
+
+func TestHashHMGet(t *testing.T) {
+	hash := NewHash()
+	hash.HSet("a", "1")
+	hash.HSet("c", "")
+
+	values, found := hash.HMGet("a", "b", "c")
+	assert.Equal(t, []string{"1", "", ""}, values)
+	assert.Equal(t, []bool{true, false, true}, found)
+}
+
+func TestHashHRandField(t *testing.T) {
+	hash := NewHash()
+	assert.Empty(t, hash.HRandField(2))
+	assert.Empty(t, hash.HRandField(-2))
+
+	hash.HSet("a", "1")
+	hash.HSet("b", "2")
+	hash.HSet("c", "3")
+	assert.ElementsMatch(t, [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}}, hash.HRandField(5))
+
+	picked := hash.HRandField(2)
+	assert.Len(t, picked, 2)
+	assert.NotEqual(t, picked[0][0], picked[1][0])
+
+	// A negative count may repeat fields and returns exactly that many
+	picked = hash.HRandField(-7)
+	assert.Len(t, picked, 7)
+	for _, pair := range picked {
+		value, ok := hash.HGet(pair[0])
+		assert.True(t, ok)
+		assert.Equal(t, value, pair[1])
+	}
+}
+
 // Integration Tests
 
 func TestDataStructuresIntegration(t *testing.T) {
@@ -1,20 +1,29 @@
 package store
 
 import (
+	"errors"
 	"hash/maphash"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+	"weak"
 )
 
 const (
 	shardCount = 256 // Increased from 256 to 4096 for massive pipelines
 	shardMask  = shardCount - 1
 
+	shardCapacity = 1024
+
 	cleanupTick     = 30 * time.Second
 	cleanupCheckMax = 8192
 )
 
+// ErrWrongType is returned by the typed accessors when a key exists but holds
+// a value of a different kind than the one requested.
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
 // UltraOptimizedItem represents a stored value with minimal overhead
 type UltraOptimizedItem struct {
 	Value      string
@@ -25,31 +34,64 @@ type UltraOptimizedItem struct {
 	Hash       *Hash
 	ZSet       *SortedSet
 	Stream     *Stream
+
+	// Access is the clock second the key was last accessed and Freq its
+	// logarithmic LFU counter; eviction ranks keys by them
+	Access uint32
+	Freq   uint8
+
+	size int64 // Memory accounted to the key
 }
 
 // UltraOptimizedDB is the fastest possible database implementation
 type shard struct {
-	_  [64]byte
-	mu sync.RWMutex
-	m  map[string]UltraOptimizedItem
-	_  [64]byte
+	_    [64]byte
+	mu   sync.RWMutex
+	m    map[string]UltraOptimizedItem
+	used int64 // Memory of the keys in m
+	// Keys in m and how many of them have an expiration, including expired
+	// keys that were not removed yet
+	keys, expires int64
+	_             [64]byte
 }
 
 type UltraOptimizedDB struct {
 	shards [shardCount]*shard
 	seed   maphash.Seed
 	stop   chan struct{}
+	used   atomic.Int64 // Memory of the keys in every shard
+
+	// Sums of the shard key counters, read by INFO and the metrics
+	keys, expires atomic.Int64
+
+	// Key event listeners, copied on write so notification is lock-free
+	listenersMu sync.Mutex
+	listeners   atomic.Pointer[[]KeyEventFunc]
 }
 
+// shardSeed is shared by every database so that a key always maps to the
+// same shard index, which lets SwapWith exchange databases shard by shard
+var shardSeed = maphash.MakeSeed()
+
+// KeyEventFunc receives the name of a modifying operation (e.g. "lpush")
+// and the key it touched
+type KeyEventFunc func(event, key string)
+
 func NewUltraOptimizedDB() *UltraOptimizedDB {
+	return newUltraOptimizedDB(shardCapacity)
+}
+
+// newUltraOptimizedDB creates a database whose shard maps are preallocated
+// for capacity keys each
+func newUltraOptimizedDB(capacity int) *UltraOptimizedDB {
 	db := &UltraOptimizedDB{
-		seed: maphash.MakeSeed(),
+		seed: shardSeed,
 		stop: make(chan struct{}),
 	}
 	for i := 0; i < shardCount; i++ {
-		db.shards[i] = &shard{m: make(map[string]UltraOptimizedItem, 1024)}
+		db.shards[i] = &shard{m: make(map[string]UltraOptimizedItem, capacity)}
 	}
-	go db.cleanupExpired()
+	go cleanupExpired(weak.Make(db), db.stop)
 	return db
 }
 
@@ -74,7 +116,7 @@ func (db *UltraOptimizedDB) Set(key, value string, expiration time.Time) {
 
 	// Minimize lock scope - prepare item before lock
 	s.mu.Lock()
-	s.m[key] = it
+	db.put(s, key, it)
 	s.mu.Unlock()
 }
 
@@ -94,29 +136,21 @@ func (db *UltraOptimizedDB) Get(key string) (string, bool) {
 		now := time.Now().UnixNano()
 		if now > it.Expiration {
 			s.mu.RUnlock()
-			// Need write lock to delete - upgrade to write lock
-			s.mu.Lock()
-			// Double-check in case another goroutine already deleted
-			if it2, exists := s.m[key]; exists && it2.Expiration != 0 && now > it2.Expiration {
-				delete(s.m, key)
-			}
-			s.mu.Unlock()
+			db.expire(s, key, now)
 			return "", false
 		}
 	}
 
 	value := it.Value
 	s.mu.RUnlock()
+	db.touch(s, key, it)
 	return value, true
 }
 
 func (db *UltraOptimizedDB) Del(key string) bool {
 	s := db.shardFor(key)
 	s.mu.Lock()
-	_, ok := s.m[key]
-	if ok {
-		delete(s.m, key)
-	}
+	_, ok := db.remove(s, key)
 	s.mu.Unlock()
 	return ok
 }
@@ -129,12 +163,11 @@ func (db *UltraOptimizedDB) Exists(key string) bool {
 	if !ok {
 		return false
 	}
-	if it.Expiration != 0 && time.Now().UnixNano() > it.Expiration {
-		s.mu.Lock()
-		delete(s.m, key)
-		s.mu.Unlock()
+	if now := time.Now().UnixNano(); it.Expiration != 0 && now > it.Expiration {
+		db.expire(s, key, now)
 		return false
 	}
+	db.touch(s, key, it)
 	return true
 }
 
@@ -151,9 +184,7 @@ func (db *UltraOptimizedDB) TTL(key string) int64 {
 	}
 	now := time.Now().UnixNano()
 	if now > it.Expiration {
-		s.mu.Lock()
-		delete(s.m, key)
-		s.mu.Unlock()
+		db.expire(s, key, now)
 		return -2
 	}
 	return (it.Expiration - now) / int64(time.Second)
@@ -172,9 +203,7 @@ func (db *UltraOptimizedDB) PTTL(key string) int64 {
 	}
 	now := time.Now().UnixNano()
 	if now > it.Expiration {
-		s.mu.Lock()
-		delete(s.m, key)
-		s.mu.Unlock()
+		db.expire(s, key, now)
 		return -2
 	}
 	return (it.Expiration - now) / int64(time.Millisecond)
@@ -186,17 +215,126 @@ func (db *UltraOptimizedDB) Expire(key string, d time.Duration) bool {
 	it, ok := s.m[key]
 	if ok {
 		if it.Expiration != 0 && time.Now().UnixNano() > it.Expiration {
-			delete(s.m, key)
+			db.remove(s, key)
 			s.mu.Unlock()
+			db.NotifyKeyEvent("expired", key)
 			return false
 		}
 		it.Expiration = time.Now().Add(d).UnixNano()
-		s.m[key] = it
+		db.put(s, key, it)
 	}
 	s.mu.Unlock()
 	return ok
 }
 
+// ExpireFlags restrict ExpireAt like the NX, XX, GT and LT options of EXPIRE
+type ExpireFlags uint8
+
+const (
+	ExpireNX ExpireFlags = 1 << iota // Only keys without an expiration
+	ExpireXX                         // Only keys with an expiration
+	ExpireGT                         // Only a later expiration
+	ExpireLT                         // Only an earlier expiration
+)
+
+// Allows reports whether a key expiring at current, in Unix nanoseconds or
+// 0 for none, may be set to expire at at. Keys without an expiration count
+// as expiring never.
+func (f ExpireFlags) Allows(current, at int64) bool {
+	switch {
+	case f&ExpireNX != 0 && current != 0,
+		f&ExpireXX != 0 && current == 0,
+		f&ExpireGT != 0 && (current == 0 || at <= current),
+		f&ExpireLT != 0 && current != 0 && at >= current:
+		return false
+	}
+	return true
+}
+
+// ExpireAt makes key of any type expire at the absolute time at when flags
+// allow it, and reports whether it did. A time that is not in the future
+// deletes the key at once.
+func (db *UltraOptimizedDB) ExpireAt(key string, at time.Time, flags ExpireFlags) bool {
+	s := db.shardFor(key)
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	if !ok || (it.Expiration != 0 && now > it.Expiration) {
+		return false
+	}
+	when := at.UnixNano()
+	if !flags.Allows(it.Expiration, when) {
+		return false
+	}
+	if when <= now {
+		db.remove(s, key)
+		return true
+	}
+	it.Expiration = when
+	db.put(s, key, it)
+	return true
+}
+
+// Persist removes the expiration of key and reports whether it had one
+func (db *UltraOptimizedDB) Persist(key string) bool {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	if !ok || it.Expiration == 0 || time.Now().UnixNano() > it.Expiration {
+		return false
+	}
+	it.Expiration = 0
+	db.put(s, key, it)
+	return true
+}
+
+// ExpireTime returns the Unix time in seconds at which key expires, -1 if
+// it has no expiration and -2 if it does not exist
+func (db *UltraOptimizedDB) ExpireTime(key string) int64 {
+	ms := db.PExpireTime(key)
+	if ms < 0 {
+		return ms
+	}
+	return ms / 1000
+}
+
+// PExpireTime is ExpireTime in milliseconds
+func (db *UltraOptimizedDB) PExpireTime(key string) int64 {
+	s := db.shardFor(key)
+	s.mu.RLock()
+	it, ok := s.m[key]
+	s.mu.RUnlock()
+	if !ok {
+		return -2
+	}
+	if it.Expiration == 0 {
+		return -1
+	}
+	if now := time.Now().UnixNano(); now > it.Expiration {
+		db.expire(s, key, now)
+		return -2
+	}
+	return it.Expiration / int64(time.Millisecond)
+}
+
+// expire deletes key if it is still expired at now and reports the
+// "expired" key event. The caller must not hold the shard lock.
+func (db *UltraOptimizedDB) expire(s *shard, key string, now int64) {
+	s.mu.Lock()
+	// Double-check in case another goroutine already deleted or rewrote it
+	it, ok := s.m[key]
+	expired := ok && it.Expiration != 0 && now > it.Expiration
+	if expired {
+		db.remove(s, key)
+	}
+	s.mu.Unlock()
+	if expired {
+		db.NotifyKeyEvent("expired", key)
+	}
+}
+
 func (db *UltraOptimizedDB) Keys() []string {
 	res := make([]string, 0, 4096)
 	now := time.Now().UnixNano()
@@ -212,6 +350,94 @@ func (db *UltraOptimizedDB) Keys() []string {
 	return res
 }
 
+// Flush removes every key
+func (db *UltraOptimizedDB) Flush() {
+	for _, s := range db.shards {
+		s.mu.Lock()
+		s.m = make(map[string]UltraOptimizedItem)
+		db.used.Add(-s.used)
+		db.keys.Add(-s.keys)
+		db.expires.Add(-s.expires)
+		s.used, s.keys, s.expires = 0, 0, 0
+		s.mu.Unlock()
+	}
+}
+
+// KeyspaceStats returns the number of keys and how many of them have an
+// expiration set. Like Redis, it counts expired keys that were not removed
+// yet; the counters are kept up to date by every write, so it never scans.
+func (db *UltraOptimizedDB) KeyspaceStats() (keys, expires int) {
+	return int(db.keys.Load()), int(db.expires.Load())
+}
+
+// SwapWith exchanges the contents of db and other. Both databases keep
+// their identity and listeners; only the keys move. Every shard of both is
+// locked first, so that no command sees the databases half swapped. Swaps
+// must be serialised by the caller, as Databases.Swap does, since they lock
+// the two databases one after the other.
+func (db *UltraOptimizedDB) SwapWith(other *UltraOptimizedDB) {
+	if db == other {
+		return
+	}
+	for _, s := range db.shards {
+		s.mu.Lock()
+	}
+	for _, s := range other.shards {
+		s.mu.Lock()
+	}
+	for i := 0; i < shardCount; i++ {
+		a, b := db.shards[i], other.shards[i]
+		a.m, b.m = b.m, a.m
+		a.used, b.used = b.used, a.used
+		a.keys, b.keys = b.keys, a.keys
+		a.expires, b.expires = b.expires, a.expires
+	}
+	// The totals only change under shard locks, so they are stable here
+	swapTotal(&db.used, &other.used)
+	swapTotal(&db.keys, &other.keys)
+	swapTotal(&db.expires, &other.expires)
+	for _, s := range other.shards {
+		s.mu.Unlock()
+	}
+	for _, s := range db.shards {
+		s.mu.Unlock()
+	}
+}
+
+// swapTotal exchanges two database totals
+func swapTotal(a, b *atomic.Int64) {
+	v := a.Load()
+	a.Store(b.Load())
+	b.Store(v)
+}
+
+// take removes key and returns its item, ignoring expired keys
+func (db *UltraOptimizedDB) take(key string) (UltraOptimizedItem, bool) {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := db.remove(s, key)
+	if !ok {
+		return it, false
+	}
+	if it.Expiration != 0 && time.Now().UnixNano() > it.Expiration {
+		return it, false
+	}
+	return it, true
+}
+
+// putIfAbsent stores it under key unless a live key already exists
+func (db *UltraOptimizedDB) putIfAbsent(key string, it UltraOptimizedItem) bool {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.m[key]; ok && (cur.Expiration == 0 || time.Now().UnixNano() <= cur.Expiration) {
+		return false
+	}
+	db.put(s, key, it)
+	return true
+}
+
 // Batch operations
 
 type kv struct{ k, v string }
@@ -229,7 +455,7 @@ func (db *UltraOptimizedDB) MSet(pairs [][2]string) {
 		s := db.shards[i]
 		s.mu.Lock()
 		for _, r := range buckets[i] {
-			s.m[r.k] = UltraOptimizedItem{Value: r.v, DataType: TypeString}
+			db.put(s, r.k, UltraOptimizedItem{Value: r.v, DataType: TypeString})
 		}
 		s.mu.Unlock()
 	}
@@ -252,7 +478,7 @@ func (db *UltraOptimizedDB) MSetParallel(pairs [][2]string) {
 				s := db.shards[i]
 				s.mu.Lock()
 				for _, r := range buckets[i] {
-					s.m[r.k] = UltraOptimizedItem{Value: r.v, DataType: TypeString}
+					db.put(s, r.k, UltraOptimizedItem{Value: r.v, DataType: TypeString})
 				}
 				s.mu.Unlock()
 			}
@@ -269,13 +495,16 @@ func (db *UltraOptimizedDB) MSetParallel(pairs [][2]string) {
 
 // Data structure helpers
 
+// GetOrCreateList returns the list stored at key, replacing any value of
+// another type. It is meant for loaders that rebuild the keyspace; command
+// handlers should use EnsureList instead.
 func (db *UltraOptimizedDB) GetOrCreateList(key string) *List {
 	s := db.shardFor(key)
 	s.mu.Lock()
 	it, ok := s.m[key]
 	if !ok || it.DataType != TypeList || it.List == nil {
 		lst := NewList()
-		s.m[key] = UltraOptimizedItem{DataType: TypeList, List: lst}
+		db.put(s, key, UltraOptimizedItem{DataType: TypeList, List: lst})
 		s.mu.Unlock()
 		return lst
 	}
@@ -283,13 +512,15 @@ func (db *UltraOptimizedDB) GetOrCreateList(key string) *List {
 	return it.List
 }
 
+// GetOrCreateSet returns the set stored at key, replacing any value of
+// another type.
 func (db *UltraOptimizedDB) GetOrCreateSet(key string) *Set {
 	s := db.shardFor(key)
 	s.mu.Lock()
 	it, ok := s.m[key]
 	if !ok || it.DataType != TypeSet || it.Set == nil {
 		st := NewSet()
-		s.m[key] = UltraOptimizedItem{DataType: TypeSet, Set: st}
+		db.put(s, key, UltraOptimizedItem{DataType: TypeSet, Set: st})
 		s.mu.Unlock()
 		return st
 	}
@@ -297,13 +528,15 @@ func (db *UltraOptimizedDB) GetOrCreateSet(key string) *Set {
 	return it.Set
 }
 
+// GetOrCreateHash returns the hash stored at key, replacing any value of
+// another type.
 func (db *UltraOptimizedDB) GetOrCreateHash(key string) *Hash {
 	s := db.shardFor(key)
 	s.mu.Lock()
 	it, ok := s.m[key]
 	if !ok || it.DataType != TypeHash || it.Hash == nil {
 		h := NewHash()
-		s.m[key] = UltraOptimizedItem{DataType: TypeHash, Hash: h}
+		db.put(s, key, UltraOptimizedItem{DataType: TypeHash, Hash: h})
 		s.mu.Unlock()
 		return h
 	}
@@ -311,13 +544,15 @@ func (db *UltraOptimizedDB) GetOrCreateHash(key string) *Hash {
 	return it.Hash
 }
 
+// GetOrCreateSortedSet returns the sorted set stored at key, replacing any
+// value of another type.
 func (db *UltraOptimizedDB) GetOrCreateSortedSet(key string) *SortedSet {
 	s := db.shardFor(key)
 	s.mu.Lock()
 	it, ok := s.m[key]
 	if !ok || it.DataType != TypeSortedSet || it.ZSet == nil {
 		z := NewSortedSet()
-		s.m[key] = UltraOptimizedItem{DataType: TypeSortedSet, ZSet: z}
+		db.put(s, key, UltraOptimizedItem{DataType: TypeSortedSet, ZSet: z})
 		s.mu.Unlock()
 		return z
 	}
@@ -336,13 +571,15 @@ func (db *UltraOptimizedDB) GetDataType(key string) DataType {
 	return it.DataType
 }
 
+// GetOrCreateStream returns the stream stored at key, replacing any value of
+// another type.
 func (db *UltraOptimizedDB) GetOrCreateStream(key string) *Stream {
 	s := db.shardFor(key)
 	s.mu.Lock()
 	it, ok := s.m[key]
 	if !ok || it.DataType != TypeStream || it.Stream == nil {
 		st := NewStream()
-		s.m[key] = UltraOptimizedItem{DataType: TypeStream, Stream: st}
+		db.put(s, key, UltraOptimizedItem{DataType: TypeStream, Stream: st})
 		s.mu.Unlock()
 		return st
 	}
@@ -350,33 +587,275 @@ func (db *UltraOptimizedDB) GetOrCreateStream(key string) *Stream {
 	return it.Stream
 }
 
+// Type-checked data structure access
+
+// lookup returns the live item stored at key without creating it.
+// Expired items are reported as missing.
+func (db *UltraOptimizedDB) lookup(key string) (UltraOptimizedItem, bool) {
+	s := db.shardFor(key)
+	s.mu.RLock()
+	it, ok := s.m[key]
+	s.mu.RUnlock()
+	if !ok {
+		return UltraOptimizedItem{}, false
+	}
+	if now := time.Now().UnixNano(); it.Expiration != 0 && now > it.Expiration {
+		db.expire(s, key, now)
+		return UltraOptimizedItem{}, false
+	}
+	db.touch(s, key, it)
+	return it, true
+}
+
+// ensure returns the item stored at key if it has type t, creates it with
+// newItem if the key is missing or expired, and fails with ErrWrongType otherwise.
+func (db *UltraOptimizedDB) ensure(key string, t DataType, newItem func() UltraOptimizedItem) (UltraOptimizedItem, error) {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	it, ok := s.m[key]
+	if ok && (it.Expiration == 0 || time.Now().UnixNano() <= it.Expiration) {
+		s.mu.Unlock()
+		if it.DataType != t {
+			return UltraOptimizedItem{}, ErrWrongType
+		}
+		db.touch(s, key, it)
+		return it, nil
+	}
+	it = newItem()
+	db.put(s, key, it)
+	s.mu.Unlock()
+	if ok {
+		// The key was replaced because it had expired
+		db.NotifyKeyEvent("expired", key)
+	}
+	return it, nil
+}
+
+// DeleteIfEmpty deletes key when it holds a list, set, hash or sorted set
+// that a command left without elements, since Redis never keeps empty
+// collections around. It reports whether key was deleted.
+func (db *UltraOptimizedDB) DeleteIfEmpty(key string) bool {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	if !ok || !it.empty() {
+		return false
+	}
+	db.remove(s, key)
+	return true
+}
+
+// empty reports whether it is a collection without elements. Streams are
+// kept when empty, like in Redis.
+func (it *UltraOptimizedItem) empty() bool {
+	switch it.DataType {
+	case TypeList:
+		return it.List != nil && it.List.LLen() == 0
+	case TypeSet:
+		return it.Set != nil && it.Set.SCard() == 0
+	case TypeHash:
+		return it.Hash != nil && it.Hash.HLen() == 0
+	case TypeSortedSet:
+		return it.ZSet != nil && it.ZSet.ZCard() == 0
+	}
+	return false
+}
+
+// GetList returns the list stored at key, or nil if the key does not exist.
+func (db *UltraOptimizedDB) GetList(key string) (*List, error) {
+	it, ok := db.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if it.DataType != TypeList {
+		return nil, ErrWrongType
+	}
+	return it.List, nil
+}
+
+// EnsureList returns the list stored at key, creating an empty one if needed.
+func (db *UltraOptimizedDB) EnsureList(key string) (*List, error) {
+	it, err := db.ensure(key, TypeList, func() UltraOptimizedItem {
+		return UltraOptimizedItem{DataType: TypeList, List: NewList()}
+	})
+	return it.List, err
+}
+
+// GetSet returns the set stored at key, or nil if the key does not exist.
+func (db *UltraOptimizedDB) GetSet(key string) (*Set, error) {
+	it, ok := db.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if it.DataType != TypeSet {
+		return nil, ErrWrongType
+	}
+	return it.Set, nil
+}
+
+// EnsureSet returns the set stored at key, creating an empty one if needed.
+func (db *UltraOptimizedDB) EnsureSet(key string) (*Set, error) {
+	it, err := db.ensure(key, TypeSet, func() UltraOptimizedItem {
+		return UltraOptimizedItem{DataType: TypeSet, Set: NewSet()}
+	})
+	return it.Set, err
+}
+
+// StoreSet replaces whatever key holds with a set of members, dropping its
+// expiration, or deletes key when members is empty. It reports whether key
+// held a value before.
+func (db *UltraOptimizedDB) StoreSet(key string, members []string) bool {
+	st := NewSet()
+	st.SAdd(members...)
+
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	existed := live(it, ok, time.Now().UnixNano())
+	if len(members) == 0 {
+		if ok {
+			db.remove(s, key)
+		}
+		return existed
+	}
+	db.put(s, key, UltraOptimizedItem{DataType: TypeSet, Set: st})
+	return existed
+}
+
+// GetHash returns the hash stored at key, or nil if the key does not exist.
+func (db *UltraOptimizedDB) GetHash(key string) (*Hash, error) {
+	it, ok := db.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if it.DataType != TypeHash {
+		return nil, ErrWrongType
+	}
+	return it.Hash, nil
+}
+
+// EnsureHash returns the hash stored at key, creating an empty one if needed.
+func (db *UltraOptimizedDB) EnsureHash(key string) (*Hash, error) {
+	it, err := db.ensure(key, TypeHash, func() UltraOptimizedItem {
+		return UltraOptimizedItem{DataType: TypeHash, Hash: NewHash()}
+	})
+	return it.Hash, err
+}
+
+// GetSortedSet returns the sorted set stored at key, or nil if the key does not exist.
+func (db *UltraOptimizedDB) GetSortedSet(key string) (*SortedSet, error) {
+	it, ok := db.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if it.DataType != TypeSortedSet {
+		return nil, ErrWrongType
+	}
+	return it.ZSet, nil
+}
+
+// EnsureSortedSet returns the sorted set stored at key, creating an empty one if needed.
+func (db *UltraOptimizedDB) EnsureSortedSet(key string) (*SortedSet, error) {
+	it, err := db.ensure(key, TypeSortedSet, func() UltraOptimizedItem {
+		return UltraOptimizedItem{DataType: TypeSortedSet, ZSet: NewSortedSet()}
+	})
+	return it.ZSet, err
+}
+
+// GetStream returns the stream stored at key, or nil if the key does not exist.
+func (db *UltraOptimizedDB) GetStream(key string) (*Stream, error) {
+	it, ok := db.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if it.DataType != TypeStream {
+		return nil, ErrWrongType
+	}
+	return it.Stream, nil
+}
+
+// EnsureStream returns the stream stored at key, creating an empty one if needed.
+func (db *UltraOptimizedDB) EnsureStream(key string) (*Stream, error) {
+	it, err := db.ensure(key, TypeStream, func() UltraOptimizedItem {
+		return UltraOptimizedItem{DataType: TypeStream, Stream: NewStream()}
+	})
+	return it.Stream, err
+}
+
 // Cleanup
 
-func (db *UltraOptimizedDB) cleanupExpired() {
+// cleanupExpired removes expired keys every cleanupTick until the database is
+// closed. It only holds the database weakly between ticks, so that one that is
+// dropped without Close can still be collected.
+func cleanupExpired(ref weak.Pointer[UltraOptimizedDB], stop <-chan struct{}) {
 	t := time.NewTicker(cleanupTick)
 	defer t.Stop()
 	for {
 		select {
-		case <-db.stop:
+		case <-stop:
 			return
 		case <-t.C:
-			now := time.Now().UnixNano()
-			for _, s := range db.shards {
-				s.mu.Lock()
-				checked := 0
-				for k, it := range s.m {
-					if it.Expiration != 0 && now > it.Expiration {
-						delete(s.m, k)
-					}
-					checked++
-					if checked >= cleanupCheckMax {
-						break
-					}
-				}
-				s.mu.Unlock()
+			db := ref.Value()
+			if db == nil {
+				return
+			}
+			db.removeExpired()
+		}
+	}
+}
+
+// removeExpired removes up to cleanupCheckMax expired keys from every shard
+func (db *UltraOptimizedDB) removeExpired() {
+	now := time.Now().UnixNano()
+	var expired []string
+	for _, s := range db.shards {
+		s.mu.Lock()
+		checked := 0
+		for k, it := range s.m {
+			if it.Expiration != 0 && now > it.Expiration {
+				db.remove(s, k)
+				expired = append(expired, k)
+			}
+			checked++
+			if checked >= cleanupCheckMax {
+				break
 			}
 		}
+		s.mu.Unlock()
+		// Listeners run outside the shard lock
+		for _, k := range expired {
+			db.NotifyKeyEvent("expired", k)
+		}
+		expired = expired[:0]
 	}
 }
 
 func (db *UltraOptimizedDB) Close() { close(db.stop) }
+
+// OnKeyEvent registers a listener for key events
+func (db *UltraOptimizedDB) OnKeyEvent(fn KeyEventFunc) {
+	db.listenersMu.Lock()
+	defer db.listenersMu.Unlock()
+	var next []KeyEventFunc
+	if cur := db.listeners.Load(); cur != nil {
+		next = append(next, *cur...)
+	}
+	next = append(next, fn)
+	db.listeners.Store(&next)
+}
+
+// NotifyKeyEvent reports that event modified key to all registered listeners.
+// Commands modify collections in place, so the key's memory is measured
+// again first.
+func (db *UltraOptimizedDB) NotifyKeyEvent(event, key string) {
+	db.remeasure(key)
+	cur := db.listeners.Load()
+	if cur == nil {
+		return
+	}
+	for _, fn := range *cur {
+		fn(event, key)
+	}
+}
@@ -102,6 +102,37 @@ func TestDBExpire(t *testing.T) {
 	require.False(t, db.Expire("nonexistent", time.Second))
 }
 
+func TestDBExpireAt(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	_, err := db.EnsureHash("hash")
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), db.PExpireTime("hash"))
+	require.Equal(t, int64(-2), db.ExpireTime("missing"))
+
+	at := time.Now().Add(time.Hour)
+	require.False(t, db.ExpireAt("hash", at, ExpireXX))
+	require.False(t, db.ExpireAt("hash", at, ExpireGT))
+	require.True(t, db.ExpireAt("hash", at, ExpireNX))
+	require.Equal(t, at.UnixMilli(), db.PExpireTime("hash"))
+	require.Equal(t, at.Unix(), db.ExpireTime("hash"))
+
+	require.False(t, db.ExpireAt("hash", at.Add(time.Minute), ExpireLT))
+	require.True(t, db.ExpireAt("hash", at.Add(time.Minute), ExpireXX|ExpireGT))
+	require.True(t, db.ExpireAt("hash", at, ExpireLT))
+	require.Equal(t, at.UnixMilli(), db.PExpireTime("hash"))
+
+	require.True(t, db.Persist("hash"))
+	require.False(t, db.Persist("hash"))
+	require.Equal(t, int64(-1), db.TTL("hash"))
+
+	// A deadline in the past deletes the key at once
+	require.True(t, db.ExpireAt("hash", time.Now().Add(-time.Second), 0))
+	require.False(t, db.Exists("hash"))
+	require.False(t, db.ExpireAt("hash", at, 0))
+}
+
 func TestDBExpiration(t *testing.T) {
 	db := NewUltraOptimizedDB()
 	defer db.Close()
@@ -212,3 +243,130 @@ func TestDBBackgroundCleanup(t *testing.T) {
 	require.False(t, db.Exists("key2"))
 	require.True(t, db.Exists("key3"))
 }
+
+func TestDBExpiredEvents(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	var mu sync.Mutex
+	var expired []string
+	db.OnKeyEvent(func(event, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event == "expired" {
+			expired = append(expired, key)
+		}
+	})
+
+	past := time.Now().Add(-time.Second)
+	db.Set("get", "v", past)
+	db.Set("exists", "v", past)
+	db.Set("ttl", "v", past)
+	db.GetOrCreateList("list")
+	db.Expire("list", -time.Second)
+	db.Set("live", "v", time.Time{})
+
+	_, ok := db.Get("get")
+	require.False(t, ok)
+	require.False(t, db.Exists("exists"))
+	require.Equal(t, int64(-2), db.TTL("ttl"))
+	l, err := db.GetList("list")
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	// A second lookup finds nothing left to expire
+	require.False(t, db.Exists("get"))
+	require.True(t, db.Exists("live"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"get", "exists", "ttl", "list"}, expired)
+}
+
+func TestDBTypedAccess(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	// Read-only lookups never create keys
+	lst, err := db.GetList("missing")
+	require.NoError(t, err)
+	require.Nil(t, lst)
+	require.False(t, db.Exists("missing"))
+
+	// Ensure creates the key and returns the same structure afterwards
+	lst, err = db.EnsureList("mylist")
+	require.NoError(t, err)
+	lst.RPush("a")
+	again, err := db.GetList("mylist")
+	require.NoError(t, err)
+	require.Same(t, lst, again)
+
+	// Type mismatches are reported and leave the value untouched
+	db.Set("str", "value", time.Time{})
+	_, err = db.EnsureList("str")
+	require.ErrorIs(t, err, ErrWrongType)
+	_, err = db.GetHash("str")
+	require.ErrorIs(t, err, ErrWrongType)
+	_, err = db.EnsureSet("mylist")
+	require.ErrorIs(t, err, ErrWrongType)
+	_, err = db.EnsureSortedSet("mylist")
+	require.ErrorIs(t, err, ErrWrongType)
+	_, err = db.GetStream("mylist")
+	require.ErrorIs(t, err, ErrWrongType)
+	v, ok := db.Get("str")
+	require.True(t, ok)
+	require.Equal(t, "value", v)
+	require.Equal(t, 1, lst.LLen())
+
+	// Expired keys are treated as missing and may be recreated with another type
+	db.Set("old", "value", time.Now().Add(-time.Second))
+	h, err := db.GetHash("old")
+	require.NoError(t, err)
+	require.Nil(t, h)
+	h, err = db.EnsureHash("old")
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	require.Equal(t, TypeHash, db.GetDataType("old"))
+}
+
+func TestDBStoreSet(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	// Any value is replaced, and its expiration dropped
+	db.Set("dst", "value", time.Now().Add(time.Hour))
+	require.True(t, db.StoreSet("dst", []string{"a", "b"}))
+	set, err := db.GetSet("dst")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b"}, set.SMembers())
+	require.Equal(t, int64(-1), db.TTL("dst"))
+
+	// An empty result deletes the key
+	require.True(t, db.StoreSet("dst", nil))
+	require.False(t, db.Exists("dst"))
+	require.False(t, db.StoreSet("dst", nil))
+	require.Equal(t, int64(0), db.UsedMemory())
+}
+
+func TestDeleteIfEmpty(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	l, err := db.EnsureList("l")
+	require.NoError(t, err)
+	l.RPush("a")
+	require.False(t, db.DeleteIfEmpty("l"))
+	l.LPop()
+	require.True(t, db.DeleteIfEmpty("l"))
+	require.False(t, db.Exists("l"))
+
+	// Strings and streams are never deleted for being empty
+	db.Set("s", "", time.Time{})
+	_, err = db.EnsureStream("x")
+	require.NoError(t, err)
+	require.False(t, db.DeleteIfEmpty("s"))
+	require.False(t, db.DeleteIfEmpty("x"))
+	require.False(t, db.DeleteIfEmpty("missing"))
+	keys, _ := db.KeyspaceStats()
+	require.Equal(t, 2, keys)
+}
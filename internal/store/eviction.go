@@ -0,0 +1,146 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// EvictionPolicy selects the keys evicted when the memory limit is reached
+type EvictionPolicy int
+
+const (
+	NoEviction     EvictionPolicy = iota // Reject writes instead
+	AllKeysLRU                           // Least recently used key
+	VolatileLRU                          // Least recently used key with an expiration
+	AllKeysLFU                           // Least frequently used key
+	VolatileLFU                          // Least frequently used key with an expiration
+	AllKeysRandom                        // Any key
+	VolatileRandom                       // Any key with an expiration
+	VolatileTTL                          // Key closest to expiring
+)
+
+var evictionPolicyNames = []string{
+	"noeviction", "allkeys-lru", "volatile-lru", "allkeys-lfu",
+	"volatile-lfu", "allkeys-random", "volatile-random", "volatile-ttl",
+}
+
+// EvictionPolicyNames returns the names of every policy, as maxmemory-policy
+// takes them
+func EvictionPolicyNames() []string {
+	return append([]string(nil), evictionPolicyNames...)
+}
+
+// ParseEvictionPolicy returns the policy called name
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	for i, n := range evictionPolicyNames {
+		if strings.EqualFold(n, name) {
+			return EvictionPolicy(i), nil
+		}
+	}
+	return NoEviction, fmt.Errorf("invalid maxmemory-policy %q", name)
+}
+
+func (p EvictionPolicy) String() string {
+	if p < 0 || int(p) >= len(evictionPolicyNames) {
+		return "unknown"
+	}
+	return evictionPolicyNames[p]
+}
+
+// volatile reports whether p only evicts keys with an expiration
+func (p EvictionPolicy) volatile() bool {
+	return p == VolatileLRU || p == VolatileLFU || p == VolatileRandom || p == VolatileTTL
+}
+
+// score ranks it as an eviction candidate under p; the highest goes first
+func (p EvictionPolicy) score(it *UltraOptimizedItem, now uint32) uint64 {
+	var idle uint64
+	if now > it.Access {
+		idle = uint64(now - it.Access)
+	}
+	switch p {
+	case AllKeysLRU, VolatileLRU:
+		return idle
+	case AllKeysLFU, VolatileLFU:
+		// Idle time breaks ties between equally frequent keys
+		return uint64(255-lfuDecay(it.Freq, it.Access, now))<<32 | idle
+	case VolatileTTL:
+		return uint64(math.MaxInt64 - it.Expiration)
+	}
+	return 0
+}
+
+// UsedMemory returns the estimated memory of the keys in every database
+func (d *Databases) UsedMemory() int64 {
+	var used int64
+	for _, db := range d.dbs {
+		used += db.UsedMemory()
+	}
+	return used
+}
+
+// Evict removes one key chosen by policy, reports the "evicted" key event
+// and returns the index of its database and the key. Like Redis it samples
+// instead of ranking every key: samples candidates from shards picked at
+// random, of which the best goes. It reports false when there is nothing to
+// evict.
+func (d *Databases) Evict(policy EvictionPolicy, samples int) (int, string, bool) {
+	if policy == NoEviction {
+		return 0, "", false
+	}
+	if samples < 1 || policy == AllKeysRandom || policy == VolatileRandom {
+		// Map iteration order already makes the first candidate random
+		samples = 1
+	}
+
+	type candidate struct {
+		index int
+		key   string
+		score uint64
+	}
+	// A concurrent write may remove the best candidate before it is evicted
+	for {
+		var best candidate
+		now, found := clock(), 0
+		total := len(d.dbs) * shardCount
+		start := rand.IntN(total)
+		// Volatile policies may walk every shard when few keys expire
+		for i := 0; i < total && found < samples; i++ {
+			pos := (start + i) % total
+			s := d.dbs[pos/shardCount].shards[pos%shardCount]
+			s.mu.RLock()
+			for key, it := range s.m {
+				if policy.volatile() && it.Expiration == 0 {
+					continue
+				}
+				if score := policy.score(&it, now); found == 0 || score > best.score {
+					best = candidate{pos / shardCount, key, score}
+				}
+				if found++; found == samples {
+					break
+				}
+			}
+			s.mu.RUnlock()
+		}
+		if found == 0 {
+			return 0, "", false
+		}
+		if d.dbs[best.index].evict(best.key) {
+			return best.index, best.key, true
+		}
+	}
+}
+
+// evict deletes key, if it still exists, and reports the "evicted" event
+func (db *UltraOptimizedDB) evict(key string) bool {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	_, ok := db.remove(s, key)
+	s.mu.Unlock()
+	if ok {
+		db.NotifyKeyEvent("evicted", key)
+	}
+	return ok
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setAccess backdates the access clock and LFU counter of key
+func setAccess(db *UltraOptimizedDB, key string, access uint32, freq uint8) {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	it := s.m[key]
+	it.Access, it.Freq = access, freq
+	s.m[key] = it
+	s.mu.Unlock()
+}
+
+func TestMemoryAccounting(t *testing.T) {
+	dbs := NewDatabases(2)
+	defer dbs.Close()
+	db := dbs.DB(0)
+	assert.Zero(t, dbs.UsedMemory())
+
+	db.Set("k", "value", time.Time{})
+	used := dbs.UsedMemory()
+	assert.Equal(t, int64(itemOverhead+len("k")+len("value")), used)
+	db.Set("k", "a longer value", time.Time{})
+	assert.Greater(t, dbs.UsedMemory(), used)
+
+	// Collections are measured again when commands report changing them
+	list, err := db.EnsureList("l")
+	require.NoError(t, err)
+	before := dbs.UsedMemory()
+	for i := 0; i < 100; i++ {
+		list.RPush("element")
+	}
+	db.NotifyKeyEvent("rpush", "l")
+	assert.Greater(t, dbs.UsedMemory(), before+100*int64(len("element")))
+
+	dbs.Swap(0, 1)
+	assert.Zero(t, dbs.DB(0).UsedMemory())
+	assert.Equal(t, dbs.UsedMemory(), dbs.DB(1).UsedMemory())
+	require.True(t, dbs.Move("k", 1, 0))
+	assert.Equal(t, int64(itemOverhead+len("k")+len("a longer value")), dbs.DB(0).UsedMemory())
+
+	dbs.DB(1).Del("l")
+	dbs.FlushAll()
+	assert.Zero(t, dbs.UsedMemory())
+}
+
+func TestKeyCounters(t *testing.T) {
+	dbs := NewDatabases(2)
+	defer dbs.Close()
+	db := dbs.DB(0)
+
+	db.Set("a", "1", time.Time{})
+	db.Set("b", "1", time.Now().Add(time.Hour))
+	_, err := db.EnsureHash("h")
+	require.NoError(t, err)
+	keys, expires := db.KeyspaceStats()
+	assert.Equal(t, 3, keys)
+	assert.Equal(t, 1, expires)
+
+	// Overwrites and expiration changes keep the key count
+	db.Set("b", "2", time.Time{})
+	assert.True(t, db.Expire("a", time.Hour))
+	assert.True(t, db.Expire("h", time.Hour))
+	assert.True(t, db.Persist("h"))
+	keys, expires = db.KeyspaceStats()
+	assert.Equal(t, 3, keys)
+	assert.Equal(t, 1, expires)
+
+	db.Del("a")
+	dbs.Swap(0, 1)
+	all, allExpires := dbs.KeyspaceStats()
+	assert.Equal(t, []int{0, 2}, all)
+	assert.Equal(t, []int{0, 0}, allExpires)
+	dbs.Flush(1)
+	all, _ = dbs.KeyspaceStats()
+	assert.Equal(t, []int{0, 0}, all)
+}
+
+func TestAccessTracking(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+	db.Set("k", "v", time.Time{})
+	setAccess(db, "k", clock()-120, lfuInitVal)
+
+	_, ok := db.Get("k")
+	require.True(t, ok)
+	it := db.shardFor("k").m["k"]
+	assert.Equal(t, clock(), it.Access)
+	// Two idle minutes decay the counter by two before the hit adds one
+	assert.Equal(t, uint8(lfuInitVal-1), it.Freq)
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	now := clock()
+	tests := []struct {
+		policy EvictionPolicy
+		evicts string
+	}{
+		{AllKeysLRU, "idle"},
+		{VolatileLRU, "expiring-idle"},
+		{AllKeysLFU, "rare"},
+		{VolatileLFU, "expiring-rare"},
+		{VolatileTTL, "expiring-soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			dbs := NewDatabases(2)
+			defer dbs.Close()
+			db := dbs.DB(1)
+			set := func(key string, ttl time.Duration, access uint32, freq uint8) {
+				var exp time.Time
+				if ttl > 0 {
+					exp = time.Now().Add(ttl)
+				}
+				db.Set(key, "v", exp)
+				setAccess(db, key, access, freq)
+			}
+			set("idle", 0, now-1000, 100)
+			set("rare", 0, now, 0)
+			set("expiring-idle", time.Hour, now-500, 100)
+			set("expiring-rare", time.Hour, now, 1)
+			set("expiring-soon", time.Minute, now, 100)
+			set("recent", 0, now, 100)
+
+			var events []string
+			db.OnKeyEvent(func(event, key string) { events = append(events, event+":"+key) })
+			index, key, ok := dbs.Evict(tt.policy, 10)
+			require.True(t, ok)
+			assert.Equal(t, 1, index)
+			assert.Equal(t, tt.evicts, key)
+			assert.Equal(t, []string{"evicted:" + tt.evicts}, events)
+			assert.False(t, db.Exists(tt.evicts))
+		})
+	}
+}
+
+func TestEvictNothing(t *testing.T) {
+	dbs := NewDatabases(1)
+	defer dbs.Close()
+	dbs.DB(0).Set("k", "v", time.Time{})
+	evicts := func(policy EvictionPolicy) bool {
+		_, _, ok := dbs.Evict(policy, 5)
+		return ok
+	}
+	assert.False(t, evicts(NoEviction))
+	assert.False(t, evicts(VolatileRandom))
+	assert.True(t, evicts(AllKeysRandom))
+	assert.False(t, evicts(AllKeysRandom))
+
+	policy, err := ParseEvictionPolicy("ALLKEYS-LFU")
+	require.NoError(t, err)
+	assert.Equal(t, AllKeysLFU, policy)
+	_, err = ParseEvictionPolicy("bogus")
+	assert.Error(t, err)
+}
@@ -14,6 +14,10 @@ type DataStore interface {
 	TTL(key string) int64
 	PTTL(key string) int64
 	Expire(key string, duration time.Duration) bool
+	ExpireAt(key string, at time.Time, flags ExpireFlags) bool
+	Persist(key string) bool
+	ExpireTime(key string) int64
+	PExpireTime(key string) int64
 
 	// Key enumeration
 	Keys() []string
@@ -25,9 +29,27 @@ type DataStore interface {
 	GetOrCreateSortedSet(key string) *SortedSet
 	GetOrCreateStream(key string) *Stream
 
+	// Type-checked data structure access: Get* never creates keys, Ensure*
+	// creates missing keys, and both return ErrWrongType on a type mismatch
+	GetList(key string) (*List, error)
+	EnsureList(key string) (*List, error)
+	GetSet(key string) (*Set, error)
+	EnsureSet(key string) (*Set, error)
+	StoreSet(key string, members []string) bool
+	GetHash(key string) (*Hash, error)
+	EnsureHash(key string) (*Hash, error)
+	GetSortedSet(key string) (*SortedSet, error)
+	EnsureSortedSet(key string) (*SortedSet, error)
+	GetStream(key string) (*Stream, error)
+	EnsureStream(key string) (*Stream, error)
+	DeleteIfEmpty(key string) bool
+
 	// Introspection
 	GetDataType(key string) DataType
 
+	// NotifyKeyEvent reports a modification made by a command
+	NotifyKeyEvent(event, key string)
+
 	// Lifecycle
 	Close()
 }
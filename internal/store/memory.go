@@ -0,0 +1,249 @@
+package store
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Memory accounting estimates what each key costs: the key and its item
+// plus, for collections, the element count times the average size of a few
+// sampled elements. The estimate is cheap enough to refresh after every
+// write and close enough to decide when to evict.
+const (
+	itemOverhead      = 96 // Map entry and UltraOptimizedItem
+	stringHeader      = 16 // Slot of a list element
+	mapEntryOverhead  = 48 // Share of a map bucket per set member or hash field
+	zsetEntryOverhead = 80 // Score map entry and order slice entry
+	streamEntryHeader = 48 // StreamEntry and its field map
+
+	memorySamples = 16 // Elements measured to estimate a collection
+)
+
+// LFU counters grow logarithmically: the more hits a key has had, the less
+// likely the next one is to increment its counter, and they lose one for
+// every lfuDecayTime seconds the key sits idle.
+const (
+	lfuInitVal   = 5 // Counter of new keys, so they are not evicted at once
+	lfuLogFactor = 10
+	lfuDecayTime = 60
+)
+
+// UsedMemory returns the estimated memory of the keys in db
+func (db *UltraOptimizedDB) UsedMemory() int64 { return db.used.Load() }
+
+// put stores it under key and accounts its memory and the key counters. New
+// values start their access clock and LFU counter. The caller holds s.mu.
+func (db *UltraOptimizedDB) put(s *shard, key string, it UltraOptimizedItem) {
+	if it.Access == 0 {
+		it.Access, it.Freq = clock(), lfuInitVal
+	}
+	if it.size == 0 {
+		it.size = itemMemory(key, &it)
+	}
+	delta, keys, expires := it.size, int64(1), it.expiring()
+	if old, ok := s.m[key]; ok {
+		delta, keys, expires = delta-old.size, 0, expires-old.expiring()
+	}
+	s.m[key] = it
+	s.used += delta
+	db.used.Add(delta)
+	s.count(db, keys, expires)
+}
+
+// remove deletes key and releases its memory. The caller holds s.mu.
+func (db *UltraOptimizedDB) remove(s *shard, key string) (UltraOptimizedItem, bool) {
+	it, ok := s.m[key]
+	if ok {
+		delete(s.m, key)
+		s.used -= it.size
+		db.used.Add(-it.size)
+		s.count(db, -1, -it.expiring())
+	}
+	return it, ok
+}
+
+// count adds to the key counters of s and db. The caller holds s.mu.
+func (s *shard) count(db *UltraOptimizedDB, keys, expires int64) {
+	if keys != 0 {
+		s.keys += keys
+		db.keys.Add(keys)
+	}
+	if expires != 0 {
+		s.expires += expires
+		db.expires.Add(expires)
+	}
+}
+
+// expiring returns 1 when it has an expiration, for the key counters
+func (it *UltraOptimizedItem) expiring() int64 {
+	if it.Expiration != 0 {
+		return 1
+	}
+	return 0
+}
+
+// remeasure accounts the current size of the collection stored at key
+func (db *UltraOptimizedDB) remeasure(key string) {
+	s := db.shardFor(key)
+	s.mu.RLock()
+	it, ok := s.m[key]
+	s.mu.RUnlock()
+	if !ok || it.DataType == TypeString {
+		return
+	}
+	// Collections in use are measured outside the shard lock
+	size := itemMemory(key, &it)
+	s.mu.Lock()
+	if cur, ok := s.m[key]; ok && cur.sameValue(&it) && cur.size != size {
+		cur.size = size
+		db.put(s, key, cur)
+	}
+	s.mu.Unlock()
+}
+
+// touch records an access to it, read from key. The clock only advances
+// once a second and the LFU counter rarely grows, so most reads never take
+// the write lock.
+func (db *UltraOptimizedDB) touch(s *shard, key string, it UltraOptimizedItem) {
+	now := clock()
+	freq := lfuIncr(lfuDecay(it.Freq, it.Access, now))
+	if it.Access == now && freq == it.Freq {
+		return
+	}
+	s.mu.Lock()
+	if cur, ok := s.m[key]; ok {
+		cur.Access, cur.Freq = now, freq
+		s.m[key] = cur
+	}
+	s.mu.Unlock()
+}
+
+// sameValue reports whether it and other hold the same collection
+func (it *UltraOptimizedItem) sameValue(other *UltraOptimizedItem) bool {
+	return it.List == other.List && it.Set == other.Set && it.Hash == other.Hash &&
+		it.ZSet == other.ZSet && it.Stream == other.Stream
+}
+
+// clock returns the current second of the access clock
+func clock() uint32 { return uint32(time.Now().Unix()) }
+
+// lfuDecay returns freq decremented for the time since access
+func lfuDecay(freq uint8, access, now uint32) uint8 {
+	if now <= access {
+		return freq
+	}
+	periods := (now - access) / lfuDecayTime
+	if periods >= uint32(freq) {
+		return 0
+	}
+	return freq - uint8(periods)
+}
+
+// lfuIncr returns freq incremented with a probability that falls as it grows
+func lfuIncr(freq uint8) uint8 {
+	if freq == 255 {
+		return freq
+	}
+	base := float64(freq) - lfuInitVal
+	if base < 0 {
+		base = 0
+	}
+	if rand.Float64() < 1/(base*lfuLogFactor+1) {
+		freq++
+	}
+	return freq
+}
+
+// itemMemory estimates the memory of key and it
+func itemMemory(key string, it *UltraOptimizedItem) int64 {
+	size := int64(itemOverhead + len(key) + len(it.Value))
+	switch {
+	case it.List != nil:
+		size += it.List.memoryUsage()
+	case it.Set != nil:
+		size += it.Set.memoryUsage()
+	case it.Hash != nil:
+		size += it.Hash.memoryUsage()
+	case it.ZSet != nil:
+		size += it.ZSet.memoryUsage()
+	case it.Stream != nil:
+		size += it.Stream.memoryUsage()
+	}
+	return size
+}
+
+// extrapolate scales the bytes of samples elements to n elements
+func extrapolate(sampled, samples, n int) int64 {
+	if samples == 0 {
+		return 0
+	}
+	return int64(sampled) * int64(n) / int64(samples)
+}
+
+func (l *OptimizedList) memoryUsage() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	n := l.tail - l.head
+	samples := min(n, memorySamples)
+	sampled := 0
+	for i := 0; i < samples; i++ {
+		sampled += len(l.items[l.head+i*n/samples])
+	}
+	return int64(len(l.items))*stringHeader + extrapolate(sampled, samples, n)
+}
+
+func (s *OptimizedSet) memoryUsage() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sampled, samples := 0, 0
+	for member := range s.items {
+		if samples == memorySamples {
+			break
+		}
+		sampled += len(member)
+		samples++
+	}
+	return int64(len(s.items))*mapEntryOverhead + extrapolate(sampled, samples, len(s.items))
+}
+
+func (h *OptimizedHash) memoryUsage() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sampled, samples := 0, 0
+	for field, value := range h.fields {
+		if samples == memorySamples {
+			break
+		}
+		sampled += len(field) + len(value)
+		samples++
+	}
+	return int64(len(h.fields))*mapEntryOverhead + extrapolate(sampled, samples, len(h.fields))
+}
+
+func (z *OptimizedSortedSet) memoryUsage() int64 {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	sampled, samples := 0, 0
+	for member := range z.scores {
+		if samples == memorySamples {
+			break
+		}
+		sampled += len(member)
+		samples++
+	}
+	return int64(len(z.scores))*zsetEntryOverhead + extrapolate(sampled, samples, len(z.scores))
+}
+
+func (st *Stream) memoryUsage() int64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	n := len(st.entries)
+	samples := min(n, memorySamples)
+	sampled := 0
+	for i := 0; i < samples; i++ {
+		for field, value := range st.entries[i*n/samples].Fields {
+			sampled += mapEntryOverhead + len(field) + len(value)
+		}
+	}
+	return int64(n)*streamEntryHeader + extrapolate(sampled, samples, n)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"strconv"
+	"time"
+)
+
+// embstrMaxLen is the longest string Redis embeds in its object header;
+// OBJECT ENCODING reports longer ones as raw
+const embstrMaxLen = 44
+
+// ObjectInfo describes how a key is stored, as OBJECT reports it
+type ObjectInfo struct {
+	Encoding string // Representation of the value, e.g. "int" or "hashtable"
+	IdleTime int64  // Seconds since the key was last accessed
+	Freq     uint8  // LFU counter, decayed to now
+}
+
+// Object returns how key is stored without counting as an access to it
+func (db *UltraOptimizedDB) Object(key string) (ObjectInfo, bool) {
+	s := db.shardFor(key)
+	s.mu.RLock()
+	it, ok := s.m[key]
+	s.mu.RUnlock()
+	if !ok {
+		return ObjectInfo{}, false
+	}
+	if now := time.Now().UnixNano(); it.Expiration != 0 && now > it.Expiration {
+		db.expire(s, key, now)
+		return ObjectInfo{}, false
+	}
+	now := clock()
+	info := ObjectInfo{Encoding: it.encoding(), Freq: lfuDecay(it.Freq, it.Access, now)}
+	if now > it.Access {
+		info.IdleTime = int64(now - it.Access)
+	}
+	return info, true
+}
+
+// encoding names the representation of the value of it. Strings follow
+// Redis; collections name the structure backing them: a deque of strings
+// for lists, Go maps for sets and hashes, a map with a lazily sorted slice
+// for sorted sets and an ID ordered slice for streams.
+func (it *UltraOptimizedItem) encoding() string {
+	switch it.DataType {
+	case TypeList:
+		return "deque"
+	case TypeSet, TypeHash:
+		return "hashtable"
+	case TypeSortedSet:
+		return "sortedslice"
+	case TypeStream:
+		return "stream"
+	}
+	// Only integers that format back to the same string are stored as such
+	if len(it.Value) <= 20 {
+		if n, err := strconv.ParseInt(it.Value, 10, 64); err == nil && strconv.FormatInt(n, 10) == it.Value {
+			return "int"
+		}
+	}
+	if len(it.Value) <= embstrMaxLen {
+		return "embstr"
+	}
+	return "raw"
+}
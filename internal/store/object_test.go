@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObject(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+	db.Set("k", "v", time.Time{})
+	setAccess(db, "k", clock()-100, 20)
+
+	info, ok := db.Object("k")
+	require.True(t, ok)
+	assert.Equal(t, "embstr", info.Encoding)
+	assert.InDelta(t, 100, info.IdleTime, 1)
+	// Over a minute idle decays the counter once
+	assert.Equal(t, uint8(19), info.Freq)
+
+	// Inspecting a key leaves its access clock alone
+	again, _ := db.Object("k")
+	assert.GreaterOrEqual(t, again.IdleTime, info.IdleTime)
+
+	db.Set("expired", "v", time.Now().Add(-time.Second))
+	_, ok = db.Object("expired")
+	assert.False(t, ok)
+	_, ok = db.Object("missing")
+	assert.False(t, ok)
+}
@@ -0,0 +1,567 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrNoGroup is returned by consumer group operations on a missing group.
+// Commands wrap it into the NOGROUP reply that names the key and group.
+var ErrNoGroup = errors.New("NOGROUP No such consumer group")
+
+// PendingEntry is a message delivered to a consumer that has not been
+// acknowledged yet
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  time.Time
+	DeliveryCount uint64
+}
+
+// ConsumerInfo describes a consumer of a group
+type ConsumerInfo struct {
+	Name       string
+	SeenTime   time.Time // last interaction of any kind
+	ActiveTime time.Time // last successful read or claim
+	Pending    int
+}
+
+// ConsumerGroupInfo is a snapshot of a consumer group. It is used by
+// introspection commands and to save and restore groups.
+type ConsumerGroupInfo struct {
+	Name        string
+	LastID      StreamID
+	EntriesRead int64 // -1 when unknown
+	Lag         int64 // entries not yet delivered to the group, -1 when unknown
+	Pending     []PendingEntry
+	Consumers   []ConsumerInfo
+}
+
+// ClaimOptions controls how XCLAIM and XAUTOCLAIM take over pending entries
+type ClaimOptions struct {
+	MinIdle      time.Duration
+	DeliveryTime time.Time // zero means now
+	RetryCount   int64     // < 0 increments the delivery count (unless JustID)
+	Force        bool      // create pending entries for IDs not yet in the PEL
+	JustID       bool      // do not increment the delivery count
+	LastID       *StreamID // move the group last delivered ID forward
+}
+
+type consumerGroup struct {
+	name        string
+	lastID      StreamID
+	entriesRead int64
+	pel         map[StreamID]*PendingEntry
+	consumers   map[string]*streamConsumer
+}
+
+type streamConsumer struct {
+	name       string
+	seenTime   time.Time
+	activeTime time.Time
+	pending    map[StreamID]*PendingEntry
+}
+
+func (s *Stream) group(name string) (*consumerGroup, error) {
+	g, ok := s.groups[name]
+	if !ok {
+		return nil, ErrNoGroup
+	}
+	return g, nil
+}
+
+// consumer returns the named consumer, creating it if needed
+func (g *consumerGroup) consumer(name string, now time.Time) *streamConsumer {
+	c, ok := g.consumers[name]
+	if !ok {
+		c = &streamConsumer{name: name, seenTime: now, pending: make(map[StreamID]*PendingEntry)}
+		g.consumers[name] = c
+	}
+	return c
+}
+
+// assign makes c the owner of the pending entry pe
+func (g *consumerGroup) assign(pe *PendingEntry, c *streamConsumer) {
+	if pe.Consumer != c.name {
+		if prev, ok := g.consumers[pe.Consumer]; ok {
+			delete(prev.pending, pe.ID)
+		}
+		pe.Consumer = c.name
+	}
+	c.pending[pe.ID] = pe
+}
+
+func (g *consumerGroup) ack(id StreamID) bool {
+	pe, ok := g.pel[id]
+	if !ok {
+		return false
+	}
+	delete(g.pel, id)
+	if c, ok := g.consumers[pe.Consumer]; ok {
+		delete(c.pending, id)
+	}
+	return true
+}
+
+// sortedPending returns the PEL entries ordered by ID
+func sortedPending(pel map[StreamID]*PendingEntry) []*PendingEntry {
+	out := make([]*PendingEntry, 0, len(pel))
+	for _, pe := range pel {
+		out = append(out, pe)
+	}
+	sort.Slice(out, func(i, j int) bool { return compareStreamID(out[i].ID, out[j].ID) < 0 })
+	return out
+}
+
+// entry returns the entry with the given ID, if it is still in the stream
+func (s *Stream) entry(id StreamID) (StreamEntry, bool) {
+	i := sort.Search(len(s.entries), func(i int) bool { return compareStreamID(s.entries[i].ID, id) >= 0 })
+	if i < len(s.entries) && s.entries[i].ID == id {
+		return s.entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+// CreateGroup creates a consumer group whose last delivered ID is id
+func (s *Stream) CreateGroup(name string, id StreamID, entriesRead int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.groups == nil {
+		s.groups = make(map[string]*consumerGroup)
+	}
+	if _, ok := s.groups[name]; ok {
+		return fmt.Errorf("BUSYGROUP Consumer Group name already exists")
+	}
+	s.groups[name] = &consumerGroup{
+		name:        name,
+		lastID:      id,
+		entriesRead: entriesRead,
+		pel:         make(map[StreamID]*PendingEntry),
+		consumers:   make(map[string]*streamConsumer),
+	}
+	return nil
+}
+
+// DestroyGroup removes a consumer group and reports whether it existed
+func (s *Stream) DestroyGroup(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// SetGroupID sets the last delivered ID of a group
+func (s *Stream) SetGroupID(name string, id StreamID, entriesRead int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(name)
+	if err != nil {
+		return err
+	}
+	g.lastID = id
+	g.entriesRead = entriesRead
+	return nil
+}
+
+// CreateConsumer adds a consumer to a group and reports whether it was created
+func (s *Stream) CreateConsumer(group, consumer string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := g.consumers[consumer]; ok {
+		return false, nil
+	}
+	g.consumer(consumer, time.Now())
+	return true, nil
+}
+
+// DelConsumer removes a consumer and its pending entries. It returns the
+// number of pending entries the consumer had.
+func (s *Stream) DelConsumer(group, consumer string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return 0, err
+	}
+	c, ok := g.consumers[consumer]
+	if !ok {
+		return 0, nil
+	}
+	for id := range c.pending {
+		delete(g.pel, id)
+	}
+	delete(g.consumers, consumer)
+	return len(c.pending), nil
+}
+
+// ReadGroup delivers entries to consumer. With newOnly it returns up to count
+// entries after the group's last delivered ID and advances it, adding them to
+// the PEL unless noAck is set. Otherwise it returns the consumer's own pending
+// entries with IDs greater than after; entries deleted from the stream since
+// delivery are returned with nil Fields.
+func (s *Stream) ReadGroup(group, consumer string, newOnly bool, after StreamID, count int, noAck bool) ([]StreamEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c := g.consumer(consumer, now)
+	c.seenTime = now
+
+	if !newOnly {
+		out := []StreamEntry{}
+		for _, pe := range sortedPending(c.pending) {
+			if compareStreamID(pe.ID, after) <= 0 {
+				continue
+			}
+			if count > 0 && len(out) == count {
+				break
+			}
+			e, ok := s.entry(pe.ID)
+			if !ok {
+				e = StreamEntry{ID: pe.ID}
+			}
+			pe.DeliveryTime = now
+			pe.DeliveryCount++
+			out = append(out, e)
+		}
+		return out, nil
+	}
+
+	idx := s.findFirstGreater(g.lastID)
+	res := s.entries[idx:]
+	if count > 0 && count < len(res) {
+		res = res[:count]
+	}
+	if len(res) == 0 {
+		return []StreamEntry{}, nil
+	}
+	out := make([]StreamEntry, len(res))
+	copy(out, res)
+	for _, e := range out {
+		if g.entriesRead >= 0 && !s.hasTombstonesFrom(g.lastID) {
+			g.entriesRead++
+		} else if s.entriesAdded > 0 {
+			g.entriesRead = s.estimateEntriesRead(e.ID)
+		}
+		g.lastID = e.ID
+	}
+	c.activeTime = now
+	if noAck {
+		return out, nil
+	}
+	for _, e := range out {
+		pe, ok := g.pel[e.ID]
+		if !ok {
+			pe = &PendingEntry{ID: e.ID, Consumer: c.name}
+			g.pel[e.ID] = pe
+		}
+		g.assign(pe, c)
+		pe.DeliveryTime = now
+		pe.DeliveryCount = 1
+	}
+	return out, nil
+}
+
+// Ack removes ids from the group's PEL and returns how many were pending
+func (s *Stream) Ack(group string, ids []StreamID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return 0, err
+	}
+	acked := 0
+	for _, id := range ids {
+		if g.ack(id) {
+			acked++
+		}
+	}
+	return acked, nil
+}
+
+// Pending returns a copy of the group's PEL ordered by ID
+func (s *Stream) Pending(group string) ([]PendingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return nil, err
+	}
+	pending := sortedPending(g.pel)
+	out := make([]PendingEntry, len(pending))
+	for i, pe := range pending {
+		out[i] = *pe
+	}
+	return out, nil
+}
+
+// IsPending reports whether id is in the PEL of group
+func (s *Stream) IsPending(group string, id StreamID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[group]
+	if !ok {
+		return false
+	}
+	_, pending := g.pel[id]
+	return pending
+}
+
+// Claim transfers ownership of pending ids to consumer. Entries that are
+// idle for less than opts.MinIdle are skipped; entries deleted from the
+// stream are dropped from the PEL and returned in deleted.
+func (s *Stream) Claim(group, consumer string, ids []StreamID, opts ClaimOptions) (claimed []StreamEntry, deleted []StreamID, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	c := g.consumer(consumer, now)
+	c.seenTime = now
+	if opts.LastID != nil && compareStreamID(*opts.LastID, g.lastID) > 0 {
+		g.lastID = *opts.LastID
+	}
+
+	claimed = []StreamEntry{}
+	for _, id := range ids {
+		e, exists := s.entry(id)
+		pe, pending := g.pel[id]
+		if !pending {
+			if !opts.Force || !exists {
+				continue
+			}
+			pe = &PendingEntry{ID: id, Consumer: c.name, DeliveryTime: now}
+			g.pel[id] = pe
+		} else if !exists {
+			g.ack(id)
+			deleted = append(deleted, id)
+			continue
+		}
+		if opts.MinIdle > 0 && now.Sub(pe.DeliveryTime) < opts.MinIdle {
+			continue
+		}
+		s.claimPending(g, c, pe, opts, now)
+		claimed = append(claimed, e)
+	}
+	if len(claimed) > 0 {
+		c.activeTime = now
+	}
+	return claimed, deleted, nil
+}
+
+// AutoClaim scans the PEL from start and claims up to count entries idle for
+// at least minIdle, examining at most count*10 entries. next is the ID to
+// resume scanning from, or 0-0 once the whole PEL was scanned.
+func (s *Stream) AutoClaim(group, consumer string, start StreamID, count int, opts ClaimOptions) (next StreamID, claimed []StreamEntry, deleted []StreamID, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.group(group)
+	if err != nil {
+		return StreamID{}, nil, nil, err
+	}
+	now := time.Now()
+	c := g.consumer(consumer, now)
+	c.seenTime = now
+
+	claimed = []StreamEntry{}
+	deleted = []StreamID{}
+	attempts := count * 10
+	pending := sortedPending(g.pel)
+	i := sort.Search(len(pending), func(i int) bool { return compareStreamID(pending[i].ID, start) >= 0 })
+	for ; i < len(pending) && attempts > 0 && len(claimed) < count; i++ {
+		attempts--
+		pe := pending[i]
+		e, exists := s.entry(pe.ID)
+		if !exists {
+			g.ack(pe.ID)
+			deleted = append(deleted, pe.ID)
+			continue
+		}
+		if opts.MinIdle > 0 && now.Sub(pe.DeliveryTime) < opts.MinIdle {
+			continue
+		}
+		s.claimPending(g, c, pe, opts, now)
+		claimed = append(claimed, e)
+	}
+	if i < len(pending) {
+		next = pending[i].ID
+	}
+	if len(claimed) > 0 {
+		c.activeTime = now
+	}
+	return next, claimed, deleted, nil
+}
+
+func (s *Stream) claimPending(g *consumerGroup, c *streamConsumer, pe *PendingEntry, opts ClaimOptions, now time.Time) {
+	g.assign(pe, c)
+	if opts.DeliveryTime.IsZero() {
+		pe.DeliveryTime = now
+	} else {
+		pe.DeliveryTime = opts.DeliveryTime
+	}
+	if opts.RetryCount >= 0 {
+		pe.DeliveryCount = uint64(opts.RetryCount)
+	} else if !opts.JustID {
+		pe.DeliveryCount++
+	}
+}
+
+// hasTombstonesFrom reports whether an entry with an ID at or after from may
+// have been deleted, making an incremental entries-read counter unreliable
+func (s *Stream) hasTombstonesFrom(from StreamID) bool {
+	if len(s.entries) == 0 || s.maxDeleted == (StreamID{}) {
+		return false
+	}
+	if compareStreamID(s.entries[0].ID, s.maxDeleted) > 0 {
+		// Every deleted entry has been trimmed away since
+		return false
+	}
+	return compareStreamID(s.maxDeleted, from) >= 0
+}
+
+// estimateEntriesRead returns the number of entries ever added to the stream
+// up to and including id, or -1 when deletions make it impossible to know
+func (s *Stream) estimateEntriesRead(id StreamID) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	last := StreamID{Ms: s.lastMs, Seq: s.lastSeq}
+	if len(s.entries) == 0 && compareStreamID(id, last) <= 0 {
+		return int64(s.entriesAdded)
+	}
+	switch cmp := compareStreamID(id, last); {
+	case cmp == 0:
+		return int64(s.entriesAdded)
+	case cmp > 0:
+		return -1
+	}
+	first := s.entries[0].ID
+	if compareStreamID(id, first) < 0 && compareStreamID(s.maxDeleted, first) < 0 {
+		// Nothing after id was ever deleted, so only trimmed entries were read
+		return int64(s.entriesAdded) - int64(len(s.entries))
+	}
+	return -1
+}
+
+// EstimateEntriesRead is the entries-read counter of a group whose last
+// delivered ID is id, or -1 when it cannot be derived
+func (s *Stream) EstimateEntriesRead(id StreamID) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.estimateEntriesRead(id)
+}
+
+// lag returns how many entries the group has not been delivered yet, or -1
+func (s *Stream) lag(g *consumerGroup) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	read := g.entriesRead
+	if read < 0 || s.hasTombstonesFrom(g.lastID) {
+		read = s.estimateEntriesRead(g.lastID)
+		if read < 0 {
+			return -1
+		}
+	}
+	return int64(s.entriesAdded) - read
+}
+
+// Groups returns a snapshot of all consumer groups ordered by name
+func (s *Stream) Groups() []ConsumerGroupInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ConsumerGroupInfo, 0, len(s.groups))
+	for _, g := range s.groups {
+		info := g.info()
+		info.Lag = s.lag(g)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// HasGroup reports whether the stream has a consumer group called name
+func (s *Stream) HasGroup(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.groups[name]
+	return ok
+}
+
+// Group returns a snapshot of a single consumer group
+func (s *Stream) Group(name string) (ConsumerGroupInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[name]
+	if !ok {
+		return ConsumerGroupInfo{}, false
+	}
+	info := g.info()
+	info.Lag = s.lag(g)
+	return info, true
+}
+
+func (g *consumerGroup) info() ConsumerGroupInfo {
+	info := ConsumerGroupInfo{
+		Name:        g.name,
+		LastID:      g.lastID,
+		EntriesRead: g.entriesRead,
+		Pending:     make([]PendingEntry, 0, len(g.pel)),
+		Consumers:   make([]ConsumerInfo, 0, len(g.consumers)),
+	}
+	for _, pe := range sortedPending(g.pel) {
+		info.Pending = append(info.Pending, *pe)
+	}
+	for _, c := range g.consumers {
+		info.Consumers = append(info.Consumers, ConsumerInfo{
+			Name:       c.name,
+			SeenTime:   c.seenTime,
+			ActiveTime: c.activeTime,
+			Pending:    len(c.pending),
+		})
+	}
+	sort.Slice(info.Consumers, func(i, j int) bool { return info.Consumers[i].Name < info.Consumers[j].Name })
+	return info
+}
+
+// RestoreGroup recreates a consumer group from a snapshot, replacing any
+// group with the same name. Pending entries are assigned to the consumers
+// they name.
+func (s *Stream) RestoreGroup(info ConsumerGroupInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.groups == nil {
+		s.groups = make(map[string]*consumerGroup)
+	}
+	g := &consumerGroup{
+		name:        info.Name,
+		lastID:      info.LastID,
+		entriesRead: info.EntriesRead,
+		pel:         make(map[StreamID]*PendingEntry, len(info.Pending)),
+		consumers:   make(map[string]*streamConsumer, len(info.Consumers)),
+	}
+	for _, ci := range info.Consumers {
+		c := g.consumer(ci.Name, ci.SeenTime)
+		c.activeTime = ci.ActiveTime
+	}
+	for _, p := range info.Pending {
+		pe := p
+		g.pel[pe.ID] = &pe
+		g.consumer(pe.Consumer, pe.DeliveryTime).pending[pe.ID] = &pe
+	}
+	s.groups[info.Name] = g
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGroupTestStream(t *testing.T, ids ...StreamID) *Stream {
+	t.Helper()
+	s := NewStream()
+	for _, id := range ids {
+		id := id
+		_, err := s.XAdd(&id, map[string]string{"f": id.String()})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func TestStreamGroupReadAndAck(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0}, StreamID{3, 0})
+	require.NoError(t, s.CreateGroup("g", StreamID{}, 0))
+	assert.EqualError(t, s.CreateGroup("g", StreamID{}, 0), "BUSYGROUP Consumer Group name already exists")
+
+	got, err := s.ReadGroup("g", "alice", true, StreamID{}, 2, false)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, StreamID{2, 0}, got[1].ID)
+
+	got, err = s.ReadGroup("g", "bob", true, StreamID{}, 0, false)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, StreamID{3, 0}, got[0].ID)
+
+	// Nothing new is left for the group
+	got, err = s.ReadGroup("g", "bob", true, StreamID{}, 0, false)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	// History only returns the consumer's own pending entries
+	got, err = s.ReadGroup("g", "alice", false, StreamID{}, 0, false)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	acked, err := s.Ack("g", []StreamID{{1, 0}, {1, 0}, {9, 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, acked)
+
+	pending, err := s.Pending("g")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "alice", pending[0].Consumer)
+	assert.Equal(t, uint64(2), pending[0].DeliveryCount)
+	assert.Equal(t, "bob", pending[1].Consumer)
+
+	info, ok := s.Group("g")
+	require.True(t, ok)
+	assert.Equal(t, StreamID{3, 0}, info.LastID)
+	assert.Equal(t, int64(3), info.EntriesRead)
+	require.Len(t, info.Consumers, 2)
+	assert.Equal(t, 1, info.Consumers[0].Pending)
+
+	_, err = s.ReadGroup("missing", "alice", true, StreamID{}, 0, false)
+	assert.ErrorIs(t, err, ErrNoGroup)
+}
+
+func TestStreamGroupNoAckAndDelConsumer(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0})
+	require.NoError(t, s.CreateGroup("g", StreamID{}, 0))
+
+	got, err := s.ReadGroup("g", "c1", true, StreamID{}, 1, true)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	pending, _ := s.Pending("g")
+	assert.Empty(t, pending)
+
+	_, err = s.ReadGroup("g", "c1", true, StreamID{}, 0, false)
+	require.NoError(t, err)
+	n, err := s.DelConsumer("g", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	pending, _ = s.Pending("g")
+	assert.Empty(t, pending)
+
+	created, err := s.CreateConsumer("g", "c2")
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, _ = s.CreateConsumer("g", "c2")
+	assert.False(t, created)
+
+	assert.True(t, s.DestroyGroup("g"))
+	assert.False(t, s.DestroyGroup("g"))
+}
+
+func TestStreamGroupClaim(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0}, StreamID{3, 0})
+	require.NoError(t, s.CreateGroup("g", StreamID{}, 0))
+	_, err := s.ReadGroup("g", "alice", true, StreamID{}, 0, false)
+	require.NoError(t, err)
+
+	// Entries delivered just now are not idle long enough
+	claimed, _, err := s.Claim("g", "bob", []StreamID{{1, 0}}, ClaimOptions{MinIdle: time.Hour, RetryCount: -1})
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, _, err = s.Claim("g", "bob", []StreamID{{1, 0}, {7, 0}}, ClaimOptions{RetryCount: -1})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	pending, _ := s.Pending("g")
+	assert.Equal(t, "bob", pending[0].Consumer)
+	assert.Equal(t, uint64(2), pending[0].DeliveryCount)
+
+	// Deleted entries are dropped from the PEL instead of being claimed
+	s.XDel([]StreamID{{2, 0}})
+	next, claimed, deleted, err := s.AutoClaim("g", "carol", StreamID{}, 10, ClaimOptions{RetryCount: -1, JustID: true})
+	require.NoError(t, err)
+	assert.Equal(t, StreamID{}, next)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, []StreamID{{2, 0}}, deleted)
+	pending, _ = s.Pending("g")
+	require.Len(t, pending, 2)
+	assert.Equal(t, "carol", pending[0].Consumer)
+	assert.Equal(t, uint64(2), pending[0].DeliveryCount)
+
+	// COUNT limits the scan and returns the cursor to resume from
+	next, claimed, _, err = s.AutoClaim("g", "dave", StreamID{}, 1, ClaimOptions{RetryCount: -1})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, StreamID{3, 0}, next)
+}
+
+func TestStreamGroupRestore(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0})
+	require.NoError(t, s.CreateGroup("g", StreamID{}, 0))
+	_, err := s.ReadGroup("g", "alice", true, StreamID{}, 0, false)
+	require.NoError(t, err)
+	_, err = s.CreateConsumer("g", "idle")
+	require.NoError(t, err)
+
+	restored := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0})
+	for _, g := range s.Groups() {
+		restored.RestoreGroup(g)
+	}
+	assert.Equal(t, s.Groups(), restored.Groups())
+
+	acked, err := restored.Ack("g", []StreamID{{1, 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, acked)
+}
+
+func TestStreamTrimAndSetID(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0}, StreamID{3, 0}, StreamID{4, 0})
+
+	assert.Equal(t, 1, s.TrimMinID(StreamID{3, 0}, 1))
+	assert.Equal(t, 1, s.TrimMinID(StreamID{3, 0}, 0))
+	assert.Equal(t, 0, s.TrimMaxLen(5, 0))
+	assert.Equal(t, 1, s.TrimMaxLen(1, 0))
+
+	id, err := s.XAddAutoSeq(4, nil)
+	require.NoError(t, err)
+	assert.Equal(t, StreamID{4, 1}, id)
+	_, err = s.XAddAutoSeq(3, nil)
+	assert.Error(t, err)
+	zero := StreamID{}
+	_, err = s.XAdd(&zero, nil)
+	assert.EqualError(t, err, "ERR The ID specified in XADD must be greater than 0-0")
+
+	rev := s.XRevRange(StreamID{9, 0}, StreamID{}, 1)
+	require.Len(t, rev, 1)
+	assert.Equal(t, StreamID{4, 1}, rev[0].ID)
+
+	assert.EqualError(t, s.SetID(StreamID{4, 0}, nil, nil), "ERR The ID specified in XSETID is smaller than the target stream top item")
+	added := uint64(1)
+	assert.Error(t, s.SetID(StreamID{5, 0}, &added, nil))
+	added = 10
+	maxDeleted := StreamID{4, 5}
+	require.NoError(t, s.SetID(StreamID{5, 0}, &added, &maxDeleted))
+	assert.Equal(t, StreamMeta{
+		Length:       2,
+		FirstID:      StreamID{4, 0},
+		LastID:       StreamID{5, 0},
+		MaxDeletedID: StreamID{4, 5},
+		EntriesAdded: 10,
+	}, s.Meta())
+}
+
+func TestStreamGroupLag(t *testing.T) {
+	s := newGroupTestStream(t, StreamID{1, 0}, StreamID{2, 0}, StreamID{3, 0})
+	require.NoError(t, s.CreateGroup("g", StreamID{}, 0))
+	info, _ := s.Group("g")
+	assert.Equal(t, int64(3), info.Lag)
+
+	_, err := s.ReadGroup("g", "c", true, StreamID{}, 1, true)
+	require.NoError(t, err)
+	info, _ = s.Group("g")
+	assert.Equal(t, int64(1), info.EntriesRead)
+	assert.Equal(t, int64(2), info.Lag)
+
+	// A deletion after the last delivered ID makes the lag unknown
+	s.XDel([]StreamID{{2, 0}})
+	info, _ = s.Group("g")
+	assert.Equal(t, int64(-1), info.Lag)
+
+	// Reading up to the last entry makes it known again
+	_, err = s.ReadGroup("g", "c", true, StreamID{}, 0, true)
+	require.NoError(t, err)
+	info, _ = s.Group("g")
+	assert.Equal(t, int64(3), info.EntriesRead)
+	assert.Equal(t, int64(0), info.Lag)
+}
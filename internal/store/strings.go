@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// MaxStringLength is the longest string SETRANGE may build, Redis's default
+// proto-max-bulk-len
+const MaxStringLength = 512 * 1024 * 1024
+
+// ErrStringTooLong is returned when a write would grow a string past
+// MaxStringLength
+var ErrStringTooLong = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// SetCondition restricts SetWithOptions to missing or existing keys
+type SetCondition uint8
+
+const (
+	SetAlways    SetCondition = iota
+	SetIfAbsent               // NX
+	SetIfPresent              // XX
+)
+
+// SetOptions are the modifiers of SET
+type SetOptions struct {
+	Condition  SetCondition
+	Expiration time.Time // Zero means the value does not expire
+	KeepTTL    bool      // Keep the expiration of the current value
+	Get        bool      // The old value is wanted, so it must be a string
+}
+
+// SetResult reports what SetWithOptions found and did
+type SetResult struct {
+	Old     string // Previous value when it was a string
+	Existed bool   // The key held a live value of any type
+	Written bool
+}
+
+// live reports whether it is present and not expired at now
+func live(it UltraOptimizedItem, ok bool, now int64) bool {
+	return ok && (it.Expiration == 0 || now <= it.Expiration)
+}
+
+// SetWithOptions stores the string value at key under the conditions of
+// opts, checking and writing under a single shard lock. With opts.Get a key
+// of another type fails with ErrWrongType and is left untouched.
+func (db *UltraOptimizedDB) SetWithOptions(key, value string, opts SetOptions) (SetResult, error) {
+	s := db.shardFor(key)
+	now := time.Now().UnixNano()
+	var res SetResult
+
+	s.mu.Lock()
+	cur, ok := s.m[key]
+	res.Existed = live(cur, ok, now)
+	if res.Existed {
+		if cur.DataType == TypeString {
+			res.Old = cur.Value
+		} else if opts.Get {
+			s.mu.Unlock()
+			return res, ErrWrongType
+		}
+	}
+	if (opts.Condition == SetIfAbsent && res.Existed) || (opts.Condition == SetIfPresent && !res.Existed) {
+		s.mu.Unlock()
+		return res, nil
+	}
+	it := UltraOptimizedItem{Value: value, DataType: TypeString}
+	if !opts.Expiration.IsZero() {
+		it.Expiration = opts.Expiration.UnixNano()
+	} else if opts.KeepTTL && res.Existed {
+		it.Expiration = cur.Expiration
+	}
+	db.put(s, key, it)
+	s.mu.Unlock()
+
+	if ok && !res.Existed {
+		// The key was replaced because it had expired
+		db.NotifyKeyEvent("expired", key)
+	}
+	res.Written = true
+	return res, nil
+}
+
+// GetDel removes the string stored at key and returns it
+func (db *UltraOptimizedDB) GetDel(key string) (string, bool, error) {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	if !live(it, ok, time.Now().UnixNano()) {
+		return "", false, nil
+	}
+	if it.DataType != TypeString {
+		return "", false, ErrWrongType
+	}
+	db.remove(s, key)
+	return it.Value, true, nil
+}
+
+// GetEx returns the string stored at key and changes its expiration: a
+// non-zero expiration replaces it and persist removes it
+func (db *UltraOptimizedDB) GetEx(key string, expiration time.Time, persist bool) (string, bool, error) {
+	s := db.shardFor(key)
+	s.mu.Lock()
+	it, ok := s.m[key]
+	if !live(it, ok, time.Now().UnixNano()) {
+		s.mu.Unlock()
+		return "", false, nil
+	}
+	if it.DataType != TypeString {
+		s.mu.Unlock()
+		return "", false, ErrWrongType
+	}
+	switch {
+	case !expiration.IsZero():
+		it.Expiration = expiration.UnixNano()
+		db.put(s, key, it)
+	case persist && it.Expiration != 0:
+		it.Expiration = 0
+		db.put(s, key, it)
+	}
+	s.mu.Unlock()
+	db.touch(s, key, it)
+	return it.Value, true, nil
+}
+
+// SetRange overwrites the string stored at key starting at offset, padding
+// it with zero bytes as needed, and returns its new length. The expiration
+// of the key is kept. An empty value leaves the key untouched.
+func (db *UltraOptimizedDB) SetRange(key string, offset int, value string) (int, error) {
+	if len(value) > 0 && offset+len(value) > MaxStringLength {
+		return 0, ErrStringTooLong
+	}
+	s := db.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.m[key]
+	if !live(it, ok, time.Now().UnixNano()) {
+		it = UltraOptimizedItem{DataType: TypeString}
+	} else if it.DataType != TypeString {
+		return 0, ErrWrongType
+	}
+	if len(value) == 0 {
+		return len(it.Value), nil
+	}
+
+	n := len(it.Value)
+	if end := offset + len(value); end > n {
+		n = end
+	}
+	buf := make([]byte, n)
+	copy(buf, it.Value)
+	copy(buf[offset:], value)
+	it.Value = string(buf)
+	it.size = 0
+	db.put(s, key, it)
+	return n, nil
+}
+
+// MSetNX stores every pair unless any of the keys exists. The shards of all
+// keys are held together, so no key can appear between check and write.
+func (db *UltraOptimizedDB) MSetNX(pairs [][2]string) bool {
+	indexes := make([]int, 0, len(pairs))
+	seen := make(map[uint64]struct{}, len(pairs))
+	for _, p := range pairs {
+		idx := db.shardIndex(p[0])
+		if _, ok := seen[idx]; !ok {
+			seen[idx] = struct{}{}
+			indexes = append(indexes, int(idx))
+		}
+	}
+	// Lock in shard order so that concurrent MSETNX calls cannot deadlock
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		db.shards[i].mu.Lock()
+	}
+	defer func() {
+		for _, i := range indexes {
+			db.shards[i].mu.Unlock()
+		}
+	}()
+
+	now := time.Now().UnixNano()
+	for _, p := range pairs {
+		it, ok := db.shardFor(p[0]).m[p[0]]
+		if live(it, ok, now) {
+			return false
+		}
+	}
+	for _, p := range pairs {
+		db.put(db.shardFor(p[0]), p[0], UltraOptimizedItem{Value: p[1], DataType: TypeString})
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithOptions(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	res, err := db.SetWithOptions("k", "v1", SetOptions{Condition: SetIfPresent})
+	require.NoError(t, err)
+	assert.False(t, res.Written)
+	assert.False(t, db.Exists("k"))
+
+	res, err = db.SetWithOptions("k", "v1", SetOptions{Condition: SetIfAbsent, Expiration: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.True(t, res.Written)
+	assert.Greater(t, db.PTTL("k"), int64(0))
+
+	res, err = db.SetWithOptions("k", "v2", SetOptions{Condition: SetIfAbsent, Get: true})
+	require.NoError(t, err)
+	assert.Equal(t, SetResult{Old: "v1", Existed: true}, res)
+
+	// KEEPTTL keeps the expiration, a plain write drops it
+	_, err = db.SetWithOptions("k", "v3", SetOptions{KeepTTL: true})
+	require.NoError(t, err)
+	assert.Greater(t, db.PTTL("k"), int64(0))
+	_, err = db.SetWithOptions("k", "v4", SetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), db.PTTL("k"))
+
+	// An expired key counts as missing
+	db.Set("old", "v", time.Now().Add(-time.Second))
+	res, err = db.SetWithOptions("old", "new", SetOptions{Condition: SetIfAbsent, Get: true})
+	require.NoError(t, err)
+	assert.Equal(t, SetResult{Written: true}, res)
+
+	_, err = db.EnsureList("list")
+	require.NoError(t, err)
+	_, err = db.SetWithOptions("list", "v", SetOptions{Get: true})
+	assert.ErrorIs(t, err, ErrWrongType)
+	assert.Equal(t, TypeList, db.GetDataType("list"))
+	res, err = db.SetWithOptions("list", "v", SetOptions{})
+	require.NoError(t, err)
+	assert.True(t, res.Existed)
+	assert.Equal(t, TypeString, db.GetDataType("list"))
+}
+
+func TestSetIfAbsentIsExclusive(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := db.SetWithOptions("lock", "token", SetOptions{Condition: SetIfAbsent})
+			if err == nil && res.Written {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, winners)
+}
+
+func TestGetDelAndGetEx(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	db.Set("k", "v", time.Time{})
+	value, ok, err := db.GetEx("k", time.Now().Add(time.Minute), false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v", value)
+	assert.Greater(t, db.TTL("k"), int64(0))
+
+	_, _, err = db.GetEx("k", time.Time{}, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), db.TTL("k"))
+
+	value, ok, err = db.GetDel("k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v", value)
+	assert.False(t, db.Exists("k"))
+
+	_, ok, err = db.GetDel("k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = db.EnsureSet("set")
+	require.NoError(t, err)
+	_, _, err = db.GetDel("set")
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, _, err = db.GetEx("set", time.Time{}, true)
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestSetRange(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	n, err := db.SetRange("k", 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, db.Exists("k"))
+
+	n, err = db.SetRange("k", 3, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	value, _ := db.Get("k")
+	assert.Equal(t, "\x00\x00\x00abc", value)
+
+	db.Set("greeting", "Hello World", time.Now().Add(time.Minute))
+	n, err = db.SetRange("greeting", 6, "Redis")
+	require.NoError(t, err)
+	assert.Equal(t, 11, n)
+	value, _ = db.Get("greeting")
+	assert.Equal(t, "Hello Redis", value)
+	assert.Greater(t, db.TTL("greeting"), int64(0))
+
+	_, err = db.SetRange("k", MaxStringLength, "x")
+	assert.ErrorIs(t, err, ErrStringTooLong)
+}
+
+func TestMSetNX(t *testing.T) {
+	db := NewUltraOptimizedDB()
+	defer db.Close()
+
+	assert.True(t, db.MSetNX([][2]string{{"a", "1"}, {"b", "2"}}))
+	assert.False(t, db.MSetNX([][2]string{{"c", "3"}, {"a", "x"}}))
+	assert.False(t, db.Exists("c"))
+	value, _ := db.Get("a")
+	assert.Equal(t, "1", value)
+}
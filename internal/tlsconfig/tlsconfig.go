@@ -0,0 +1,96 @@
+// Package tlsconfig builds the TLS configurations of the server listener
+// and of the connections the server, the CLI and the benchmark dial.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ClientAuth values, as tls-auth-clients takes them
+const (
+	ClientAuthNo       = "no"
+	ClientAuthOptional = "optional"
+	ClientAuthYes      = "yes"
+)
+
+// ParseClientAuth returns the client certificate policy named by value:
+// no, optional or yes; empty means yes
+func ParseClientAuth(value string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(value) {
+	case ClientAuthNo:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthYes, "":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("invalid tls-auth-clients %q, must be no, optional or yes", value)
+}
+
+// Server returns the config of a TLS listener presenting the certificate
+// in certFile and keyFile. Client certificates are verified against the
+// CA certificates in caFile and required as clientAuth says.
+func Server(certFile, keyFile, caFile string, clientAuth tls.ClientAuthType) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file are required")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientAuth != tls.NoClientCert {
+		if caFile == "" {
+			return nil, errors.New("tls-ca-cert-file is required to authenticate clients")
+		}
+		if config.ClientCAs, err = loadCAs(caFile); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// Client returns the config of a connection to a TLS server. The server
+// certificate is verified against the CA certificates in caFile, or the
+// system ones when it is empty, unless insecure is set. certFile and
+// keyFile, when set, are the certificate presented to the server.
+func Client(caFile, certFile, keyFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		roots, err := loadCAs(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = roots
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// loadCAs reads the PEM encoded CA certificates in path
+func loadCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no CA certificates found in %s", path)
+	}
+	return pool, nil
+}
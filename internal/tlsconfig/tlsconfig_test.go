@@ -0,0 +1,47 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClientAuth(t *testing.T) {
+	for value, want := range map[string]tls.ClientAuthType{
+		"no":       tls.NoClientCert,
+		"optional": tls.VerifyClientCertIfGiven,
+		"YES":      tls.RequireAndVerifyClientCert,
+		"":         tls.RequireAndVerifyClientCert,
+	} {
+		got, err := ParseClientAuth(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	_, err := ParseClientAuth("maybe")
+	assert.EqualError(t, err, `invalid tls-auth-clients "maybe", must be no, optional or yes`)
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	bogus := filepath.Join(dir, "bogus.pem")
+	require.NoError(t, os.WriteFile(bogus, []byte("not a certificate"), 0600))
+
+	_, err := Server("", "", "", tls.NoClientCert)
+	assert.EqualError(t, err, "tls-cert-file and tls-key-file are required")
+	_, err = Server(bogus, bogus, "", tls.NoClientCert)
+	assert.ErrorContains(t, err, "failed to load certificate")
+
+	_, err = Client(bogus, "", "", false)
+	assert.EqualError(t, err, "no CA certificates found in "+bogus)
+	_, err = Client(filepath.Join(dir, "missing.pem"), "", "", false)
+	assert.ErrorContains(t, err, "failed to load CA certificates")
+
+	config, err := Client("", "", "", true)
+	require.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+	assert.Nil(t, config.RootCAs)
+}